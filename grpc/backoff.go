@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+)
+
+// defaultMinConnectTimeout is the gRPC's default minimal timeout of a connection attempt
+const defaultMinConnectTimeout = 20 * time.Second
+
+// backoffParams is the parameters of the reconnections' backoff, so the reconnect storms
+// (e.g. after a rolling restart of the target) could be modelled like the production clients do.
+type backoffParams struct {
+	grpc.ConnectParams
+}
+
+// newBackoffParams constructs the backoff parameters from the input value,
+// the unset ones are the gRPC's defaults.
+func newBackoffParams(rt *goja.Runtime, input goja.Value) (*backoffParams, error) {
+	if common.IsNullish(input) {
+		return nil, nil //nolint:nilnil
+	}
+
+	result := &backoffParams{
+		ConnectParams: grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: defaultMinConnectTimeout,
+		},
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf("invalid backoff value: '%#v', it needs to be an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "baseDelay", "maxDelay", "minConnectTimeout":
+			d, err := types.GetDurationValue(v)
+			if err != nil || d <= 0 {
+				return result, fmt.Errorf("invalid backoff %s value: '%#v', it needs to be a positive duration", k, v)
+			}
+
+			switch k {
+			case "baseDelay":
+				result.Backoff.BaseDelay = d
+			case "maxDelay":
+				result.Backoff.MaxDelay = d
+			default:
+				result.MinConnectTimeout = d
+			}
+		case "multiplier":
+			m, ok := toFloat64(v)
+			if !ok || m < 1 {
+				return result, fmt.Errorf("invalid backoff multiplier value: '%#v', it needs to be a number of at least 1", v)
+			}
+			result.Backoff.Multiplier = m
+		case "jitter":
+			j, ok := toFloat64(v)
+			if !ok || j < 0 || j > 1 {
+				return result, fmt.Errorf("invalid backoff jitter value: '%#v', it needs to be a number between 0 and 1", v)
+			}
+			result.Backoff.Jitter = j
+		default:
+			return result, fmt.Errorf("unknown backoff param: %q", k)
+		}
+	}
+
+	if result.Backoff.MaxDelay < result.Backoff.BaseDelay {
+		return result, fmt.Errorf("invalid backoff maxDelay value: %s, it needs to be at least the baseDelay %s",
+			result.Backoff.MaxDelay, result.Backoff.BaseDelay)
+	}
+
+	return result, nil
+}
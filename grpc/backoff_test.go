@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/backoff"
+)
+
+func TestNewBackoffParams(t *testing.T) {
+	t.Parallel()
+
+	rt := goja.New()
+	parse := func(code string) (*backoffParams, error) {
+		t.Helper()
+
+		v, err := rt.RunString("(" + code + ")")
+		require.NoError(t, err)
+
+		return newBackoffParams(rt, v)
+	}
+
+	p, err := parse(`undefined`)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	p, err = parse(`{ baseDelay: "500ms", multiplier: 2, jitter: 0.1, maxDelay: "30s", minConnectTimeout: "5s" }`)
+	require.NoError(t, err)
+	assert.Equal(t, backoff.Config{
+		BaseDelay:  500 * time.Millisecond,
+		Multiplier: 2,
+		Jitter:     0.1,
+		MaxDelay:   30 * time.Second,
+	}, p.Backoff)
+	assert.Equal(t, 5*time.Second, p.MinConnectTimeout)
+
+	p, err = parse(`{ maxDelay: "10s" }`)
+	require.NoError(t, err)
+	assert.Equal(t, backoff.DefaultConfig.BaseDelay, p.Backoff.BaseDelay)
+	assert.Equal(t, defaultMinConnectTimeout, p.MinConnectTimeout)
+
+	for code, errContains := range map[string]string{
+		`"fast"`:                   `invalid backoff value`,
+		`{ baseDelay: "-1s" }`:     `invalid backoff baseDelay value`,
+		`{ multiplier: 0.5 }`:      `invalid backoff multiplier value`,
+		`{ jitter: 2 }`:            `invalid backoff jitter value`,
+		`{ maxDelay: "100ms" }`:    `it needs to be at least the baseDelay`,
+		`{ retries: 3 }`:           `unknown backoff param: "retries"`,
+		`{ minConnectTimeout: 0 }`: `invalid backoff minConnectTimeout value`,
+	} {
+		_, err = parse(code)
+		assert.ErrorContains(t, err, errContains, code)
+	}
+}
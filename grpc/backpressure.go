@@ -0,0 +1,105 @@
+package grpc
+
+// writableState is the stream's writing state given to js, e.g. to pause the writes until the drain event
+type writableState struct {
+	// Writable is set if the stream accepts the writes, it's open and its buffer isn't full
+	Writable bool `js:"writable"`
+	// Buffered is the count of the written messages waiting to be sent to the server
+	Buffered int64 `js:"buffered"`
+	// Limit is the maxBufferedWrites, zero if the buffer is unlimited
+	Limit int64 `js:"limit"`
+}
+
+// limitBuffers limits the stream's buffered messages, the unlimited ones are left nil
+func (s *stream) limitBuffers(p *callParams) {
+	if p.MaxBufferedWrites > 0 {
+		s.writeSlots = make(chan struct{}, p.MaxBufferedWrites)
+	}
+
+	if p.MaxBufferedReads > 0 {
+		s.readSlots = make(chan struct{}, p.MaxBufferedReads)
+	}
+}
+
+// getWritableState returns the stream's writing state
+func (s *stream) getWritableState() writableState {
+	return writableState{
+		Writable: s.writingState == opened && (s.writeSlots == nil || len(s.writeSlots) < cap(s.writeSlots)),
+		Buffered: s.buffered.Load(),
+		Limit:    int64(cap(s.writeSlots)),
+	}
+}
+
+// tryBufferWrite takes a slot of the write buffer without blocking the event loop,
+// if the buffer is full the drain event is emitted once there's a room again
+func (s *stream) tryBufferWrite() bool {
+	if s.writeSlots != nil {
+		select {
+		case s.writeSlots <- struct{}{}:
+		default:
+			s.drain.Store(true)
+
+			return false
+		}
+	}
+
+	s.buffered.Add(1)
+
+	return true
+}
+
+// bufferWrite takes a slot of the write buffer, waiting for it until the stream is closed
+func (s *stream) bufferWrite() bool {
+	if s.writeSlots != nil {
+		select {
+		case s.writeSlots <- struct{}{}:
+		case <-s.done:
+			return false
+		}
+	}
+
+	s.buffered.Add(1)
+
+	return true
+}
+
+// releaseWrite releases the slot of the message sent to the server
+func (s *stream) releaseWrite() {
+	s.buffered.Add(-1)
+
+	if s.writeSlots == nil {
+		return
+	}
+
+	<-s.writeSlots
+
+	if s.drain.CompareAndSwap(true, false) {
+		s.tq.Queue(func() error {
+			return s.callEventListeners(eventDrain, struct{}{})
+		})
+	}
+}
+
+// bufferRead takes a slot of the read buffer, the stream isn't read (so the server's flow control
+// applies) while the event loop is handling the buffered messages
+func (s *stream) bufferRead() bool {
+	if s.readSlots == nil {
+		return true
+	}
+
+	select {
+	case s.readSlots <- struct{}{}:
+		return true
+	case <-s.done:
+		return false
+	case <-s.vu.Context().Done():
+		return false
+	}
+}
+
+// releaseRead releases the slot of the message handled by the event loop
+func (s *stream) releaseRead() {
+	if s.readSlots != nil {
+		<-s.readSlots
+	}
+}
@@ -0,0 +1,38 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_WriteBufferLimit(t *testing.T) {
+	t.Parallel()
+
+	s := &stream{writingState: opened}
+	s.limitBuffers(&callParams{MaxBufferedWrites: 2})
+
+	assert.True(t, s.tryBufferWrite())
+	assert.True(t, s.tryBufferWrite())
+	assert.Equal(t, writableState{Writable: false, Buffered: 2, Limit: 2}, s.getWritableState())
+
+	// the rejected write is signaled with the drain once there's a room again
+	assert.False(t, s.tryBufferWrite())
+	assert.True(t, s.drain.Load())
+
+	s.buffered.Add(-1)
+	<-s.writeSlots
+	assert.Equal(t, writableState{Writable: true, Buffered: 1, Limit: 2}, s.getWritableState())
+}
+
+func TestStream_WriteBufferUnlimited(t *testing.T) {
+	t.Parallel()
+
+	s := &stream{writingState: opened}
+	s.limitBuffers(&callParams{})
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, s.tryBufferWrite())
+	}
+	assert.Equal(t, writableState{Writable: true, Buffered: 10}, s.getWritableState())
+}
@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/js/common"
+)
+
+// newLoadBalancingParams constructs the load balancing policy from the input value,
+// either the policy's name or an object with the policy and its config.
+func newLoadBalancingParams(rt *goja.Runtime, input goja.Value) (*grpcext.LoadBalancing, error) {
+	if common.IsNullish(input) {
+		return nil, nil //nolint:nilnil
+	}
+
+	result := &grpcext.LoadBalancing{}
+
+	switch v := input.Export().(type) {
+	case string:
+		result.Policy = v
+	case map[string]interface{}:
+		params := input.ToObject(rt)
+
+		for _, k := range params.Keys() {
+			if k == "policy" {
+				policy, ok := params.Get(k).Export().(string)
+				if !ok {
+					return result, fmt.Errorf("invalid loadBalancing policy value: '%#v', it needs to be a string",
+						params.Get(k).Export())
+				}
+				result.Policy = policy
+
+				continue
+			}
+
+			if result.Config == nil {
+				result.Config = make(map[string]interface{})
+			}
+			result.Config[k] = params.Get(k).Export()
+		}
+	default:
+		return result, fmt.Errorf("invalid loadBalancing value: '%#v', it needs to be a string or an object", v)
+	}
+
+	if result.Policy == "" {
+		return result, errors.New("invalid loadBalancing value: it needs a policy")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// AbortController cancels the calls made with its signal, like the web's AbortController,
+// so the scripts could model the clients giving up early.
+type AbortController struct {
+	Signal *AbortSignal `js:"signal"`
+}
+
+// AbortSignal is the signal of the AbortController, passed as the calls' signal param.
+type AbortSignal struct {
+	Aborted bool `js:"aborted"`
+
+	once sync.Once
+	done chan struct{}
+}
+
+// newAbortController is the JS constructor of the AbortController
+func (mi *ModuleInstance) newAbortController(_ goja.ConstructorCall) *goja.Object {
+	rt := mi.vu.Runtime()
+
+	return rt.ToValue(&AbortController{
+		Signal: &AbortSignal{done: make(chan struct{})},
+	}).ToObject(rt)
+}
+
+// Abort cancels the calls in flight with the signal and the following ones
+func (a *AbortController) Abort() {
+	a.Signal.once.Do(func() {
+		a.Signal.Aborted = true
+		close(a.Signal.done)
+	})
+}
+
+// cancellable returns the context cancelled by the call's signal or after its cancelAfter,
+// the returned func releases the resources once the call ends.
+func (p *callParams) cancellable(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.Signal == nil && p.CancelAfter == 0 {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	var timer *time.Timer
+	if p.CancelAfter > 0 {
+		timer = time.AfterFunc(p.CancelAfter, cancel)
+	}
+
+	if p.Signal != nil {
+		go func() {
+			select {
+			case <-p.Signal.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	return ctx, func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		cancel()
+	}
+}
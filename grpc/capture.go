@@ -0,0 +1,270 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+	"google.golang.org/grpc/codes"
+)
+
+// maxCaptures is the capacity of the captured calls' ring buffer, the oldest ones are overwritten
+const maxCaptures = 100
+
+// redacted replaces the values of the credential-bearing metadata in the captured calls
+const redacted = "[REDACTED]"
+
+// The reasons of the calls' capture.
+const (
+	captureFailed = "failed"
+	captureSlow   = "slow"
+)
+
+// captureParams is the parameters of the failed (and the slow) calls' capture.
+type captureParams struct {
+	// SlowerThan captures the calls exceeding the latency too, zero captures the failed ones only
+	SlowerThan time.Duration
+}
+
+// newCaptureParams constructs the capture parameters from the input value.
+func newCaptureParams(rt *goja.Runtime, input goja.Value) (*captureParams, error) {
+	result := &captureParams{}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	if v, ok := input.Export().(bool); ok {
+		if !v {
+			return nil, nil //nolint:nilnil
+		}
+
+		return result, nil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf("invalid capture value: '%#v', it needs to be boolean or an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "slowerThan":
+			var err error
+			result.SlowerThan, err = types.GetDurationValue(v)
+			if err != nil || result.SlowerThan <= 0 {
+				return result, fmt.Errorf("invalid capture slowerThan value: '%#v', it needs to be a positive duration", v)
+			}
+		default:
+			return result, fmt.Errorf("unknown capture param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// capturedCall is the call captured for the post-mortem, its messages are kept as JSON,
+// so they're converted into the new objects of the VU reading them.
+type capturedCall struct {
+	method    string
+	reason    string
+	vu        uint64
+	iteration int64
+	time      time.Time
+	duration  time.Duration
+	status    codes.Code
+	metadata  json.RawMessage
+	headers   json.RawMessage
+	trailers  json.RawMessage
+	request   json.RawMessage
+	response  json.RawMessage
+	err       json.RawMessage
+}
+
+// captures is the ring buffer of the calls captured by all the VUs, e.g. to dump them in the teardown.
+// The zero value is ready to use.
+type captures struct {
+	mu      sync.Mutex
+	entries []*capturedCall
+	next    int
+}
+
+func (c *captures) add(call *capturedCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) < maxCaptures {
+		c.entries = append(c.entries, call)
+		return
+	}
+
+	c.entries[c.next] = call
+	c.next = (c.next + 1) % maxCaptures
+}
+
+// list returns the captured calls, the oldest first
+func (c *captures) list() []*capturedCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := make([]*capturedCall, 0, len(c.entries))
+	list = append(list, c.entries[c.next:]...)
+
+	return append(list, c.entries[:c.next]...)
+}
+
+// captures returns the calls captured by all the VUs' clients with the capture enabled, the oldest first.
+func (mi *ModuleInstance) captures() (*goja.Object, error) {
+	rt := mi.vu.Runtime()
+	list := mi.captured.list()
+	values := make([]interface{}, 0, len(list))
+
+	for _, call := range list {
+		date, err := rt.New(rt.Get("Date"), rt.ToValue(call.time.UnixNano()/int64(time.Millisecond)))
+		if err != nil {
+			return nil, err
+		}
+
+		obj := rt.NewObject()
+		for k, v := range map[string]interface{}{
+			"method":    call.method,
+			"reason":    call.reason,
+			"vu":        call.vu,
+			"iteration": call.iteration,
+			"time":      date,
+			"duration":  float64(call.duration) / float64(time.Millisecond),
+			"status":    call.status,
+			"metadata":  decodeCaptured(call.metadata),
+			"headers":   decodeCaptured(call.headers),
+			"trailers":  decodeCaptured(call.trailers),
+			"request":   decodeCaptured(call.request),
+			"response":  decodeCaptured(call.response),
+			"error":     decodeCaptured(call.err),
+		} {
+			if err = obj.Set(k, v); err != nil {
+				return nil, err
+			}
+		}
+
+		values = append(values, obj)
+	}
+
+	return rt.NewArray(values...), nil
+}
+
+func decodeCaptured(b json.RawMessage) interface{} {
+	if b == nil {
+		return nil
+	}
+
+	var v interface{}
+	_ = json.Unmarshal(b, &v)
+
+	return v
+}
+
+// captureCall captures the call if it has failed or exceeded the capture's latency,
+// b is the serialised request, the marshaled ones are captured as the base64.
+func (c *Client) captureCall(method string, p *callParams, b []byte, resp *grpcext.Response, start time.Time) {
+	if c.capture == nil {
+		return
+	}
+
+	duration := time.Since(start)
+
+	var reason string
+	switch {
+	case p.ExpectedStatuses.failed(resp.Status):
+		reason = captureFailed
+	case c.capture.SlowerThan > 0 && duration > c.capture.SlowerThan:
+		reason = captureSlow
+	default:
+		return
+	}
+
+	state := c.vu.State()
+	call := &capturedCall{
+		method:    method,
+		reason:    reason,
+		vu:        state.VUID,
+		iteration: state.Iteration,
+		time:      start,
+		duration:  duration,
+		status:    resp.Status,
+		request:   encodeCaptured(b),
+	}
+
+	// the captures are kept for the whole test and readable by any VU, so the credentials aren't kept
+	call.metadata, _ = json.Marshal(redactMetadata(p.Metadata))
+	call.headers, _ = json.Marshal(redactMetadata(resp.Headers))
+	call.trailers, _ = json.Marshal(redactMetadata(resp.Trailers))
+
+	if resp.Error != nil {
+		call.err, _ = json.Marshal(resp.Error)
+	}
+
+	// the lazy messages are converted for the capture, the marshaled ones are captured as the base64
+	msg := resp.Message
+	if msg == nil && resp.JSON != nil {
+		msg, _ = resp.JSON()
+	}
+	if msg != nil {
+		call.response, _ = json.Marshal(msg)
+	}
+
+	c.captured.add(call)
+}
+
+// encodeCaptured returns the JSON request as it is, or the marshaled protobuf as the base64 string
+func encodeCaptured(b []byte) json.RawMessage {
+	if json.Valid(b) {
+		return append(json.RawMessage(nil), b...)
+	}
+
+	encoded, _ := json.Marshal(b)
+
+	return encoded
+}
+
+// redactMetadata returns a copy of the metadata with the credential-bearing values redacted
+func redactMetadata(md map[string][]string) map[string][]string {
+	if md == nil {
+		return nil
+	}
+
+	result := make(map[string][]string, len(md))
+	for k, values := range md {
+		if !isCredentialKey(k) {
+			result[k] = values
+			continue
+		}
+
+		masked := make([]string, len(values))
+		for i := range masked {
+			masked[i] = redacted
+		}
+		result[k] = masked
+	}
+
+	return result
+}
+
+// isCredentialKey tells whether the metadata key carries the credentials, e.g. the authorization or a token
+func isCredentialKey(key string) bool {
+	key = strings.ToLower(key)
+	switch key {
+	case "authorization", "proxy-authorization", "cookie", "set-cookie", "x-api-key":
+		return true
+	}
+
+	return strings.HasSuffix(key, "-token") || strings.HasSuffix(key, "_token")
+}
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptures_RingBuffer(t *testing.T) {
+	t.Parallel()
+
+	var c captures
+	for i := 0; i < maxCaptures+3; i++ {
+		c.add(&capturedCall{method: strconv.Itoa(i)})
+	}
+
+	list := c.list()
+	require.Len(t, list, maxCaptures)
+
+	// the oldest calls are overwritten, the others are listed in their order
+	assert.Equal(t, "3", list[0].method)
+	assert.Equal(t, strconv.Itoa(maxCaptures+2), list[maxCaptures-1].method)
+}
+
+func TestRedactMetadata(t *testing.T) {
+	t.Parallel()
+
+	md := map[string][]string{
+		"Authorization":   {"Bearer secret"},
+		"cookie":          {"session=secret"},
+		"x-session-token": {"a", "b"},
+		"x-api-key":       {"secret"},
+		"x-request-id":    {"42"},
+	}
+
+	assert.Equal(t, map[string][]string{
+		"Authorization":   {redacted},
+		"cookie":          {redacted},
+		"x-session-token": {redacted, redacted},
+		"x-api-key":       {redacted},
+		"x-request-id":    {"42"},
+	}, redactMetadata(md))
+
+	// the call's metadata isn't changed
+	assert.Equal(t, []string{"Bearer secret"}, md["Authorization"])
+	assert.Nil(t, redactMetadata(nil))
+}
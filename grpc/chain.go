@@ -0,0 +1,259 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/js/common"
+	"google.golang.org/grpc/codes"
+)
+
+// the kinds of the chain's sources and targets
+const (
+	chainHeader   = "header"
+	chainTrailer  = "trailer"
+	chainField    = "field"
+	chainMetadata = "metadata"
+)
+
+// chainDefaultMaxCalls is the default limit of the calls made by the chain
+const chainDefaultMaxCalls = 100
+
+// chainRef references a value in a response (source) or in a request (target),
+// e.g. "trailer:x-next-token" or "field:page.token".
+type chainRef struct {
+	Kind string
+	Name string
+}
+
+func parseChainRef(v interface{}, kinds ...string) (chainRef, error) {
+	s, ok := v.(string)
+	if !ok {
+		return chainRef{}, fmt.Errorf("'%#v', it needs to be a string", v)
+	}
+
+	kind, name, ok := strings.Cut(s, ":")
+	if !ok || name == "" {
+		return chainRef{}, fmt.Errorf("%q, it needs to be in the <kind>:<name> format", s)
+	}
+
+	for _, k := range kinds {
+		if k == kind {
+			return chainRef{Kind: kind, Name: name}, nil
+		}
+	}
+
+	return chainRef{}, fmt.Errorf("%q, the kind needs to be one of %s", s, strings.Join(kinds, ", "))
+}
+
+// chainParams is the parameters of the client.chain().
+type chainParams struct {
+	From     chainRef
+	To       chainRef
+	MaxCalls int64
+
+	Call *callParams
+}
+
+// newChainParams constructs the chain parameters from the input value,
+// the keys other than the chain ones are parsed as the call parameters.
+func newChainParams(c *Client, input goja.Value) (*chainParams, error) {
+	if common.IsNullish(input) {
+		return nil, errors.New("from and to are required")
+	}
+
+	rt := c.vu.Runtime()
+	params := input.ToObject(rt)
+	callInput := rt.NewObject()
+	result := &chainParams{
+		MaxCalls: chainDefaultMaxCalls,
+	}
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "from":
+			var err error
+			if result.From, err = parseChainRef(v, chainHeader, chainTrailer, chainField); err != nil {
+				return nil, fmt.Errorf("invalid from value: %w", err)
+			}
+		case "to":
+			var err error
+			if result.To, err = parseChainRef(v, chainMetadata, chainField); err != nil {
+				return nil, fmt.Errorf("invalid to value: %w", err)
+			}
+		case "maxCalls":
+			var ok bool
+			result.MaxCalls, ok = v.(int64)
+			if !ok || result.MaxCalls <= 0 {
+				return nil, fmt.Errorf("invalid maxCalls value: '%#v', it needs to be a positive integer", v)
+			}
+		default:
+			if err := callInput.Set(k, params.Get(k)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if result.From.Kind == "" || result.To.Kind == "" {
+		return nil, errors.New("from and to are required")
+	}
+
+	var err error
+	if result.Call, err = newCallParams(c.vu, c.extOptions, c.tags, callInput); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// chainResult is the result of the client.chain().
+type chainResult struct {
+	Calls    int64             `js:"calls"`
+	Response *grpcext.Response `js:"response"`
+}
+
+// Chain calls the unary RPC repeatedly, each call gets the value taken from the previous call's
+// response (a header, a trailer or a message field) injected into its metadata or its request's field,
+// e.g. for following the continuation tokens. It stops when the value is missing or empty,
+// when a call fails or when the maxCalls limit is reached, and returns the last response.
+func (c *Client) Chain(method string, req goja.Value, params goja.Value) (*chainResult, error) {
+	state := c.vu.State()
+	if state == nil {
+		return nil, common.NewInitContextError("invoking RPC methods in the init context is not supported")
+	}
+	if c.conn == nil {
+		return nil, errors.New("no gRPC connection, you must call connect first")
+	}
+
+	method = sanitizeMethodName(method)
+	methodDesc, err := c.getMethodDescriptor(method)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newChainParams(c, params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GRPC's client.chain() parameters: %w", err)
+	}
+
+	// k6 GRPC Invoke's default timeout is 2 minutes
+	if p.Call.Timeout == time.Duration(0) && p.Call.Deadline.IsZero() {
+		p.Call.Timeout = 2 * time.Minute
+	}
+
+	if common.IsNullish(req) {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	reqmsg, ok := req.Export().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid request value: '%#v', it needs to be an object", req.Export())
+	}
+
+	result := &chainResult{}
+	md := p.Call.Metadata
+
+	for result.Calls < p.MaxCalls {
+		b, err := json.Marshal(reqmsg)
+		if err != nil {
+			return nil, fmt.Errorf("unable to serialise request object: %w", err)
+		}
+
+		p.Call.Metadata = md.Copy()
+		result.Response, err = c.invoke(method, methodDesc, p.Call, b, false)
+		if err != nil {
+			return nil, err
+		}
+		result.Calls++
+
+		if result.Response.Status != codes.OK {
+			break
+		}
+
+		value, ok := chainValue(result.Response, p.From)
+		if !ok {
+			break
+		}
+
+		if p.To.Kind == chainMetadata {
+			md.Set(p.To.Name, fmt.Sprint(value))
+		} else {
+			setFieldPath(reqmsg, p.To.Name, value)
+		}
+	}
+
+	return result, nil
+}
+
+// chainValue returns the referenced non-empty value of the response
+func chainValue(resp *grpcext.Response, ref chainRef) (interface{}, bool) {
+	var value interface{}
+
+	switch ref.Kind {
+	case chainHeader, chainTrailer:
+		source := resp.Headers
+		if ref.Kind == chainTrailer {
+			source = resp.Trailers
+		}
+
+		values := source[strings.ToLower(ref.Name)]
+		if len(values) == 0 {
+			return nil, false
+		}
+		value = values[0]
+	case chainField:
+		var ok bool
+		if value, ok = getFieldPath(resp.Message, ref.Name); !ok {
+			return nil, false
+		}
+	}
+
+	if value == nil || value == "" {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// getFieldPath returns the value of the message's field referenced by the dot separated path
+func getFieldPath(msg interface{}, path string) (interface{}, bool) {
+	value := msg
+
+	for _, name := range strings.Split(path, ".") {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if value, ok = fields[name]; !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// setFieldPath sets the value of the message's field referenced by the dot separated path,
+// creating the missing intermediate messages
+func setFieldPath(msg map[string]interface{}, path string, value interface{}) {
+	names := strings.Split(path, ".")
+	fields := msg
+
+	for _, name := range names[:len(names)-1] {
+		next, ok := fields[name].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			fields[name] = next
+		}
+		fields = next
+	}
+
+	fields[names[len(names)-1]] = value
+}
@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"google.golang.org/grpc/codes"
+)
+
+// checkStatus reports whether the response (or the stream's end event) has finished with one of the statuses,
+// the OK status if none is given, so it could be used in the k6 checks without the boilerplate.
+func (mi *ModuleInstance) checkStatus(resp goja.Value, statuses ...goja.Value) bool {
+	rt := mi.vu.Runtime()
+
+	if common.IsNullish(resp) {
+		common.Throw(rt, errors.New("invalid checkStatus response: it's empty"))
+	}
+
+	status := resp.ToObject(rt).Get("status")
+	if common.IsNullish(status) {
+		common.Throw(rt, fmt.Errorf("invalid checkStatus response: '%#v', it has no status", resp.Export()))
+	}
+
+	code, ok := toCode(status.Export())
+	if !ok {
+		common.Throw(rt, fmt.Errorf("invalid checkStatus response's status: '%#v'", status.Export()))
+	}
+
+	if len(statuses) == 0 {
+		return code == codes.OK
+	}
+
+	for _, s := range statuses {
+		expected, ok := toCode(s.Export())
+		if !ok {
+			common.Throw(rt, fmt.Errorf("invalid checkStatus status: '%#v', it needs to be a status code", s.Export()))
+		}
+
+		if code == expected {
+			return true
+		}
+	}
+
+	return false
+}
+
+// toCode converts the exported status, the grpc.Status* constant or a number, to the code
+func toCode(v interface{}) (codes.Code, bool) {
+	switch c := v.(type) {
+	case codes.Code:
+		return c, true
+	case int64:
+		return codes.Code(c), c >= 0
+	default:
+		return 0, false
+	}
+}
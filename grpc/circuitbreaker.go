@@ -0,0 +1,222 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// errCircuitOpen is the error message of the calls rejected by an open circuit breaker
+var errCircuitOpen = errors.New("circuit breaker is open") //nolint:gochecknoglobals
+
+// circuitBreakerParams is the parameters of the client-side circuit breaker.
+type circuitBreakerParams struct {
+	MaxFailures int64
+	Cooldown    time.Duration
+}
+
+// newCircuitBreakerParams constructs the circuit breaker parameters from the input value.
+func newCircuitBreakerParams(rt *goja.Runtime, input goja.Value) (*circuitBreakerParams, error) {
+	result := &circuitBreakerParams{
+		MaxFailures: 5,
+		Cooldown:    10 * time.Second,
+	}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf("invalid circuitBreaker value: '%#v', it needs to be an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "maxFailures":
+			var ok bool
+			result.MaxFailures, ok = v.(int64)
+			if !ok || result.MaxFailures <= 0 {
+				return result, fmt.Errorf("invalid circuitBreaker maxFailures value: '%#v', it needs to be a positive integer", v)
+			}
+		case "cooldown":
+			var err error
+			result.Cooldown, err = types.GetDurationValue(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid circuitBreaker cooldown value: %w", err)
+			}
+		default:
+			return result, fmt.Errorf("unknown circuitBreaker param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// circuitBreaker emulates the client-side circuit breaking,
+// after maxFailures consecutive failures the circuit is opened and calls are
+// rejected locally, once the cooldown has passed a single trial call is allowed (half-open)
+// and depending on its result the circuit is closed or opened again. The other calls
+// are rejected while the trial call is in flight.
+type circuitBreaker struct {
+	mu sync.Mutex
+
+	params circuitBreakerParams
+
+	state    string
+	failures int64
+	openedAt time.Time
+	// probing is set while the half-open circuit's trial call is in flight
+	probing bool
+
+	now func() time.Time
+}
+
+func newCircuitBreaker(p *circuitBreakerParams) *circuitBreaker {
+	return &circuitBreaker{
+		params: *p,
+		state:  circuitClosed,
+		now:    time.Now,
+	}
+}
+
+// circuitTransition describes a change of the circuit breaker state
+type circuitTransition struct {
+	From string `js:"from"`
+	To   string `js:"to"`
+}
+
+// allow reports whether a call could be done and a state transition if it happened
+func (cb *circuitBreaker) allow() (bool, *circuitTransition) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if cb.now().Sub(cb.openedAt) < cb.params.Cooldown {
+			return false, nil
+		}
+
+		cb.probing = true
+
+		return true, cb.transit(circuitHalfOpen)
+	case circuitHalfOpen:
+		if cb.probing {
+			return false, nil
+		}
+
+		cb.probing = true
+
+		return true, nil
+	default:
+		return true, nil
+	}
+}
+
+// release gives up the call allowed by the allow without sending it,
+// so the half-open circuit allows another trial call
+func (cb *circuitBreaker) release() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+}
+
+// record records the result of a call and returns a state transition if it happened
+func (cb *circuitBreaker) record(code codes.Code) *circuitTransition {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+
+	if !isCircuitFailure(code) {
+		cb.failures = 0
+
+		if cb.state != circuitClosed {
+			return cb.transit(circuitClosed)
+		}
+
+		return nil
+	}
+
+	cb.failures++
+
+	if cb.state == circuitHalfOpen || (cb.state == circuitClosed && cb.failures >= cb.params.MaxFailures) {
+		cb.openedAt = cb.now()
+
+		return cb.transit(circuitOpen)
+	}
+
+	return nil
+}
+
+func (cb *circuitBreaker) transit(to string) *circuitTransition {
+	t := &circuitTransition{From: cb.state, To: to}
+	cb.state = to
+
+	return t
+}
+
+// circuitBreakers are the VU's circuit breakers by the target, shared by its clients
+// so the clients connected to the same target share its circuit's state.
+type circuitBreakers struct {
+	targets map[string]*circuitBreaker
+}
+
+// get returns the target's circuit breaker, it's replaced if the params have been changed
+func (b *circuitBreakers) get(target string, p *circuitBreakerParams) *circuitBreaker {
+	if cb, ok := b.targets[target]; ok && cb.params == *p {
+		return cb
+	}
+
+	if b.targets == nil {
+		b.targets = make(map[string]*circuitBreaker)
+	}
+
+	cb := newCircuitBreaker(p)
+	b.targets[target] = cb
+
+	return cb
+}
+
+// isCircuitFailure reports whether the status code signals a failure of the target
+// and not an application-level error
+func isCircuitFailure(code codes.Code) bool {
+	switch code { //nolint:exhaustive
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// circuitTransited emits the metric and calls the listeners of the circuit breaker's state change
+func (c *Client) circuitTransited(t *circuitTransition, tagsAndMeta *metrics.TagsAndMeta) error {
+	if t == nil {
+		return nil
+	}
+
+	stateTags := &metrics.TagsAndMeta{
+		Tags:     tagsAndMeta.Tags.With("state", t.To),
+		Metadata: tagsAndMeta.Metadata,
+	}
+	c.pushMetric(c.metrics.CircuitBreakerStateChanges, stateTags, 1)
+
+	return c.listeners.call(eventCircuitBreaker, c.vu.Runtime().ToValue(t))
+}
@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	cb := newCircuitBreaker(&circuitBreakerParams{MaxFailures: 2, Cooldown: time.Second})
+	cb.now = func() time.Time { return now }
+
+	allowed, transition := cb.allow()
+	assert.True(t, allowed)
+	assert.Nil(t, transition)
+
+	assert.Nil(t, cb.record(codes.Unavailable))
+	assert.Nil(t, cb.record(codes.NotFound), "application errors aren't failures")
+	assert.Nil(t, cb.record(codes.Unavailable))
+	assert.Equal(t, &circuitTransition{From: circuitClosed, To: circuitOpen}, cb.record(codes.DeadlineExceeded))
+
+	allowed, transition = cb.allow()
+	assert.False(t, allowed)
+	assert.Nil(t, transition)
+
+	now = now.Add(time.Second)
+
+	allowed, transition = cb.allow()
+	assert.True(t, allowed)
+	assert.Equal(t, &circuitTransition{From: circuitOpen, To: circuitHalfOpen}, transition)
+
+	allowed, transition = cb.allow()
+	assert.False(t, allowed, "a single trial call is allowed in the half-open state")
+	assert.Nil(t, transition)
+
+	assert.Equal(t, &circuitTransition{From: circuitHalfOpen, To: circuitOpen}, cb.record(codes.Unavailable))
+
+	now = now.Add(time.Second)
+
+	allowed, _ = cb.allow()
+	assert.True(t, allowed)
+	cb.release()
+
+	allowed, _ = cb.allow()
+	assert.True(t, allowed, "the released trial call allows another one")
+	assert.Equal(t, &circuitTransition{From: circuitHalfOpen, To: circuitClosed}, cb.record(codes.OK))
+
+	allowed, _ = cb.allow()
+	assert.True(t, allowed)
+	allowed, _ = cb.allow()
+	assert.True(t, allowed)
+}
+
+func TestCircuitBreakersByTarget(t *testing.T) {
+	t.Parallel()
+
+	var breakers circuitBreakers
+	p := &circuitBreakerParams{MaxFailures: 1, Cooldown: time.Hour}
+
+	a := breakers.get("a:443", p)
+	assert.Same(t, a, breakers.get("a:443", &circuitBreakerParams{MaxFailures: 1, Cooldown: time.Hour}))
+	assert.NotSame(t, a, breakers.get("b:443", p))
+
+	a.record(codes.Unavailable)
+	allowed, _ := breakers.get("a:443", p).allow()
+	assert.False(t, allowed, "the target's circuit is shared")
+	allowed, _ = breakers.get("b:443", p).allow()
+	assert.True(t, allowed)
+
+	allowed, _ = breakers.get("a:443", &circuitBreakerParams{MaxFailures: 2, Cooldown: time.Hour}).allow()
+	assert.True(t, allowed, "the changed params replace the circuit breaker")
+}
@@ -1,6 +1,8 @@
 package grpc
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -8,22 +10,26 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
 
 	"github.com/dop251/goja"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -37,10 +43,79 @@ type Client struct {
 	conn *grpcext.Conn
 	vu   modules.VU
 	addr string
+
+	// name is the name of the named connection's client, named are the client's named connections
+	name  string
+	named map[string]*Client
+	// authority is the connection's authority param, authorityOf is the connect params of the connection
+	// which the authority connection (a named one overriding its authority) has been dialed for
+	authority   string
+	authorityOf goja.Value
+	// authorities are the names of the authority connections, the least recently used first
+	authorities []string
+	// tags are the connection's tags attached to all its samples
+	tags map[string]string
+	// connTarget, connParams and connIteration are the connect's target, params and the iteration
+	// it's been called in, so the connection could be renewed in each iteration
+	connTarget    goja.Value
+	connParams    goja.Value
+	connIteration int64
+
+	metrics     *instanceMetrics
+	listeners   *eventListeners
+	events      *clientEvents
+	breaker     *circuitBreaker
+	throttler   *adaptiveThrottler
+	rateLimiter *rateLimiter
+	health      *healthGate
+
+	healthWatches []*HealthWatch
+
+	plaintext       bool
+	callCredentials *callCredentialsParams
+
+	duplicateDetection *duplicateDetectionParams
+	warnedDuplicates   map[string]bool
+	tracePropagation   *tracePropagationParams
+	interceptors       []interceptor
+	capture            *captureParams
+
+	latencies   *latencyHistograms
+	reflections *reflectionLimiter
+	duplicates  *duplicateDetector
+	pool        *connPool
+	prewarmed   *prewarmedConns
+	xds         *xdsReporters
+	stopXDS     func()
+	captured    *captures
+	inFlight    inFlight
+	pooled      bool
+	channelz    bool
+	idle        bool
+	svidSource  *workloadapi.X509Source
+
+	reflectionCache *reflectionCache
+	reflected       *reflectedSetup
+	statusCallback  *statusCallback
+	breakers        *circuitBreakers
+	extOptions      *extOptionsCache
+	descriptors     *descriptorRegistry
+	registryImages  *reflectionCache
+	mocks           *mockJobs
+}
+
+// On registers a listener for a certain client's event type
+func (c *Client) On(event string, listener func(goja.Value) (goja.Value, error)) error {
+	return c.listeners.add(event, listener)
 }
 
 // Load will parse the given proto files and make the file descriptors available to request.
-func (c *Client) Load(importPaths []string, filenames ...string) ([]MethodInfo, error) {
+// The file names could also be the glob patterns (e.g. services/**/*.proto) or the directories,
+// which are expanded into the proto files found under the import paths. The import paths are either
+// an array of the paths or an object with the paths and the mappings of the import prefixes to the local
+// directories. The imports which aren't found (e.g. the google/rpc/*.proto) are resolved from the descriptors
+// compiled into the binary.
+func (c *Client) Load(paths goja.Value, filenames ...string) ([]MethodInfo, error) {
 	if c.vu.State() != nil {
 		return nil, errors.New("load must be called in the init context")
 	}
@@ -50,20 +125,76 @@ func (c *Client) Load(importPaths []string, filenames ...string) ([]MethodInfo,
 		return nil, errors.New("missing init environment")
 	}
 
+	imports, err := newImportPaths(c.vu.Runtime(), paths)
+	if err != nil {
+		return nil, err
+	}
+	importPaths := imports.Paths
+
 	// If no import paths are specified, use the current working directory
 	if len(importPaths) == 0 {
 		importPaths = append(importPaths, initEnv.CWD.Path)
 	}
 
+	filenames, err = expandProtoFiles(initEnv.FileSystems["file"], initEnv.GetAbsFilePath, importPaths, filenames)
+	if err != nil {
+		return nil, err
+	}
+
+	open := func(filename string) (io.ReadCloser, error) {
+		absFilePath := initEnv.GetAbsFilePath(filename)
+		return initEnv.FileSystems["file"].Open(absFilePath)
+	}
+
+	parser := protoparse.Parser{
+		ImportPaths:       importPaths,
+		InferImportPaths:  false,
+		Accessor:          open,
+		LookupImportProto: lookupEmbeddedImport,
+	}
+
+	// the mapped imports are resolved before the import paths
+	if len(imports.Mappings) > 0 {
+		parser.ImportPaths = nil
+		parser.Accessor = mappedAccessor(open, importPaths, imports.Mappings)
+	}
+
+	return c.parseProtos(parser, filenames)
+}
+
+// LoadProtoContent will parse the given in-memory proto sources (file name to source) and make the file
+// descriptors available to request. The imports are the optional sources which are only imported by them.
+func (c *Client) LoadProtoContent(sources map[string]string, imports map[string]string) ([]MethodInfo, error) {
+	if c.vu.State() != nil {
+		return nil, errors.New("load must be called in the init context")
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("no proto sources to load")
+	}
+
+	contents := make(map[string]string, len(sources)+len(imports))
+	for name, source := range imports {
+		contents[name] = source
+	}
+
+	filenames := make([]string, 0, len(sources))
+	for name, source := range sources {
+		contents[name] = source
+		filenames = append(filenames, name)
+	}
+	sort.Strings(filenames)
+
 	parser := protoparse.Parser{
-		ImportPaths:      importPaths,
-		InferImportPaths: false,
-		Accessor: protoparse.FileAccessor(func(filename string) (io.ReadCloser, error) {
-			absFilePath := initEnv.GetAbsFilePath(filename)
-			return initEnv.FileSystems["file"].Open(absFilePath)
-		}),
+		Accessor:          protoparse.FileContentsFromMap(contents),
+		LookupImportProto: lookupEmbeddedImport,
 	}
 
+	return c.parseProtos(parser, filenames)
+}
+
+// parseProtos parses the proto files and makes their file descriptors available to request
+func (c *Client) parseProtos(parser protoparse.Parser, filenames []string) ([]MethodInfo, error) {
 	fds, err := parser.ParseFiles(filenames...)
 	if err != nil {
 		return nil, err
@@ -78,9 +209,11 @@ func (c *Client) Load(importPaths []string, filenames ...string) ([]MethodInfo,
 	return c.convertToMethodInfo(fdset)
 }
 
-// LoadProtoset will parse the given protoset file (serialized FileDescriptorSet) and make the file
-// descriptors available to request.
-func (c *Client) LoadProtoset(protosetPath string) ([]MethodInfo, error) {
+// LoadProtoset will parse the given protosets (serialized FileDescriptorSets) and make the file
+// descriptors available to request. Each protoset is either a file path or its content, e.g. an ArrayBuffer
+// returned by the open(path, "b"), optionally gzipped. The protosets could be given as an array too,
+// e.g. the per-module buf build outputs, their files are merged.
+func (c *Client) LoadProtoset(protosets ...goja.Value) ([]MethodInfo, error) {
 	if c.vu.State() != nil {
 		return nil, errors.New("load must be called in the init context")
 	}
@@ -90,26 +223,89 @@ func (c *Client) LoadProtoset(protosetPath string) ([]MethodInfo, error) {
 		return nil, errors.New("missing init environment")
 	}
 
-	absFilePath := initEnv.GetAbsFilePath(protosetPath)
-	fdsetFile, err := initEnv.FileSystems["file"].Open(absFilePath)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't open protoset: %w", err)
-	}
+	var inputs []interface{}
+	for _, protoset := range protosets {
+		if common.IsNullish(protoset) {
+			continue
+		}
 
-	defer func() { _ = fdsetFile.Close() }()
-	fdsetBytes, err := io.ReadAll(fdsetFile)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't read protoset: %w", err)
+		if list, ok := protoset.Export().([]interface{}); ok {
+			inputs = append(inputs, list...)
+		} else {
+			inputs = append(inputs, protoset.Export())
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("protoset is required")
 	}
 
 	fdset := &descriptorpb.FileDescriptorSet{}
-	if err = proto.Unmarshal(fdsetBytes, fdset); err != nil {
-		return nil, fmt.Errorf("couldn't unmarshal protoset file %s: %w", protosetPath, err)
+	seen := make(map[string]struct{})
+	for _, input := range inputs {
+		set, err := readProtoset(initEnv, input)
+		if err != nil {
+			return nil, err
+		}
+
+		// the shared dependencies (e.g. the well-known types) are included by each of the protosets
+		for _, fd := range set.File {
+			if _, ok := seen[fd.GetName()]; ok {
+				continue
+			}
+			seen[fd.GetName()] = struct{}{}
+			fdset.File = append(fdset.File, fd)
+		}
 	}
 
 	return c.convertToMethodInfo(fdset)
 }
 
+// readProtoset reads the protoset from the file path or its content, gunzipping it if it's gzipped.
+func readProtoset(initEnv *common.InitEnvironment, protoset interface{}) (*descriptorpb.FileDescriptorSet, error) {
+	name := "protoset"
+	var fdsetBytes []byte
+
+	if protosetPath, ok := protoset.(string); ok {
+		absFilePath := initEnv.GetAbsFilePath(protosetPath)
+		fdsetFile, err := initEnv.FileSystems["file"].Open(absFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open protoset: %w", err)
+		}
+
+		defer func() { _ = fdsetFile.Close() }()
+		fdsetBytes, err = io.ReadAll(fdsetFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read protoset: %w", err)
+		}
+
+		name = "protoset file " + protosetPath
+	} else {
+		var err error
+		if fdsetBytes, err = common.ToBytes(protoset); err != nil {
+			return nil, fmt.Errorf("invalid protoset value: %w", err)
+		}
+	}
+
+	// the gzip's magic number, e.g. the buf build -o image.binpb.gz outputs
+	if len(fdsetBytes) > 1 && fdsetBytes[0] == 0x1f && fdsetBytes[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(fdsetBytes))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't gunzip %s: %w", name, err)
+		}
+
+		if fdsetBytes, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("couldn't gunzip %s: %w", name, err)
+		}
+	}
+
+	fdset := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(fdsetBytes, fdset); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal %s: %w", name, err)
+	}
+
+	return fdset, nil
+}
+
 // Note: this function was lifted from `lib/options.go`
 func decryptPrivateKey(key, password []byte) ([]byte, error) {
 	block, _ := pem.Decode(key)
@@ -205,42 +401,246 @@ func buildTLSConfigFromMap(parentConfig *tls.Config, tlsConfigMap map[string]int
 	return buildTLSConfig(parentConfig, cert, key, ca)
 }
 
-// Connect is a block dial to the gRPC server at the given address (host:port)
-func (c *Client) Connect(addr string, params goja.Value) (bool, error) {
+// Connect is a block dial to the gRPC server at the given address (host:port),
+// the target could be an array of the addresses the VUs are spread across, see the pickTarget.
+func (c *Client) Connect(target goja.Value, params goja.Value) (bool, error) {
+	if c.vu.State() == nil {
+		return false, common.NewInitContextError("connecting to a gRPC server in the init context is not supported")
+	}
+
+	addr, err := c.pickTarget(target, params)
+	if err != nil {
+		return false, fmt.Errorf("invalid grpc.connect() target: %w", err)
+	}
+
+	return c.connectAddr(addr, target, params)
+}
+
+// connectAddr is a block dial to the gRPC server at the address picked of the connect's target,
+// the target is kept so the renewed connections pick it again
+func (c *Client) connectAddr(addr string, target goja.Value, params goja.Value) (bool, error) {
 	state := c.vu.State()
 	if state == nil {
 		return false, common.NewInitContextError("connecting to a gRPC server in the init context is not supported")
 	}
 
+	opts, err := c.extOptions.load(c.vu)
+	if err != nil {
+		return false, err
+	}
+	connParams := params
+	if opts != nil {
+		params = withDefaults(c.vu.Runtime(), opts.Connect, params)
+		if addr == "" {
+			addr = opts.Target
+		}
+	}
+
 	p, err := newConnectParams(c.vu, params)
 	if err != nil {
 		return false, fmt.Errorf("invalid grpc.connect() parameters: %w", err)
 	}
 
-	opts := grpcext.DefaultOptions(c.vu.State)
+	if p.Name != c.name {
+		return c.connectNamed(addr, target, p.Name, params)
+	}
 
-	var tcred credentials.TransportCredentials
-	if !p.IsPlaintext {
-		tlsCfg := state.TLSConfig.Clone()
-		if len(p.TLS) > 0 {
-			if tlsCfg, err = buildTLSConfigFromMap(tlsCfg, p.TLS); err != nil {
-				return false, err
-			}
-		}
-		tlsCfg.NextProtos = []string{"h2"}
+	// the gRPC internals are shared, so the most verbose level of all the clients is used
+	if level, ok := grpcLogLevels[p.LogLevel]; ok {
+		grpcLogger.Raise(level.severity, level.verbosity)
+	}
 
-		tcred = credentials.NewTLS(tlsCfg)
+	ctx, cancel := context.WithTimeout(c.vuContext(), p.Timeout)
+	defer cancel()
+
+	if err = c.closeSVIDSource(); err != nil {
+		return false, err
+	}
+
+	c.addr = addr
+	c.tags = p.Tags
+	c.pooled = p.Pool
+	// the xDS client's exchanges are made while dialing, so it's observed before
+	c.stopXDSEvents()
+	if isXDSTarget(addr) {
+		c.xds.register(c)
+		c.watchXDSEvents()
+	}
+	c.idle = p.IdleTimeout > 0
+	c.channelz = p.Channelz
+	key, err := c.connKey(p, addr)
+	if err != nil {
+		return false, err
+	}
+	if p.Pool {
+		c.conn, err = c.pool.get(ctx, key, c, func() (dialedConn, error) {
+			owner := newConnOwner(c)
+			conn, err := c.dial(ctx, state, addr, p, owner)
+			svidSource := c.svidSource
+			c.svidSource = nil
+
+			return dialedConn{conn: conn, owner: owner, svidSource: svidSource}, err
+		})
+	} else if prewarmed, ok := c.prewarmed.take(key, c); ok {
+		c.conn, c.svidSource = prewarmed.conn, prewarmed.svidSource
 	} else {
-		tcred = insecure.NewCredentials()
+		c.conn, err = c.dial(ctx, state, addr, p, newConnOwner(c))
+	}
+	if err != nil {
+		return false, err
+	}
+	c.connTarget, c.connParams, c.connIteration = target, connParams, state.Iteration
+
+	c.plaintext = p.IsPlaintext
+	c.authority = p.Authority
+	c.callCredentials = p.CallCredentials
+	c.duplicateDetection = p.DuplicateDetection
+	c.capture = p.Capture
+	c.tracePropagation = p.TracePropagation
+
+	c.breaker = nil
+	if p.CircuitBreaker != nil {
+		c.breaker = c.breakers.get(addr, p.CircuitBreaker)
+	}
+
+	c.throttler = nil
+	if p.AdaptiveThrottling != nil {
+		c.throttler = newAdaptiveThrottler(p.AdaptiveThrottling)
+	}
+
+	c.rateLimiter = nil
+	if p.RateLimit != nil {
+		c.rateLimiter = newRateLimiter(p.RateLimit)
+	}
+
+	c.stopHealthGate()
+	c.stopHealthWatches()
+	if p.HealthGating != nil {
+		c.health = newHealthGate(p.HealthGating)
+		c.health.watch(c.vu.Context(), c.conn, p.HealthGating.Service)
+	}
+
+	c.reflectOnReconnect(addr, p)
+	if !p.UseReflectionProtocol && len(p.AnyTypes) == 0 {
+		return true, nil
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, p.ReflectionMetadata)
+
+	if p.UseReflectionProtocol {
+		fdset, err := c.reflect(ctx, p, p.ReflectionSymbols)
+		if err != nil {
+			return false, err
+		}
+		_, err = c.convertToMethodInfo(fdset)
+		if err != nil {
+			return false, fmt.Errorf("can't convert method info: %w", err)
+		}
+		c.reflected = &reflectedSetup{addr: addr, symbols: p.ReflectionSymbols, metadata: p.ReflectionMetadata}
+	}
+
+	if len(p.AnyTypes) > 0 {
+		if err = c.reflectAnyTypes(ctx, p); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// reflectAnyTypes fetches the descriptors of the message types packed into the Any fields
+// using the reflection and registers them, since they usually aren't the services' dependencies.
+func (c *Client) reflectAnyTypes(ctx context.Context, p *connectParams) error {
+	fdset, err := c.reflect(ctx, p, p.AnyTypes)
+	if err != nil {
+		return fmt.Errorf("can't reflect the any types: %w", err)
+	}
+
+	files, err := c.descriptors.get(fdset)
+	if err != nil {
+		return fmt.Errorf("can't convert the any types: %w", err)
+	}
+
+	return registerMessageTypes(files)
+}
+
+// dial establishes a new connection to the gRPC server at the given address,
+// The connection's own samples and events are reported by the owner, the calls' ones by the VUs making them.
+func (c *Client) dial(
+	ctx context.Context, state *lib.State, addr string, p *connectParams, owner *connOwner,
+) (*grpcext.Conn, error) {
+	if p.Channelz {
+		grpcext.EnableChannelz()
+	}
+
+	opts := grpcext.DefaultOptions(owner.state)
+	opts = append(opts, grpcext.WithMetrics(owner.state, &grpcext.Metrics{
+		MessagesSent:     c.metrics.MessagesSent,
+		MessagesReceived: c.metrics.MessagesReceived,
+		DataSent:         c.metrics.DataSent,
+		DataReceived:     c.metrics.DataReceived,
+		Blocked:          c.metrics.ReqBlocked,
+		Sending:          c.metrics.ReqSending,
+		Waiting:          c.metrics.ReqWaiting,
+		Receiving:        c.metrics.ReqReceiving,
+	}))
+	opts = append(opts, grpcext.WithDialObserver(owner.state, p.Proxy, p.Socket, owner.observeConn()))
+
+	target := addr
+	if len(p.Endpoints) > 0 {
+		var eopts []grpc.DialOption
+		target, eopts = grpcext.WithEndpoints(addr, p.Endpoints)
+		opts = append(opts, eopts...)
+	}
+
+	if p.DNSRefresh != nil {
+		if len(p.Endpoints) > 0 {
+			return nil, errors.New("the dnsRefresh param can't be used with the endpoints")
+		}
+		if p.LoadBalancing != nil {
+			// the VU's resolver picks a single address of the target, so there's nothing to balance
+			return nil, errors.New("the dnsRefresh param can't be used with the loadBalancing")
+		}
+
+		var (
+			dopts []grpc.DialOption
+			err   error
+		)
+		target, dopts, err = grpcext.WithDNSRefresh(addr, *p.DNSRefresh, state.Dialer)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, dopts...)
+	}
+
+	if p.LoadBalancing != nil {
+		if isXDSTarget(addr) {
+			return nil, errors.New("the loadBalancing param can't be used with the xds: targets, their policy is delivered by the xDS")
+		}
+
+		lbOpt, err := grpcext.WithLoadBalancing(*p.LoadBalancing)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, lbOpt)
 	}
-	opts = append(opts, grpc.WithTransportCredentials(tcred))
+
+	tcredOpt, err := c.transportCredentialsDialOption(state, p, owner)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, tcredOpt)
 
 	if ua := state.Options.UserAgent; ua.Valid {
 		opts = append(opts, grpc.WithUserAgent(ua.ValueOrZero()))
 	}
 
-	ctx, cancel := context.WithTimeout(c.vu.Context(), p.Timeout)
-	defer cancel()
+	// the authority is also used as the TLS server name (SNI) if it isn't set explicitly
+	if p.Authority != "" {
+		opts = append(opts, grpc.WithAuthority(p.Authority))
+	}
+
+	opts = append(opts, callCredentialsDialOptions(p)...)
 
 	if p.MaxReceiveSize > 0 {
 		opts = append(opts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(int(p.MaxReceiveSize))))
@@ -250,28 +650,46 @@ func (c *Client) Connect(addr string, params goja.Value) (bool, error) {
 		opts = append(opts, grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(int(p.MaxSendSize))))
 	}
 
-	c.addr = addr
-	c.conn, err = grpcext.Dial(ctx, addr, opts...)
+	opts = append(opts, flowControlDialOptions(p)...)
+	opts = append(opts, owner.streamLimitDialOptions(p)...)
+	opts = append(opts, grpc.WithIdleTimeout(p.IdleTimeout))
+
+	if p.Backoff != nil {
+		opts = append(opts, grpc.WithConnectParams(p.Backoff.ConnectParams))
+	}
+
+	conn, err := grpcext.DialChannels(ctx, target, int(p.Channels), opts...)
 	if err != nil {
-		return false, err
+		_ = c.closeSVIDSource()
+
+		return nil, err
 	}
+	owner.watchConnEvents(conn)
 
-	if !p.UseReflectionProtocol {
-		return true, nil
+	return conn, nil
+}
+
+// flowControlDialOptions returns the dial options of the HTTP/2 flow control windows and the transport's buffers
+func flowControlDialOptions(p *connectParams) []grpc.DialOption {
+	var opts []grpc.DialOption
+
+	if p.InitialWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialWindowSize(int32(p.InitialWindowSize)))
 	}
 
-	ctx = metadata.NewOutgoingContext(ctx, p.ReflectionMetadata)
+	if p.InitialConnWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialConnWindowSize(int32(p.InitialConnWindowSize)))
+	}
 
-	fdset, err := c.conn.Reflect(ctx)
-	if err != nil {
-		return false, err
+	if p.WriteBufferSize > 0 {
+		opts = append(opts, grpc.WithWriteBufferSize(int(p.WriteBufferSize)))
 	}
-	_, err = c.convertToMethodInfo(fdset)
-	if err != nil {
-		return false, fmt.Errorf("can't convert method info: %w", err)
+
+	if p.ReadBufferSize > 0 {
+		opts = append(opts, grpc.WithReadBufferSize(int(p.ReadBufferSize)))
 	}
 
-	return true, err
+	return opts
 }
 
 // Invoke creates and calls a unary RPC by fully qualified method name
@@ -284,6 +702,10 @@ func (c *Client) Invoke(
 	if state == nil {
 		return nil, common.NewInitContextError("invoking RPC methods in the init context is not supported")
 	}
+	c, err := c.connection(params)
+	if err != nil {
+		return nil, err
+	}
 	if c.conn == nil {
 		return nil, errors.New("no gRPC connection, you must call connect first")
 	}
@@ -293,51 +715,338 @@ func (c *Client) Invoke(
 	if method[0] != '/' {
 		method = "/" + method
 	}
+
+	p, err := newCallParams(c.vu, c.extOptions, c.tags, params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GRPC's client.invoke() parameters: %w", err)
+	}
+
+	// k6 GRPC Invoke's default timeout is 2 minutes
+	if p.Timeout == time.Duration(0) && p.Deadline.IsZero() {
+		p.Timeout = 2 * time.Minute
+	}
+
+	method, req, callbacks, err := c.intercept(method, req, p)
+	if err != nil {
+		return nil, err
+	}
+
 	methodDesc := c.mds[method]
 	if methodDesc == nil {
-		return nil, fmt.Errorf("method %q not found in file descriptors", method)
+		return nil, c.methodNotFound(method)
 	}
 
-	p, err := newCallParams(c.vu, params)
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+	b, marshaled, err := c.requestMessage(methodDesc, req)
 	if err != nil {
-		return nil, fmt.Errorf("invalid GRPC's client.invoke() parameters: %w", err)
+		return nil, err
+	}
+
+	resp, err := c.invoke(method, methodDesc, p, b, marshaled)
+	if err != nil {
+		return nil, err
+	}
+	if p.TimestampDates {
+		responseDates(c.vu.Runtime(), methodDesc.Output(), resp)
+	}
+
+	return resp, callbacks.call(c.vu.Runtime(), resp)
+}
+
+// InvokeRaw creates and calls a unary RPC by fully qualified method name with the already
+// marshaled protobuf request (e.g. an ArrayBuffer), the response's message is the marshaled
+// protobuf ArrayBuffer. The method's descriptors don't need to be loaded.
+func (c *Client) InvokeRaw(
+	method string,
+	req goja.Value,
+	params goja.Value,
+) (*grpcext.Response, error) {
+	state := c.vu.State()
+	if state == nil {
+		return nil, common.NewInitContextError("invoking RPC methods in the init context is not supported")
+	}
+	c, err := c.connection(params)
+	if err != nil {
+		return nil, err
+	}
+	if c.conn == nil {
+		return nil, errors.New("no gRPC connection, you must call connect first")
+	}
+	if method == "" {
+		return nil, errors.New("method to invoke cannot be empty")
+	}
+	if method[0] != '/' {
+		method = "/" + method
+	}
+
+	p, err := newCallParams(c.vu, c.extOptions, c.tags, params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GRPC's client.invokeRaw() parameters: %w", err)
 	}
 
 	// k6 GRPC Invoke's default timeout is 2 minutes
-	if p.Timeout == time.Duration(0) {
+	if p.Timeout == time.Duration(0) && p.Deadline.IsZero() {
 		p.Timeout = 2 * time.Minute
 	}
 
-	if req == nil {
+	method, req, callbacks, err := c.intercept(method, req, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if common.IsNullish(req) {
 		return nil, errors.New("request cannot be nil")
 	}
-	b, err := req.ToObject(c.vu.Runtime()).MarshalJSON()
+	b, err := common.ToBytes(req.Export())
+	if err != nil {
+		return nil, fmt.Errorf("invalid request value: %w", err)
+	}
+
+	resp, err := c.call(method, p, b, func(ctx context.Context, copts []grpc.CallOption) (*grpcext.Response, error) {
+		reqmsg := grpcext.RawRequest{
+			Message:        b,
+			TagsAndMeta:    &p.TagsAndMeta,
+			ExpectedStatus: p.ExpectedStatuses.callback(),
+		}
+
+		return c.conn.InvokeRaw(ctx, method, p.Metadata, reqmsg, copts...)
+	})
+
 	if err != nil {
-		return nil, fmt.Errorf("unable to serialise request object: %w", err)
+		return nil, err
+	}
+
+	if msg, ok := resp.Message.([]byte); ok {
+		resp.Message = c.vu.Runtime().NewArrayBuffer(msg)
 	}
 
-	ctx, cancel := context.WithTimeout(c.vu.Context(), p.Timeout)
+	return resp, callbacks.call(c.vu.Runtime(), resp)
+}
+
+// invoke calls the unary RPC with the serialised request, applying the client-side policies.
+func (c *Client) invoke(
+	method string,
+	methodDesc protoreflect.MethodDescriptor,
+	p *callParams,
+	b []byte,
+	marshaled bool,
+) (*grpcext.Response, error) {
+	return c.call(method, p, b, func(ctx context.Context, copts []grpc.CallOption) (*grpcext.Response, error) {
+		reqmsg := grpcext.Request{
+			MethodDescriptor: methodDesc,
+			Message:          b,
+			Marshaled:        marshaled,
+			LazyMessage:      p.LazyMessage,
+			Int64Numbers:     p.Int64Numbers,
+			DiscardUnknown:   !p.StrictRequest,
+			TagsAndMeta:      &p.TagsAndMeta,
+			ExpectedStatus:   p.ExpectedStatuses.callback(),
+		}
+
+		return c.conn.Invoke(ctx, method, p.Metadata, reqmsg, copts...)
+	})
+}
+
+// call applies the client-side policies to the unary RPC and sends it using the send,
+// b is the serialised request used to detect the duplicates.
+func (c *Client) call(
+	method string,
+	p *callParams,
+	b []byte,
+	send func(ctx context.Context, copts []grpc.CallOption) (*grpcext.Response, error),
+) (resp *grpcext.Response, err error) {
+	ctx, cancel := p.withDeadline(c.vuContext())
 	defer cancel()
+	ctx, release := p.cancellable(ctx)
+	defer release()
+	ctx = p.withDebug(ctx)
+	defer func() {
+		if resp != nil {
+			c.arrayBufferBinary(resp)
+			c.arrayBufferUnknown(resp)
+			resp.OK = resp.Status == codes.OK
+		}
 
-	p.SetSystemTags(state, c.addr, method)
+		if flushErr := c.events.flush(); err == nil {
+			err = flushErr
+		}
+	}()
 
-	reqmsg := grpcext.Request{
-		MethodDescriptor: methodDesc,
-		Message:          b,
-		TagsAndMeta:      &p.TagsAndMeta,
+	p.SetSystemTags(c.vu.State(), c.addr, method)
+	if p.ExpectedStatuses == nil {
+		p.ExpectedStatuses = c.statusCallback.expected
 	}
 
-	return c.conn.Invoke(ctx, method, p.Metadata, reqmsg)
+	copts, err := c.callOptions()
+	if err != nil {
+		return nil, err
+	}
+	copts = append(copts, p.callOptions()...)
+
+	c.detectDuplicate(method, b, p)
+	c.pace(ctx, &p.TagsAndMeta)
+
+	resp, err = c.admit(&p.TagsAndMeta)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	// the admitted call which isn't sent gives up its circuit breaker's trial call
+	observed := false
+	defer func() {
+		if !observed {
+			c.releaseBreaker()
+		}
+	}()
+
+	if p.AbortOnBudgetExceeded && !c.conn.AwaitConnection(ctx) {
+		c.setStatusTag(&p.TagsAndMeta, codes.DeadlineExceeded)
+		p.TagsAndMeta.SetTag(grpcext.ErrorKindTag, grpcext.ErrorKindTimeout)
+		c.pushMetric(c.metrics.BudgetExceededAborts, &p.TagsAndMeta, 1)
+
+		resp = grpcext.NewStatusResponse(status.New(codes.DeadlineExceeded, errBudgetExceeded.Error()))
+		resp.ErrorKind = grpcext.ErrorKindTimeout
+
+		return resp, nil
+	}
+
+	if c.idle {
+		// the call waits for the idle channels to reconnect, it's measured apart from the call's latency
+		if d, ok := c.conn.ExitIdle(ctx); ok {
+			c.pushMetric(c.metrics.IdleReactivationDuration, &p.TagsAndMeta, metrics.D(d))
+		}
+	}
+
+	trace, err := c.injectTrace(p.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.conn.Channels() > 1 {
+		c.pushMetric(c.metrics.ChannelsReady, &p.TagsAndMeta, float64(c.conn.ReadyChannels()))
+	}
+
+	var grpcTimeout string
+	if deadline, ok := ctx.Deadline(); ok {
+		grpcTimeout = grpcext.EncodeTimeout(time.Until(deadline))
+	}
+
+	start := time.Now()
+	c.inFlight.add()
+	resp, err = c.mocks.pump(func() (*grpcext.Response, error) {
+		return send(ctx, copts)
+	})
+	c.inFlight.done()
+	if err != nil {
+		return nil, err
+	}
+	resp.Trace = trace
+	resp.GRPCTimeout = grpcTimeout
+	c.latencies.record(method, time.Since(start))
+	c.pushReqFailed(p.ExpectedStatuses, resp.Status, &p.TagsAndMeta)
+	c.captureCall(method, p, b, resp, start)
+
+	if resp.ErrorKind == grpcext.ErrorKindCircuitBreaker {
+		c.pushMetric(c.metrics.XDSCircuitBreakerRejections, &p.TagsAndMeta, 1)
+	}
+
+	observed = true
+
+	return resp, c.observe(resp.Status, &p.TagsAndMeta)
+}
+
+// arrayBufferBinary makes the response's binary message an ArrayBuffer
+func (c *Client) arrayBufferBinary(resp *grpcext.Response) {
+	binary := resp.Binary
+	if binary == nil {
+		return
+	}
+
+	resp.Binary = func() (interface{}, error) {
+		v, err := binary()
+		if err != nil {
+			return nil, err
+		}
+
+		switch b := v.(type) {
+		case []byte:
+			return c.vu.Runtime().NewArrayBuffer(b), nil
+		default:
+			return v, nil
+		}
+	}
+}
+
+// arrayBufferUnknown makes the response's unknown fields ArrayBuffers
+func (c *Client) arrayBufferUnknown(resp *grpcext.Response) {
+	unknown := resp.Unknown
+	if unknown == nil {
+		return
+	}
+
+	resp.Unknown = func() map[string]interface{} {
+		fields := unknown()
+		for k, v := range fields {
+			if b, ok := v.([]byte); ok {
+				fields[k] = c.vu.Runtime().NewArrayBuffer(b)
+			}
+		}
+
+		return fields
+	}
+}
+
+// Close will close the client gRPC connection and its named connections. The graceful close returns
+// a promise resolved once the in-flight RPCs and streams have ended (or the timeout has elapsed)
+// and the connections are closed, the VU's event loop keeps running meanwhile.
+func (c *Client) Close(params goja.Value) (interface{}, error) {
+	p, err := newCloseParams(c.vu.Runtime(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Graceful {
+		return c.closeGracefully(p.Timeout), nil
+	}
+
+	return nil, c.closeAll()
 }
 
-// Close will close the client gRPC connection
-func (c *Client) Close() error {
+// closeAll closes the client's own connection and its named connections
+func (c *Client) closeAll() error {
+	err := c.closeNamed()
+	if cerr := c.close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// close closes the client's own connection
+func (c *Client) close() error {
 	if c.conn == nil {
 		return nil
 	}
+
+	c.stopHealthGate()
+	c.stopHealthWatches()
+	c.stopXDSEvents()
+
+	// the pooled connections stay open for the other clients
+	if c.pooled {
+		c.conn = nil
+		return nil
+	}
+
 	err := c.conn.Close()
 	c.conn = nil
 
+	if serr := c.closeSVIDSource(); err == nil {
+		err = serr
+	}
+
 	return err
 }
 
@@ -350,7 +1059,7 @@ type MethodInfo struct {
 }
 
 func (c *Client) convertToMethodInfo(fdset *descriptorpb.FileDescriptorSet) ([]MethodInfo, error) {
-	files, err := protodesc.NewFiles(fdset)
+	files, err := c.descriptors.get(fdset)
 	if err != nil {
 		return nil, err
 	}
@@ -360,23 +1069,10 @@ func (c *Client) convertToMethodInfo(fdset *descriptorpb.FileDescriptorSet) ([]M
 		// previously loaded definitions.
 		c.mds = make(map[string]protoreflect.MethodDescriptor)
 	}
-	appendMethodInfo := func(
-		fd protoreflect.FileDescriptor,
-		sd protoreflect.ServiceDescriptor,
-		md protoreflect.MethodDescriptor,
-	) {
-		name := fmt.Sprintf("/%s/%s", sd.FullName(), md.Name())
-		c.mds[name] = md
-		rtn = append(rtn, MethodInfo{
-			MethodInfo: grpc.MethodInfo{
-				Name:           string(md.Name()),
-				IsClientStream: md.IsStreamingClient(),
-				IsServerStream: md.IsStreamingServer(),
-			},
-			Package:    string(fd.Package()),
-			Service:    string(sd.Name()),
-			FullMethod: name,
-		})
+	appendMethodInfo := func(md protoreflect.MethodDescriptor) {
+		info := newMethodInfo(md)
+		c.mds[info.FullMethod] = md
+		rtn = append(rtn, info)
 	}
 	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
 		sds := fd.Services()
@@ -385,10 +1081,36 @@ func (c *Client) convertToMethodInfo(fdset *descriptorpb.FileDescriptorSet) ([]M
 			mds := sd.Methods()
 			for j := 0; j < mds.Len(); j++ {
 				md := mds.Get(j)
-				appendMethodInfo(fd, sd, md)
+				appendMethodInfo(md)
 			}
 		}
 
+		return true
+	})
+
+	if err = registerMessageTypes(files); err != nil {
+		return nil, err
+	}
+	return rtn, nil
+}
+
+// messageTypesMu makes the registerMessageTypes' lookups and registrations atomic, since the VUs
+// loading or reflecting the same types register them into the process' registry concurrently
+var messageTypesMu sync.Mutex //nolint:gochecknoglobals
+
+// registerMessageTypes registers the files' messages, including the nested ones,
+// so they're resolved when the Any fields are marshaled or unmarshaled. The extensions
+// are registered too, so the proto2 messages' extension fields could be set as "[full.name]".
+func registerMessageTypes(files *protoregistry.Files) error {
+	messageTypesMu.Lock()
+	defer messageTypesMu.Unlock()
+
+	var err error
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		if err = registerExtensionTypes(fd.Extensions()); err != nil {
+			return false
+		}
+
 		messages := fd.Messages()
 
 		stack := make([]protoreflect.MessageDescriptor, 0, messages.Len())
@@ -408,6 +1130,10 @@ func (c *Client) convertToMethodInfo(fdset *descriptorpb.FileDescriptorSet) ([]M
 				}
 			}
 
+			if err = registerExtensionTypes(message.Extensions()); err != nil {
+				return false
+			}
+
 			nested := message.Messages()
 			for i := 0; i < nested.Len(); i++ {
 				stack = append(stack, nested.Get(i))
@@ -416,10 +1142,26 @@ func (c *Client) convertToMethodInfo(fdset *descriptorpb.FileDescriptorSet) ([]M
 
 		return true
 	})
-	if err != nil {
-		return nil, err
+
+	return err
+}
+
+// registerExtensionTypes registers the extensions which aren't registered yet
+func registerExtensionTypes(extensions protoreflect.ExtensionDescriptors) error {
+	for i := 0; i < extensions.Len(); i++ {
+		xd := extensions.Get(i)
+
+		_, err := protoregistry.GlobalTypes.FindExtensionByName(xd.FullName())
+		if !errors.Is(err, protoregistry.NotFound) {
+			continue
+		}
+
+		if err = protoregistry.GlobalTypes.RegisterExtension(dynamicpb.NewExtensionType(xd)); err != nil {
+			return err
+		}
 	}
-	return rtn, nil
+
+	return nil
 }
 
 func walkFileDescriptors(seen map[string]struct{}, fd *desc.FileDescriptor) []*descriptorpb.FileDescriptorProto {
@@ -463,7 +1205,7 @@ func (c *Client) getMethodDescriptor(method string) (protoreflect.MethodDescript
 	methodDesc := c.mds[method]
 
 	if methodDesc == nil {
-		return nil, fmt.Errorf("method %q not found in file descriptors", method)
+		return nil, c.methodNotFound(method)
 	}
 
 	return methodDesc, nil
@@ -2,19 +2,38 @@ package grpc_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/reflection"
+	v1alphareflectiongrpc "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	"gopkg.in/guregu/null.v3"
@@ -24,6 +43,9 @@ import (
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/lib/testutils/httpmultibin"
 	grpcanytesting "go.k6.io/k6/lib/testutils/httpmultibin/grpc_any_testing"
 	"go.k6.io/k6/lib/testutils/httpmultibin/grpc_testing"
@@ -87,6 +109,76 @@ func TestClient(t *testing.T) {
 				val: []xk6grpc.MethodInfo{{MethodInfo: grpc.MethodInfo{Name: "EmptyCall", IsClientStream: false, IsServerStream: false}, Package: "grpc.testing", Service: "TestService", FullMethod: "/grpc.testing.TestService/EmptyCall"}, {MethodInfo: grpc.MethodInfo{Name: "UnaryCall", IsClientStream: false, IsServerStream: false}, Package: "grpc.testing", Service: "TestService", FullMethod: "/grpc.testing.TestService/UnaryCall"}, {MethodInfo: grpc.MethodInfo{Name: "StreamingOutputCall", IsClientStream: false, IsServerStream: true}, Package: "grpc.testing", Service: "TestService", FullMethod: "/grpc.testing.TestService/StreamingOutputCall"}, {MethodInfo: grpc.MethodInfo{Name: "StreamingInputCall", IsClientStream: true, IsServerStream: false}, Package: "grpc.testing", Service: "TestService", FullMethod: "/grpc.testing.TestService/StreamingInputCall"}, {MethodInfo: grpc.MethodInfo{Name: "FullDuplexCall", IsClientStream: true, IsServerStream: true}, Package: "grpc.testing", Service: "TestService", FullMethod: "/grpc.testing.TestService/FullDuplexCall"}, {MethodInfo: grpc.MethodInfo{Name: "HalfDuplexCall", IsClientStream: true, IsServerStream: true}, Package: "grpc.testing", Service: "TestService", FullMethod: "/grpc.testing.TestService/HalfDuplexCall"}},
 			},
 		},
+		{
+			name: "LoadGlob",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.load(["../grpc/testdata/grpc_protoset_testing"], "**/test_*.proto");`,
+				val: []xk6grpc.MethodInfo{
+					{
+						MethodInfo: grpc.MethodInfo{Name: "Test", IsClientStream: false, IsServerStream: false},
+						Package:    "grpc.protoset.testing", Service: "TestService", FullMethod: "/grpc.protoset.testing.TestService/Test",
+					},
+				},
+			},
+		},
+		{
+			name: "LoadDirectory",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			var methods = client.load(["../grpc/testdata"], "grpc_testing");
+			if (methods.length !== 6 || methods[0].full_method !== "/grpc.testing.TestService/EmptyCall") {
+				throw new Error("unexpected methods: " + JSON.stringify(methods));
+			}`,
+			},
+		},
+		{
+			name: "LoadImportMappings",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.load({
+				paths: ["../grpc/testdata/import_mapping"],
+				mappings: { "github.com/acme/apis/": "../grpc/testdata/import_mapping/third_party/acme/" },
+			}, "orders.proto");`,
+				val: []xk6grpc.MethodInfo{
+					{
+						MethodInfo: grpc.MethodInfo{Name: "Place", IsClientStream: false, IsServerStream: false},
+						Package:    "grpc.testdata.importmapping", Service: "OrderService",
+						FullMethod: "/grpc.testdata.importmapping.OrderService/Place",
+					},
+				},
+			},
+		},
+		{
+			name: "LoadImportNotMapped",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.load(["../grpc/testdata/import_mapping"], "orders.proto");`,
+				err: "github.com/acme/apis/common/money.proto",
+			},
+		},
+		{
+			name: "LoadImportPathsBadParam",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.load({ path: ["../grpc/testdata/import_mapping"] }, "orders.proto");`,
+				err: `unknown import paths param: "path"`,
+			},
+		},
+		{
+			name: "LoadGlobNoMatch",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.load(["../grpc/testdata"], "**/does_not_exist_*.proto");`,
+				err: `no proto files match "**/does_not_exist_*.proto" in the import paths`,
+			},
+		},
 		{
 			name: "LoadProtosetNotFound",
 			initString: codeBlock{
@@ -119,6 +211,101 @@ func TestClient(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ListServicesAndMethods",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.load([], "../grpc/testdata/grpc_testing/test.proto");
+			client.loadProtoset("testdata/grpc_protoset_testing/test.protoset");
+			var services = client.listServices();
+			if (JSON.stringify(services) !== '["grpc.protoset.testing.TestService","grpc.testing.TestService"]') {
+				throw new Error("unexpected services: " + JSON.stringify(services));
+			}
+			if (client.listMethods("grpc.testing.TestService").length !== 6 || client.listMethods().length !== 7) {
+				throw new Error("unexpected methods count");
+			}
+			client.listMethods("grpc.protoset.testing.TestService");`,
+				val: []xk6grpc.MethodInfo{
+					{
+						MethodInfo: grpc.MethodInfo{Name: "Test", IsClientStream: false, IsServerStream: false},
+						Package:    "grpc.protoset.testing", Service: "TestService", FullMethod: "/grpc.protoset.testing.TestService/Test",
+					},
+				},
+			},
+		},
+		{
+			name: "ListMethodsUnknownService",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.load([], "../grpc/testdata/grpc_testing/test.proto");
+			client.listMethods("grpc.testing.Unknown");`,
+				err: `service "grpc.testing.Unknown" not found in the loaded file descriptors`,
+			},
+		},
+		{
+			name: "SampleRequest",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.loadProtoContent({
+				"sample.proto": 'syntax = "proto3"; package sample; import "google/protobuf/timestamp.proto";' +
+					'enum Kind { KIND_UNKNOWN = 0; KIND_A = 1; }' +
+					'message Node { string name = 1; repeated Node children = 2; }' +
+					'message Req { Kind kind = 1; repeated int32 ids = 2; map<string, Node> nodes = 3; ' +
+					'oneof choice { string text = 4; bool flag = 5; } google.protobuf.Timestamp at = 6; Node root = 7; }' +
+					'service Svc { rpc Do(Req) returns (Req); }',
+			});
+			client.sampleRequest("sample.Svc/Do");`,
+				val: map[string]interface{}{
+					"kind":  "KIND_UNKNOWN",
+					"ids":   []interface{}{float64(0)},
+					"nodes": map[string]interface{}{"": map[string]interface{}{"name": "", "children": []interface{}{}}},
+					"text":  "",
+					"at":    nil,
+					"root":  map[string]interface{}{"name": "", "children": []interface{}{}},
+				},
+			},
+		},
+		{
+			name: "SampleRequestUnknownMethod",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.load([], "../grpc/testdata/grpc_testing/test.proto");
+			client.sampleRequest("grpc.testing.TestService/Unknown");`,
+				err: `method "/grpc.testing.TestService/Unknown" not found in file descriptors`,
+			},
+		},
+		{
+			name: "LoadProtoContent",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.loadProtoContent({
+				"inline/svc.proto": 'syntax = "proto3"; package inline; import "inline/common.proto"; import "google/protobuf/empty.proto";' +
+					'service InlineService { rpc Get(Id) returns (google.protobuf.Empty); }',
+			}, {
+				"inline/common.proto": 'syntax = "proto3"; package inline; message Id { string id = 1; }',
+			});`,
+				val: []xk6grpc.MethodInfo{
+					{
+						MethodInfo: grpc.MethodInfo{Name: "Get", IsClientStream: false, IsServerStream: false},
+						Package:    "inline", Service: "InlineService", FullMethod: "/inline.InlineService/Get",
+					},
+				},
+			},
+		},
+		{
+			name: "LoadProtoContentSyntaxError",
+			initString: codeBlock{
+				code: `
+			var client = new grpc.Client();
+			client.loadProtoContent({ "broken.proto": 'syntax = "proto3"; message {' });`,
+				err: "broken.proto:1:28: syntax error",
+			},
+		},
 		{
 			name: "ConnectInit",
 			initString: codeBlock{
@@ -156,8 +343,8 @@ func TestClient(t *testing.T) {
 				var client = new grpc.Client();
 				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", { name: "k6" });`,
-				err:  `unknown connect param: "name"`,
+				code: `client.connect("GRPCBIN_ADDR", { label: "k6" });`,
+				err:  `unknown connect param: "label"`,
 			},
 		},
 		{
@@ -244,158 +431,150 @@ func TestClient(t *testing.T) {
 				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
 					samplesBuf := metrics.GetBufferedSamples(samples)
 					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+					assertMetricEmitted(t, "grpc_msgs_sent", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+					assertMetricEmitted(t, "grpc_msgs_received", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+					assertMetricEmitted(t, "grpc_data_sent", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+					assertMetricEmitted(t, "grpc_data_received", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+					assertMetricEmitted(t, "grpc_req_waiting", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+					assert.Len(t, metricValues(samplesBuf, "grpc_conn_duration"), 1)
+					assert.Len(t, metricValues(samplesBuf, "grpc_handshake_duration"), 1)
 				},
 			},
 		},
 		{
-			name: "InvokeAnyProto",
+			name: "Channelz",
 			initString: codeBlock{code: `
 				var client = new grpc.Client();
-				client.load([], "../grpc/testdata/grpc_any_testing/any_test.proto");`},
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				tb.GRPCAnyStub.SumFunc = func(ctx context.Context, req *grpcanytesting.SumRequest) (*grpcanytesting.SumReply, error) {
-					var sumRequestData grpcanytesting.SumRequestData
-					if err := req.Data.UnmarshalTo(&sumRequestData); err != nil {
-						return nil, err
-					}
-
-					sumReplyData := &grpcanytesting.SumReplyData{
-						V:   sumRequestData.A + sumRequestData.B,
-						Err: "",
-					}
-					sumReply := &grpcanytesting.SumReply{
-						Data: &any.Any{},
-					}
-					if err := sumReply.Data.MarshalFrom(sumReplyData); err != nil {
-						return nil, err
-					}
-
-					return sumReply, nil
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
 				}
 			},
 			vuString: codeBlock{
 				code: `
-				client.connect("GRPCBIN_ADDR");
-				var resp = client.invoke("grpc.any.testing.AnyTestService/Sum",  {
-					data: {
-						"@type": "type.googleapis.com/grpc.any.testing.SumRequestData",
-						"a": 1,
-						"b": 2,
-					},
-				})
-				if (resp.status !== grpc.StatusOK) {
-					throw new Error("unexpected error: " + JSON.stringify(resp.error) + "or status: " + resp.status)
+				client.connect("GRPCBIN_ADDR", { channelz: true });
+				client.invoke("grpc.testing.TestService/EmptyCall", {})
+				// the channelz is process-wide, the other tests' channels could be left to a reused port
+				var channels = client.channelz().filter((ch) => ch.callsSucceeded > 0)
+				if (channels.length !== 1 || channels[0].target !== "GRPCBIN_ADDR" || channels[0].state !== "READY") {
+					throw new Error("unexpected channels: " + JSON.stringify(channels))
 				}
-				if (resp.message.data.v !== "3") {
-					throw new Error("unexpected resp message data")
+				if (channels[0].callsStarted !== 1 || channels[0].subchannels.length !== 1) {
+					throw new Error("unexpected channel: " + JSON.stringify(channels[0]))
 				}`,
-				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
-					samplesBuf := metrics.GetBufferedSamples(samples)
-					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.any.testing.AnyTestService/Sum"))
-				},
 			},
 		},
 		{
-			name: "RequestMessage",
-			initString: codeBlock{
-				code: `
+			name: "Channels",
+			initString: codeBlock{code: `
 				var client = new grpc.Client();
-				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
-			},
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				tb.GRPCStub.UnaryCallFunc = func(_ context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
-					if req.Payload == nil || string(req.Payload.Body) != "负载测试" {
-						return nil, status.Error(codes.InvalidArgument, "")
-					}
-					return &grpc_testing.SimpleResponse{}, nil
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
 				}
 			},
-			vuString: codeBlock{code: `
-				client.connect("GRPCBIN_ADDR");
-				var resp = client.invoke("grpc.testing.TestService/UnaryCall", { payload: { body: "6LSf6L295rWL6K+V"} })
-				if (resp.status !== grpc.StatusOK) {
-					throw new Error("server did not receive the correct request message")
-				}`},
-		},
-		{
-			name: "RequestHeaders",
-			initString: codeBlock{
+			vuString: codeBlock{
 				code: `
-				var client = new grpc.Client();
-				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
-			},
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					md, ok := metadata.FromIncomingContext(ctx)
-					if !ok || len(md["x-load-tester"]) == 0 || md["x-load-tester"][0] != "k6" {
-						return nil, status.Error(codes.FailedPrecondition, "")
-					}
-
-					return &grpc_testing.Empty{}, nil
+				client.connect("GRPCBIN_ADDR", { channels: 3, channelz: true });
+				if (!client.waitForState("READY", "5s")) {
+					throw new Error("the channels aren't ready")
 				}
-			},
-			vuString: codeBlock{code: `
-				client.connect("GRPCBIN_ADDR");
-				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { metadata: { "X-Load-Tester": "k6" } })
-				if (resp.status !== grpc.StatusOK) {
-					throw new Error("failed to send correct headers in the request")
+				for (var i = 0; i < 6; i++) {
+					client.invoke("grpc.testing.TestService/EmptyCall", {})
 				}
-			`},
+				var channels = client.channelz().filter((ch) => ch.callsSucceeded > 0)
+				if (channels.length !== 3) {
+					throw new Error("unexpected channels: " + JSON.stringify(channels))
+				}
+				channels.forEach(function (ch) {
+					if (ch.callsStarted !== 2) {
+						throw new Error("the calls aren't round-robined: " + JSON.stringify(channels))
+					}
+				})`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					ready := metricValues(metrics.GetBufferedSamples(samples), "grpc_channels_ready")
+					assert.Equal(t, []float64{3, 3, 3, 3, 3, 3}, ready)
+				},
+			},
 		},
 		{
-			name: "RequestBinHeaders",
+			name: "RateLimit",
 			initString: codeBlock{
 				code: `
 				var client = new grpc.Client();
 				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
 			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					md, ok := metadata.FromIncomingContext(ctx)
-					if !ok || len(md["x-load-tester-bin"]) == 0 || md["x-load-tester-bin"][0] != string([]byte{2, 200}) {
-						return nil, status.Error(codes.FailedPrecondition, "")
-					}
-
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
 					return &grpc_testing.Empty{}, nil
 				}
 			},
-			vuString: codeBlock{code: `
-				client.connect("GRPCBIN_ADDR");
-				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { metadata: { "X-Load-Tester-bin": new Uint8Array([2, 200]) } })
-				if (resp.status !== grpc.StatusOK) {
-					throw new Error("failed to send correct headers in the request")
-				}
-			`},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { rateLimit: { rps: 20, burst: 2 } });
+				for (var i = 0; i < 4; i++) {
+					client.invoke("grpc.testing.TestService/EmptyCall", {})
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					// the burst is sent at once, the rest is paced by the rate
+					paced := metricValues(metrics.GetBufferedSamples(samples), "grpc_rate_limit_duration")
+					require.Len(t, paced, 2)
+					assert.Greater(t, paced[0]+paced[1], float64(50))
+				},
+			},
 		},
 		{
-			name: "ResponseMessage",
+			name:       "RateLimitBadParam",
+			initString: codeBlock{code: `var client = new grpc.Client();`},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { rateLimit: { burst: 2 } })`,
+				err:  `invalid rateLimit value: the rps is required`,
+			},
+		},
+		{
+			name: "ConnectTags",
 			initString: codeBlock{
 				code: `
 				var client = new grpc.Client();
 				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
 			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
 				tb.GRPCStub.UnaryCallFunc = func(context.Context, *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
-					return &grpc_testing.SimpleResponse{
-						OauthScope: "水",
-					}, nil
+					return &grpc_testing.SimpleResponse{}, nil
 				}
 			},
 			vuString: codeBlock{
 				code: `
-				client.connect("GRPCBIN_ADDR");
-				var resp = client.invoke("grpc.testing.TestService/UnaryCall", {})
-				if (!resp.message || resp.message.username !== "" || resp.message.oauthScope !== "水") {
-					throw new Error("unexpected response message: " + JSON.stringify(resp.message))
-				}`,
+				client.connect("GRPCBIN_ADDR", { tags: { region: "eu" } });
+				client.invoke("grpc.testing.TestService/EmptyCall", {});
+				client.invoke("grpc.testing.TestService/UnaryCall", {}, { tags: { region: "us" } });`,
 				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
 					samplesBuf := metrics.GetBufferedSamples(samples)
-					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/UnaryCall"))
+
+					// the call's tags override the connection's ones
+					assert.Equal(t, map[string]string{
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"): "eu",
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/UnaryCall"): "us",
+					}, metricTagValues(samplesBuf, metrics.GRPCReqDurationName, "region"))
+					assert.Equal(t, map[string]string{"": "eu"}, metricTagValues(samplesBuf, "grpc_conn_duration", "region"))
 				},
 			},
 		},
 		{
-			name: "ResponseError",
+			name:       "ConnectTagsBadParam",
+			initString: codeBlock{code: `var client = new grpc.Client();`},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { tags: { region: 1 } })`,
+				err:  `invalid tags value: "region" value must be a string`,
+			},
+		},
+		{
+			name: "NamedConnections",
 			initString: codeBlock{
 				code: `
 				var client = new grpc.Client();
@@ -403,832 +582,4095 @@ func TestClient(t *testing.T) {
 			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
 				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					return nil, status.Error(codes.DataLoss, "foobar")
+					return &grpc_testing.Empty{}, nil
 				}
 			},
 			vuString: codeBlock{
 				code: `
 				client.connect("GRPCBIN_ADDR");
-				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
-				if (resp.status !== grpc.StatusDataLoss) {
-					throw new Error("unexpected error status: " + resp.status)
-				}
-				if (!resp.error || resp.error.message !== "foobar" || resp.error.code !== 15) {
-					throw new Error("unexpected error object: " + JSON.stringify(resp.error.code))
-				}`,
+				client.connect("example.com:443", { name: "eu", endpoints: ["GRPCBIN_ADDR"] });
+				[{}, { connection: "eu" }].forEach(function (params) {
+					var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, params);
+					if (resp.status !== grpc.StatusOK) {
+						throw new Error("unexpected status: " + resp.status);
+					}
+				});
+				client.close();`,
 				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
 					samplesBuf := metrics.GetBufferedSamples(samples)
-					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf,
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf,
+						"example.com:443/grpc.testing.TestService/EmptyCall")
 				},
 			},
 		},
 		{
-			name: "ResponseHeaders",
+			name: "NamedConnectionUnknown",
 			initString: codeBlock{
 				code: `
 				var client = new grpc.Client();
 				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
 			},
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					md := metadata.Pairs("foo", "bar")
-					_ = grpc.SetHeader(ctx, md)
-					return &grpc_testing.Empty{}, nil
-				}
-			},
 			vuString: codeBlock{
 				code: `
-				client.connect("GRPCBIN_ADDR");
-				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
-				if (resp.status !== grpc.StatusOK) {
-					throw new Error("unexpected error status: " + resp.status)
-				}
-				if (!resp.headers || !resp.headers["foo"] || resp.headers["foo"][0] !== "bar") {
-					throw new Error("unexpected headers object: " + JSON.stringify(resp.trailers))
-				}`,
-				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
-					samplesBuf := metrics.GetBufferedSamples(samples)
-					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
-				},
+				client.connect("GRPCBIN_ADDR", { name: "eu" });
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { connection: "us" });`,
+				err: `unknown connection: "us"`,
 			},
 		},
 		{
-			name: "ResponseTrailers",
+			name: "NamedConnectionOnly",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { name: "eu" });
+				client.invoke("grpc.testing.TestService/EmptyCall", {});`,
+				err: `no gRPC connection, you must call connect first`,
+			},
+		},
+		{
+			name:       "CloseBadParam",
+			initString: codeBlock{code: `var client = new grpc.Client();`},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.close({ graceful: "yes" });`,
+				err: `invalid graceful value: '"yes"', it needs to be boolean`,
+			},
+		},
+		{
+			name: "CheckHelpers",
 			initString: codeBlock{
 				code: `
 				var client = new grpc.Client();
 				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
 			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					md := metadata.Pairs("foo", "bar")
-					_ = grpc.SetTrailer(ctx, md)
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
 					return &grpc_testing.Empty{}, nil
 				}
+				tb.GRPCStub.UnaryCallFunc = func(context.Context, *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					return nil, status.Error(codes.NotFound, "no such user")
+				}
 			},
 			vuString: codeBlock{
 				code: `
 				client.connect("GRPCBIN_ADDR");
 				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
-				if (resp.status !== grpc.StatusOK) {
-					throw new Error("unexpected error status: " + resp.status)
+				if (!resp.ok || resp.errorMessage() !== "" || !grpc.checkStatus(resp)) {
+					throw new Error("unexpected OK response: " + JSON.stringify(resp))
 				}
-				if (!resp.trailers || !resp.trailers["foo"] || resp.trailers["foo"][0] !== "bar") {
-					throw new Error("unexpected trailers object: " + JSON.stringify(resp.trailers))
+				resp = client.invoke("grpc.testing.TestService/UnaryCall", {})
+				if (resp.ok || resp.errorMessage() !== "no such user") {
+					throw new Error("unexpected failed response: " + JSON.stringify(resp))
+				}
+				if (grpc.checkStatus(resp) || !grpc.checkStatus(resp, grpc.StatusOK, grpc.StatusNotFound)) {
+					throw new Error("unexpected checkStatus of the failed response")
 				}`,
-				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
-					samplesBuf := metrics.GetBufferedSamples(samples)
-					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
-				},
 			},
 		},
 		{
-			name: "LoadNotInit",
+			name:       "CheckStatusBadParam",
+			initString: codeBlock{code: `var client = new grpc.Client();`},
+			vuString: codeBlock{
+				code: `grpc.checkStatus({})`,
+				err:  `invalid checkStatus response: 'map[string]interface {}{}', it has no status`,
+			},
+		},
+		{
+			name: "InvokeMethodInfo",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				var methods = client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					md := metadata.Pairs("foo", "bar")
-					_ = grpc.SetTrailer(ctx, md)
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
 					return &grpc_testing.Empty{}, nil
 				}
 			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var method = methods.find(function (m) { return m.full_method === "/grpc.testing.TestService/EmptyCall" });
+				var resp = client.invoke(method, {})
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + resp.status)
+				}`,
+			},
+		},
+		{
+			name: "InvokeMethodSuggestions",
 			initString: codeBlock{
 				code: `
 				var client = new grpc.Client();
 				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
 			},
 			vuString: codeBlock{
-				code: `client.load()`,
-				err:  "load must be called in the init context",
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/emptycal", {})`,
+				err: `method "/grpc.testing.TestService/emptycal" not found in file descriptors, ` +
+					`did you mean "/grpc.testing.TestService/EmptyCall"`,
 			},
 		},
 		{
-			name: "ReflectUnregistered",
-			initString: codeBlock{
-				code: `var client = new grpc.Client();`,
-			},
+			name:       "ChannelsBadParam",
+			initString: codeBlock{code: `var client = new grpc.Client();`},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", {reflect: true})`,
-				err:  "rpc error: code = Unimplemented desc = unknown service grpc.reflection.v1alpha.ServerReflection",
+				code: `client.connect("GRPCBIN_ADDR", { channels: 0 })`,
+				err:  `invalid channels value`,
 			},
 		},
 		{
-			name: "Reflect",
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				reflection.Register(tb.ServerGRPC)
-			},
-			initString: codeBlock{
-				code: `var client = new grpc.Client();`,
-			},
+			name:       "ConnectInvalidMaxConcurrentStreams",
+			initString: codeBlock{code: `var client = new grpc.Client();`},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", {reflect: true})`,
+				code: `client.connect("GRPCBIN_ADDR", { maxConcurrentStreams: { reject: true } })`,
+				err:  `invalid maxConcurrentStreams value: the limit is required`,
 			},
 		},
 		{
-			name: "ReflectV1",
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				reflection.RegisterV1(tb.ServerGRPC)
-			},
-			initString: codeBlock{
-				code: `var client = new grpc.Client();`,
-			},
+			name:       "ChannelzNotConnected",
+			initString: codeBlock{code: `var client = new grpc.Client();`},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", {reflect: true})`,
+				code: `client.channelz()`,
+				err:  `no gRPC connection`,
 			},
 		},
 		{
-			name: "ReflectBadParam",
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				reflection.Register(tb.ServerGRPC)
-			},
-			initString: codeBlock{
-				code: `var client = new grpc.Client();`,
-			},
+			name:       "ChannelzDisabled",
+			initString: codeBlock{code: `var client = new grpc.Client();`},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", {reflect: "true"})`,
-				err:  `invalid reflect value`,
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.channelz()`,
+				err: `the channelz isn't enabled`,
 			},
 		},
 		{
-			name: "ReflectInvokeNoExist",
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				reflection.Register(tb.ServerGRPC)
-				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					return &grpc_testing.Empty{}, nil
+			name: "WaitForState",
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				if (!client.waitForState("READY", "5s")) {
+					throw new Error("the connection isn't ready")
 				}
+				if (client.getState() !== "READY") {
+					throw new Error("unexpected state: " + client.getState())
+				}
+				if (client.waitForState("SHUTDOWN", "10ms")) {
+					throw new Error("the connection is shut down")
+				}`,
 			},
-			initString: codeBlock{
-				code: `var client = new grpc.Client();`,
-			},
+		},
+		{
+			name: "WaitForStateInvalidState",
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
 			vuString: codeBlock{
 				code: `
-					client.connect("GRPCBIN_ADDR", {reflect: true})
-					client.invoke("foo/bar", {})
-				`,
-				err: `method "/foo/bar" not found in file descriptors`,
+				client.connect("GRPCBIN_ADDR");
+				client.waitForState("ready")`,
+				err: `invalid state value: "ready"`,
 			},
 		},
 		{
-			name: "ReflectInvoke",
+			name:       "GetStateNotConnected",
+			initString: codeBlock{code: `var client = new grpc.Client();`},
+			vuString: codeBlock{
+				code: `client.getState()`,
+				err:  `no gRPC connection`,
+			},
+		},
+		{
+			name: "InvokeAnyProto",
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_any_testing/any_test.proto");`},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				// this register both reflection APIs v1 and v1alpha
-				reflection.Register(tb.ServerGRPC)
+				tb.GRPCAnyStub.SumFunc = func(ctx context.Context, req *grpcanytesting.SumRequest) (*grpcanytesting.SumReply, error) {
+					var sumRequestData grpcanytesting.SumRequestData
+					if err := req.Data.UnmarshalTo(&sumRequestData); err != nil {
+						return nil, err
+					}
 
-				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					return &grpc_testing.Empty{}, nil
+					sumReplyData := &grpcanytesting.SumReplyData{
+						V:   sumRequestData.A + sumRequestData.B,
+						Err: "",
+					}
+					sumReply := &grpcanytesting.SumReply{
+						Data: &any.Any{},
+					}
+					if err := sumReply.Data.MarshalFrom(sumReplyData); err != nil {
+						return nil, err
+					}
+
+					return sumReply, nil
 				}
 			},
-			initString: codeBlock{
-				code: `var client = new grpc.Client();`,
-			},
 			vuString: codeBlock{
 				code: `
-					client.connect("GRPCBIN_ADDR", {reflect: true})
-					client.invoke("grpc.testing.TestService/EmptyCall", {})
-				`,
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.any.testing.AnyTestService/Sum",  {
+					data: {
+						"@type": "type.googleapis.com/grpc.any.testing.SumRequestData",
+						"a": 1,
+						"b": 2,
+					},
+				})
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected error: " + JSON.stringify(resp.error) + "or status: " + resp.status)
+				}
+				if (resp.message.data.v !== "3") {
+					throw new Error("unexpected resp message data")
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.any.testing.AnyTestService/Sum"))
+				},
 			},
 		},
 		{
-			name: "ReflectV1Alpha_Invoke",
+			name: "RequestMessage",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				// this register only v1alpha (this could be removed with removal v1alpha from grpc-go)
-				s := tb.ServerGRPC
-				svr := reflection.NewServer(reflection.ServerOptions{Services: s})
-				v1alphagrpc.RegisterServerReflectionServer(s, svr)
-
-				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					return &grpc_testing.Empty{}, nil
+				tb.GRPCStub.UnaryCallFunc = func(_ context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					if req.Payload == nil || string(req.Payload.Body) != "负载测试" {
+						return nil, status.Error(codes.InvalidArgument, "")
+					}
+					return &grpc_testing.SimpleResponse{}, nil
 				}
 			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/UnaryCall", { payload: { body: "6LSf6L295rWL6K+V"} })
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("server did not receive the correct request message")
+				}`},
+		},
+		{
+			name: "InvokeRaw",
 			initString: codeBlock{
 				code: `var client = new grpc.Client();`,
 			},
-			vuString: codeBlock{
-				code: `
-					client.connect("GRPCBIN_ADDR", {reflect: true})
-					client.invoke("grpc.testing.TestService/EmptyCall", {})
-				`,
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.UnaryCallFunc = func(_ context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					if req.ResponseSize != 5 {
+						return nil, status.Error(codes.InvalidArgument, "")
+					}
+					return &grpc_testing.SimpleResponse{Username: "k6"}, nil
+				}
 			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invokeRaw("grpc.testing.TestService/UnaryCall", new Uint8Array([0x10, 0x05]).buffer)
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + resp.status)
+				}
+				var msg = new Uint8Array(resp.message)
+				if (msg.length !== 4 || String.fromCharCode(msg[2], msg[3]) !== "k6") {
+					throw new Error("unexpected message: " + msg)
+				}`},
 		},
 		{
-			name: "ReflectV1Invoke",
+			name: "InvokeTracePropagation",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				// this register only reflection APIs v1
-				reflection.RegisterV1(tb.ServerGRPC)
-
 				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					return &grpc_testing.Empty{}, nil
+					md, _ := metadata.FromIncomingContext(ctx)
+					return &grpc_testing.Empty{}, grpc.SetHeader(ctx, metadata.Pairs(
+						"traceparent", strings.Join(md.Get("traceparent"), ","),
+						"b3", strings.Join(md.Get("b3"), ","),
+					))
 				}
 			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR", { tracePropagation: ["w3c", "b3"] });
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (!/^[0-9a-f]{32}$/.test(resp.trace.traceId) || !/^[0-9a-f]{16}$/.test(resp.trace.spanId)) {
+					throw new Error("unexpected trace: " + JSON.stringify(resp.trace))
+				}
+				if (resp.headers.traceparent[0] !== "00-" + resp.trace.traceId + "-" + resp.trace.spanId + "-01") {
+					throw new Error("unexpected traceparent: " + resp.headers.traceparent)
+				}
+				if (resp.headers.b3[0] !== resp.trace.traceId + "-" + resp.trace.spanId + "-1") {
+					throw new Error("unexpected b3: " + resp.headers.b3)
+				}`},
+		},
+		{
+			name: "InvokeTracePropagationBadParam",
 			initString: codeBlock{
 				code: `var client = new grpc.Client();`,
 			},
 			vuString: codeBlock{
-				code: `
-					client.connect("GRPCBIN_ADDR", {reflect: true})
-					client.invoke("grpc.testing.TestService/EmptyCall", {})
-				`,
+				code: `client.connect("GRPCBIN_ADDR", { tracePropagation: "jaeger" })`,
+				err:  `invalid tracePropagation value`,
 			},
 		},
 		{
-			name: "MaxReceiveSizeBadParam",
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				reflection.Register(tb.ServerGRPC)
-			},
+			name: "InvokeInterceptors",
 			initString: codeBlock{
-				code: `var client = new grpc.Client();`,
-			},
-			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", {maxReceiveSize: "error"})`,
-				err:  `invalid maxReceiveSize value: '"error"', it needs to be an integer`,
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var seen = [];
+				client.addInterceptor(function (call) {
+					call.method = "grpc.testing.TestService/UnaryCall";
+					call.metadata["x-request-id"] = "42";
+					call.message = { responseSize: call.message.size };
+					return function (resp) { seen.push("first:" + resp.message.username) };
+				});
+				client.addInterceptor(function (call) {
+					call.metadata["x-signature"] = call.method + ":" + call.metadata["x-request-id"];
+					return function (resp) { seen.push("second:" + resp.status) };
+				});`,
 			},
-		},
-		{
-			name: "MaxReceiveSizeNonPositiveInteger",
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				reflection.Register(tb.ServerGRPC)
+				tb.GRPCStub.UnaryCallFunc = func(ctx context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					md, _ := metadata.FromIncomingContext(ctx)
+					if req.ResponseSize != 5 || md.Get("x-request-id")[0] != "42" || md.Get("tenant")[0] != "acme" ||
+						md.Get("token-bin")[0] != string([]byte{2, 200}) {
+						return nil, status.Error(codes.InvalidArgument, "")
+					}
+					return &grpc_testing.SimpleResponse{Username: md.Get("x-signature")[0]}, nil
+				}
 			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", { size: 5 }, { metadata: { tenant: "acme", "token-bin": new Uint8Array([2, 200]) } })
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + resp.status)
+				}
+				if (seen.join() !== "second:0,first:grpc.testing.TestService/UnaryCall:42") {
+					throw new Error("unexpected callbacks: " + seen.join())
+				}`},
+		},
+		{
+			name: "InvokeInterceptorError",
 			initString: codeBlock{
-				code: `var client = new grpc.Client();`,
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				client.addInterceptor(function (call) { throw new Error("not signed") });`,
 			},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", {maxReceiveSize: -1})`,
-				err:  `invalid maxReceiveSize value: '-1, it needs to be a positive integer`,
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {})`,
+				err: `not signed`,
 			},
 		},
 		{
-			name: "ReceivedMessageLargerThanMax",
+			name: "InvokeMarshaled",
 			initString: codeBlock{
 				code: `
 				var client = new grpc.Client();
-				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var req = client.marshal("grpc.testing.TestService/UnaryCall", { responseSize: 5 });`,
 			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
 				tb.GRPCStub.UnaryCallFunc = func(_ context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
-					response := &grpc_testing.SimpleResponse{}
-					response.Payload = req.Payload
-					return response, nil
+					if req.ResponseSize != 5 {
+						return nil, status.Error(codes.InvalidArgument, "")
+					}
+					return &grpc_testing.SimpleResponse{Username: "k6"}, nil
 				}
 			},
-			vuString: codeBlock{
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				for (var i = 0; i < 2; i++) {
+					var resp = client.invoke("grpc.testing.TestService/UnaryCall", req)
+					if (resp.status !== grpc.StatusOK || resp.message.username !== "k6") {
+						throw new Error("unexpected response: " + JSON.stringify(resp))
+					}
+				}`},
+		},
+		{
+			name: "InvokeLazyMessage",
+			initString: codeBlock{
 				code: `
-				client.connect("GRPCBIN_ADDR", {maxReceiveSize: 1})
-				var resp = client.invoke("grpc.testing.TestService/UnaryCall", { payload: { body: "testMaxReceiveSize"} })
-				if (resp.status == grpc.StatusResourceExhausted) {
-					throw new Error(resp.error.message)
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.UnaryCallFunc = func(context.Context, *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					return &grpc_testing.SimpleResponse{Username: "k6"}, nil
 				}
-				`,
-				err: `received message larger than max`,
 			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/UnaryCall", {}, { lazyMessage: true })
+				if (resp.status !== grpc.StatusOK || resp.message !== null) {
+					throw new Error("unexpected response: " + JSON.stringify(resp))
+				}
+				if (resp.json().username !== "k6") {
+					throw new Error("unexpected message: " + JSON.stringify(resp.json()))
+				}
+				var msg = new Uint8Array(resp.binary())
+				if (msg.length !== 4 || String.fromCharCode(msg[2], msg[3]) !== "k6") {
+					throw new Error("unexpected binary message: " + msg)
+				}`},
 		},
 		{
-			name: "MaxSendSizeBadParam",
+			name: "InvokeEagerMessage",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				reflection.Register(tb.ServerGRPC)
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
 			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (JSON.stringify(resp.json()) !== JSON.stringify(resp.message) || resp.binary().byteLength !== 0) {
+					throw new Error("unexpected accessors' messages: " + JSON.stringify(resp.json()))
+				}`},
+		},
+		{
+			name: "InvokeMarshaledOtherInput",
 			initString: codeBlock{
-				code: `var client = new grpc.Client();`,
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var req = client.marshal("grpc.testing.TestService/UnaryCall", { responseSize: 5 });`,
 			},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", {maxSendSize: "error"})`,
-				err:  `invalid maxSendSize value: '"error"', it needs to be an integer`,
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", req)`,
+				err: `the request is marshaled as grpc.testing.SimpleRequest, but the method's input is grpc.testing.Empty`,
 			},
 		},
 		{
-			name: "MaxSendSizeNonPositiveInteger",
+			name: "InvokeRawError",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				reflection.Register(tb.ServerGRPC)
+				tb.GRPCStub.UnaryCallFunc = func(context.Context, *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					return nil, status.Error(codes.InvalidArgument, "bad request")
+				}
 			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invokeRaw("grpc.testing.TestService/UnaryCall", new ArrayBuffer(0))
+				if (resp.status !== grpc.StatusInvalidArgument || resp.message !== null || resp.error.message !== "bad request") {
+					throw new Error("unexpected response: " + JSON.stringify(resp))
+				}`},
+		},
+		{
+			name: "InvokeRawBadRequest",
 			initString: codeBlock{
 				code: `var client = new grpc.Client();`,
 			},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", {maxSendSize: -1})`,
-				err:  `invalid maxSendSize value: '-1, it needs to be a positive integer`,
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invokeRaw("grpc.testing.TestService/UnaryCall", {})`,
+				err: `invalid request value`,
 			},
 		},
 		{
-			name: "SentMessageLargerThanMax",
+			name: "RequestHeaders",
 			initString: codeBlock{
 				code: `
 				var client = new grpc.Client();
 				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
 			},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				tb.GRPCStub.UnaryCallFunc = func(context.Context, *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
-					return &grpc_testing.SimpleResponse{}, nil
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					md, ok := metadata.FromIncomingContext(ctx)
+					if !ok || len(md["x-load-tester"]) == 0 || md["x-load-tester"][0] != "k6" {
+						return nil, status.Error(codes.FailedPrecondition, "")
+					}
+
+					return &grpc_testing.Empty{}, nil
 				}
 			},
-			vuString: codeBlock{
-				code: `
-				client.connect("GRPCBIN_ADDR", {maxSendSize: 1})
-				var resp = client.invoke("grpc.testing.TestService/UnaryCall", { payload: { body: "testMaxSendSize"} })
-				if (resp.status == grpc.StatusResourceExhausted) {
-					throw new Error(resp.error.message)
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { metadata: { "X-Load-Tester": "k6" } })
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("failed to send correct headers in the request")
 				}
-				`,
-				err: `trying to send message larger than max`,
-			},
+			`},
 		},
 		{
-			name: "Close",
+			name: "ConnectAuthority",
 			initString: codeBlock{
 				code: `
 				var client = new grpc.Client();
 				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
 			},
-			vuString: codeBlock{
-				code: `
-			client.close();
-			client.invoke();`,
-				err: "no gRPC connection",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					md, ok := metadata.FromIncomingContext(ctx)
+					if !ok || len(md[":authority"]) == 0 || md[":authority"][0] != "example.com" {
+						return nil, status.Error(codes.FailedPrecondition, "")
+					}
+
+					return &grpc_testing.Empty{}, nil
+				}
 			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR", { authority: "example.com" });
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("failed to send the overridden authority in the request")
+				}
+			`},
 		},
 		{
-			name: "Wrappers",
-			setup: func(hb *httpmultibin.HTTPMultiBin) {
-				srv := wrappers_testing.Register(hb.ServerGRPC)
+			name: "CallAuthority",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					md, ok := metadata.FromIncomingContext(ctx)
+					if !ok || len(md[":authority"]) == 0 || md[":authority"][0] != "example.com" {
+						return nil, status.Error(codes.FailedPrecondition, "")
+					}
 
-				srv.TestStringImplementation = func(_ context.Context, sv *wrappers.StringValue) (*wrappers.StringValue, error) {
-					return &wrapperspb.StringValue{
-						Value: "hey " + sv.Value,
-					}, nil
+					return &grpc_testing.Empty{}, nil
 				}
 			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { authority: "example.com" })
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("failed to send the call's authority in the request")
+				}
+				resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusFailedPrecondition) {
+					throw new Error("the call's authority has overridden the connection's one")
+				}
+				resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { authority: "example.com" })
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("failed to reuse the call's authority connection")
+				}
+			`},
+		},
+		{
+			name: "CallAuthorityBadParam",
 			initString: codeBlock{
 				code: `
-				const client = new grpc.Client();
-				client.load([], "../grpc/testdata/wrappers_testing/test.proto");
-				`,
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
 			},
 			vuString: codeBlock{
 				code: `
 				client.connect("GRPCBIN_ADDR");
-				
-				let respString = client.invoke("grpc.wrappers.testing.Service/TestString", "John")
-				if (respString.message !== "hey John") {
-					throw new Error("expected to get 'hey John', but got a " + respString.message)
-				}				
-			`,
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { authority: "" })`,
+				err: `invalid authority value: '""', it needs to be a non-empty string`,
 			},
 		},
 		{
-			name: "WrappersWithReflection",
-			setup: func(hb *httpmultibin.HTTPMultiBin) {
-				reflection.Register(hb.ServerGRPC)
-
-				srv := wrappers_testing.Register(hb.ServerGRPC)
-
-				srv.TestIntegerImplementation = func(_ context.Context, iv *wrappers.Int64Value) (*wrappers.Int64Value, error) {
-					return &wrappers.Int64Value{
-						Value: 2 * iv.Value,
-					}, nil
-				}
-
-				srv.TestStringImplementation = func(_ context.Context, sv *wrappers.StringValue) (*wrappers.StringValue, error) {
-					return &wrapperspb.StringValue{
-						Value: "hey " + sv.Value,
-					}, nil
-				}
-
-				srv.TestBooleanImplementation = func(_ context.Context, bv *wrappers.BoolValue) (*wrappers.BoolValue, error) {
-					return &wrapperspb.BoolValue{
-						Value: bv.Value != true,
-					}, nil
-				}
-
-				srv.TestDoubleImplementation = func(_ context.Context, bv *wrappers.DoubleValue) (*wrappers.DoubleValue, error) {
-					return &wrapperspb.DoubleValue{
-						Value: bv.Value * 2,
-					}, nil
-				}
+			name: "ConnectAuthorityBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { authority: 42 })`,
+				err:  `invalid authority value: '42', it needs to be a non-empty string`,
 			},
+		},
+		{
+			name: "CircuitBreaker",
 			initString: codeBlock{
 				code: `
-				const client = new grpc.Client();
-				`,
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var transitions = [];
+				client.on("circuitBreaker", function (e) {
+					transitions.push(e.from + " -> " + e.to);
+				});`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return nil, status.Error(codes.Unavailable, "backend is down")
+				}
 			},
 			vuString: codeBlock{
 				code: `
-				client.connect("GRPCBIN_ADDR", {reflect: true});
-				
-				let respString = client.invoke("grpc.wrappers.testing.Service/TestString", "John")
-				if (respString.message !== "hey John") {
-					throw new Error("expected to get 'hey John', but got a " + respString.message)
-				}
-
-				let respInt = client.invoke("grpc.wrappers.testing.Service/TestInteger", "3")
-				if (respInt.message !== "6") {
-					throw new Error("expected to get '6', but got a " + respInt.message)
-				}
-
-				let respDouble = client.invoke("grpc.wrappers.testing.Service/TestDouble", "2.7")
-				if (respDouble.message !== 5.4) {
-					throw new Error("expected to get '5.4', but got a " + respDouble.message)
+				client.connect("GRPCBIN_ADDR", { circuitBreaker: { maxFailures: 2, cooldown: "1h" } });
+				client.invoke("grpc.testing.TestService/EmptyCall", {});
+				client.invoke("grpc.testing.TestService/EmptyCall", {});
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+				if (resp.status !== grpc.StatusUnavailable || resp.error.message !== "circuit breaker is open") {
+					throw new Error("unexpected response: " + JSON.stringify(resp));
 				}
-			`,
+				if (transitions.join(", ") !== "closed -> open") {
+					throw new Error("unexpected transitions: " + transitions.join(", "));
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, "grpc_circuit_breaker_rejections", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+					assertMetricEmitted(t, "grpc_circuit_breaker_state_changes", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+				},
 			},
 		},
 		{
-			name: "ValueReflection",
-			setup: func(hb *httpmultibin.HTTPMultiBin) {
-				reflection.Register(hb.ServerGRPC)
-
-				srv := wrappers_testing.Register(hb.ServerGRPC)
-
-				srv.TestValueImplementation = func(_ context.Context, in *_struct.Value) (*_struct.Value, error) {
-					if in.GetNumberValue() == 12 {
-						return &_struct.Value{
-							Kind: &_struct.Value_NumberValue{
-								NumberValue: 42,
-							},
-						}, nil
-					}
-
-					if in.GetStringValue() != "" {
-						return &_struct.Value{
-							Kind: &_struct.Value_StringValue{
-								StringValue: "hey " + in.GetStringValue(),
-							},
-						}, nil
-					}
-
-					return &_struct.Value{
-						Kind: &_struct.Value_StringValue{
-							StringValue: "I don't know what to answer",
-						},
-					}, nil
-				}
-			},
+			name: "CircuitBreakerBadParam",
 			initString: codeBlock{
-				code: `
-				const client = new grpc.Client();
-				`,
+				code: `var client = new grpc.Client();`,
 			},
 			vuString: codeBlock{
-				code: `
-				client.connect("GRPCBIN_ADDR", {reflect: true});
-
-				let respString = client.invoke("grpc.wrappers.testing.Service/TestValue", "John")
-				if (respString.message !== "hey John") {
-					throw new Error("expected to get 'hey John', but got a " + respString.message)
-				}
-
-				let respNumber = client.invoke("grpc.wrappers.testing.Service/TestValue", 12)
-				if (respNumber.message !== 42) {
-					throw new Error("expected to get '42', but got a " + respNumber.message)
-				}
-
-				let respBool = client.invoke("grpc.wrappers.testing.Service/TestValue", false)
-				if (respBool.message !== "I don't know what to answer") {
-					throw new Error("expected to get 'I don't know what to answer', but got a " + respBool.message)
-				}
-			`,
+				code: `client.connect("GRPCBIN_ADDR", { circuitBreaker: { maxFailures: 0 } })`,
+				err:  `invalid circuitBreaker maxFailures value: '0', it needs to be a positive integer`,
 			},
 		},
-	}
-
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-
-			ts := newTestState(t)
-
-			// setup necessary environment if needed by a test
-			if tt.setup != nil {
-				tt.setup(ts.httpBin)
-			}
-
-			val, err := ts.Run(tt.initString.code)
-			assertResponse(t, tt.initString, err, val, ts)
-
-			ts.ToVUContext()
-			val, err = ts.Run(tt.vuString.code)
-			assertResponse(t, tt.vuString, err, val, ts)
-		})
-	}
-}
-
-func TestClient_TlsParameters(t *testing.T) {
-	t.Parallel()
-
-	testingKey := func(s string) string {
-		t.Helper()
-		return strings.ReplaceAll(s, "TESTING KEY", "PRIVATE KEY")
-	}
-
-	clientAuthCA := []byte("-----BEGIN CERTIFICATE-----\nMIIBWzCCAQGgAwIBAgIJAIQMBgLi+DV6MAoGCCqGSM49BAMCMBAxDjAMBgNVBAMM\nBU15IENBMCAXDTIyMDEyMTEyMjkzNloYDzMwMjEwNTI0MTIyOTM2WjAQMQ4wDAYD\nVQQDDAVNeSBDQTBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABHnrghULHa2hSa/C\nWimwCn42KWdlPqd6/zs3JgLIxTvBHJJlfbhWbBqtybqyovWd3QykHMIpx0NZmpYn\nG8FoWpmjQjBAMA4GA1UdDwEB/wQEAwIBBjAPBgNVHRMBAf8EBTADAQH/MB0GA1Ud\nDgQWBBSkukBA8lgFvvBJAYKsoSUR+PX71jAKBggqhkjOPQQDAgNIADBFAiEAiFF7\nY54CMNRSBSVMgd4mQgrzJInRH88KpLsQ7VeOAaQCIEa0vaLln9zxIDZQKocml4Db\nAEJr8tDzMKIds6sRTBT4\n-----END CERTIFICATE-----")
-	localHostCert := "-----BEGIN CERTIFICATE-----\\nMIIDOTCCAiGgAwIBAgIQSRJrEpBGFc7tNb1fb5pKFzANBgkqhkiG9w0BAQsFADAS\\nMRAwDgYDVQQKEwdBY21lIENvMCAXDTcwMDEwMTAwMDAwMFoYDzIwODQwMTI5MTYw\\nMDAwWjASMRAwDgYDVQQKEwdBY21lIENvMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8A\\nMIIBCgKCAQEA6Gba5tHV1dAKouAaXO3/ebDUU4rvwCUg/CNaJ2PT5xLD4N1Vcb8r\\nbFSW2HXKq+MPfVdwIKR/1DczEoAGf/JWQTW7EgzlXrCd3rlajEX2D73faWJekD0U\\naUgz5vtrTXZ90BQL7WvRICd7FlEZ6FPOcPlumiyNmzUqtwGhO+9ad1W5BqJaRI6P\\nYfouNkwR6Na4TzSj5BrqUfP0FwDizKSJ0XXmh8g8G9mtwxOSN3Ru1QFc61Xyeluk\\nPOGKBV/q6RBNklTNe0gI8usUMlYyoC7ytppNMW7X2vodAelSu25jgx2anj9fDVZu\\nh7AXF5+4nJS4AAt0n1lNY7nGSsdZas8PbQIDAQABo4GIMIGFMA4GA1UdDwEB/wQE\\nAwICpDATBgNVHSUEDDAKBggrBgEFBQcDATAPBgNVHRMBAf8EBTADAQH/MB0GA1Ud\\nDgQWBBStsdjh3/JCXXYlQryOrL4Sh7BW5TAuBgNVHREEJzAlggtleGFtcGxlLmNv\\nbYcEfwAAAYcQAAAAAAAAAAAAAAAAAAAAATANBgkqhkiG9w0BAQsFAAOCAQEAxWGI\\n5NhpF3nwwy/4yB4i/CwwSpLrWUa70NyhvprUBC50PxiXav1TeDzwzLx/o5HyNwsv\\ncxv3HdkLW59i/0SlJSrNnWdfZ19oTcS+6PtLoVyISgtyN6DpkKpdG1cOkW3Cy2P2\\n+tK/tKHRP1Y/Ra0RiDpOAmqn0gCOFGz8+lqDIor/T7MTpibL3IxqWfPrvfVRHL3B\\ngrw/ZQTTIVjjh4JBSW3WyWgNo/ikC1lrVxzl4iPUGptxT36Cr7Zk2Bsg0XqwbOvK\\n5d+NTDREkSnUbie4GeutujmX3Dsx88UiV6UY/4lHJa6I5leHUNOHahRbpbWeOfs/\\nWkBKOclmOV2xlTVuPw==\\n-----END CERTIFICATE-----"
-	clientAuth := "-----BEGIN CERTIFICATE-----\\nMIIBVzCB/6ADAgECAgkAg/SeNG3XqB0wCgYIKoZIzj0EAwIwEDEOMAwGA1UEAwwF\\nTXkgQ0EwIBcNMjIwMTIxMTUxMjM0WhgPMzAyMTA1MjQxNTEyMzRaMBExDzANBgNV\\nBAMMBmNsaWVudDBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABKM7OJQMYG4KLtDA\\ngZ8zOg2PimHMmQnjD2HtI4cSwIUJJnvHWLowbFe9fk6XeP9b3dK1ImUI++/EZdVr\\nABAcngejPzA9MA4GA1UdDwEB/wQEAwIBBjAMBgNVHRMBAf8EAjAAMB0GA1UdDgQW\\nBBSttJe1mcPEnBOZ6wvKPG4zL0m1CzAKBggqhkjOPQQDAgNHADBEAiBPSLgKA/r9\\nu/FW6W+oy6Odm1kdNMGCI472iTn545GwJgIgb3UQPOUTOj0IN4JLJYfmYyXviqsy\\nzk9eWNHFXDA9U6U=\\n-----END CERTIFICATE-----"
-	clientAuthKey := testingKey("-----BEGIN EC TESTING KEY-----\\nMHcCAQEEINDaMGkOT3thu1A0LfLJr3Jd011/aEG6OArmEQaujwgpoAoGCCqGSM49\\nAwEHoUQDQgAEozs4lAxgbgou0MCBnzM6DY+KYcyZCeMPYe0jhxLAhQkme8dYujBs\\nV71+Tpd4/1vd0rUiZQj778Rl1WsAEByeBw==\\n-----END EC TESTING KEY-----")
-	clientAuthKeyEncrypted := testingKey("-----BEGIN EC TESTING KEY-----\\nProc-Type: 4,ENCRYPTED\\nDEK-Info: AES-256-CBC,3E311E9B602231BFB5C752071EE7D652\\n\\nsAKeqbacug0v4ruE1A0CACwGVEGBQVOl1CiGVp5RsxgNZKXzMS6EsTTNLw378coF\\nKXbF+he05HIuzToOz2ANLXov1iCrVpotKVB4l2obTQvg+5VET902ky99Mc9Us7jd\\nUwW8LpXlSlhcNWuUfK6wyosL42TbcIxjqZWaESW+6ww=\\n-----END EC TESTING KEY-----")
-	clientAuthBad := "-----BEGIN CERTIFICATE-----\\nMIIB2TCCAX6gAwIBAgIUJIZKiR78AH2ioZ+Jae/sElgH85kwCgYIKoZIzj0EAwIw\\nEDEOMAwGA1UEAwwFTXkgQ0EwHhcNMjMwNzA3MTAyNjQ2WhcNMjQwNzA2MTAyNjQ2\\nWjARMQ8wDQYDVQQDDAZjbGllbnQwWTATBgcqhkjOPQIBBggqhkjOPQMBBwNCAASj\\nOziUDGBuCi7QwIGfMzoNj4phzJkJ4w9h7SOHEsCFCSZ7x1i6MGxXvX5Ol3j/W93S\\ntSJlCPvvxGXVawAQHJ4Ho4G0MIGxMAkGA1UdEwQCMAAwEQYJYIZIAYb4QgEBBAQD\\nAgWgMCwGCWCGSAGG+EIBDQQfFh1Mb2NhbCBUZXN0IENsaWVudCBDZXJ0aWZpY2F0\\nZTAdBgNVHQ4EFgQUrbSXtZnDxJwTmesLyjxuMy9JtQswHwYDVR0jBBgwFoAUpLpA\\nQPJYBb7wSQGCrKElEfj1+9YwDgYDVR0PAQH/BAQDAgXgMBMGA1UdJQQMMAoGCCsG\\nAQUFBwMEMAoGCCqGSM49BAMCA0kAMEYCIQDcHrzug3V3WvUU+tEKhG1C4cPG5rPJ\\n/y3oOoM0roOnsgIhAP23UmiC6Qdgj+MOhXWSaNt3exWvlxdKmLm2edkxaTs+\\n-----END CERTIFICATE-----"
-
-	trivialKeyPassword := "abc123"
-	trivialWrongKeyPassword := "abc321"
-
-	tests := []testcase{
 		{
-			name:       "ConnectTlsEmptyTlsSuccess",
-			initString: codeBlock{code: "var client = new grpc.Client();"},
-			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", { tls: { }});`,
+			name: "AdaptiveThrottling",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
 			},
-		},
-		{
-			name:       "ConnectTlsInvalidTlsParamCertType",
-			initString: codeBlock{code: "var client = new grpc.Client();"},
-			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", { tls: { cert: 0 }});`,
-				err:  `invalid grpc.connect() parameters: invalid tls cert value: 'map[string]interface {}{"cert":0}', it needs to be a PEM formatted string`,
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return nil, status.Error(codes.ResourceExhausted, "overloaded")
+				}
 			},
-		},
-		{
-			name:       "ConnectTlsInvalidTlsParamKeyType",
-			initString: codeBlock{code: "var client = new grpc.Client();"},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", { tls: { cert: "", key: 0 }});`,
-				err:  `invalid grpc.connect() parameters: invalid tls key value: 'map[string]interface {}{"cert":"", "key":0}', it needs to be a PEM formatted string`,
+				code: `
+				client.connect("GRPCBIN_ADDR", { adaptiveThrottling: { k: 1, window: "1m" } });
+				var throttled = 0;
+				for (var i = 0; i < 20; i++) {
+					var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+					if (resp.error.message === "rejected by the client-side adaptive throttling") {
+						throttled++;
+					}
+				}
+				if (throttled === 0) {
+					throw new Error("no requests were throttled");
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, "grpc_adaptive_throttling_rejections", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+				},
 			},
 		},
 		{
-			name:       "ConnectTlsInvalidTlsParamPasswordType",
-			initString: codeBlock{code: "var client = new grpc.Client();"},
-			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", { tls: { cert: "", key: "", password: 0 }});`,
-				err:  `invalid grpc.connect() parameters: invalid tls password value: 'map[string]interface {}{"cert":"", "key":"", "password":0}', it needs to be a string`,
+			name: "AdaptiveThrottlingBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
 			},
-		},
-		{
-			name:       "ConnectTlsInvalidTlsParamCACertsType",
-			initString: codeBlock{code: "var client = new grpc.Client();"},
 			vuString: codeBlock{
-				code: `client.connect("GRPCBIN_ADDR", { tls: { cert: "", key: "", cacerts: 0 }});`,
-				err:  `invalid grpc.connect() parameters: invalid tls cacerts value: 'map[string]interface {}{"cacerts":0, "cert":"", "key":""}', it needs to be a string or an array of PEM formatted strings`,
+				code: `client.connect("GRPCBIN_ADDR", { adaptiveThrottling: { window: "10ms" } })`,
+				err:  `invalid adaptiveThrottling window value: '"10ms"', it needs to be at least 1s`,
 			},
 		},
 		{
-			name: "ConnectTls",
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				clientCAPool := x509.NewCertPool()
-				clientCAPool.AppendCertsFromPEM(clientAuthCA)
-				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
-				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+			name: "XDSCircuitBreakerRejection",
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { loadBalancing: "k6_test_xds_circuit_breaker" });
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusUnavailable || resp.errorKind !== "circuit_breaker") {
+					throw new Error("unexpected status: " + resp.status + " or error kind: " + resp.errorKind)
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					url := rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall")
+					assertMetricEmitted(t, "grpc_xds_circuit_breaker_rejections", samplesBuf, url)
+					assert.Equal(t, map[string]string{url: "circuit_breaker"},
+						metricTagValues(samplesBuf, metrics.GRPCReqDurationName, "error_kind"))
+				},
 			},
-			initString: codeBlock{code: "var client = new grpc.Client();"},
-			vuString:   codeBlock{code: fmt.Sprintf(`client.connect("GRPCBIN_ADDR", { tls: { cacerts: ["%s"], cert: "%s", key: "%s" }});`, localHostCert, clientAuth, clientAuthKey)},
 		},
 		{
-			name: "ConnectTlsEncryptedKey",
+			name: "XDSCircuitBreakerReturned",
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				clientCAPool := x509.NewCertPool()
-				clientCAPool.AppendCertsFromPEM(clientAuthCA)
-				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
-				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+				// the same error returned by an upstream server isn't the client's rejection
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return nil, status.Error(codes.Unavailable, "max requests 1 exceeded on service cluster-a")
+				}
 			},
-			initString: codeBlock{code: "var client = new grpc.Client();"},
-			vuString:   codeBlock{code: fmt.Sprintf(`client.connect("GRPCBIN_ADDR", { tls: { cacerts: ["%s"], cert: "%s", key: "%s", password: "%s" }});`, localHostCert, clientAuth, clientAuthKeyEncrypted, trivialKeyPassword)},
-		},
-		{
-			name:       "ConnectTlsEncryptedKeyDecryptionFailed",
-			initString: codeBlock{code: "var client = new grpc.Client();"},
 			vuString: codeBlock{
-				code: fmt.Sprintf(`client.connect("GRPCBIN_ADDR", { timeout: '5s', tls: { cert: "%s", key: "%s", password: "%s" }});`,
-					clientAuth,
-					clientAuthKeyEncrypted,
-					trivialWrongKeyPassword,
-				),
-				err: "x509: decryption password incorrect",
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusUnavailable || resp.errorKind !== "application") {
+					throw new Error("unexpected status: " + resp.status + " or error kind: " + resp.errorKind)
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assert.Empty(t, metricValues(samplesBuf, "grpc_xds_circuit_breaker_rejections"))
+					assert.Equal(t, map[string]string{
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"): "application",
+					}, metricTagValues(samplesBuf, metrics.GRPCReqDurationName, "error_kind"))
+				},
 			},
 		},
 		{
-			name: "ConnectTlsClientCertNoClientAuth",
+			name: "ErrorKind",
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				clientCAPool := x509.NewCertPool()
-				clientCAPool.AppendCertsFromPEM(clientAuthCA)
-				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
-				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return nil, status.Error(codes.Unavailable, "the backend is down")
+				}
+				tb.GRPCStub.UnaryCallFunc = func(ctx context.Context, _ *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
 			},
-			initString: codeBlock{code: `var client = new grpc.Client();`},
 			vuString: codeBlock{
-				code: fmt.Sprintf(`client.connect("GRPCBIN_ADDR", { timeout: '2s', tls: { cacerts: ["%s"], cert: "%s", key: "%s" }});`,
-					localHostCert,
-					clientAuthBad,
-					clientAuthKey),
-				err: "remote error: tls: bad certificate",
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.errorKind !== "application") {
+					throw new Error("unexpected error kind of the returned status: " + resp.errorKind)
+				}
+				resp = client.invoke("grpc.testing.TestService/UnaryCall", {}, { timeout: "50ms" })
+				if (resp.errorKind !== "timeout") {
+					throw new Error("unexpected error kind of the timeout: " + resp.errorKind)
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assert.Equal(t, map[string]string{
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"): "application",
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/UnaryCall"): "timeout",
+					}, metricTagValues(samplesBuf, metrics.GRPCReqDurationName, "error_kind"))
+				},
 			},
 		},
 		{
-			name: "ConnectTlsClientCertWithPasswordNoClientAuth",
+			name: "FaultInjected",
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
 			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				clientCAPool := x509.NewCertPool()
-				clientCAPool.AppendCertsFromPEM(clientAuthCA)
-				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
-				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+				// the Envoy's fault filter's abort
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return nil, status.Error(codes.Unavailable, "fault filter abort")
+				}
+				tb.GRPCStub.UnaryCallFunc = func(context.Context, *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					return nil, status.Error(codes.Unavailable, "the backend is down")
+				}
 			},
-			initString: codeBlock{code: `var client = new grpc.Client();`},
 			vuString: codeBlock{
-				code: fmt.Sprintf(`
-				client.connect("GRPCBIN_ADDR", { timeout: '2s', tls: { cacerts: ["%s"], cert: "%s", key: "%s", password: "%s" }});
-				`,
-					localHostCert,
-					clientAuthBad,
-					clientAuthKeyEncrypted,
-					trivialKeyPassword),
-				err: "remote error: tls: bad certificate",
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {})
+				client.invoke("grpc.testing.TestService/UnaryCall", {})`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assert.Equal(t, map[string]string{
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"): "injected",
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/UnaryCall"): "",
+					}, metricTagValues(samplesBuf, metrics.GRPCReqDurationName, "fault"))
+				},
 			},
 		},
 		{
-			name: "ConnectTlsInvokeSuccess",
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				clientCAPool := x509.NewCertPool()
-				clientCAPool.AppendCertsFromPEM(clientAuthCA)
-				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
-				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
-				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
-					return &grpc_testing.Empty{}, nil
-				}
-			},
+			name: "TimedOutSamples",
 			initString: codeBlock{code: `
 				var client = new grpc.Client();
 				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.UnaryCallFunc = func(ctx context.Context, _ *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+			},
 			vuString: codeBlock{
-				code: fmt.Sprintf(`
-				client.connect("GRPCBIN_ADDR", { timeout: '5s', tls: { cacerts: ["%s"], cert: "%s", key: "%s" }});
-				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
-				if (resp.status !== grpc.StatusOK) {
-					throw new Error("unexpected error: " + JSON.stringify(resp.error) + "or status: " + resp.status)
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/UnaryCall", {}, { timeout: "50ms" })
+				if (resp.status !== grpc.StatusDeadlineExceeded) {
+					throw new Error("unexpected status: " + resp.status)
 				}`,
-					localHostCert,
-					clientAuth,
-					clientAuthKey),
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					// the samples of the call ended by its timeout aren't dropped
+					assertMetricEmitted(t, metrics.GRPCReqDurationName, metrics.GetBufferedSamples(samples),
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/UnaryCall"))
+				},
 			},
 		},
 		{
-			name: "ConnectTlsBadAuthInvokeFails",
-			setup: func(tb *httpmultibin.HTTPMultiBin) {
-				clientCAPool := x509.NewCertPool()
-				clientCAPool.AppendCertsFromPEM(clientAuthCA)
-				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
-				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
-				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+			name: "Version",
+			initString: codeBlock{
+				code: `
+				var info = grpc.version();
+				if (!info.grpcVersion || !info.version) {
+					throw new Error("unexpected version info: " + JSON.stringify(info));
+				}
+				if (info.features.xds !== true || grpc.FeatureXDS !== true) {
+					throw new Error("xds feature isn't enabled");
+				}
+				if (info.features.spiffe !== grpc.FeatureSPIFFE) {
+					throw new Error("inconsistent spiffe feature");
+				}
+				if (info.features.http3 !== false || grpc.FeatureHTTP3 !== false || grpc.FeatureGRPCWeb !== false) {
+					throw new Error("unsupported transports aren't disabled");
+				}`,
+			},
+		},
+		{
+			name: "MethodThresholds",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var thresholds = client.methodThresholds({
+					"*": ["p(95)<500"],
+					"grpc.testing.TestService/EmptyCall": ["p(95)<100"],
+				});
+				var empty = thresholds["grpc_req_duration{name:/grpc.testing.TestService/EmptyCall}"];
+				if (empty.length !== 1 || empty[0] !== "p(95)<100") {
+					throw new Error("unexpected EmptyCall thresholds: " + JSON.stringify(empty));
+				}
+				var unary = thresholds["grpc_req_duration{name:/grpc.testing.TestService/UnaryCall}"];
+				if (unary.length !== 1 || unary[0] !== "p(95)<500") {
+					throw new Error("unexpected UnaryCall thresholds: " + JSON.stringify(unary));
+				}`,
+			},
+		},
+		{
+			name: "MethodThresholdsUnknownMethod",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				client.methodThresholds({ "grpc.testing.TestService/EmptyCal": ["p(95)<100"] });`,
+				err: `method "/grpc.testing.TestService/EmptyCal" not found in the loaded file descriptors`,
+			},
+		},
+		{
+			name: "ChainTrailerToMetadata",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					md, _ := metadata.FromIncomingContext(ctx)
+					page := 0
+					if tokens := md.Get("x-page-token"); len(tokens) > 0 {
+						_, _ = fmt.Sscanf(tokens[0], "page-%d", &page)
+					}
+					if page < 3 {
+						_ = grpc.SetTrailer(ctx, metadata.Pairs("x-next-page-token", fmt.Sprintf("page-%d", page+1)))
+					}
+
 					return &grpc_testing.Empty{}, nil
 				}
 			},
-			initString: codeBlock{code: `
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var result = client.chain("grpc.testing.TestService/EmptyCall", {}, {
+					from: "trailer:x-next-page-token",
+					to: "metadata:x-page-token",
+				});
+				if (result.calls !== 4) {
+					throw new Error("unexpected number of calls: " + result.calls);
+				}
+				if (result.response.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + result.response.status);
+				}`,
+			},
+		},
+		{
+			name: "ChainFieldMaxCalls",
+			initString: codeBlock{
+				code: `
 				var client = new grpc.Client();
-				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.UnaryCallFunc = func(ctx context.Context, _ *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					md, _ := metadata.FromIncomingContext(ctx)
+
+					return &grpc_testing.SimpleResponse{Username: "user" + strings.Join(md.Get("x-user"), "")}, nil
+				}
+			},
 			vuString: codeBlock{
-				code: fmt.Sprintf(`
-				client.connect("GRPCBIN_ADDR", { timeout: '2s', tls: { cacerts: ["%s"], cert: "%s", key: "%s" }});
-				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var result = client.chain("grpc.testing.TestService/UnaryCall", {}, {
+					from: "field:username",
+					to: "metadata:x-user",
+					maxCalls: 3,
+				});
+				if (result.calls !== 3) {
+					throw new Error("unexpected number of calls: " + result.calls);
+				}
+				if (result.response.message.username !== "useruseruser") {
+					throw new Error("unexpected username: " + result.response.message.username);
+				}`,
+			},
+		},
+		{
+			name: "ChainBadParam",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.chain("grpc.testing.TestService/EmptyCall", {}, { from: "body:token", to: "metadata:x-token" });`,
+				err: `invalid from value: "body:token", the kind needs to be one of header, trailer, field`,
+			},
+		},
+		{
+			name: "HealthGating",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				hs := health.NewServer()
+				hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+				healthpb.RegisterHealthServer(tb.ServerGRPC, hs)
+				time.AfterFunc(300*time.Millisecond, func() {
+					hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+				})
+
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { healthGating: { maxPause: "5s" } });
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
 				if (resp.status !== grpc.StatusOK) {
-					throw new Error("unexpected error: " + JSON.stringify(resp.error) + "or status: " + resp.status)
+					throw new Error("unexpected status: " + resp.status);
 				}`,
-					localHostCert,
-					clientAuthBad,
-					clientAuthKey),
-				err: "remote error: tls: bad certificate",
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, "grpc_health_gating_pause_duration", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+				},
+			},
+		},
+		{
+			name: "HealthWatch",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				hs := health.NewServer()
+				hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+				healthpb.RegisterHealthServer(tb.ServerGRPC, hs)
+				time.AfterFunc(300*time.Millisecond, func() {
+					hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+				})
+
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					time.Sleep(50 * time.Millisecond)
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var statuses = [];
+				var watch = client.healthWatch("", (s) => statuses.push(s));
+				var deadline = Date.now() + 3000;
+				while (watch.status() !== "SERVING" && Date.now() < deadline) {
+					client.invoke("grpc.testing.TestService/EmptyCall", {});
+				}
+				client.invoke("grpc.testing.TestService/EmptyCall", {});
+				watch.close();
+				if (statuses.join() !== "NOT_SERVING,SERVING") {
+					throw new Error("unexpected statuses: " + statuses.join());
+				}`,
+			},
+		},
+		{
+			name: "HealthWatchNotConnected",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.healthWatch("", () => {});`,
+				err:  `no gRPC connection, you must call connect first`,
+			},
+		},
+		{
+			name: "MockServer",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var server = new grpc.Server(client);
+				server.handle("grpc.testing.TestService/UnaryCall", (req, md) => {
+					return { username: md["x-user"] + ":" + req.responseSize };
+				});
+				server.handle("grpc.testing.TestService/EmptyCall", () => {
+					throw { code: grpc.StatusNotFound, message: "no such thing" };
+				});`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				var resp = client.invoke("grpc.testing.TestService/UnaryCall", { responseSize: 42 }, { metadata: { "x-user": "k6" } });
+				if (resp.status !== grpc.StatusOK || resp.message.username !== "k6:42") {
+					throw new Error("unexpected response: " + JSON.stringify(resp));
+				}
+				resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+				if (resp.status !== grpc.StatusNotFound || resp.error.message !== "no such thing") {
+					throw new Error("unexpected error: " + JSON.stringify(resp.error));
+				}
+				resp = client.invokeRaw("grpc.testing.OtherService/Call", "");
+				if (resp.status !== grpc.StatusUnimplemented) {
+					throw new Error("unexpected status: " + resp.status);
+				}
+				client.close();
+				server.stop();`,
+			},
+		},
+		{
+			name: "MockServerStartLoad",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var server = new grpc.Server(client);
+				server.handle("grpc.testing.TestService/EmptyCall", () => ({}));`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				try {
+					client.startLoad({ method: "grpc.testing.TestService/EmptyCall", rps: 10, duration: "1s" });
+				} finally {
+					client.close();
+					server.stop();
+				}`,
+				err: "starting a load while a mock server is started is not supported",
+			},
+		},
+		{
+			name: "Proto2Extensions",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.loadProtoContent({ "legacy.proto": ` + "`" + `
+					syntax = "proto2";
+					package legacy;
+					message Req {
+						required string id = 1;
+						optional group Meta = 2 {
+							optional int32 priority = 3;
+						}
+						extensions 100 to 199;
+					}
+					extend Req {
+						optional string tenant = 100;
+					}
+					message Resp {
+						required string id = 1;
+						optional string tenant = 2;
+						optional int32 priority = 3;
+						extensions 100 to 199;
+					}
+					extend Resp {
+						optional bool cached = 101;
+					}
+					service Legacy {
+						rpc Get(Req) returns (Resp);
+					}` + "`" + ` });
+				var server = new grpc.Server(client);
+				server.handle("legacy.Legacy/Get", (req) => {
+					return { id: req.id, tenant: req["[legacy.tenant]"], priority: req.meta.priority, "[legacy.cached]": true };
+				});`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				var resp = client.invoke("legacy.Legacy/Get", { id: "1", meta: { priority: 2 }, "[legacy.tenant]": "acme" });
+				if (resp.status !== grpc.StatusOK || resp.message.tenant !== "acme" || resp.message.priority !== 2 ||
+					resp.message["[legacy.cached]"] !== true) {
+					throw new Error("unexpected response: " + JSON.stringify(resp));
+				}
+				client.close();
+				server.stop();`,
+			},
+		},
+		{
+			name: "Proto2RequiredField",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.loadProtoContent({ "legacy.proto": ` + "`" + `
+					syntax = "proto2";
+					package legacy.required;
+					message Req {
+						required string id = 1;
+					}
+					service Legacy {
+						rpc Get(Req) returns (Req);
+					}` + "`" + ` });
+				var server = new grpc.Server(client);
+				server.handle("legacy.required.Legacy/Get", (req) => req);`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				client.invoke("legacy.required.Legacy/Get", {});`,
+				err: "required field legacy.required.Req.id not set",
+			},
+		},
+		{
+			name: "Int64Numbers",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.loadProtoContent({ "counters.proto": ` + "`" + `
+					syntax = "proto3";
+					package counters;
+					import "google/protobuf/wrappers.proto";
+					message Counters {
+						int64 small = 1;
+						uint64 large = 2;
+						repeated sint64 history = 3;
+						map<string, fixed64> totals = 4;
+						google.protobuf.Int64Value wrapped = 5;
+						Counters nested = 6;
+					}
+					service Stats {
+						rpc Get(Counters) returns (Counters);
+					}` + "`" + ` });
+				var server = new grpc.Server(client);
+				server.handle("counters.Stats/Get", (req) => req);`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				var req = {
+					small: -42,
+					large: "18446744073709551615",
+					history: [1, "9007199254740993"],
+					totals: { a: 7 },
+					wrapped: 9007199254740991,
+					nested: { small: "5" },
+				};
+				var resp = client.invoke("counters.Stats/Get", req);
+				if (resp.message.small !== "-42" || resp.message.nested.small !== "5") {
+					throw new Error("unexpected default response: " + JSON.stringify(resp.message));
+				}
+				resp = client.invoke("counters.Stats/Get", req, { int64: "number" });
+				var m = resp.message;
+				if (m.small !== -42 || m.large !== "18446744073709551615" || m.history[0] !== 1 ||
+					m.history[1] !== "9007199254740993" || m.totals.a !== 7 || m.wrapped !== 9007199254740991 ||
+					m.nested.small !== 5) {
+					throw new Error("unexpected response: " + JSON.stringify(m));
+				}
+				client.close();
+				server.stop();`,
+			},
+		},
+		{
+			name: "WellKnownTypes",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.loadProtoContent({ "schedule.proto": ` + "`" + `
+					syntax = "proto3";
+					package schedule;
+					import "google/protobuf/duration.proto";
+					import "google/protobuf/struct.proto";
+					import "google/protobuf/timestamp.proto";
+					message Job {
+						google.protobuf.Timestamp at = 1;
+						google.protobuf.Duration every = 2;
+						repeated google.protobuf.Duration retries = 3;
+						map<string, google.protobuf.Duration> timeouts = 4;
+						google.protobuf.Struct labels = 5;
+						google.protobuf.Value extra = 6;
+						google.protobuf.Duration retry_delay = 7;
+					}
+					service Scheduler {
+						rpc Schedule(Job) returns (Job);
+					}` + "`" + ` });
+				var server = new grpc.Server(client);
+				server.handle("schedule.Scheduler/Schedule", (req) => req);`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				var at = new Date(Date.UTC(2024, 0, 2, 3, 4, 5, 678));
+				var req = {
+					at: at,
+					every: "1m30s",
+					retries: ["1.5s", 250],
+					timeouts: { read: "2s" },
+					labels: { team: "core", shards: [1, 2], nested: { on: true } },
+					extra: "x",
+					retry_delay: "1.5s", // the proto names are accepted too
+				};
+				var resp = client.invoke("schedule.Scheduler/Schedule", req);
+				var m = resp.message;
+				if (m.at !== "2024-01-02T03:04:05.678Z" || m.every !== "90s" || m.retries[0] !== "1.500s" ||
+					m.retries[1] !== "0.250s" || m.timeouts.read !== "2s" || m.labels.team !== "core" ||
+					m.labels.shards[1] !== 2 || m.labels.nested.on !== true || m.extra !== "x" || m.retryDelay !== "1.500s") {
+					throw new Error("unexpected response: " + JSON.stringify(m));
+				}
+				resp = client.invoke("schedule.Scheduler/Schedule", req, { timestamp: "date" });
+				if (!(resp.message.at instanceof Date) || resp.message.at.getTime() !== at.getTime()) {
+					throw new Error("unexpected timestamp: " + resp.message.at);
+				}
+				resp = client.invoke("schedule.Scheduler/Schedule", req, { timestamp: "date", lazyMessage: true });
+				if (!(resp.json().at instanceof Date)) {
+					throw new Error("unexpected lazy timestamp: " + resp.json().at);
+				}
+				client.close();
+				server.stop();`,
+			},
+		},
+		{
+			name: "WellKnownTypesBadDuration",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.loadProtoContent({ "schedule.proto": ` + "`" + `
+					syntax = "proto3";
+					package schedule.bad;
+					import "google/protobuf/duration.proto";
+					message Job {
+						google.protobuf.Duration every = 1;
+					}
+					service Scheduler {
+						rpc Schedule(Job) returns (Job);
+					}` + "`" + ` });`,
+			},
+			vuString: codeBlock{
+				code: `client.marshal("schedule.bad.Scheduler/Schedule", { every: "soon" });`,
+				err:  `invalid google.protobuf.Duration value "soon"`,
+			},
+		},
+		{
+			name: "FieldMasks",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.loadProtoContent({ "library.proto": ` + "`" + `
+					syntax = "proto3";
+					package library;
+					import "google/protobuf/field_mask.proto";
+					message Author {
+						string display_name = 1;
+					}
+					message Book {
+						string title = 1;
+						Author author = 2;
+						repeated string tags = 3;
+					}
+					message UpdateBookRequest {
+						Book book = 1;
+						google.protobuf.FieldMask update_mask = 2;
+					}
+					message GetBookRequest {
+						string name = 1;
+						google.protobuf.FieldMask read_mask = 2;
+					}
+					service Library {
+						rpc UpdateBook(UpdateBookRequest) returns (Book);
+						rpc GetBook(GetBookRequest) returns (Book);
+					}` + "`" + ` });
+				var server = new grpc.Server(client);
+				server.handle("library.Library/UpdateBook", (req) => ({ title: req.updateMask }));
+				server.handle("library.Library/GetBook", (req) => ({ title: req.readMask }));`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				var resp = client.invoke("library.Library/UpdateBook",
+					{ book: { title: "Dune" }, update_mask: ["title", "author.display_name", "author.displayName", "tags"] });
+				if (resp.message.title !== "title,author.displayName,author.displayName,tags") {
+					throw new Error("unexpected update mask: " + resp.message.title);
+				}
+				resp = client.invoke("library.Library/GetBook", { name: "dune", readMask: ["author"] });
+				if (resp.message.title !== "author") {
+					throw new Error("unexpected read mask: " + resp.message.title);
+				}
+				resp = client.invoke("library.Library/GetBook", { name: "dune", readMask: "unchecked" });
+				if (resp.message.title !== "unchecked") {
+					throw new Error("unexpected string mask: " + resp.message.title);
+				}
+				var errors = [];
+				[["titel"], ["tags.name"], ["author.name"], [1]].forEach((mask) => {
+					try {
+						client.invoke("library.Library/UpdateBook", { update_mask: mask });
+					} catch (e) {
+						errors.push(e.message);
+					}
+				});
+				var expected = [
+					'invalid update_mask path "titel": library.Book has no field "titel", did you mean "title"?',
+					'invalid update_mask path "tags.name": the field "tags" isn\'t a singular message',
+					'invalid update_mask path "author.name": library.Author has no field "name"',
+					"invalid update_mask path: '1', it needs to be a string",
+				];
+				errors.forEach((e, i) => {
+					if (e.indexOf(expected[i]) < 0) {
+						throw new Error("unexpected error: " + e);
+					}
+				});
+				if (errors.length !== expected.length) {
+					throw new Error("unexpected errors: " + errors);
+				}
+				client.close();
+				server.stop();`,
+			},
+		},
+		{
+			name: "Oneofs",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.loadProtoContent({ "payments.proto": ` + "`" + `
+					syntax = "proto3";
+					package payments;
+					message Card {
+						oneof number {
+							string pan = 1;
+							string token = 2;
+						}
+					}
+					message Payment {
+						optional string note = 1;
+						oneof method {
+							Card card = 2;
+							string iban = 3;
+						}
+						repeated Card fallbacks = 4;
+					}
+					service Payments {
+						rpc Pay(Payment) returns (Payment);
+					}` + "`" + ` });
+				var server = new grpc.Server(client);
+				server.handle("payments.Payments/Pay", (req) => req);`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				var resp = client.invoke("payments.Payments/Pay", { note: "x", card: { token: "t" }, iban: null });
+				var oneofs = resp.oneofs();
+				if (Object.keys(oneofs).length !== 2 || oneofs.method !== "card" || oneofs["card.number"] !== "token") {
+					throw new Error("unexpected oneofs: " + JSON.stringify(oneofs));
+				}
+				resp = client.invoke("payments.Payments/Pay", { iban: "DE00" }, { lazyMessage: true });
+				if (resp.oneofs().method !== "iban") {
+					throw new Error("unexpected lazy oneofs: " + JSON.stringify(resp.oneofs()));
+				}
+				var errors = [];
+				[{ card: {}, iban: "DE00" }, { fallbacks: [{}, { pan: "4242", token: "t" }] }].forEach((req) => {
+					try {
+						client.invoke("payments.Payments/Pay", req);
+					} catch (e) {
+						errors.push(e.message);
+					}
+				});
+				if (errors.length !== 2 ||
+					errors[0].indexOf('oneof payments.Payment.method has multiple members set: "card", "iban"') < 0 ||
+					errors[1].indexOf('oneof payments.Card.number has multiple members set: "pan", "token"') < 0) {
+					throw new Error("unexpected errors: " + errors);
+				}
+				client.close();
+				server.stop();`,
 			},
 		},
+		{
+			name: "StrictRequest",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.loadProtoContent({ "profiles.proto": ` + "`" + `
+					syntax = "proto3";
+					package profiles;
+					message Address {
+						string city = 1;
+					}
+					message Profile {
+						string name = 1;
+						Address address = 2;
+					}
+					service Profiles {
+						rpc Save(Profile) returns (Profile);
+					}` + "`" + ` });
+				var server = new grpc.Server(client);
+				server.handle("profiles.Profiles/Save", (req) => req);`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				var errors = [];
+				[{ nmae: "a" }, { address: { ctiy: "b" } }].forEach((req) => {
+					try {
+						client.invoke("profiles.Profiles/Save", req);
+					} catch (e) {
+						errors.push(e.message);
+					}
+				});
+				if (errors.length !== 2 || errors[0].indexOf('unknown field "nmae"') < 0 ||
+					errors[1].indexOf('unknown field "ctiy"') < 0) {
+					throw new Error("unexpected errors: " + errors);
+				}
+				var resp = client.invoke("profiles.Profiles/Save",
+					{ name: "a", nmae: "b", address: { city: "c", ctiy: "d" } }, { strictRequest: false });
+				if (resp.message.name !== "a" || resp.message.address.city !== "c") {
+					throw new Error("unexpected response: " + JSON.stringify(resp.message));
+				}
+				client.close();
+				server.stop();`,
+			},
+		},
+		{
+			name: "UnknownFields",
+			initString: codeBlock{
+				code: `
+				var proto = (fields) => ` + "`" + `
+					syntax = "proto3";
+					package skew;
+					message Item {
+						string sku = 1;
+						${fields}
+					}
+					message Order {
+						string id = 1;
+						repeated Item items = 2;
+						${fields}
+					}
+					service Orders {
+						rpc Get(Order) returns (Order);
+					}` + "`" + `;
+				var client = new grpc.Client();
+				client.loadProtoContent({ "skew.proto": proto("") });
+				var newer = new grpc.Client();
+				newer.loadProtoContent({ "skew.proto": proto("int32 priority = 9;") });
+				var server = new grpc.Server(newer);
+				server.handle("skew.Orders/Get", () => ({ id: "1", priority: 3, items: [{ sku: "a" }, { sku: "b", priority: 150 }] }));`,
+			},
+			vuString: codeBlock{
+				code: `
+				var addr = server.start();
+				client.connect(addr, { plaintext: true });
+				var resp = client.invoke("skew.Orders/Get", {});
+				var unknown = resp.unknown();
+				if (resp.message.priority !== undefined || Object.keys(unknown).sort().join() !== "9,items.1.9") {
+					throw new Error("unexpected unknown fields: " + Object.keys(unknown));
+				}
+				// the tags (9 << 3 | 0) and the varints
+				if (new Uint8Array(unknown["9"]).join() !== "72,3" || new Uint8Array(unknown["items.1.9"]).join() !== "72,150,1") {
+					throw new Error("unexpected unknown fields' protobuf");
+				}
+				client.close();
+				server.stop();`,
+			},
+		},
+		{
+			name: "StrictRequestBadParam",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { strictRequest: "no" });`,
+				err: `invalid strictRequest value: '"no"', it needs to be boolean`,
+			},
+		},
+		{
+			name: "TimestampBadParam",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { timestamp: "epoch" });`,
+				err: `invalid timestamp value: '"epoch"', it needs to be "string" or "date"`,
+			},
+		},
+		{
+			name: "Int64BadParam",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { int64: "bigint" });`,
+				err: `invalid int64 value: '"bigint"', it needs to be "string" or "number"`,
+			},
+		},
+		{
+			name: "MockServerStreamingMethod",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var server = new grpc.Server(client);
+				server.handle("grpc.testing.TestService/StreamingOutputCall", () => ({}));`,
+				err: `the method "grpc.testing.TestService/StreamingOutputCall" is streaming, only the unary methods could be mocked`,
+			},
+		},
+		{
+			name: "InvokeCancelAfter",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { cancelAfter: "50ms" });
+				if (resp.status !== grpc.StatusCanceled) {
+					throw new Error("unexpected status: " + resp.status);
+				}`,
+			},
+		},
+		{
+			name: "InvokeAbortSignal",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var server = new grpc.Server(client);
+				var controller = new grpc.AbortController();
+				server.handle("grpc.testing.TestService/EmptyCall", () => {
+					// the client gives up while the server is handling the call
+					controller.abort();
+					return {};
+				});`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect(server.start(), { plaintext: true });
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { signal: controller.signal });
+				if (resp.status !== grpc.StatusCanceled || !controller.signal.aborted) {
+					throw new Error("unexpected status: " + resp.status);
+				}
+				// the aborted signal cancels the following calls too
+				resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { signal: controller.signal });
+				if (resp.status !== grpc.StatusCanceled) {
+					throw new Error("unexpected status: " + resp.status);
+				}
+				server.stop();`,
+			},
+		},
+		{
+			name: "InvokeInvalidSignal",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { signal: new grpc.AbortController() });`,
+				err: `invalid signal value`,
+			},
+		},
+		{
+			name: "InvokeDeadline",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				tb.GRPCStub.UnaryCallFunc = func(ctx context.Context, _ *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					return &grpc_testing.SimpleResponse{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { deadline: new Date(Date.now() + 100) });
+				if (resp.status !== grpc.StatusDeadlineExceeded) {
+					throw new Error("unexpected status: " + resp.status);
+				}
+				if (!/^[0-9]+[nu]$/.test(resp.grpcTimeout)) {
+					throw new Error("unexpected grpc-timeout: " + resp.grpcTimeout);
+				}
+				// the earliest of the timeout and the deadline applies
+				resp = client.invoke("grpc.testing.TestService/UnaryCall", {}, { deadline: Date.now() + 3600000, timeout: "5s" });
+				if (resp.status !== grpc.StatusOK || !/^[0-9]+u$/.test(resp.grpcTimeout) || parseInt(resp.grpcTimeout) > 5000000) {
+					throw new Error("unexpected grpc-timeout: " + resp.grpcTimeout);
+				}`,
+			},
+		},
+		{
+			name: "InvokeInvalidDeadline",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { deadline: "tomorrow" });`,
+				err: `invalid deadline value: '"tomorrow"', it needs to be a Date or the epoch milliseconds`,
+			},
+		},
+		{
+			name: "AbortOnBudgetExceeded",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				hs := health.NewServer()
+				hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+				healthpb.RegisterHealthServer(tb.ServerGRPC, hs)
+				time.AfterFunc(300*time.Millisecond, func() {
+					hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+				})
+
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { healthGating: { maxPause: "5s" } });
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { timeout: "100ms", abortOnBudgetExceeded: true });
+				if (resp.status !== grpc.StatusDeadlineExceeded) {
+					throw new Error("unexpected status: " + resp.status);
+				}
+				if (resp.error.message !== "the call's deadline has been exceeded before sending it") {
+					throw new Error("unexpected error: " + resp.error.message);
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, "grpc_budget_exceeded_aborts", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+				},
+			},
+		},
+		{
+			name: "ExpectedStatuses",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return nil, status.Error(codes.NotFound, "")
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { expectedStatuses: [grpc.StatusOK, grpc.StatusNotFound] });`,
+				asserts: func(t *testing.T, _ *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assert.Equal(t, []float64{0}, metricValues(samplesBuf, "grpc_req_failed"))
+				},
+			},
+		},
+		{
+			name: "StatusCallback",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				grpc.setStatusCallback(grpc.expectedStatuses(grpc.StatusOK));`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return nil, status.Error(codes.NotFound, "")
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {});
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { expectedStatuses: grpc.expectedStatuses(grpc.StatusNotFound) });`,
+				asserts: func(t *testing.T, _ *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assert.Equal(t, []float64{1, 0}, metricValues(samplesBuf, "grpc_req_failed"))
+				},
+			},
+		},
+		{
+			name: "ExpectedStatusesBadParam",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { expectedStatuses: [42] });`,
+				err: `invalid expectedStatuses value`,
+			},
+		},
+		{
+			name: "AbortOnBudgetExceededBadParam",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, { abortOnBudgetExceeded: "yes" })`,
+				err: `invalid abortOnBudgetExceeded value`,
+			},
+		},
+		{
+			name: "DuplicateDetection",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { duplicateDetection: { window: "1m" } });
+				client.invoke("grpc.testing.TestService/EmptyCall", {});
+				client.invoke("grpc.testing.TestService/EmptyCall", {});`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, "grpc_duplicate_requests", samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+				},
+			},
+		},
+		{
+			name: "ConnectLogLevelBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { logLevel: "trace" })`,
+				err:  `invalid logLevel value: '"trace"'`,
+			},
+		},
+		{
+			name: "ConnectPool",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");
+				var other = new grpc.Client();
+				other.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { pool: true });
+				client.close();
+				other.connect("GRPCBIN_ADDR", { pool: true });
+				var resp = other.invoke("grpc.testing.TestService/EmptyCall", {});
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + resp.status);
+				}`,
+			},
+		},
+		{
+			name: "ConnectPoolBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { pool: "global" })`,
+				err:  `invalid pool value`,
+			},
+		},
+		{
+			name: "DuplicateDetectionBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { duplicateDetection: { window: "-1s" } })`,
+				err:  `invalid duplicateDetection window value: '"-1s"', it needs to be a positive duration`,
+			},
+		},
+		{
+			name: "CaptureFailedAndSlowCalls",
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					_ = grpc.SetTrailer(ctx, metadata.Pairs("x-reason", "down"))
+					return nil, status.Error(codes.Unavailable, "the backend is down")
+				}
+				tb.GRPCStub.UnaryCallFunc = func(_ context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					if req.ResponseSize > 0 {
+						time.Sleep(50 * time.Millisecond)
+					}
+					return &grpc_testing.SimpleResponse{Username: "k6"}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { capture: { slowerThan: "20ms" } });
+				client.invoke("grpc.testing.TestService/EmptyCall", {}, {
+					metadata: { "x-test": "a", "authorization": "Bearer secret", "x-session-token": "secret" },
+				});
+				client.invoke("grpc.testing.TestService/UnaryCall", {});
+				client.invoke("grpc.testing.TestService/UnaryCall", { responseSize: 1 }, { lazyMessage: true });
+
+				var captured = grpc.captures();
+				if (captured.length !== 2) {
+					throw new Error("unexpected captures: " + JSON.stringify(captured));
+				}
+
+				var failed = captured[0];
+				if (failed.reason !== "failed" || failed.status !== grpc.StatusUnavailable ||
+					failed.method !== "/grpc.testing.TestService/EmptyCall" || failed.metadata["x-test"][0] !== "a" ||
+					failed.metadata["authorization"][0] !== "[REDACTED]" || failed.metadata["x-session-token"][0] !== "[REDACTED]" ||
+					failed.trailers["x-reason"][0] !== "down" || failed.error.message !== "the backend is down") {
+					throw new Error("unexpected failed call's capture: " + JSON.stringify(failed));
+				}
+
+				var slow = captured[1];
+				if (slow.reason !== "slow" || slow.duration < 20 || slow.request.responseSize !== 1 ||
+					slow.response.username !== "k6" || !(slow.time instanceof Date)) {
+					throw new Error("unexpected slow call's capture: " + JSON.stringify(slow));
+				}`,
+			},
+		},
+		{
+			name: "CaptureBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { capture: { slowerThan: "0s" } })`,
+				err:  `invalid capture slowerThan value: '"0s"', it needs to be a positive duration`,
+			},
+		},
+		{
+			name: "HealthGatingBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { healthGating: { maxPause: "0s" } })`,
+				err:  `invalid healthGating maxPause value: '"0s"', it needs to be a positive duration`,
+			},
+		},
+		{
+			name: "LatencySnapshot",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				for (var i = 0; i < 3; i++) {
+					client.invoke("grpc.testing.TestService/EmptyCall", {});
+				}
+				var snapshot = JSON.parse(JSON.stringify(grpc.latencySnapshot()))["/grpc.testing.TestService/EmptyCall"];
+				if (snapshot.count !== 3) {
+					throw new Error("unexpected count: " + snapshot.count);
+				}
+				if (!(snapshot.percentiles["p(99)"] <= snapshot.max)) {
+					throw new Error("unexpected p(99): " + JSON.stringify(snapshot));
+				}`,
+			},
+		},
+		{
+			name: "CallCredentialsStaticToken",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					md, ok := metadata.FromIncomingContext(ctx)
+					if !ok || len(md["authorization"]) == 0 || md["authorization"][0] != "Bearer static-token" {
+						return nil, status.Error(codes.Unauthenticated, "")
+					}
+
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { callCredentials: { type: "bearer", token: "static-token" } });
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + resp.status);
+				}`,
+			},
+		},
+		{
+			name: "CallCredentialsTokenCallback",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				var calls int
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					calls++
+					md, ok := metadata.FromIncomingContext(ctx)
+					if !ok || len(md["authorization"]) == 0 || md["authorization"][0] != fmt.Sprintf("Bearer token-%d", calls) {
+						return nil, status.Error(codes.Unauthenticated, "")
+					}
+
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				var n = 0;
+				client.connect("GRPCBIN_ADDR", {
+					callCredentials: { type: "bearer", token: function() { n++; return "token-" + n; } },
+				});
+				for (var i = 0; i < 2; i++) {
+					var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+					if (resp.status !== grpc.StatusOK) {
+						throw new Error("unexpected status: " + resp.status);
+					}
+				}`,
+			},
+		},
+		{
+			name: "CallCredentialsBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { callCredentials: { type: "basic", token: "secret" } })`,
+				err:  `invalid callCredentials type value: '"basic"', only "bearer" is supported`,
+			},
+		},
+		{
+			name: "ConnectCredentialsBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { credentials: "aws_default" })`,
+				err:  `invalid credentials type value: "aws_default", it needs to be one of "google_default", "alts", "spiffe" or "xds"`,
+			},
+		},
+		{
+			name: "ConnectALTSCredentialsBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { credentials: { type: "alts", targetServiceAccounts: "sa@example.com" } })`,
+				err:  `invalid credentials targetServiceAccounts value: '"sa@example.com"', it needs to be an array of strings`,
+			},
+		},
+		{
+			name: "ConnectGoogleDefaultCredentialsWithALTSOptions",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { credentials: { type: "google_default", targetServiceAccounts: ["sa@example.com"] } })`,
+				err:  `the targetServiceAccounts and handshakerServiceAddress are only supported by the "alts" credentials`,
+			},
+		},
+		{
+			name: "ConnectXDSCredentialsFallback",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { credentials: "xds" });
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + resp.status);
+				}`,
+			},
+		},
+		{
+			name: "ConnectSPIFFECredentialsBadServerID",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { credentials: { type: "spiffe", serverId: "https://example.org/server" } })`,
+				err:  `invalid credentials serverId value: scheme is missing or invalid`,
+			},
+		},
+		{
+			name: "ConnectSPIFFECredentialsNoWorkloadAPI",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {
+					timeout: "100ms",
+					credentials: { type: "spiffe", workloadApiAddress: "unix:///nonexistent/agent.sock" },
+				})`,
+				err: `failed to fetch the X.509 SVID from the SPIFFE Workload API`,
+			},
+		},
+		{
+			name: "ConnectCredentialsWithPlaintext",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { credentials: "google_default", plaintext: true })`,
+				err:  `the "google_default" credentials can't be combined with the plaintext or tls params`,
+			},
+		},
+		{
+			name: "RequestBinHeaders",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					md, ok := metadata.FromIncomingContext(ctx)
+					if !ok || len(md["x-load-tester-bin"]) == 0 || md["x-load-tester-bin"][0] != string([]byte{2, 200}) {
+						return nil, status.Error(codes.FailedPrecondition, "")
+					}
+
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { metadata: { "X-Load-Tester-bin": new Uint8Array([2, 200]) } })
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("failed to send correct headers in the request")
+				}
+			`},
+		},
+		{
+			name: "ResponseMessage",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.UnaryCallFunc = func(context.Context, *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					return &grpc_testing.SimpleResponse{
+						OauthScope: "水",
+					}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/UnaryCall", {})
+				if (!resp.message || resp.message.username !== "" || resp.message.oauthScope !== "水") {
+					throw new Error("unexpected response message: " + JSON.stringify(resp.message))
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/UnaryCall"))
+				},
+			},
+		},
+		{
+			name: "ResponseError",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return nil, status.Error(codes.DataLoss, "foobar")
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusDataLoss) {
+					throw new Error("unexpected error status: " + resp.status)
+				}
+				if (!resp.error || resp.error.message !== "foobar" || resp.error.code !== 15) {
+					throw new Error("unexpected error object: " + JSON.stringify(resp.error.code))
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+				},
+			},
+		},
+		{
+			name: "ResponseHeaders",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					md := metadata.Pairs("foo", "bar")
+					_ = grpc.SetHeader(ctx, md)
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected error status: " + resp.status)
+				}
+				if (!resp.headers || !resp.headers["foo"] || resp.headers["foo"][0] !== "bar") {
+					throw new Error("unexpected headers object: " + JSON.stringify(resp.trailers))
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+				},
+			},
+		},
+		{
+			name: "ResponseTrailers",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					md := metadata.Pairs("foo", "bar")
+					_ = grpc.SetTrailer(ctx, md)
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected error status: " + resp.status)
+				}
+				if (!resp.trailers || !resp.trailers["foo"] || resp.trailers["foo"][0] !== "bar") {
+					throw new Error("unexpected trailers object: " + JSON.stringify(resp.trailers))
+				}`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf, rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+				},
+			},
+		},
+		{
+			name: "LoadNotInit",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					md := metadata.Pairs("foo", "bar")
+					_ = grpc.SetTrailer(ctx, md)
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `client.load()`,
+				err:  "load must be called in the init context",
+			},
+		},
+		{
+			name: "ReflectUnregistered",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflect: true})`,
+				err:  "rpc error: code = Unimplemented desc = unknown service grpc.reflection.v1alpha.ServerReflection",
+			},
+		},
+		{
+			name: "Reflect",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflect: true})`,
+			},
+		},
+		{
+			name: "ReflectV1",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.RegisterV1(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflect: true})`,
+			},
+		},
+		{
+			name: "ReflectMaxConcurrentReflections",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflect: true, maxConcurrentReflections: 1})`,
+			},
+		},
+		{
+			name: "ReflectMaxConcurrentReflectionsBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflect: true, maxConcurrentReflections: 0})`,
+				err:  `invalid maxConcurrentReflections value`,
+			},
+		},
+		{
+			name: "ReflectOnReconnectBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflectOnReconnect: true})`,
+				err:  `invalid reflectOnReconnect value`,
+			},
+		},
+		{
+			name: "ConnectEndpoints",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("example.com:443", { endpoints: ["GRPCBIN_ADDR"] });
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + resp.status);
+				}`,
+			},
+		},
+		{
+			name: "ConnectLoadBalancing",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				var policies = [
+					"least_request",
+					{ policy: "least_request", choiceCount: 3 },
+					{ policy: "weighted_round_robin", enableOobLoadReport: true, oobReportingPeriod: "1s" },
+				];
+				for (var i = 0; i < policies.length; i++) {
+					client.connect("example.com:443", { endpoints: ["GRPCBIN_ADDR", "GRPCBIN_ADDR"], loadBalancing: policies[i] });
+					var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+					if (resp.status !== grpc.StatusOK) {
+						throw new Error("unexpected status: " + resp.status);
+					}
+					client.close();
+				}`,
+			},
+		},
+		{
+			name: "ConnectLoadBalancingUnknownPolicy",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { loadBalancing: "random" })`,
+				err:  `unknown load balancing policy "random"`,
+			},
+		},
+		{
+			name: "ConnectLoadBalancingInvalidConfig",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { loadBalancing: { policy: "least_request", choiceCount: 1 } })`,
+				err:  `invalid least_request config`,
+			},
+		},
+		{
+			name: "ConnectEndpointsBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { endpoints: ["10.0.0.1"] })`,
+				err:  `invalid endpoints value: "10.0.0.1", it needs to be a host:port string`,
+			},
+		},
+		{
+			name: "ConnectProxyBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {proxy: "socks5://localhost:1080"})`,
+				err:  `invalid proxy value`,
+			},
+		},
+		{
+			name: "ReflectSymbols",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `
+					client.connect("GRPCBIN_ADDR", {reflect: true, reflectSymbols: ["grpc.testing.TestService"]})
+					var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+					if (resp.status !== grpc.StatusOK) {
+						throw new Error("unexpected status: " + resp.status)
+					}`,
+			},
+		},
+		{
+			name: "ReflectSymbolsUnknown",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflect: true, reflectSymbols: ["foo.Bar"]})`,
+				err:  `can't get the file containing "foo.Bar"`,
+			},
+		},
+		{
+			name: "ConnectAnyTypes",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {anyTypes: ["grpc.testing.Payload"]})`,
+			},
+		},
+		{
+			name: "ConnectAnyTypesUnknown",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {anyTypes: ["foo.Bar"]})`,
+				err:  `can't reflect the any types`,
+			},
+		},
+		{
+			name: "ConnectAnyTypesBadParam",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {anyTypes: "grpc.testing.Payload"})`,
+				err:  `invalid anyTypes value`,
+			},
+		},
+		{
+			name: "ReflectNoCache",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflect: true, reflectCache: false})`,
+			},
+		},
+		{
+			name: "ReflectV1AlphaOnly",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				v1alphareflectiongrpc.RegisterServerReflectionServer(tb.ServerGRPC,
+					reflection.NewServer(reflection.ServerOptions{Services: tb.ServerGRPC}))
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflect: true})`,
+			},
+		},
+		{
+			name: "ReflectBadParam",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {reflect: "true"})`,
+				err:  `invalid reflect value`,
+			},
+		},
+		{
+			name: "ReflectInvokeNoExist",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `
+					client.connect("GRPCBIN_ADDR", {reflect: true})
+					client.invoke("foo/bar", {})
+				`,
+				err: `method "/foo/bar" not found in file descriptors`,
+			},
+		},
+		{
+			name: "ReflectInvoke",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				// this register both reflection APIs v1 and v1alpha
+				reflection.Register(tb.ServerGRPC)
+
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `
+					client.connect("GRPCBIN_ADDR", {reflect: true})
+					client.invoke("grpc.testing.TestService/EmptyCall", {})
+				`,
+			},
+		},
+		{
+			name: "ReflectV1Alpha_Invoke",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				// this register only v1alpha (this could be removed with removal v1alpha from grpc-go)
+				s := tb.ServerGRPC
+				svr := reflection.NewServer(reflection.ServerOptions{Services: s})
+				v1alphagrpc.RegisterServerReflectionServer(s, svr)
+
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `
+					client.connect("GRPCBIN_ADDR", {reflect: true})
+					client.invoke("grpc.testing.TestService/EmptyCall", {})
+				`,
+			},
+		},
+		{
+			name: "ReflectV1Invoke",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				// this register only reflection APIs v1
+				reflection.RegisterV1(tb.ServerGRPC)
+
+				tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `
+					client.connect("GRPCBIN_ADDR", {reflect: true})
+					client.invoke("grpc.testing.TestService/EmptyCall", {})
+				`,
+			},
+		},
+		{
+			name: "MaxReceiveSizeBadParam",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {maxReceiveSize: "error"})`,
+				err:  `invalid maxReceiveSize value: '"error"', it needs to be an integer`,
+			},
+		},
+		{
+			name: "MaxReceiveSizeNonPositiveInteger",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {maxReceiveSize: -1})`,
+				err:  `invalid maxReceiveSize value: '-1, it needs to be a positive integer`,
+			},
+		},
+		{
+			name: "ReceivedMessageLargerThanMax",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.UnaryCallFunc = func(_ context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					response := &grpc_testing.SimpleResponse{}
+					response.Payload = req.Payload
+					return response, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", {maxReceiveSize: 1})
+				var resp = client.invoke("grpc.testing.TestService/UnaryCall", { payload: { body: "testMaxReceiveSize"} })
+				if (resp.status == grpc.StatusResourceExhausted) {
+					throw new Error(resp.error.message)
+				}
+				`,
+				err: `received message larger than max`,
+			},
+		},
+		{
+			name: "MaxSendSizeBadParam",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {maxSendSize: "error"})`,
+				err:  `invalid maxSendSize value: '"error"', it needs to be an integer`,
+			},
+		},
+		{
+			name: "MaxSendSizeNonPositiveInteger",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(tb.ServerGRPC)
+			},
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", {maxSendSize: -1})`,
+				err:  `invalid maxSendSize value: '-1, it needs to be a positive integer`,
+			},
+		},
+		{
+			name: "SentMessageLargerThanMax",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.UnaryCallFunc = func(context.Context, *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					return &grpc_testing.SimpleResponse{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", {maxSendSize: 1})
+				var resp = client.invoke("grpc.testing.TestService/UnaryCall", { payload: { body: "testMaxSendSize"} })
+				if (resp.status == grpc.StatusResourceExhausted) {
+					throw new Error(resp.error.message)
+				}
+				`,
+				err: `trying to send message larger than max`,
+			},
+		},
+		{
+			name: "CallMaxReceiveSizeOverride",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.UnaryCallFunc = func(_ context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					return &grpc_testing.SimpleResponse{Payload: req.Payload}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", {maxReceiveSize: 1})
+				var resp = client.invoke("grpc.testing.TestService/UnaryCall", { payload: { body: "dGVzdA=="} }, { maxReceiveSize: 1024 })
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + resp.status)
+				}
+				resp = client.invoke("grpc.testing.TestService/UnaryCall", { payload: { body: "dGVzdA=="} })
+				if (resp.status !== grpc.StatusResourceExhausted) {
+					throw new Error("the connection's limit isn't kept: " + resp.status)
+				}
+				`,
+			},
+		},
+		{
+			name: "CallMaxSendSizeOverride",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.UnaryCallFunc = func(context.Context, *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+					return &grpc_testing.SimpleResponse{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR")
+				var resp = client.invoke("grpc.testing.TestService/UnaryCall", { payload: { body: "dGVzdA=="} }, { maxSendSize: 1 })
+				if (resp.status == grpc.StatusResourceExhausted) {
+					throw new Error(resp.error.message)
+				}
+				`,
+				err: `trying to send message larger than max`,
+			},
+		},
+		{
+			name: "CallMaxSendSizeInvalid",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR")
+				client.invoke("grpc.testing.TestService/UnaryCall", {}, { maxSendSize: 0 })
+				`,
+				err: `invalid maxSendSize value: '0', it needs to be a positive integer`,
+			},
+		},
+		{
+			name: "IdleTimeoutReactivation",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", { idleTimeout: "50ms" })
+				client.invoke("grpc.testing.TestService/EmptyCall", {})
+				var until = Date.now() + 300
+				while (Date.now() < until) {}
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected status: " + resp.status)
+				}
+				`,
+				asserts: func(t *testing.T, rb *httpmultibin.HTTPMultiBin, samples chan metrics.SampleContainer, _ error) {
+					samplesBuf := metrics.GetBufferedSamples(samples)
+					assertMetricEmitted(t, "grpc_idle_reactivation_duration", samplesBuf,
+						rb.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+				},
+			},
+		},
+		{
+			name: "IdleTimeoutInvalid",
+			initString: codeBlock{
+				code: `var client = new grpc.Client();`,
+			},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { idleTimeout: "-1s" })`,
+				err:  `invalid idleTimeout value: '"-1s"', it needs to be a non-negative duration`,
+			},
+		},
+		{
+			name: "Close",
+			initString: codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			},
+			vuString: codeBlock{
+				code: `
+			client.close();
+			client.invoke();`,
+				err: "no gRPC connection",
+			},
+		},
+		{
+			name: "Wrappers",
+			setup: func(hb *httpmultibin.HTTPMultiBin) {
+				srv := wrappers_testing.Register(hb.ServerGRPC)
+
+				srv.TestStringImplementation = func(_ context.Context, sv *wrappers.StringValue) (*wrappers.StringValue, error) {
+					return &wrapperspb.StringValue{
+						Value: "hey " + sv.Value,
+					}, nil
+				}
+			},
+			initString: codeBlock{
+				code: `
+				const client = new grpc.Client();
+				client.load([], "../grpc/testdata/wrappers_testing/test.proto");
+				`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				
+				let respString = client.invoke("grpc.wrappers.testing.Service/TestString", "John")
+				if (respString.message !== "hey John") {
+					throw new Error("expected to get 'hey John', but got a " + respString.message)
+				}				
+			`,
+			},
+		},
+		{
+			name: "WrappersWithReflection",
+			setup: func(hb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(hb.ServerGRPC)
+
+				srv := wrappers_testing.Register(hb.ServerGRPC)
+
+				srv.TestIntegerImplementation = func(_ context.Context, iv *wrappers.Int64Value) (*wrappers.Int64Value, error) {
+					return &wrappers.Int64Value{
+						Value: 2 * iv.Value,
+					}, nil
+				}
+
+				srv.TestStringImplementation = func(_ context.Context, sv *wrappers.StringValue) (*wrappers.StringValue, error) {
+					return &wrapperspb.StringValue{
+						Value: "hey " + sv.Value,
+					}, nil
+				}
+
+				srv.TestBooleanImplementation = func(_ context.Context, bv *wrappers.BoolValue) (*wrappers.BoolValue, error) {
+					return &wrapperspb.BoolValue{
+						Value: bv.Value != true,
+					}, nil
+				}
+
+				srv.TestDoubleImplementation = func(_ context.Context, bv *wrappers.DoubleValue) (*wrappers.DoubleValue, error) {
+					return &wrapperspb.DoubleValue{
+						Value: bv.Value * 2,
+					}, nil
+				}
+			},
+			initString: codeBlock{
+				code: `
+				const client = new grpc.Client();
+				`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", {reflect: true});
+				
+				let respString = client.invoke("grpc.wrappers.testing.Service/TestString", "John")
+				if (respString.message !== "hey John") {
+					throw new Error("expected to get 'hey John', but got a " + respString.message)
+				}
+
+				let respInt = client.invoke("grpc.wrappers.testing.Service/TestInteger", "3")
+				if (respInt.message !== "6") {
+					throw new Error("expected to get '6', but got a " + respInt.message)
+				}
+
+				let respDouble = client.invoke("grpc.wrappers.testing.Service/TestDouble", "2.7")
+				if (respDouble.message !== 5.4) {
+					throw new Error("expected to get '5.4', but got a " + respDouble.message)
+				}
+			`,
+			},
+		},
+		{
+			name: "ValueReflection",
+			setup: func(hb *httpmultibin.HTTPMultiBin) {
+				reflection.Register(hb.ServerGRPC)
+
+				srv := wrappers_testing.Register(hb.ServerGRPC)
+
+				srv.TestValueImplementation = func(_ context.Context, in *_struct.Value) (*_struct.Value, error) {
+					if in.GetNumberValue() == 12 {
+						return &_struct.Value{
+							Kind: &_struct.Value_NumberValue{
+								NumberValue: 42,
+							},
+						}, nil
+					}
+
+					if in.GetStringValue() != "" {
+						return &_struct.Value{
+							Kind: &_struct.Value_StringValue{
+								StringValue: "hey " + in.GetStringValue(),
+							},
+						}, nil
+					}
+
+					return &_struct.Value{
+						Kind: &_struct.Value_StringValue{
+							StringValue: "I don't know what to answer",
+						},
+					}, nil
+				}
+			},
+			initString: codeBlock{
+				code: `
+				const client = new grpc.Client();
+				`,
+			},
+			vuString: codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", {reflect: true});
+
+				let respString = client.invoke("grpc.wrappers.testing.Service/TestValue", "John")
+				if (respString.message !== "hey John") {
+					throw new Error("expected to get 'hey John', but got a " + respString.message)
+				}
+
+				let respNumber = client.invoke("grpc.wrappers.testing.Service/TestValue", 12)
+				if (respNumber.message !== 42) {
+					throw new Error("expected to get '42', but got a " + respNumber.message)
+				}
+
+				let respBool = client.invoke("grpc.wrappers.testing.Service/TestValue", false)
+				if (respBool.message !== "I don't know what to answer") {
+					throw new Error("expected to get 'I don't know what to answer', but got a " + respBool.message)
+				}
+			`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestState(t)
+
+			// setup necessary environment if needed by a test
+			if tt.setup != nil {
+				tt.setup(ts.httpBin)
+			}
+
+			val, err := ts.Run(tt.initString.code)
+			assertResponse(t, tt.initString, err, val, ts)
+
+			ts.ToVUContext()
+			val, err = ts.Run(tt.vuString.code)
+			assertResponse(t, tt.vuString, err, val, ts)
+		})
+	}
+}
+
+func TestClient_TlsParameters(t *testing.T) {
+	t.Parallel()
+
+	testingKey := func(s string) string {
+		t.Helper()
+		return strings.ReplaceAll(s, "TESTING KEY", "PRIVATE KEY")
+	}
+
+	clientAuthCA := []byte("-----BEGIN CERTIFICATE-----\nMIIBWzCCAQGgAwIBAgIJAIQMBgLi+DV6MAoGCCqGSM49BAMCMBAxDjAMBgNVBAMM\nBU15IENBMCAXDTIyMDEyMTEyMjkzNloYDzMwMjEwNTI0MTIyOTM2WjAQMQ4wDAYD\nVQQDDAVNeSBDQTBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABHnrghULHa2hSa/C\nWimwCn42KWdlPqd6/zs3JgLIxTvBHJJlfbhWbBqtybqyovWd3QykHMIpx0NZmpYn\nG8FoWpmjQjBAMA4GA1UdDwEB/wQEAwIBBjAPBgNVHRMBAf8EBTADAQH/MB0GA1Ud\nDgQWBBSkukBA8lgFvvBJAYKsoSUR+PX71jAKBggqhkjOPQQDAgNIADBFAiEAiFF7\nY54CMNRSBSVMgd4mQgrzJInRH88KpLsQ7VeOAaQCIEa0vaLln9zxIDZQKocml4Db\nAEJr8tDzMKIds6sRTBT4\n-----END CERTIFICATE-----")
+	localHostCert := "-----BEGIN CERTIFICATE-----\\nMIIDOTCCAiGgAwIBAgIQSRJrEpBGFc7tNb1fb5pKFzANBgkqhkiG9w0BAQsFADAS\\nMRAwDgYDVQQKEwdBY21lIENvMCAXDTcwMDEwMTAwMDAwMFoYDzIwODQwMTI5MTYw\\nMDAwWjASMRAwDgYDVQQKEwdBY21lIENvMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8A\\nMIIBCgKCAQEA6Gba5tHV1dAKouAaXO3/ebDUU4rvwCUg/CNaJ2PT5xLD4N1Vcb8r\\nbFSW2HXKq+MPfVdwIKR/1DczEoAGf/JWQTW7EgzlXrCd3rlajEX2D73faWJekD0U\\naUgz5vtrTXZ90BQL7WvRICd7FlEZ6FPOcPlumiyNmzUqtwGhO+9ad1W5BqJaRI6P\\nYfouNkwR6Na4TzSj5BrqUfP0FwDizKSJ0XXmh8g8G9mtwxOSN3Ru1QFc61Xyeluk\\nPOGKBV/q6RBNklTNe0gI8usUMlYyoC7ytppNMW7X2vodAelSu25jgx2anj9fDVZu\\nh7AXF5+4nJS4AAt0n1lNY7nGSsdZas8PbQIDAQABo4GIMIGFMA4GA1UdDwEB/wQE\\nAwICpDATBgNVHSUEDDAKBggrBgEFBQcDATAPBgNVHRMBAf8EBTADAQH/MB0GA1Ud\\nDgQWBBStsdjh3/JCXXYlQryOrL4Sh7BW5TAuBgNVHREEJzAlggtleGFtcGxlLmNv\\nbYcEfwAAAYcQAAAAAAAAAAAAAAAAAAAAATANBgkqhkiG9w0BAQsFAAOCAQEAxWGI\\n5NhpF3nwwy/4yB4i/CwwSpLrWUa70NyhvprUBC50PxiXav1TeDzwzLx/o5HyNwsv\\ncxv3HdkLW59i/0SlJSrNnWdfZ19oTcS+6PtLoVyISgtyN6DpkKpdG1cOkW3Cy2P2\\n+tK/tKHRP1Y/Ra0RiDpOAmqn0gCOFGz8+lqDIor/T7MTpibL3IxqWfPrvfVRHL3B\\ngrw/ZQTTIVjjh4JBSW3WyWgNo/ikC1lrVxzl4iPUGptxT36Cr7Zk2Bsg0XqwbOvK\\n5d+NTDREkSnUbie4GeutujmX3Dsx88UiV6UY/4lHJa6I5leHUNOHahRbpbWeOfs/\\nWkBKOclmOV2xlTVuPw==\\n-----END CERTIFICATE-----"
+	clientAuth := "-----BEGIN CERTIFICATE-----\\nMIIBVzCB/6ADAgECAgkAg/SeNG3XqB0wCgYIKoZIzj0EAwIwEDEOMAwGA1UEAwwF\\nTXkgQ0EwIBcNMjIwMTIxMTUxMjM0WhgPMzAyMTA1MjQxNTEyMzRaMBExDzANBgNV\\nBAMMBmNsaWVudDBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABKM7OJQMYG4KLtDA\\ngZ8zOg2PimHMmQnjD2HtI4cSwIUJJnvHWLowbFe9fk6XeP9b3dK1ImUI++/EZdVr\\nABAcngejPzA9MA4GA1UdDwEB/wQEAwIBBjAMBgNVHRMBAf8EAjAAMB0GA1UdDgQW\\nBBSttJe1mcPEnBOZ6wvKPG4zL0m1CzAKBggqhkjOPQQDAgNHADBEAiBPSLgKA/r9\\nu/FW6W+oy6Odm1kdNMGCI472iTn545GwJgIgb3UQPOUTOj0IN4JLJYfmYyXviqsy\\nzk9eWNHFXDA9U6U=\\n-----END CERTIFICATE-----"
+	clientAuthKey := testingKey("-----BEGIN EC TESTING KEY-----\\nMHcCAQEEINDaMGkOT3thu1A0LfLJr3Jd011/aEG6OArmEQaujwgpoAoGCCqGSM49\\nAwEHoUQDQgAEozs4lAxgbgou0MCBnzM6DY+KYcyZCeMPYe0jhxLAhQkme8dYujBs\\nV71+Tpd4/1vd0rUiZQj778Rl1WsAEByeBw==\\n-----END EC TESTING KEY-----")
+	clientAuthKeyEncrypted := testingKey("-----BEGIN EC TESTING KEY-----\\nProc-Type: 4,ENCRYPTED\\nDEK-Info: AES-256-CBC,3E311E9B602231BFB5C752071EE7D652\\n\\nsAKeqbacug0v4ruE1A0CACwGVEGBQVOl1CiGVp5RsxgNZKXzMS6EsTTNLw378coF\\nKXbF+he05HIuzToOz2ANLXov1iCrVpotKVB4l2obTQvg+5VET902ky99Mc9Us7jd\\nUwW8LpXlSlhcNWuUfK6wyosL42TbcIxjqZWaESW+6ww=\\n-----END EC TESTING KEY-----")
+	clientAuthBad := "-----BEGIN CERTIFICATE-----\\nMIIB2TCCAX6gAwIBAgIUJIZKiR78AH2ioZ+Jae/sElgH85kwCgYIKoZIzj0EAwIw\\nEDEOMAwGA1UEAwwFTXkgQ0EwHhcNMjMwNzA3MTAyNjQ2WhcNMjQwNzA2MTAyNjQ2\\nWjARMQ8wDQYDVQQDDAZjbGllbnQwWTATBgcqhkjOPQIBBggqhkjOPQMBBwNCAASj\\nOziUDGBuCi7QwIGfMzoNj4phzJkJ4w9h7SOHEsCFCSZ7x1i6MGxXvX5Ol3j/W93S\\ntSJlCPvvxGXVawAQHJ4Ho4G0MIGxMAkGA1UdEwQCMAAwEQYJYIZIAYb4QgEBBAQD\\nAgWgMCwGCWCGSAGG+EIBDQQfFh1Mb2NhbCBUZXN0IENsaWVudCBDZXJ0aWZpY2F0\\nZTAdBgNVHQ4EFgQUrbSXtZnDxJwTmesLyjxuMy9JtQswHwYDVR0jBBgwFoAUpLpA\\nQPJYBb7wSQGCrKElEfj1+9YwDgYDVR0PAQH/BAQDAgXgMBMGA1UdJQQMMAoGCCsG\\nAQUFBwMEMAoGCCqGSM49BAMCA0kAMEYCIQDcHrzug3V3WvUU+tEKhG1C4cPG5rPJ\\n/y3oOoM0roOnsgIhAP23UmiC6Qdgj+MOhXWSaNt3exWvlxdKmLm2edkxaTs+\\n-----END CERTIFICATE-----"
+
+	trivialKeyPassword := "abc123"
+	trivialWrongKeyPassword := "abc321"
+
+	tests := []testcase{
+		{
+			name:       "ConnectTlsEmptyTlsSuccess",
+			initString: codeBlock{code: "var client = new grpc.Client();"},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { tls: { }});`,
+			},
+		},
+		{
+			name:       "ConnectTlsInvalidTlsParamCertType",
+			initString: codeBlock{code: "var client = new grpc.Client();"},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { tls: { cert: 0 }});`,
+				err:  `invalid grpc.connect() parameters: invalid tls cert value: 'map[string]interface {}{"cert":0}', it needs to be a PEM formatted string`,
+			},
+		},
+		{
+			name:       "ConnectTlsInvalidTlsParamKeyType",
+			initString: codeBlock{code: "var client = new grpc.Client();"},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { tls: { cert: "", key: 0 }});`,
+				err:  `invalid grpc.connect() parameters: invalid tls key value: 'map[string]interface {}{"cert":"", "key":0}', it needs to be a PEM formatted string`,
+			},
+		},
+		{
+			name:       "ConnectTlsInvalidTlsParamPasswordType",
+			initString: codeBlock{code: "var client = new grpc.Client();"},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { tls: { cert: "", key: "", password: 0 }});`,
+				err:  `invalid grpc.connect() parameters: invalid tls password value: 'map[string]interface {}{"cert":"", "key":"", "password":0}', it needs to be a string`,
+			},
+		},
+		{
+			name:       "ConnectTlsInvalidTlsParamCACertsType",
+			initString: codeBlock{code: "var client = new grpc.Client();"},
+			vuString: codeBlock{
+				code: `client.connect("GRPCBIN_ADDR", { tls: { cert: "", key: "", cacerts: 0 }});`,
+				err:  `invalid grpc.connect() parameters: invalid tls cacerts value: 'map[string]interface {}{"cacerts":0, "cert":"", "key":""}', it needs to be a string or an array of PEM formatted strings`,
+			},
+		},
+		{
+			name: "ConnectTls",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				clientCAPool := x509.NewCertPool()
+				clientCAPool.AppendCertsFromPEM(clientAuthCA)
+				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+			},
+			initString: codeBlock{code: "var client = new grpc.Client();"},
+			vuString:   codeBlock{code: fmt.Sprintf(`client.connect("GRPCBIN_ADDR", { tls: { cacerts: ["%s"], cert: "%s", key: "%s" }});`, localHostCert, clientAuth, clientAuthKey)},
+		},
+		{
+			name: "ConnectTlsEncryptedKey",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				clientCAPool := x509.NewCertPool()
+				clientCAPool.AppendCertsFromPEM(clientAuthCA)
+				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+			},
+			initString: codeBlock{code: "var client = new grpc.Client();"},
+			vuString:   codeBlock{code: fmt.Sprintf(`client.connect("GRPCBIN_ADDR", { tls: { cacerts: ["%s"], cert: "%s", key: "%s", password: "%s" }});`, localHostCert, clientAuth, clientAuthKeyEncrypted, trivialKeyPassword)},
+		},
+		{
+			name:       "ConnectTlsEncryptedKeyDecryptionFailed",
+			initString: codeBlock{code: "var client = new grpc.Client();"},
+			vuString: codeBlock{
+				code: fmt.Sprintf(`client.connect("GRPCBIN_ADDR", { timeout: '5s', tls: { cert: "%s", key: "%s", password: "%s" }});`,
+					clientAuth,
+					clientAuthKeyEncrypted,
+					trivialWrongKeyPassword,
+				),
+				err: "x509: decryption password incorrect",
+			},
+		},
+		{
+			name: "ConnectTlsClientCertNoClientAuth",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				clientCAPool := x509.NewCertPool()
+				clientCAPool.AppendCertsFromPEM(clientAuthCA)
+				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+			},
+			initString: codeBlock{code: `var client = new grpc.Client();`},
+			vuString: codeBlock{
+				code: fmt.Sprintf(`client.connect("GRPCBIN_ADDR", { timeout: '2s', tls: { cacerts: ["%s"], cert: "%s", key: "%s" }});`,
+					localHostCert,
+					clientAuthBad,
+					clientAuthKey),
+				err: "remote error: tls: bad certificate",
+			},
+		},
+		{
+			name: "ConnectTlsClientCertWithPasswordNoClientAuth",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				clientCAPool := x509.NewCertPool()
+				clientCAPool.AppendCertsFromPEM(clientAuthCA)
+				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+			},
+			initString: codeBlock{code: `var client = new grpc.Client();`},
+			vuString: codeBlock{
+				code: fmt.Sprintf(`
+				client.connect("GRPCBIN_ADDR", { timeout: '2s', tls: { cacerts: ["%s"], cert: "%s", key: "%s", password: "%s" }});
+				`,
+					localHostCert,
+					clientAuthBad,
+					clientAuthKeyEncrypted,
+					trivialKeyPassword),
+				err: "remote error: tls: bad certificate",
+			},
+		},
+		{
+			name: "ConnectTlsInvokeSuccess",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				clientCAPool := x509.NewCertPool()
+				clientCAPool.AppendCertsFromPEM(clientAuthCA)
+				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
+			vuString: codeBlock{
+				code: fmt.Sprintf(`
+				client.connect("GRPCBIN_ADDR", { timeout: '5s', tls: { cacerts: ["%s"], cert: "%s", key: "%s" }});
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected error: " + JSON.stringify(resp.error) + "or status: " + resp.status)
+				}`,
+					localHostCert,
+					clientAuth,
+					clientAuthKey),
+			},
+		},
+		{
+			name: "ConnectTlsBadAuthInvokeFails",
+			setup: func(tb *httpmultibin.HTTPMultiBin) {
+				clientCAPool := x509.NewCertPool()
+				clientCAPool.AppendCertsFromPEM(clientAuthCA)
+				tb.ServerHTTP2.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+				tb.ServerHTTP2.TLS.ClientCAs = clientCAPool
+				tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+					return &grpc_testing.Empty{}, nil
+				}
+			},
+			initString: codeBlock{code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`},
+			vuString: codeBlock{
+				code: fmt.Sprintf(`
+				client.connect("GRPCBIN_ADDR", { timeout: '2s', tls: { cacerts: ["%s"], cert: "%s", key: "%s" }});
+				var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+				if (resp.status !== grpc.StatusOK) {
+					throw new Error("unexpected error: " + JSON.stringify(resp.error) + "or status: " + resp.status)
+				}`,
+					localHostCert,
+					clientAuthBad,
+					clientAuthKey),
+				err: "remote error: tls: bad certificate",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestState(t)
+
+			// setup necessary environment if needed by a test
+			if tt.setup != nil {
+				tt.setup(ts.httpBin)
+			}
+
+			val, err := ts.Run(tt.initString.code)
+			assertResponse(t, tt.initString, err, val, ts)
+
+			ts.ToVUContext()
+			val, err = ts.Run(tt.vuString.code)
+			assertResponse(t, tt.vuString, err, val, ts)
+		})
+	}
+}
+
+func TestDebugStat(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name     string
+		stat     grpcstats.RPCStats
+		expected string
+	}{
+		{
+			"OutHeader",
+			&grpcstats.OutHeader{},
+			"Out Header:",
+		},
+		{
+			"OutTrailer",
+			&grpcstats.OutTrailer{
+				Trailer: metadata.MD{
+					"x-trail": []string{"out"},
+				},
+			},
+			"Out Trailer:",
+		},
+		{
+			"OutPayload",
+			&grpcstats.OutPayload{
+				Payload: &grpc_testing.SimpleRequest{
+					FillUsername: true,
+				},
+			},
+			"fill_username:",
+		},
+		{
+			"InHeader",
+			&grpcstats.InHeader{
+				Header: metadata.MD{
+					"x-head": []string{"in"},
+				},
+			},
+			"x-head: in",
+		},
+		{
+			"InTrailer",
+			&grpcstats.InTrailer{
+				Trailer: metadata.MD{
+					"x-trail": []string{"in"},
+				},
+			},
+			"x-trail: in",
+		},
+		{
+			"InPayload",
+			&grpcstats.InPayload{
+				Payload: &grpc_testing.SimpleResponse{
+					Username: "k6-user",
+				},
+			},
+			"username:",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var b bytes.Buffer
+			logger := logrus.New()
+			logger.Out = &b
+
+			grpcext.DebugStat(logger.WithField("source", "test"), tt.stat, "full")
+			assert.Contains(t, b.String(), tt.expected)
+		})
+	}
+}
+
+func TestClientConnectionReflectMetadata(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	reflection.Register(ts.httpBin.ServerGRPC)
+
+	initString := codeBlock{
+		code: `var client = new grpc.Client();`,
+	}
+	vuString := codeBlock{
+		code: `client.connect("GRPCBIN_ADDR", {reflect: true, reflectMetadata: {"x-test": "custom-header-for-reflection"}})`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	// this should trigger logging of the outgoing gRPC metadata
+	ts.VU.State().Options.HTTPDebug = null.NewString("full", true)
+
+	val, err = ts.Run(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+
+	entries := ts.loggerHook.Drain()
+
+	// since we enable debug logging, we should see the metadata in the logs
+	foundReflectionCall := false
+	for _, entry := range entries {
+		if strings.Contains(entry.Message, "ServerReflection/ServerReflectionInfo") {
+			foundReflectionCall = true
+
+			// check that the metadata is present
+			assert.Contains(t, entry.Message, "x-test: custom-header-for-reflection")
+			// check that user-agent header is present
+			assert.Contains(t, entry.Message, "user-agent: k6-test")
+		}
+	}
+
+	assert.True(t, foundReflectionCall, "expected to find a reflection call in the logs, but didn't")
+}
+
+func TestClient_ReflectOnReconnect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		reconnect string
+		reflected bool
+	}{
+		{
+			name:      "AutoAnotherTarget",
+			reconnect: `client.connect("example.com:443", { endpoints: ["GRPCBIN_ADDR"] })`,
+			reflected: true,
+		},
+		{
+			name:      "AutoSameTarget",
+			reconnect: `client.connect("GRPCBIN_ADDR")`,
+		},
+		{
+			name:      "Always",
+			reconnect: `client.connect("GRPCBIN_ADDR", { reflectOnReconnect: "always", reflectCache: false })`,
+			reflected: true,
+		},
+		{
+			name: "Never",
+			reconnect: `client.connect("example.com:443", {
+				endpoints: ["GRPCBIN_ADDR"],
+				reflectOnReconnect: "never",
+			})`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestState(t)
+
+			reflection.Register(ts.httpBin.ServerGRPC)
+			ts.httpBin.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+				return &grpc_testing.Empty{}, nil
+			}
+
+			initString := codeBlock{
+				code: `var client = new grpc.Client();`,
+			}
+			vuString := codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", {reflect: true, reflectMetadata: {"x-test": "reflect-on-reconnect"}});
+				client.close();`,
+			}
+
+			val, err := ts.Run(initString.code)
+			assertResponse(t, initString, err, val, ts)
+
+			ts.ToVUContext()
+			// the reconnect's reflection is told by the logged exchange, the debug is set
+			// before the first connect, since its handlers read the options while it's live
+			ts.VU.State().Options.HTTPDebug = null.NewString("full", true)
+
+			val, err = ts.Run(vuString.code)
+			assertResponse(t, vuString, err, val, ts)
+
+			ts.loggerHook.Drain()
+
+			reconnectString := codeBlock{
+				code: tt.reconnect + `
+				client.invoke("grpc.testing.TestService/EmptyCall", {});`,
+			}
+			val, err = ts.Run(reconnectString.code)
+			assertResponse(t, reconnectString, err, val, ts)
+
+			reflected := false
+			for _, entry := range ts.loggerHook.Drain() {
+				if strings.Contains(entry.Message, "ServerReflection/ServerReflectionInfo") {
+					reflected = true
+
+					// the previous connection's reflection metadata is reused
+					assert.Contains(t, entry.Message, "x-test: reflect-on-reconnect")
+				}
+			}
+
+			assert.Equal(t, tt.reflected, reflected)
+		})
+	}
+}
+
+func TestClient_CallDebug(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	ts.httpBin.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+		return &grpc_testing.Empty{}, nil
+	}
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+	}
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	countPayloads := func() int {
+		var payloads int
+		for _, entry := range ts.loggerHook.Drain() {
+			if strings.Contains(entry.Message, "Out Payload") {
+				payloads++
+			}
+		}
+
+		return payloads
+	}
+
+	// the call's debug logs it without the --http-debug
+	vuString := codeBlock{code: `
+		client.connect("GRPCBIN_ADDR");
+		client.invoke("grpc.testing.TestService/EmptyCall", {}, { debug: "full" });
+		client.invoke("grpc.testing.TestService/EmptyCall", {}, { debug: "headers" });
+		client.invoke("grpc.testing.TestService/EmptyCall", {});`}
+	val, err = ts.Run(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+	assert.Equal(t, 1, countPayloads())
+
+	// the calls sampled out aren't logged even with the --http-debug
+	ts.VU.State().Options.HTTPDebug = null.NewString("full", true)
+
+	vuString = codeBlock{code: `
+		for (var i = 0; i < 20; i++) {
+			client.invoke("grpc.testing.TestService/EmptyCall", {}, { debugSampleRate: 0.000001 });
+		}
+		client.invoke("grpc.testing.TestService/EmptyCall", {}, { debugSampleRate: 1 });`}
+	val, err = ts.Run(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+	assert.Equal(t, 1, countPayloads())
+}
+
+func TestClient_ExtOptionsDefaults(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	ts.httpBin.GRPCStub.UnaryCallFunc = func(ctx context.Context, _ *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if got := md.Get("x-env"); len(got) != 1 || got[0] != "staging" {
+			return nil, status.Errorf(codes.InvalidArgument, "unexpected x-env: %v", got)
+		}
+		if got := md.Get("x-call"); len(got) != 1 || got[0] != "1" {
+			return nil, status.Errorf(codes.InvalidArgument, "unexpected x-call: %v", got)
+		}
+
+		return &grpc_testing.SimpleResponse{}, nil
+	}
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect()
+		var resp = client.invoke("grpc.testing.TestService/UnaryCall", {}, { metadata: { "x-call": "1" } })
+		if (resp.status !== grpc.StatusOK) {
+			throw new Error("unexpected status: " + resp.status + " " + resp.error.message)
+		}`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	ts.VU.State().Options.External = map[string]json.RawMessage{
+		"grpc": json.RawMessage(ts.httpBin.Replacer.Replace(`{
+			"target": "GRPCBIN_ADDR",
+			"connect": { "plaintext": false, "timeout": "5s" },
+			"params": { "metadata": { "x-env": "staging" }, "timeout": "5s" }
+		}`)),
+	}
+
+	val, err = ts.Run(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+}
+
+func TestClient_ExtOptionsConnectionDefaults(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	ts.httpBin.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if got := md.Get(":authority"); len(got) != 1 || got[0] != "example.com" {
+			return nil, status.Errorf(codes.InvalidArgument, "unexpected :authority: %v", got)
+		}
+
+		return &grpc_testing.Empty{}, nil
+	}
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+	}
+	// the client's own connection isn't made, so the calls are sent by the default one
+	vuString := codeBlock{
+		code: `
+		client.connect("GRPCBIN_ADDR", { name: "eu" })
+		var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+		if (resp.status !== grpc.StatusOK) {
+			throw new Error("unexpected status: " + resp.status + " " + resp.error.message)
+		}`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	ts.VU.State().Options.External = map[string]json.RawMessage{
+		"grpc": json.RawMessage(`{"params": { "connection": "eu", "authority": "example.com" }}`),
+	}
+
+	val, err = ts.Run(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+}
+
+func TestClient_ExtOptionsInvalid(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	val, err := ts.Run(`var client = new grpc.Client();`)
+	assertResponse(t, codeBlock{}, err, val, ts)
+
+	ts.ToVUContext()
+
+	ts.VU.State().Options.External = map[string]json.RawMessage{
+		"grpc": json.RawMessage(`{"target": 1}`),
+	}
+
+	_, err = ts.Run(`client.connect()`)
+	require.ErrorContains(t, err, "invalid options.ext.grpc")
+}
+
+func TestClient_ConnectionReuse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		reuse       string
+		connections int
+	}{
+		{name: "Default", connections: 1},
+		{name: "VU", reuse: "vu", connections: 1},
+		{name: "Iteration", reuse: "iteration", connections: 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestState(t)
+
+			var mu sync.Mutex
+			peers := make(map[string]bool)
+			ts.httpBin.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+				p, _ := peer.FromContext(ctx)
+
+				mu.Lock()
+				defer mu.Unlock()
+				peers[p.Addr.String()] = true
+
+				return &grpc_testing.Empty{}, nil
+			}
+
+			val, err := ts.Run(`
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`)
+			assertResponse(t, codeBlock{}, err, val, ts)
+
+			ts.ToVUContext()
+
+			if tt.reuse != "" {
+				ts.VU.State().Options.External = map[string]json.RawMessage{
+					"grpc": json.RawMessage(`{"connectionReuse": "` + tt.reuse + `"}`),
+				}
+			}
+
+			val, err = ts.Run(ts.httpBin.Replacer.Replace(`
+				client.connect("GRPCBIN_ADDR");
+				client.invoke("grpc.testing.TestService/EmptyCall", {});`))
+			assertResponse(t, codeBlock{}, err, val, ts)
+
+			// the next iteration doesn't connect again
+			ts.VU.State().Iteration++
+
+			val, err = ts.Run(`client.invoke("grpc.testing.TestService/EmptyCall", {});`)
+			assertResponse(t, codeBlock{}, err, val, ts)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Len(t, peers, tt.connections)
+		})
+	}
+}
+
+func TestClient_CallAuthorityConnectionsEvicted(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	var mu sync.Mutex
+	peers := make(map[string]bool)
+	ts.httpBin.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+		p, _ := peer.FromContext(ctx)
+
+		mu.Lock()
+		defer mu.Unlock()
+		peers[p.Addr.String()] = true
+
+		return &grpc_testing.Empty{}, nil
+	}
+
+	val, err := ts.Run(`
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");`)
+	assertResponse(t, codeBlock{}, err, val, ts)
+
+	ts.ToVUContext()
+
+	// the 17th authority evicts the first one, so only it's dialed again
+	val, err = ts.Run(ts.httpBin.Replacer.Replace(`
+		client.connect("GRPCBIN_ADDR");
+		for (var i = 0; i <= 16; i++) {
+			client.invoke("grpc.testing.TestService/EmptyCall", {}, { authority: "a" + i + ".example.com" });
+		}
+		client.invoke("grpc.testing.TestService/EmptyCall", {}, { authority: "a16.example.com" });
+		client.invoke("grpc.testing.TestService/EmptyCall", {}, { authority: "a0.example.com" });`))
+	assertResponse(t, codeBlock{}, err, val, ts)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, peers, 18)
+}
+
+func TestClient_ConnectionReuseInvalid(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	val, err := ts.Run(`var client = new grpc.Client();`)
+	assertResponse(t, codeBlock{}, err, val, ts)
+
+	ts.ToVUContext()
+
+	ts.VU.State().Options.External = map[string]json.RawMessage{
+		"grpc": json.RawMessage(`{"connectionReuse": "call"}`),
+	}
+
+	_, err = ts.Run(`client.connect("localhost:1")`)
+	require.ErrorContains(t, err, `invalid options.ext.grpc.connectionReuse value: "call", it needs to be "vu" or "iteration"`)
+}
+
+func TestClient_UnixSocket(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	socket := filepath.Join(t.TempDir(), "grpc.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	grpc_testing.RegisterTestServiceServer(srv, &httpmultibin.GRPCStub{
+		EmptyCallFunc: func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+			return &grpc_testing.Empty{}, nil
+		},
+	})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect("unix://` + socket + `", { plaintext: true });
+		var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+		if (resp.status !== grpc.StatusOK) {
+			throw new Error("unexpected status: " + resp.status);
+		}`,
 	}
 
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
 
-			ts := newTestState(t)
+	ts.ToVUContext()
 
-			// setup necessary environment if needed by a test
-			if tt.setup != nil {
-				tt.setup(ts.httpBin)
-			}
+	val, err = ts.Run(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+}
 
-			val, err := ts.Run(tt.initString.code)
-			assertResponse(t, tt.initString, err, val, ts)
+// countingListener counts the accepted connections
+type countingListener struct {
+	net.Listener
+	accepted atomic.Int64
+}
 
-			ts.ToVUContext()
-			val, err = ts.Run(tt.vuString.code)
-			assertResponse(t, tt.vuString, err, val, ts)
-		})
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
 	}
+
+	return conn, err
 }
 
-func TestDebugStat(t *testing.T) {
+func TestClient_Prewarm(t *testing.T) {
 	t.Parallel()
 
-	tests := [...]struct {
-		name     string
-		stat     grpcstats.RPCStats
-		expected string
-	}{
-		{
-			"OutHeader",
-			&grpcstats.OutHeader{},
-			"Out Header:",
-		},
-		{
-			"OutTrailer",
-			&grpcstats.OutTrailer{
-				Trailer: metadata.MD{
-					"x-trail": []string{"out"},
-				},
-			},
-			"Out Trailer:",
-		},
-		{
-			"OutPayload",
-			&grpcstats.OutPayload{
-				Payload: &grpc_testing.SimpleRequest{
-					FillUsername: true,
-				},
-			},
-			"fill_username:",
-		},
-		{
-			"InHeader",
-			&grpcstats.InHeader{
-				Header: metadata.MD{
-					"x-head": []string{"in"},
-				},
-			},
-			"x-head: in",
-		},
-		{
-			"InTrailer",
-			&grpcstats.InTrailer{
-				Trailer: metadata.MD{
-					"x-trail": []string{"in"},
-				},
-			},
-			"x-trail: in",
+	ts := newTestState(t)
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	lis := &countingListener{Listener: tcp}
+
+	srv := grpc.NewServer()
+	grpc_testing.RegisterTestServiceServer(srv, &httpmultibin.GRPCStub{
+		EmptyCallFunc: func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+			return &grpc_testing.Empty{}, nil
 		},
-		{
-			"InPayload",
-			&grpcstats.InPayload{
-				Payload: &grpc_testing.SimpleResponse{
-					Username: "k6-user",
-				},
-			},
-			"username:",
+	})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	events := event.NewEventSystem(10, logrus.New())
+	ts.VU.EventsField = common.Events{Global: events}
+	m, ok := xk6grpc.New().NewModuleInstance(ts.VU).(*xk6grpc.ModuleInstance)
+	require.True(t, ok)
+	require.NoError(t, ts.VU.Runtime().Set("grpc", m.Exports().Named))
+
+	val, err := ts.Run(`
+		var clients = [new grpc.Client(), new grpc.Client(), new grpc.Client()];
+		clients.forEach((c) => c.load([], "../grpc/testdata/grpc_testing/test.proto"));`)
+	assertResponse(t, codeBlock{}, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.Run(`clients[0].prewarm("` + tcp.Addr().String() + `", 2, { plaintext: true })`)
+	assertResponse(t, codeBlock{}, err, val, ts)
+	assert.Equal(t, int64(2), val.Export())
+	assert.Equal(t, int64(2), lis.accepted.Load())
+
+	// the prewarmed connections are taken by the clients connecting with the same params
+	val, err = ts.Run(`
+		clients.forEach((c) => {
+			c.connect("` + tcp.Addr().String() + `", { plaintext: true });
+			var resp = c.invoke("grpc.testing.TestService/EmptyCall", {});
+			if (resp.status !== grpc.StatusOK) {
+				throw new Error("unexpected status: " + resp.status);
+			}
+		});`)
+	assertResponse(t, codeBlock{}, err, val, ts)
+	assert.Equal(t, int64(3), lis.accepted.Load())
+
+	_, err = ts.Run(`clients[0].prewarm("` + tcp.Addr().String() + `", 0)`)
+	require.ErrorContains(t, err, "invalid prewarm count: 0, it needs to be positive")
+
+	_, err = ts.Run(`clients[0].prewarm("` + tcp.Addr().String() + `", 1, { pool: true })`)
+	require.ErrorContains(t, err, "the pooled connections can't be prewarmed")
+
+	// the connections which haven't been taken are closed once the test ends
+	val, err = ts.Run(`clients[0].prewarm("` + tcp.Addr().String() + `", 1, { plaintext: true })`)
+	assertResponse(t, codeBlock{}, err, val, ts)
+	assert.Equal(t, int64(4), lis.accepted.Load())
+
+	require.NoError(t, events.Emit(&event.Event{Type: event.TestEnd})(context.Background()))
+
+	val, err = ts.Run(`clients[0].connect("` + tcp.Addr().String() + `", { plaintext: true })`)
+	assertResponse(t, codeBlock{}, err, val, ts)
+	assert.Equal(t, int64(5), lis.accepted.Load())
+
+	_, err = ts.Run(`clients[0].prewarm("` + tcp.Addr().String() + `", 1, { plaintext: true })`)
+	require.ErrorContains(t, err, "the prewarmed connections are closed, the test has ended")
+}
+
+func TestClient_SharedPool(t *testing.T) {
+	t.Parallel()
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	lis := &countingListener{Listener: tcp}
+
+	srv := grpc.NewServer()
+	grpc_testing.RegisterTestServiceServer(srv, &httpmultibin.GRPCStub{
+		EmptyCallFunc: func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+			return &grpc_testing.Empty{}, nil
 		},
+	})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	// the VUs of the same test share the root module, which closes the pool once the test ends
+	root := xk6grpc.New()
+	events := event.NewEventSystem(10, logrus.New())
+
+	vus := []testState{newTestState(t), newTestState(t)}
+	for _, ts := range vus {
+		ts.VU.EventsField = common.Events{Global: events}
+		m, ok := root.NewModuleInstance(ts.VU).(*xk6grpc.ModuleInstance)
+		require.True(t, ok)
+		require.NoError(t, ts.VU.Runtime().Set("grpc", m.Exports().Named))
+
+		val, err := ts.Run(`
+			var client = new grpc.Client();
+			client.load([], "../grpc/testdata/grpc_testing/test.proto");`)
+		assertResponse(t, codeBlock{}, err, val, ts)
+
+		ts.ToVUContext()
 	}
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-			var b bytes.Buffer
-			logger := logrus.New()
-			logger.Out = &b
 
-			grpcext.DebugStat(logger.WithField("source", "test"), tt.stat, "full")
-			assert.Contains(t, b.String(), tt.expected)
-		})
+	for _, ts := range vus {
+		val, err := ts.Run(`
+			client.connect("` + tcp.Addr().String() + `", { plaintext: true, pool: true });
+			var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+			if (resp.status !== grpc.StatusOK) {
+				throw new Error("unexpected status: " + resp.status);
+			}`)
+		assertResponse(t, codeBlock{}, err, val, ts)
+
+		// the calls' samples are pushed by the VU making them
+		assert.Len(t, metricValues(metrics.GetBufferedSamples(ts.samples), "grpc_req_duration"), 1)
 	}
+	assert.Equal(t, int64(1), lis.accepted.Load())
+
+	require.NoError(t, events.Emit(&event.Event{Type: event.TestEnd})(context.Background()))
+
+	val, err := vus[1].Run(`client.invoke("grpc.testing.TestService/EmptyCall", {}).status`)
+	require.NoError(t, err)
+	assert.Equal(t, codes.Canceled, val.Export())
+
+	_, err = vus[1].Run(`client.connect("` + tcp.Addr().String() + `", { plaintext: true, pool: true })`)
+	require.ErrorContains(t, err, "the connection pool is closed")
 }
 
-func TestClientConnectionReflectMetadata(t *testing.T) {
+func TestClient_Sharding(t *testing.T) {
 	t.Parallel()
 
 	ts := newTestState(t)
 
-	reflection.Register(ts.httpBin.ServerGRPC)
+	listeners := make([]*countingListener, 2)
+	addrs := make([]string, 2)
+	for i := range listeners {
+		tcp, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		listeners[i] = &countingListener{Listener: tcp}
+		addrs[i] = tcp.Addr().String()
 
-	initString := codeBlock{
-		code: `var client = new grpc.Client();`,
+		srv := grpc.NewServer()
+		grpc_testing.RegisterTestServiceServer(srv, &httpmultibin.GRPCStub{})
+		go func(lis net.Listener) { _ = srv.Serve(lis) }(listeners[i])
+		t.Cleanup(srv.Stop)
 	}
-	vuString := codeBlock{
-		code: `client.connect("GRPCBIN_ADDR", {reflect: true, reflectMetadata: {"x-test": "custom-header-for-reflection"}})`,
+
+	val, err := ts.Run(`
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");`)
+	assertResponse(t, codeBlock{}, err, val, ts)
+
+	ts.ToVUContext()
+
+	// the VUs are numbered per instance, so the global IDs pick the targets
+	ts.VU.State().VUID = 1
+	ts.VU.State().Options.External = map[string]json.RawMessage{
+		"grpc": json.RawMessage(`{"connectionReuse": "iteration"}`),
 	}
+	connect := func(vuID uint64, targets, sharding string) error {
+		ts.VU.State().VUIDGlobal = vuID
+		_, err := ts.Run(`
+			client.connect(` + targets + `, { plaintext: true, sharding: ` + sharding + ` });
+			client.close();`)
 
-	val, err := ts.Run(initString.code)
-	assertResponse(t, initString, err, val, ts)
+		return err
+	}
+	accepted := func() []int64 {
+		return []int64{listeners[0].accepted.Load(), listeners[1].accepted.Load()}
+	}
+
+	// the VUs are spread round-robin by default
+	targets := `["` + addrs[0] + `", "` + addrs[1] + `"]`
+	for vuID := uint64(1); vuID <= 3; vuID++ {
+		require.NoError(t, connect(vuID, targets, "undefined"))
+	}
+	assert.Equal(t, []int64{2, 1}, accepted())
+
+	// the weighted ones get the consecutive VUs in proportion to the weights
+	targets = `[{ target: "` + addrs[0] + `", weight: 1 }, { target: "` + addrs[1] + `", weight: 3 }]`
+	for vuID := uint64(1); vuID <= 4; vuID++ {
+		require.NoError(t, connect(vuID, targets, `"weighted"`))
+	}
+	assert.Equal(t, []int64{3, 4}, accepted())
+
+	require.NoError(t, connect(1, targets, `"random"`))
+	assert.Equal(t, int64(8), listeners[0].accepted.Load()+listeners[1].accepted.Load())
+
+	err = connect(1, targets, `"hash"`)
+	require.ErrorContains(t, err, `invalid sharding value: '"hash"', it needs to be "vu-modulo", "random" or "weighted"`)
+
+	err = connect(1, "[]", "undefined")
+	require.ErrorContains(t, err, "invalid targets value: it needs to be a non-empty array")
+
+	err = connect(1, `[{ target: "`+addrs[0]+`", weight: 0 }]`, `"weighted"`)
+	require.ErrorContains(t, err, `invalid weight value of "`+addrs[0]+`": 0, it needs to be a positive integer`)
+
+	// the connections renewed in each iteration pick the target again
+	ts.VU.State().VUIDGlobal = 1
+	_, err = ts.Run(`client.connect(["` + addrs[0] + `", "` + addrs[1] + `"], { plaintext: true });`)
+	require.NoError(t, err)
+
+	before := accepted()
+	ts.VU.State().VUIDGlobal = 2
+	ts.VU.State().Iteration++
+	_, err = ts.Run(`client.invoke("grpc.testing.TestService/EmptyCall", {});`)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{before[0], before[1] + 1}, accepted())
+
+	err = connect(1, "[42]", "undefined")
+	require.ErrorContains(t, err, "invalid target value: '42', it needs to be an address or an object with the target")
+}
+
+func TestClient_ConnRotation(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	// the server rotates the connections by their max age
+	srv := grpc.NewServer(grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionAge:      100 * time.Millisecond,
+		MaxConnectionAgeGrace: time.Second,
+	}))
+	grpc_testing.RegisterTestServiceServer(srv, &httpmultibin.GRPCStub{
+		EmptyCallFunc: func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+			return &grpc_testing.Empty{}, nil
+		},
+	})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	val, err := ts.Run(`
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");
+		var rotations = [];
+		client.on("rotation", (e) => rotations.push(e));`)
+	assertResponse(t, codeBlock{}, err, val, ts)
 
 	ts.ToVUContext()
 
-	// this should trigger logging of the outgoing gRPC metadata
-	ts.VU.State().Options.HTTPDebug = null.NewString("full", true)
+	val, err = ts.Run(`client.connect("` + lis.Addr().String() + `", { plaintext: true })`)
+	assertResponse(t, codeBlock{}, err, val, ts)
 
-	val, err = ts.Run(vuString.code)
-	assertResponse(t, vuString, err, val, ts)
+	// the calls succeed across the rotations, the rotation's event is dispatched by the call after the reconnect
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		val, err = ts.Run(`
+			var resp = client.invoke("grpc.testing.TestService/EmptyCall", {});
+			if (resp.status !== grpc.StatusOK) {
+				throw new Error("unexpected status: " + resp.status);
+			}
+			rotations.length`)
+		require.NoError(t, err)
 
-	entries := ts.loggerHook.Drain()
+		if val.ToInteger() > 0 {
+			break
+		}
+		require.True(t, time.Now().Before(deadline), "the connection wasn't rotated")
+	}
 
-	// since we enable debug logging, we should see the metadata in the logs
-	foundReflectionCall := false
-	for _, entry := range entries {
-		if strings.Contains(entry.Message, "ServerReflection/ServerReflectionInfo") {
-			foundReflectionCall = true
+	val, err = ts.Run(`rotations[0]`)
+	require.NoError(t, err)
+	rotation := val.ToObject(ts.VU.Runtime())
+	assert.Equal(t, lis.Addr().String(), rotation.Get("remoteAddress").String())
+	assert.Positive(t, rotation.Get("downtime").ToFloat())
 
-			// check that the metadata is present
-			assert.Contains(t, entry.Message, "x-test: custom-header-for-reflection")
-			// check that user-agent header is present
-			assert.Contains(t, entry.Message, "user-agent: k6-test")
+	samplesBuf := metrics.GetBufferedSamples(ts.samples)
+	assert.NotEmpty(t, metricValues(samplesBuf, "grpc_conn_rotations"))
+	assert.NotEmpty(t, metricValues(samplesBuf, "grpc_conn_rotation_downtime"))
+}
+
+func TestClient_TrailersOnly(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	// the HTTP/2 handler of the test server always sends the headers, unlike the gRPC's server
+	socket := filepath.Join(t.TempDir(), "grpc.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	grpc_testing.RegisterTestServiceServer(srv, &httpmultibin.GRPCStub{
+		// the status returned before anything is sent is replied trailers-only
+		EmptyCallFunc: func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+			return nil, status.Error(codes.Unavailable, "no healthy upstream")
+		},
+		UnaryCallFunc: func(ctx context.Context, _ *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+			_ = grpc.SendHeader(ctx, metadata.Pairs("x-served-by", "backend"))
+			return nil, status.Error(codes.Unavailable, "the backend is down")
+		},
+	})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect("unix://` + socket + `", { plaintext: true });
+		var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+		if (!resp.trailersOnly || resp.status !== grpc.StatusUnavailable) {
+			throw new Error("the trailers-only response isn't flagged: " + JSON.stringify(resp))
 		}
+		resp = client.invoke("grpc.testing.TestService/UnaryCall", {})
+		if (resp.trailersOnly || resp.status !== grpc.StatusUnavailable) {
+			throw new Error("the response with the headers is flagged: " + JSON.stringify(resp))
+		}
+
+		// the stub's streams are unimplemented
+		var stream = new grpc.Stream(client, "grpc.testing.TestService/FullDuplexCall")
+		stream.on("error", (e) => {
+			if (!e.trailersOnly) {
+				throw new Error("the trailers-only stream isn't flagged: " + JSON.stringify(e))
+			}
+			call("error")
+		})`,
 	}
 
-	assert.True(t, foundReflectionCall, "expected to find a reflection call in the logs, but didn't")
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+	assert.Equal(t, []string{"error"}, ts.callRecorder.Recorded())
 }
 
 func TestClientLoadProto(t *testing.T) {
@@ -1264,3 +4706,155 @@ func TestClientLoadProto(t *testing.T) {
 		assert.Nil(t, err, "It was not expected that there would be an error, but it got: %v", err)
 	}
 }
+
+func TestClient_LoadProtosetContent(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	b, err := os.ReadFile("testdata/grpc_protoset_testing/test.protoset") //nolint:forbidigo
+	require.NoError(t, err)
+
+	rt := ts.VU.Runtime()
+	require.NoError(t, rt.Set("protoset", rt.NewArrayBuffer(b)))
+	require.NoError(t, rt.Set("broken", rt.NewArrayBuffer([]byte("not a protoset"))))
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.loadProtoset(protoset);`,
+		val: []xk6grpc.MethodInfo{
+			{
+				MethodInfo: grpc.MethodInfo{Name: "Test", IsClientStream: false, IsServerStream: false},
+				Package:    "grpc.protoset.testing", Service: "TestService", FullMethod: "/grpc.protoset.testing.TestService/Test",
+			},
+		},
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	brokenString := codeBlock{
+		code: `client.loadProtoset(broken);`,
+		err:  "couldn't unmarshal protoset",
+	}
+
+	val, err = ts.Run(brokenString.code)
+	assertResponse(t, brokenString, err, val, ts)
+}
+
+func TestClient_LoadProtosetGzippedMultiple(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	// the second protoset is the gzipped descriptors of the grpc.testing package
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(grpc_testing.File_test_grpc_testing_test_proto)},
+	})
+	require.NoError(t, err)
+
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	_, err = zw.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	rt := ts.VU.Runtime()
+	require.NoError(t, rt.Set("gzipped", rt.NewArrayBuffer(gzipped.Bytes())))
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		var methods = client.loadProtoset([
+			"testdata/grpc_protoset_testing/test.protoset",
+			gzipped,
+			"testdata/grpc_protoset_testing/test.protoset",
+		]);
+		var names = methods.map(function (m) { return m.full_method; });
+		if (names.indexOf("/grpc.protoset.testing.TestService/Test") < 0 ||
+			names.indexOf("/grpc.testing.TestService/EmptyCall") < 0) {
+			throw new Error("unexpected methods: " + names);
+		}`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	brokenString := codeBlock{
+		code: `client.loadProtoset("testdata/grpc_protoset_testing/test.protoset", new Uint8Array([0x1f, 0x8b, 1]).buffer);`,
+		err:  "couldn't gunzip protoset",
+	}
+
+	val, err = ts.Run(brokenString.code)
+	assertResponse(t, brokenString, err, val, ts)
+}
+
+func TestClient_LoadFromRegistry(t *testing.T) {
+	t.Parallel()
+
+	protoset, err := os.ReadFile("testdata/grpc_protoset_testing/test.protoset") //nolint:forbidigo
+	require.NoError(t, err)
+
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"unauthenticated","message":"invalid token"}`))
+
+			return
+		}
+
+		assert.Equal(t, "/buf.alpha.registry.v1alpha1.ImageService/GetImage", r.URL.Path)
+		assert.Equal(t, "application/proto", r.Header.Get("Content-Type"))
+
+		// the response's image is the protoset
+		b := protowire.AppendTag(nil, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, protoset)
+		w.Header().Set("Content-Type", "application/proto")
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+
+	ts := newTestState(t)
+	require.NoError(t, ts.VU.Runtime().Set("registryURL", srv.URL))
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		var params = { url: registryURL, token: "secret" };
+		client.loadFromRegistry("buf.build/acme/protoset:v1", params);
+		client.loadFromRegistry("buf.build/acme/protoset:v1", params);`,
+		val: []xk6grpc.MethodInfo{
+			{
+				MethodInfo: grpc.MethodInfo{Name: "Test", IsClientStream: false, IsServerStream: false},
+				Package:    "grpc.protoset.testing", Service: "TestService", FullMethod: "/grpc.protoset.testing.TestService/Test",
+			},
+		},
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	// the image is cached
+	assert.Equal(t, int64(1), requests.Load())
+
+	unauthenticatedString := codeBlock{
+		code: `client.loadFromRegistry("buf.build/acme/private", { url: registryURL, token: "wrong" });`,
+		err:  `can't load "buf.build/acme/private" from the registry: unauthenticated: invalid token`,
+	}
+
+	val, err = ts.Run(unauthenticatedString.code)
+	assertResponse(t, unauthenticatedString, err, val, ts)
+
+	invalidString := codeBlock{
+		code: `client.loadFromRegistry("acme/protoset");`,
+		err:  `invalid module reference: "acme/protoset"`,
+	}
+
+	val, err = ts.Run(invalidString.code)
+	assertResponse(t, invalidString, err, val, ts)
+}
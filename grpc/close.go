@@ -0,0 +1,161 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/mstoykov/k6-taskqueue-lib/taskqueue"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+)
+
+// defaultGracefulCloseTimeout is the default limit of the graceful close's wait for the in-flight RPCs
+const defaultGracefulCloseTimeout = 30 * time.Second
+
+// closeParams is the parameters of the client's close.
+type closeParams struct {
+	// Graceful waits for the in-flight RPCs and streams to end before the connection is closed
+	Graceful bool
+	// Timeout limits the graceful wait, the connection is closed anyway once it's elapsed
+	Timeout time.Duration
+}
+
+// newCloseParams constructs the close parameters from the input value
+func newCloseParams(rt *goja.Runtime, input goja.Value) (*closeParams, error) {
+	result := &closeParams{Timeout: defaultGracefulCloseTimeout}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf("invalid close params: '%#v', it needs to be an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "graceful":
+			var ok bool
+			result.Graceful, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid graceful value: '%#v', it needs to be boolean", v)
+			}
+		case "timeout":
+			var err error
+			result.Timeout, err = types.GetDurationValue(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid timeout value: %w", err)
+			}
+		default:
+			return result, fmt.Errorf("unknown close param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// inFlight counts the client's in-flight RPCs (the unary calls of the VU and of the loads, and the streams
+// until their end is delivered), so the graceful close could wait for them to end.
+type inFlight struct {
+	mu sync.Mutex
+
+	count int
+	idle  chan struct{} // closed once the count drops to zero, nil while no one waits for it
+}
+
+// add counts the started RPC
+func (f *inFlight) add() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count++
+}
+
+// done uncounts the ended RPC
+func (f *inFlight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count--
+	if f.count == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// wait waits for all the in-flight RPCs to end, it returns the count of the remaining ones if the ctx is done first
+func (f *inFlight) wait(ctx context.Context) int {
+	f.mu.Lock()
+	if f.count == 0 {
+		f.mu.Unlock()
+
+		return 0
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle := f.idle
+	f.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0
+	case <-ctx.Done():
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		return f.count
+	}
+}
+
+// closeGracefully waits for the in-flight RPCs of the client and of its named connections to end, up to
+// the timeout, off the VU's event loop, so the streams' events are still delivered (and their reads released)
+// meanwhile. The connections are closed on the event loop then, and the returned promise is resolved.
+func (c *Client) closeGracefully(timeout time.Duration) *goja.Promise {
+	promise, resolve, reject := c.vu.Runtime().NewPromise()
+	tq := taskqueue.New(c.vu.RegisterCallback)
+
+	clients := []*Client{c}
+	for _, nc := range c.named {
+		clients = append(clients, nc)
+	}
+
+	c.events.hold()
+	go func() {
+		ctx, cancel := context.WithTimeout(c.vu.Context(), timeout)
+		defer cancel()
+
+		remaining := 0
+		for _, client := range clients {
+			remaining += client.inFlight.wait(ctx)
+		}
+
+		tq.Queue(func() error {
+			defer tq.Close()
+			c.events.release()
+
+			if remaining > 0 {
+				c.vu.State().Logger.Warnf("the graceful close has timed out after %s, %d in-flight RPCs are abandoned",
+					timeout, remaining)
+			}
+
+			if err := c.closeAll(); err != nil {
+				reject(err)
+
+				return nil
+			}
+			resolve(goja.Undefined())
+
+			return nil
+		})
+	}()
+
+	return promise
+}
@@ -0,0 +1,219 @@
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxAuthorityConnections is the most connections dialed for the calls' authority params kept open,
+// the least recently used one is closed when a call overrides yet another authority.
+const maxAuthorityConnections = 16
+
+// connectNamed connects the client's named connection, it's a client of its own sharing
+// the descriptors, the listeners and the interceptors with the client, so the targets
+// (e.g. the regions) could be compared without loading the descriptors for each of them.
+func (c *Client) connectNamed(addr string, target goja.Value, name string, params goja.Value) (bool, error) {
+	nc, ok := c.named[name]
+	if !ok {
+		// the descriptors loaded or reflected later are shared too
+		if c.mds == nil {
+			c.mds = make(map[string]protoreflect.MethodDescriptor)
+		}
+
+		nc = &Client{
+			name:        name,
+			mds:         c.mds,
+			vu:          c.vu,
+			metrics:     c.metrics,
+			listeners:   c.listeners,
+			events:      c.events,
+			latencies:   c.latencies,
+			reflections: c.reflections,
+			duplicates:  c.duplicates,
+			pool:        c.pool,
+			prewarmed:   c.prewarmed,
+			xds:         c.xds,
+			captured:    c.captured,
+
+			reflectionCache: c.reflectionCache,
+			statusCallback:  c.statusCallback,
+			breakers:        c.breakers,
+			extOptions:      c.extOptions,
+			descriptors:     c.descriptors,
+			registryImages:  c.registryImages,
+			mocks:           c.mocks,
+		}
+
+		if c.named == nil {
+			c.named = make(map[string]*Client)
+		}
+		c.named[name] = nc
+	}
+
+	return nc.connectAddr(addr, target, params)
+}
+
+// connection returns the client of the connection picked by the call's connection param,
+// the client itself if the param isn't set. The connection is renewed if it's reconnected in each iteration.
+// The connection and the authority params default to the ones of the options' ext params.
+func (c *Client) connection(params goja.Value) (*Client, error) {
+	opts, err := c.extOptions.load(c.vu)
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil {
+		params = withDefaults(c.vu.Runtime(), opts.Params, params)
+	}
+
+	nc, err := c.namedConnection(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := nc.renewConnection(); err != nil {
+		return nil, err
+	}
+
+	return c.authorityConnection(nc, params)
+}
+
+// authorityConnection returns the connection of the call's authority param, the connection's client if
+// the param isn't set. The gRPC sets the :authority (and the TLS server name) per connection, so the calls
+// overriding it are sent by a named connection of their own, dialed once by the connection's connect params.
+func (c *Client) authorityConnection(nc *Client, params goja.Value) (*Client, error) {
+	if common.IsNullish(params) || nc.conn == nil {
+		return nc, nil
+	}
+
+	rt := c.vu.Runtime()
+
+	v := params.ToObject(rt).Get("authority")
+	if common.IsNullish(v) {
+		return nc, nil
+	}
+
+	authority, ok := v.Export().(string)
+	if !ok || authority == "" {
+		return nil, fmt.Errorf("invalid authority value: '%#v', it needs to be a non-empty string", v.Export())
+	}
+
+	if authority == nc.authority {
+		return nc, nil
+	}
+
+	name := nc.name + "\x00authority=" + authority
+	if ac, ok := c.named[name]; ok {
+		if ac.conn != nil && ac.addr == nc.addr && ac.authorityOf == nc.connParams {
+			c.useAuthority(name)
+			ac.interceptors = nc.interceptors
+
+			return ac, ac.renewConnection()
+		}
+
+		// the connection has been reconnected since, so the one dialed for its previous params is dropped
+		if err := ac.close(); err != nil {
+			return nil, err
+		}
+	} else if err := c.evictAuthority(); err != nil {
+		return nil, err
+	}
+
+	connParams := rt.NewObject()
+	if !common.IsNullish(nc.connParams) {
+		base := nc.connParams.ToObject(rt)
+		for _, k := range base.Keys() {
+			_ = connParams.Set(k, base.Get(k))
+		}
+	}
+	_ = connParams.Set("name", name)
+	_ = connParams.Set("authority", authority)
+
+	if _, err := c.connectNamed(nc.addr, rt.ToValue(nc.addr), name, connParams); err != nil {
+		return nil, err
+	}
+	c.useAuthority(name)
+
+	ac := c.named[name]
+	ac.authorityOf = nc.connParams
+	ac.interceptors = nc.interceptors
+
+	return ac, nil
+}
+
+// useAuthority marks the authority connection as the most recently used one
+func (c *Client) useAuthority(name string) {
+	for i, n := range c.authorities {
+		if n == name {
+			c.authorities = append(c.authorities[:i], c.authorities[i+1:]...)
+
+			break
+		}
+	}
+
+	c.authorities = append(c.authorities, name)
+}
+
+// evictAuthority closes the least recently used authority connection if there are
+// the maxAuthorityConnections already, so the calls' distinct authorities don't pile up the connections.
+func (c *Client) evictAuthority() error {
+	if len(c.authorities) < maxAuthorityConnections {
+		return nil
+	}
+
+	name := c.authorities[0]
+	c.authorities = c.authorities[1:]
+
+	ac, ok := c.named[name]
+	if !ok {
+		return nil
+	}
+	delete(c.named, name)
+
+	return ac.close()
+}
+
+// namedConnection returns the client of the connection picked by the call's connection param
+func (c *Client) namedConnection(params goja.Value) (*Client, error) {
+	if common.IsNullish(params) {
+		return c, nil
+	}
+
+	v := params.ToObject(c.vu.Runtime()).Get("connection")
+	if common.IsNullish(v) {
+		return c, nil
+	}
+
+	name, ok := v.Export().(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid connection value: '%#v', it needs to be a non-empty string", v.Export())
+	}
+
+	if name == c.name {
+		return c, nil
+	}
+
+	nc, ok := c.named[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown connection: %q, you must connect it first", name)
+	}
+	nc.interceptors = c.interceptors
+
+	return nc, nil
+}
+
+// closeNamed closes the client's named connections
+func (c *Client) closeNamed() error {
+	var err error
+	for _, nc := range c.named {
+		if cerr := nc.close(); err == nil {
+			err = cerr
+		}
+	}
+	c.named = nil
+	c.authorities = nil
+
+	return err
+}
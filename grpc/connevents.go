@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"time"
+
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// withTransportCredentials returns the dial option of the transport credentials. If the client listens
+// to the connection or the rotation events, the GOAWAYs, the drops and the reconnects of the connections
+// are observed, counted and passed to the listeners. The server's rotations of the connections
+// (e.g. by their max age) are counted too, and their downtime is measured by the reconnects.
+func (o *connOwner) withTransportCredentials(tcred credentials.TransportCredentials) grpc.DialOption {
+	if !o.get().observesConnEvents() {
+		return grpc.WithTransportCredentials(tcred)
+	}
+
+	o.connEvents = grpcext.NewConnEvents(func(e grpcext.ConnEvent) {
+		c := o.get()
+
+		tm := c.tagsAndMeta
+		tm.Tags = tm.Tags.With("event", e.Type)
+		if e.Code != "" {
+			tm.Tags = tm.Tags.With("code", e.Code)
+		}
+		c.pushMetric(c.metrics.ConnEvents, &tm, 1)
+
+		if e.Rotation {
+			tm := c.tagsAndMeta
+			switch e.Type {
+			case grpcext.ConnGoAway:
+				c.pushMetric(c.metrics.ConnRotations, &tm, 1)
+			case grpcext.ConnReconnect:
+				c.pushMetric(c.metrics.ConnRotationDowntime, &tm, float64(e.Downtime)/float64(time.Millisecond))
+			}
+		}
+
+		c.events.post(func() error {
+			return c.deliverConnEvent(e)
+		})
+	})
+
+	return grpc.WithTransportCredentials(o.connEvents.Credentials(tcred))
+}
+
+// observesConnEvents reports whether the client listens to the connection events, so its connections observe them
+func (c *Client) observesConnEvents() bool {
+	return c.listeners.has(eventConnection) || c.listeners.has(eventRotation)
+}
+
+// connKey returns the key of the client's pooled and prewarmed connections, the ones observing the connection
+// events aren't shared with the clients which don't listen to them.
+func (c *Client) connKey(p *connectParams, addr string) (string, error) {
+	key, err := p.connKey(addr)
+	if err != nil || !c.observesConnEvents() {
+		return key, err
+	}
+
+	return key + "\x00connEvents", nil
+}
+
+// watchConnEvents watches the states of the connection's channels for its events, if they're observed
+func (o *connOwner) watchConnEvents(conn *grpcext.Conn) {
+	if o.connEvents != nil {
+		o.connEvents.Watch(conn)
+	}
+}
+
+// deliverConnEvent calls the connection's listeners with the event,
+// and the rotation's ones with the reconnect completing the server's rotation.
+func (c *Client) deliverConnEvent(e grpcext.ConnEvent) error {
+	rt := c.vu.Runtime()
+
+	if err := c.listeners.call(eventConnection, rt.ToValue(e)); err != nil {
+		return err
+	}
+
+	if e.Type != grpcext.ConnReconnect || !e.Rotation {
+		return nil
+	}
+
+	return c.listeners.call(eventRotation, rt.ToValue(map[string]interface{}{
+		"remoteAddress": e.RemoteAddr,
+		"downtime":      float64(e.Downtime) / float64(time.Millisecond),
+	}))
+}
@@ -0,0 +1,434 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/alts"
+	"google.golang.org/grpc/credentials/google"
+	"google.golang.org/grpc/credentials/insecure"
+	xdscreds "google.golang.org/grpc/credentials/xds"
+)
+
+const (
+	callCredentialsBearer = "bearer"
+
+	// credentialsGoogleDefault builds the channel with the Google default credentials,
+	// it's required by the GCP Traffic Director managed backends.
+	credentialsGoogleDefault = "google_default"
+	// credentialsALTS builds the channel with the ALTS credentials used between GCP workloads.
+	credentialsALTS = "alts"
+	// credentialsXDS builds the channel with the xDS credentials, the connections to the xds:/// targets
+	// use the security configuration (UpstreamTlsContext) delivered by the management server
+	// with the certificates of the bootstrap's certificate_providers, e.g. the mesh-issued ones.
+	// The plaintext or tls params configure the fallback credentials used for the other targets
+	// and when the management server doesn't deliver any security configuration.
+	credentialsXDS = "xds"
+	// credentialsSPIFFE builds the channel with the mTLS credentials using the X.509 SVID
+	// fetched from the SPIFFE Workload API, the SVID is rotated automatically.
+	credentialsSPIFFE = "spiffe"
+)
+
+// credentialsParams is the parameters of the channel's credentials,
+// it replaces the default TLS (or plaintext) transport credentials.
+type credentialsParams struct {
+	Type string
+	// TargetServiceAccounts is the list of the expected ALTS target service accounts
+	TargetServiceAccounts []string
+	// HandshakerServiceAddress is the ALTS handshaker service address
+	HandshakerServiceAddress string
+	// WorkloadAPIAddress is the SPIFFE Workload API socket address,
+	// the SPIFFE_ENDPOINT_SOCKET environment variable is used if it's empty
+	WorkloadAPIAddress string
+	// ServerID is the expected SPIFFE ID of the server, any ID is accepted if it's empty
+	ServerID spiffeid.ID
+}
+
+// newCredentialsParams constructs the channel's credentials parameters from the input value,
+// which is either the credentials type or an object with the type and its options.
+func newCredentialsParams(rt *goja.Runtime, input goja.Value) (*credentialsParams, error) {
+	if common.IsNullish(input) {
+		return nil, nil //nolint:nilnil
+	}
+
+	result := &credentialsParams{}
+
+	if t, ok := input.Export().(string); ok {
+		result.Type = t
+
+		return result, validateCredentialsType(result.Type)
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("invalid credentials value: '%#v', it needs to be a string or an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "type":
+			var ok bool
+			if result.Type, ok = v.(string); !ok {
+				return nil, fmt.Errorf("invalid credentials type value: '%#v', it needs to be a string", v)
+			}
+		case "targetServiceAccounts":
+			accounts, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid credentials targetServiceAccounts value: '%#v', "+
+					"it needs to be an array of strings", v)
+			}
+			for _, account := range accounts {
+				a, ok := account.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid credentials targetServiceAccounts value: '%#v', "+
+						"it needs to be an array of strings", v)
+				}
+				result.TargetServiceAccounts = append(result.TargetServiceAccounts, a)
+			}
+		case "handshakerServiceAddress":
+			var ok bool
+			result.HandshakerServiceAddress, ok = v.(string)
+			if !ok || result.HandshakerServiceAddress == "" {
+				return nil, fmt.Errorf("invalid credentials handshakerServiceAddress value: '%#v', "+
+					"it needs to be a non-empty string", v)
+			}
+		case "workloadApiAddress":
+			var ok bool
+			result.WorkloadAPIAddress, ok = v.(string)
+			if !ok || result.WorkloadAPIAddress == "" {
+				return nil, fmt.Errorf("invalid credentials workloadApiAddress value: '%#v', "+
+					"it needs to be a non-empty string", v)
+			}
+		case "serverId":
+			id, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid credentials serverId value: '%#v', it needs to be a string", v)
+			}
+
+			var err error
+			if result.ServerID, err = spiffeid.FromString(id); err != nil {
+				return nil, fmt.Errorf("invalid credentials serverId value: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown credentials param: %q", k)
+		}
+	}
+
+	if err := validateCredentialsType(result.Type); err != nil {
+		return nil, err
+	}
+
+	if result.Type != credentialsALTS &&
+		(len(result.TargetServiceAccounts) > 0 || result.HandshakerServiceAddress != "") {
+		return nil, fmt.Errorf("the targetServiceAccounts and handshakerServiceAddress are only supported by the %q credentials",
+			credentialsALTS)
+	}
+
+	if result.Type != credentialsSPIFFE && (result.WorkloadAPIAddress != "" || !result.ServerID.IsZero()) {
+		return nil, fmt.Errorf("the workloadApiAddress and serverId are only supported by the %q credentials",
+			credentialsSPIFFE)
+	}
+
+	return result, nil
+}
+
+func validateCredentialsType(t string) error {
+	switch t {
+	case credentialsGoogleDefault, credentialsALTS, credentialsSPIFFE, credentialsXDS:
+		return nil
+	case "":
+		return errors.New("credentials type is required")
+	default:
+		return fmt.Errorf("invalid credentials type value: %q, it needs to be one of %q, %q, %q or %q",
+			t, credentialsGoogleDefault, credentialsALTS, credentialsSPIFFE, credentialsXDS)
+	}
+}
+
+// transportCredentialsDialOption returns the dial option of the channel's credentials.
+func (c *Client) transportCredentialsDialOption(
+	state *lib.State, p *connectParams, owner *connOwner,
+) (grpc.DialOption, error) {
+	if p.Credentials != nil {
+		switch p.Credentials.Type {
+		case credentialsGoogleDefault:
+			return grpc.WithCredentialsBundle(google.NewDefaultCredentials()), nil
+		case credentialsALTS:
+			opts := alts.DefaultClientOptions()
+			opts.TargetServiceAccounts = p.Credentials.TargetServiceAccounts
+			if p.Credentials.HandshakerServiceAddress != "" {
+				opts.HandshakerServiceAddress = p.Credentials.HandshakerServiceAddress
+			}
+
+			return owner.withTransportCredentials(owner.timedHandshake(alts.NewClientCreds(opts))), nil
+		case credentialsSPIFFE:
+			return c.spiffeDialOption(p, owner)
+		case credentialsXDS:
+			fallback, err := defaultTransportCredentials(state, p)
+			if err != nil {
+				return nil, err
+			}
+
+			tcred, err := xdscreds.NewClientCredentials(xdscreds.ClientOptions{FallbackCreds: fallback})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create the xDS credentials: %w", err)
+			}
+
+			return owner.withTransportCredentials(owner.timedHandshake(tcred)), nil
+		}
+	}
+
+	tcred, err := defaultTransportCredentials(state, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.IsPlaintext {
+		tcred = owner.timedHandshake(tcred)
+	}
+
+	return owner.withTransportCredentials(tcred), nil
+}
+
+// defaultTransportCredentials returns the TLS transport credentials configured by the tls param
+// on top of the k6's TLS options, or the insecure ones for the plaintext connections.
+func defaultTransportCredentials(state *lib.State, p *connectParams) (credentials.TransportCredentials, error) {
+	if p.IsPlaintext {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsCfg := state.TLSConfig.Clone()
+	if len(p.TLS) > 0 {
+		var err error
+		if tlsCfg, err = buildTLSConfigFromMap(tlsCfg, p.TLS); err != nil {
+			return nil, err
+		}
+	}
+	tlsCfg.NextProtos = []string{"h2"}
+
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+// spiffeDialOption returns the dial option of the mTLS credentials using the X.509 SVID
+// from the SPIFFE Workload API. The source keeps watching the Workload API,
+// so the rotated SVIDs and bundles are used by the following handshakes.
+func (c *Client) spiffeDialOption(p *connectParams, owner *connOwner) (grpc.DialOption, error) {
+	var opts []workloadapi.ClientOption
+	if p.Credentials.WorkloadAPIAddress != "" {
+		opts = append(opts, workloadapi.WithAddr(p.Credentials.WorkloadAPIAddress))
+	}
+
+	ctx, cancel := context.WithTimeout(c.vu.Context(), p.Timeout)
+	defer cancel()
+
+	source, err := workloadapi.NewX509Source(ctx, workloadapi.WithClientOptions(opts...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the X.509 SVID from the SPIFFE Workload API: %w", err)
+	}
+	c.svidSource = source
+
+	authorizer := tlsconfig.AuthorizeAny()
+	if !p.Credentials.ServerID.IsZero() {
+		authorizer = tlsconfig.AuthorizeID(p.Credentials.ServerID)
+	}
+
+	tlsCfg := tlsconfig.MTLSClientConfig(source, source, authorizer)
+	tlsCfg.NextProtos = []string{"h2"}
+
+	return owner.withTransportCredentials(owner.timedHandshake(credentials.NewTLS(tlsCfg))), nil
+}
+
+// closeSVIDSource closes the SPIFFE X.509 SVID source if the client has one.
+func (c *Client) closeSVIDSource() error {
+	if c.svidSource == nil {
+		return nil
+	}
+
+	err := c.svidSource.Close()
+	c.svidSource = nil
+
+	return err
+}
+
+// timedCredentials wraps the transport credentials to measure the duration of the client's handshakes.
+type timedCredentials struct {
+	credentials.TransportCredentials
+	observe func(authType string, d time.Duration)
+}
+
+// ClientHandshake implements the credentials.TransportCredentials interface
+func (tc timedCredentials) ClientHandshake(
+	ctx context.Context, authority string, rawConn net.Conn,
+) (net.Conn, credentials.AuthInfo, error) {
+	start := time.Now()
+
+	conn, info, err := tc.TransportCredentials.ClientHandshake(ctx, authority, rawConn)
+	if err == nil {
+		tc.observe(info.AuthType(), time.Since(start))
+	}
+
+	return conn, info, err
+}
+
+// Clone implements the credentials.TransportCredentials interface
+func (tc timedCredentials) Clone() credentials.TransportCredentials {
+	return timedCredentials{
+		TransportCredentials: tc.TransportCredentials.Clone(),
+		observe:              tc.observe,
+	}
+}
+
+// timedHandshake wraps the transport credentials to emit the handshake duration metric,
+// tagged with the target and the security protocol.
+func (o *connOwner) timedHandshake(tcred credentials.TransportCredentials) credentials.TransportCredentials {
+	return timedCredentials{
+		TransportCredentials: tcred,
+		observe: func(authType string, d time.Duration) {
+			c := o.get()
+			tm := c.tagsAndMeta
+			tm.Tags = tm.Tags.With("security", authType)
+			c.pushMetric(c.metrics.HandshakeDuration, &tm, metrics.D(d))
+		},
+	}
+}
+
+// observeConn returns the observer of the connections dialed to the target, emitting their durations
+func (o *connOwner) observeConn() func(time.Duration) {
+	return func(d time.Duration) {
+		c := o.get()
+		c.pushMetric(c.metrics.ConnDuration, &c.tagsAndMeta, metrics.D(d))
+	}
+}
+
+// callCredentialsParams is the parameters of the per-RPC credentials.
+type callCredentialsParams struct {
+	Type string
+	// Token is a static token
+	Token string
+	// TokenFn is a JS callback returning a fresh token, it's called on each RPC
+	TokenFn goja.Callable
+}
+
+// newCallCredentialsParams constructs the per-RPC credentials parameters from the input value.
+func newCallCredentialsParams(rt *goja.Runtime, input goja.Value) (*callCredentialsParams, error) {
+	if common.IsNullish(input) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("invalid callCredentials value: '%#v', it needs to be an object", input.Export())
+	}
+
+	result := &callCredentialsParams{}
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k)
+
+		switch k {
+		case "type":
+			if t, ok := v.Export().(string); !ok || t != callCredentialsBearer {
+				return nil, fmt.Errorf("invalid callCredentials type value: '%#v', only %q is supported",
+					v.Export(), callCredentialsBearer)
+			}
+			result.Type = callCredentialsBearer
+		case "token":
+			if fn, ok := goja.AssertFunction(v); ok {
+				result.TokenFn = fn
+				continue
+			}
+
+			token, ok := v.Export().(string)
+			if !ok || token == "" {
+				return nil, fmt.Errorf("invalid callCredentials token value: '%#v', "+
+					"it needs to be a non-empty string or a function returning a string", v.Export())
+			}
+			result.Token = token
+		default:
+			return nil, fmt.Errorf("unknown callCredentials param: %q", k)
+		}
+	}
+
+	if result.Type == "" {
+		return nil, fmt.Errorf("callCredentials type is required")
+	}
+
+	if result.Token == "" && result.TokenFn == nil {
+		return nil, fmt.Errorf("callCredentials token is required")
+	}
+
+	return result, nil
+}
+
+// bearerCredentials is the per-RPC credentials which attach the token as the authorization metadata.
+type bearerCredentials struct {
+	token      string
+	requireTLS bool
+}
+
+var _ credentials.PerRPCCredentials = bearerCredentials{}
+
+// GetRequestMetadata implements the credentials.PerRPCCredentials interface
+func (b bearerCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Bearer " + b.token,
+	}, nil
+}
+
+// RequireTransportSecurity implements the credentials.PerRPCCredentials interface
+func (b bearerCredentials) RequireTransportSecurity() bool {
+	return b.requireTLS
+}
+
+// callCredentialsDialOptions returns the dial options for the static per-RPC credentials.
+func callCredentialsDialOptions(p *connectParams) []grpc.DialOption {
+	if p.CallCredentials == nil || p.CallCredentials.Token == "" {
+		return nil
+	}
+
+	return []grpc.DialOption{
+		grpc.WithPerRPCCredentials(bearerCredentials{
+			token:      p.CallCredentials.Token,
+			requireTLS: !p.IsPlaintext,
+		}),
+	}
+}
+
+// callOptions returns the call options of the client that has to be applied to each RPC,
+// if the per-RPC credentials' token is provided by a JS callback it's called here.
+func (c *Client) callOptions() ([]grpc.CallOption, error) {
+	if c.callCredentials == nil || c.callCredentials.TokenFn == nil {
+		return nil, nil
+	}
+
+	v, err := c.callCredentials.TokenFn(goja.Undefined())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the callCredentials token: %w", err)
+	}
+
+	token, ok := v.Export().(string)
+	if !ok || token == "" {
+		return nil, fmt.Errorf("invalid callCredentials token: '%#v', it needs to be a non-empty string", v.Export())
+	}
+
+	return []grpc.CallOption{
+		grpc.PerRPCCredentials(bearerCredentials{
+			token:      token,
+			requireTLS: !c.plaintext,
+		}),
+	}, nil
+}
@@ -0,0 +1,156 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTimedCredentials(t *testing.T) {
+	t.Parallel()
+
+	var (
+		observedType     string
+		observedDuration time.Duration
+	)
+
+	tcred := timedCredentials{
+		TransportCredentials: insecure.NewCredentials(),
+		observe: func(authType string, d time.Duration) {
+			observedType = authType
+			observedDuration = d
+		},
+	}
+
+	client, server := net.Pipe()
+	defer func() { _ = server.Close() }()
+
+	conn, info, err := tcred.Clone().ClientHandshake(context.Background(), "example.com", client)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	assert.Equal(t, info.AuthType(), observedType)
+	assert.GreaterOrEqual(t, observedDuration, time.Duration(0))
+}
+
+// googleDefaultChildEnv is the directory of the fake service account's key and the server's certificate
+// of the test's child process, the system's roots are read once, so the parent can't trust the certificate
+const googleDefaultChildEnv = "K6_TEST_GOOGLE_DEFAULT_DIR"
+
+func TestGoogleDefaultCredentials(t *testing.T) { //nolint:paralleltest // it runs in a child process
+	if dir := os.Getenv(googleDefaultChildEnv); dir != "" {
+		invokeWithGoogleDefault(t, dir)
+
+		return
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	// the application default credentials of a fake service account, they're signed locally as the JWTs
+	adc, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "k6@example.iam.gserviceaccount.com",
+		"private_key_id": "k6",
+		"private_key":    string(keyPEM),
+	})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "adc.json"), adc, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	cmd := exec.Command(os.Args[0], "-test.run=^"+t.Name()+"$", "-test.count=1") //nolint:gosec
+	cmd.Env = append(os.Environ(),
+		googleDefaultChildEnv+"="+dir,
+		"GOOGLE_APPLICATION_CREDENTIALS="+filepath.Join(dir, "adc.json"),
+		"SSL_CERT_FILE="+filepath.Join(dir, "cert.pem"),
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func invokeWithGoogleDefault(t *testing.T, dir string) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	require.NoError(t, err)
+
+	authorization := make(chan string, 1)
+	srv := grpc.NewServer(
+		grpc.Creds(credentials.NewServerTLSFromCert(&cert)),
+		grpc.UnaryInterceptor(func(
+			ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			authorization <- strings.Join(md["authorization"], ",")
+
+			return handler(ctx, req)
+		}),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	c := &Client{}
+	opt, err := c.transportCredentialsDialOption(nil, &connectParams{
+		Credentials: &credentialsParams{Type: credentialsGoogleDefault},
+	}, nil)
+	require.NoError(t, err)
+
+	_, port, err := net.SplitHostPort(lis.Addr().String())
+	require.NoError(t, err)
+	cc, err := grpc.Dial("localhost:"+port, opt)
+	require.NoError(t, err)
+	defer cc.Close() //nolint:errcheck
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = grpc_health_v1.NewHealthClient(cc).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(<-authorization, "Bearer "), "the call isn't authorized by the ADC")
+}
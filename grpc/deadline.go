@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+)
+
+// parseDeadline parses the call's deadline, a Date or the epoch milliseconds
+func parseDeadline(v interface{}) (time.Time, error) {
+	switch d := v.(type) {
+	case time.Time:
+		return d, nil
+	case int64:
+		return time.UnixMilli(d), nil
+	case float64:
+		return time.UnixMilli(int64(d)), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid deadline value: '%#v', it needs to be a Date or the epoch milliseconds", v)
+	}
+}
+
+// withDeadline returns the context with the call's timeout and deadline, if they're set.
+// The call's samples are still pushed once it has exceeded them, until the ctx is done.
+func (p *callParams) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = grpcext.WithSamplesContext(ctx, ctx)
+
+	cancelTimeout := context.CancelFunc(func() {})
+	if p.Timeout != time.Duration(0) {
+		ctx, cancelTimeout = context.WithTimeout(ctx, p.Timeout)
+	}
+
+	if p.Deadline.IsZero() {
+		return ctx, cancelTimeout
+	}
+
+	ctx, cancelDeadline := context.WithDeadline(ctx, p.Deadline)
+
+	return ctx, func() {
+		cancelDeadline()
+		cancelTimeout()
+	}
+}
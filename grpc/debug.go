@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+)
+
+// parseDebug parses the call's debug logging mode
+func parseDebug(v interface{}) (string, error) {
+	switch v {
+	case grpcext.DebugHeaders, grpcext.DebugFull:
+		return v.(string), nil //nolint:forcetypeassert
+	default:
+		return "", fmt.Errorf("invalid debug value: '%#v', it needs to be \"headers\" or \"full\"", v)
+	}
+}
+
+// parseDebugSampleRate parses the share of the calls logged with the debug logging
+func parseDebugSampleRate(v interface{}) (float64, error) {
+	var rate float64
+	switch r := v.(type) {
+	case int64:
+		rate = float64(r)
+	case float64:
+		rate = r
+	default:
+		return 0, fmt.Errorf("invalid debugSampleRate value: '%#v', it needs to be a number between 0 and 1", v)
+	}
+
+	if rate <= 0 || rate > 1 {
+		return 0, fmt.Errorf("invalid debugSampleRate value: '%#v', it needs to be a number between 0 and 1", v)
+	}
+
+	return rate, nil
+}
+
+// withDebug returns the context of the call logged with its debug mode, or with the --http-debug's one.
+// The calls which aren't sampled by the debugSampleRate aren't logged.
+func (p *callParams) withDebug(ctx context.Context) context.Context {
+	if p.DebugSampleRate > 0 && rand.Float64() >= p.DebugSampleRate { //nolint:gosec
+		return grpcext.WithDebug(ctx, "")
+	}
+
+	if p.Debug != "" {
+		return grpcext.WithDebug(ctx, p.Debug)
+	}
+
+	return ctx
+}
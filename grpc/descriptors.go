@@ -0,0 +1,65 @@
+package grpc
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// descriptorRegistry is the files' descriptors loaded (or reflected) by all the VUs, keyed by the hash
+// of their content, so the VUs loading the same protos share the descriptors instead of keeping their
+// own copies. It's copy-on-write, the lookups don't lock. The zero value is ready to use.
+type descriptorRegistry struct {
+	mu sync.Mutex
+	// files is the map[[sha256.Size]byte]*protoregistry.Files, replaced on each addition
+	files atomic.Value
+}
+
+// current returns the current files
+func (r *descriptorRegistry) current() map[[sha256.Size]byte]*protoregistry.Files {
+	files, _ := r.files.Load().(map[[sha256.Size]byte]*protoregistry.Files)
+
+	return files
+}
+
+// get returns the shared files of the set, they're created once for the same content
+func (r *descriptorRegistry) get(fdset *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(fdset)
+	if err != nil {
+		return nil, fmt.Errorf("can't hash the file descriptors: %w", err)
+	}
+	key := sha256.Sum256(b)
+
+	if files, ok := r.current()[key]; ok {
+		return files, nil
+	}
+
+	files, err := protodesc.NewFiles(fdset)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.current()
+	// another VU could have added them meanwhile
+	if existing, ok := current[key]; ok {
+		return existing, nil
+	}
+
+	next := make(map[[sha256.Size]byte]*protoregistry.Files, len(current)+1)
+	for k, v := range current {
+		next[k] = v
+	}
+	next[key] = files
+	r.files.Store(next)
+
+	return files, nil
+}
@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDescriptorRegistry(t *testing.T) {
+	t.Parallel()
+
+	fdset := func(name string) *descriptorpb.FileDescriptorSet {
+		return &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String(name),
+			Package: proto.String("test"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Message"),
+			}},
+		}}}
+	}
+
+	var r descriptorRegistry
+
+	a, err := r.get(fdset("a.proto"))
+	require.NoError(t, err)
+
+	same, err := r.get(fdset("a.proto"))
+	require.NoError(t, err)
+	assert.Same(t, a, same)
+
+	b, err := r.get(fdset("b.proto"))
+	require.NoError(t, err)
+	assert.NotSame(t, a, b)
+	assert.Len(t, r.current(), 2)
+
+	_, err = r.get(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:       proto.String("c.proto"),
+		Dependency: []string{"missing.proto"},
+	}}})
+	require.Error(t, err)
+	assert.Len(t, r.current(), 2)
+}
+
+func TestRegisterMessageTypesConcurrently(t *testing.T) {
+	t.Parallel()
+
+	pkg := fmt.Sprintf("k6.test.concurrent%d", time.Now().UnixNano())
+	messages := []*descriptorpb.DescriptorProto{{
+		Name:       proto.String("Message"),
+		NestedType: []*descriptorpb.DescriptorProto{{Name: proto.String("Nested")}},
+	}}
+	for i := 0; i < 100; i++ {
+		messages = append(messages, &descriptorpb.DescriptorProto{Name: proto.String(fmt.Sprintf("Message%d", i))})
+	}
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:        proto.String("concurrent.proto"),
+		Package:     proto.String(pkg),
+		MessageType: messages,
+	}}})
+	require.NoError(t, err)
+
+	// the VUs register the same types at once
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			<-start
+			errs <- registerMessageTypes(files)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	_, err = protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(pkg + ".Message.Nested"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+)
+
+// newDNSRefreshParams constructs the re-resolution of the dns:/// target from the input value.
+func newDNSRefreshParams(rt *goja.Runtime, input goja.Value) (*grpcext.DNSRefresh, error) {
+	if common.IsNullish(input) {
+		return nil, nil //nolint:nilnil
+	}
+
+	result := &grpcext.DNSRefresh{}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf("invalid dnsRefresh value: '%#v', it needs to be an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "interval":
+			d, err := types.GetDurationValue(v)
+			if err != nil || d <= 0 {
+				return result, fmt.Errorf("invalid dnsRefresh interval value: '%#v', it needs to be a positive duration", v)
+			}
+			result.Interval = d
+		case "onError":
+			var ok bool
+			result.OnError, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid dnsRefresh onError value: '%#v', it needs to be boolean", v)
+			}
+		default:
+			return result, fmt.Errorf("unknown dnsRefresh param: %q", k)
+		}
+	}
+
+	if result.Interval == 0 && !result.OnError {
+		return result, errors.New("invalid dnsRefresh value: it needs an interval or onError")
+	}
+
+	return result, nil
+}
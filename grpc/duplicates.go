@@ -0,0 +1,130 @@
+package grpc
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+)
+
+// duplicateSweepInterval is the minimal interval between the removals of the expired requests
+const duplicateSweepInterval = time.Second
+
+// duplicateDetectionParams is the parameters of the duplicate requests detection.
+type duplicateDetectionParams struct {
+	Window time.Duration
+}
+
+// newDuplicateDetectionParams constructs the duplicate detection parameters from the input value.
+func newDuplicateDetectionParams(rt *goja.Runtime, input goja.Value) (*duplicateDetectionParams, error) {
+	result := &duplicateDetectionParams{
+		Window: time.Second,
+	}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	if v, ok := input.Export().(bool); ok {
+		if !v {
+			return nil, nil //nolint:nilnil
+		}
+
+		return result, nil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf(
+			"invalid duplicateDetection value: '%#v', it needs to be boolean or an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "window":
+			var err error
+			result.Window, err = types.GetDurationValue(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid duplicateDetection window value: %w", err)
+			}
+			if result.Window <= 0 {
+				return result, fmt.Errorf(
+					"invalid duplicateDetection window value: '%#v', it needs to be a positive duration", v)
+			}
+		default:
+			return result, fmt.Errorf("unknown duplicateDetection param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// duplicateDetector remembers the hashes of the requests sent by all the VUs,
+// to detect the identical ones sent within the window. The zero value is ready to use.
+type duplicateDetector struct {
+	mu        sync.Mutex
+	expiry    map[uint64]time.Time
+	lastSweep time.Time
+}
+
+// seen records the request and reports whether an identical one has been sent within the window
+func (d *duplicateDetector) seen(method string, msg []byte, window time.Duration) bool {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(method))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write(msg)
+	key := h.Sum64()
+
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.expiry == nil {
+		d.expiry = make(map[uint64]time.Time)
+	}
+
+	if now.Sub(d.lastSweep) >= duplicateSweepInterval {
+		for k, t := range d.expiry {
+			if !t.After(now) {
+				delete(d.expiry, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	expiry, ok := d.expiry[key]
+	d.expiry[key] = now.Add(window)
+
+	return ok && expiry.After(now)
+}
+
+// detectDuplicate reports the request if an identical one has been sent within the window,
+// it warns once per method and counts all of them in the grpc_duplicate_requests metric.
+func (c *Client) detectDuplicate(method string, msg []byte, p *callParams) {
+	if c.duplicateDetection == nil || !c.duplicates.seen(method, msg, c.duplicateDetection.Window) {
+		return
+	}
+
+	c.pushMetric(c.metrics.DuplicateRequests, &p.TagsAndMeta, 1)
+
+	if c.warnedDuplicates[method] {
+		return
+	}
+
+	if c.warnedDuplicates == nil {
+		c.warnedDuplicates = make(map[string]bool)
+	}
+	c.warnedDuplicates[method] = true
+
+	c.vu.State().Logger.WithField("method", method).Warnf(
+		"an identical request has been sent within %s, check the request's parameterization",
+		c.duplicateDetection.Window)
+}
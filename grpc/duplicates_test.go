@@ -0,0 +1,29 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateDetector(t *testing.T) {
+	t.Parallel()
+
+	var d duplicateDetector
+
+	assert.False(t, d.seen("/pkg.Svc/Get", []byte(`{"id":1}`), time.Minute))
+	assert.True(t, d.seen("/pkg.Svc/Get", []byte(`{"id":1}`), time.Minute))
+	assert.False(t, d.seen("/pkg.Svc/Get", []byte(`{"id":2}`), time.Minute))
+	assert.False(t, d.seen("/pkg.Svc/List", []byte(`{"id":1}`), time.Minute))
+}
+
+func TestDuplicateDetector_Window(t *testing.T) {
+	t.Parallel()
+
+	var d duplicateDetector
+
+	assert.False(t, d.seen("/pkg.Svc/Get", []byte(`{"id":1}`), 20*time.Millisecond))
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, d.seen("/pkg.Svc/Get", []byte(`{"id":1}`), 20*time.Millisecond))
+}
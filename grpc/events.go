@@ -0,0 +1,123 @@
+package grpc
+
+import (
+	"sync"
+
+	"github.com/mstoykov/k6-taskqueue-lib/taskqueue"
+	"go.k6.io/k6/js/modules"
+)
+
+// maxPendingEvents caps the events waiting for the VU's event loop, the newer ones are dropped
+const maxPendingEvents = 100
+
+// clientEvents delivers the events observed by the gRPC's goroutines (the connection events, the health
+// statuses and the xDS updates) to the client's listeners on the VU's event loop. While the client's
+// asynchronous work (its streams, loads or graceful closes) keeps the event loop running, the events are
+// queued to it as soon as they're observed. Otherwise they wait for the client's next call or asynchronous
+// work, since holding the event loop would keep the iteration from ending. It's shared by the client
+// and its named connections.
+type clientEvents struct {
+	vu modules.VU
+
+	mu      sync.Mutex
+	holds   int
+	tq      *taskqueue.TaskQueue
+	stop    chan struct{}
+	pending []func() error
+}
+
+func newClientEvents(vu modules.VU) *clientEvents {
+	return &clientEvents{vu: vu}
+}
+
+// post delivers the event on the event loop, it could be called from any goroutine
+func (e *clientEvents) post(deliver func() error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.tq != nil {
+		e.tq.Queue(deliver)
+
+		return
+	}
+
+	if len(e.pending) < maxPendingEvents {
+		e.pending = append(e.pending, deliver)
+	}
+}
+
+// hold delivers the events as soon as they're observed until it's released, it's called
+// on the event loop by the asynchronous work keeping it running. The held event loop
+// is released anyway once the VU's context is done.
+func (e *clientEvents) hold() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.holds++
+	if e.holds > 1 {
+		return
+	}
+
+	e.tq = taskqueue.New(e.vu.RegisterCallback)
+	for _, deliver := range e.pending {
+		e.tq.Queue(deliver)
+	}
+	e.pending = nil
+
+	stop, tq := make(chan struct{}), e.tq
+	e.stop = stop
+	go func() {
+		select {
+		case <-stop:
+		case <-e.vu.Context().Done():
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			if e.tq == tq {
+				e.closeQueue()
+			}
+		}
+	}()
+}
+
+// release releases the event loop held by the asynchronous work once it's done
+func (e *clientEvents) release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.holds == 0 {
+		return
+	}
+
+	e.holds--
+	if e.holds == 0 {
+		e.closeQueue()
+	}
+}
+
+func (e *clientEvents) closeQueue() {
+	e.holds = 0
+	if e.tq == nil {
+		return
+	}
+
+	close(e.stop)
+	e.tq.Close()
+	e.tq, e.stop = nil, nil
+}
+
+// flush delivers the pending events, it's called on the event loop by the client's calls
+func (e *clientEvents) flush() error {
+	e.mu.Lock()
+	pending := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	for _, deliver := range pending {
+		if err := deliver(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"google.golang.org/grpc/codes"
+)
+
+// expectedStatuses is the set of the status codes the calls are expected to finish with,
+// like the http.expectedStatuses. The calls finished with the other codes are failed.
+type expectedStatuses struct {
+	codes map[codes.Code]struct{}
+}
+
+// has reports whether the code is expected
+func (e *expectedStatuses) has(code codes.Code) bool {
+	_, ok := e.codes[code]
+	return ok
+}
+
+// failed reports whether the call with the code is failed, without the expectations
+// only the OK code isn't failed
+func (e *expectedStatuses) failed(code codes.Code) bool {
+	if e == nil {
+		return code != codes.OK
+	}
+
+	return !e.has(code)
+}
+
+// callback returns the function reporting whether the call's status is expected,
+// or nil if there are no expectations.
+func (e *expectedStatuses) callback() func(codes.Code) bool {
+	if e == nil {
+		return nil
+	}
+
+	return e.has
+}
+
+// newExpectedStatuses creates the expected statuses from the list of the codes
+func newExpectedStatuses(list []interface{}) (*expectedStatuses, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("'%#v', it needs to be a non-empty list of status codes", list)
+	}
+
+	e := &expectedStatuses{codes: make(map[codes.Code]struct{}, len(list))}
+	for _, v := range list {
+		// the grpc.Status* constants are exported as the codes
+		code, ok := v.(codes.Code)
+		if n, isInt := v.(int64); isInt {
+			code, ok = codes.Code(n), n >= 0
+		}
+
+		if !ok || code > codes.Unauthenticated {
+			return nil, fmt.Errorf("'%#v', it needs to be a status code between 0 and %d", v, codes.Unauthenticated)
+		}
+
+		e.codes[code] = struct{}{}
+	}
+
+	return e, nil
+}
+
+// parseExpectedStatuses parses either the result of the grpc.expectedStatuses or the list of the codes
+func parseExpectedStatuses(v interface{}) (*expectedStatuses, error) {
+	switch e := v.(type) {
+	case *expectedStatuses:
+		return e, nil
+	case []interface{}:
+		return newExpectedStatuses(e)
+	default:
+		return nil, fmt.Errorf("'%#v', it needs to be a list of status codes or the grpc.expectedStatuses() result", v)
+	}
+}
+
+// statusCallback is the VU's default expected statuses of the calls without their own, set
+// by the grpc.setStatusCallback. The zero value means there are no expectations.
+type statusCallback struct {
+	expected *expectedStatuses
+}
+
+// expectedStatuses returns the expected statuses of the given codes for the grpc.setStatusCallback
+// or the calls' expectedStatuses param.
+func (mi *ModuleInstance) expectedStatuses(args ...goja.Value) *expectedStatuses {
+	rt := mi.vu.Runtime()
+
+	list := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		list = append(list, arg.Export())
+	}
+
+	e, err := newExpectedStatuses(list)
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("invalid expectedStatuses value: %w", err))
+	}
+
+	return e
+}
+
+// setStatusCallback sets the VU's default expected statuses, null removes them.
+func (mi *ModuleInstance) setStatusCallback(callback goja.Value) {
+	if common.IsNullish(callback) {
+		mi.statusCallback.expected = nil
+		return
+	}
+
+	e, ok := callback.Export().(*expectedStatuses)
+	if !ok {
+		common.Throw(mi.vu.Runtime(),
+			fmt.Errorf("invalid status callback: '%#v', it needs to be the grpc.expectedStatuses() result", callback.Export()))
+	}
+
+	mi.statusCallback.expected = e
+}
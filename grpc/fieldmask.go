@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const fieldMaskMessage protoreflect.FullName = "google.protobuf.FieldMask"
+
+// fieldMaskPaths converts the FieldMask field's array of the paths into the protojson's string, the paths are
+// validated against the mask's target message, so the typos fail the call instead of being ignored by the server.
+// The protojson's strings are left as they are.
+func fieldMaskPaths(
+	fd protoreflect.FieldDescriptor, masked protoreflect.MessageDescriptor, v interface{},
+) (interface{}, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return v, nil
+	}
+
+	target := maskTarget(fd, masked)
+
+	paths := make([]string, 0, len(list))
+	for _, p := range list {
+		path, ok := p.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s path: '%v', it needs to be a string", fd.Name(), p)
+		}
+
+		converted, err := maskPath(target, path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s path %q: %w", fd.Name(), path, err)
+		}
+
+		paths = append(paths, converted)
+	}
+
+	return strings.Join(paths, ","), nil
+}
+
+// maskTarget returns the message the mask applies to, the resource of the mask's message (its only other
+// singular message field, e.g. the book of the UpdateBookRequest), or the masked one (e.g. the method's output).
+func maskTarget(fd protoreflect.FieldDescriptor, masked protoreflect.MessageDescriptor) protoreflect.MessageDescriptor {
+	var resource protoreflect.MessageDescriptor
+
+	fields := fd.ContainingMessage().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		if f == fd || f.Message() == nil || f.IsList() || f.IsMap() ||
+			strings.HasPrefix(string(f.Message().FullName()), "google.protobuf.") {
+			continue
+		}
+
+		if resource != nil {
+			return masked
+		}
+		resource = f.Message()
+	}
+
+	if resource == nil {
+		return masked
+	}
+
+	return resource
+}
+
+// maskPath validates the mask's path of the message's fields (their names or JSON names)
+// and returns it with the protojson's camel case names
+func maskPath(md protoreflect.MessageDescriptor, path string) (string, error) {
+	segments := strings.Split(path, ".")
+	for i, s := range segments {
+		if md == nil {
+			return "", fmt.Errorf("the field %q isn't a singular message", segments[i-1])
+		}
+
+		fd := md.Fields().ByName(protoreflect.Name(s))
+		if fd == nil {
+			fd = md.Fields().ByJSONName(s)
+		}
+		if fd == nil {
+			return "", unknownField(md, s)
+		}
+
+		segments[i] = jsonCamelCase(string(fd.Name()))
+
+		md = nil
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() {
+			md = fd.Message()
+		}
+	}
+
+	return strings.Join(segments, "."), nil
+}
+
+// unknownField returns the error of the field which the message doesn't have, suggesting the closest one
+func unknownField(md protoreflect.MessageDescriptor, name string) error {
+	closest, distance := "", len(name)/3+2
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fname := string(fields.Get(i).Name())
+		if d := editDistance(strings.ToLower(name), fname); d < distance {
+			closest, distance = fname, d
+		}
+	}
+
+	if closest == "" {
+		return fmt.Errorf("%s has no field %q", md.FullName(), name)
+	}
+
+	return fmt.Errorf("%s has no field %q, did you mean %q?", md.FullName(), name, closest)
+}
+
+// jsonCamelCase converts the field's name like the protojson, e.g. the update_time into the updateTime
+func jsonCamelCase(s string) string {
+	var b strings.Builder
+
+	afterUnderscore := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '_' {
+			if afterUnderscore && 'a' <= c && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			b.WriteByte(c)
+		}
+		afterUnderscore = c == '_'
+	}
+
+	return b.String()
+}
@@ -4,24 +4,63 @@ package grpc
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
 	"github.com/mstoykov/k6-taskqueue-lib/taskqueue"
+	"github.com/sirupsen/logrus"
+	"go.k6.io/k6/event"
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
 )
 
 type (
 	// RootModule is the global module instance that will create module
 	// instances for each VU.
-	RootModule struct{}
+	RootModule struct {
+		latencies   latencyHistograms
+		reflections reflectionLimiter
+		duplicates  duplicateDetector
+		pool        connPool
+		prewarmed   prewarmedConns
+		xds         xdsReporters
+		captured    captures
+		files       recordFiles
+
+		reflectionCache reflectionCache
+		descriptors     descriptorRegistry
+		// registryImages caches the images downloaded from the registry like the reflected descriptors
+		registryImages reflectionCache
+
+		// closeOnce subscribes the closing of the shared connections to the test's end
+		closeOnce sync.Once
+	}
 
 	// ModuleInstance represents an instance of the GRPC module for every VU.
 	ModuleInstance struct {
 		vu      modules.VU
 		exports map[string]interface{}
 		metrics *instanceMetrics
+
+		latencies   *latencyHistograms
+		reflections *reflectionLimiter
+		duplicates  *duplicateDetector
+		pool        *connPool
+		prewarmed   *prewarmedConns
+		xds         *xdsReporters
+		captured    *captures
+		files       *recordFiles
+
+		reflectionCache *reflectionCache
+		statusCallback  *statusCallback
+		breakers        *circuitBreakers
+		extOptions      *extOptionsCache
+		descriptors     *descriptorRegistry
+		registryImages  *reflectionCache
+		mocks           *mockJobs
 	}
 )
 
@@ -30,6 +69,13 @@ var (
 	_ modules.Instance = &ModuleInstance{}
 )
 
+var (
+	// grpcLogger routes the gRPC internal logs into the k6 logger
+	grpcLogger = grpcext.NewLogger() //nolint:gochecknoglobals
+	// grpcLoggerOnce installs the grpcLogger before the first connection is made
+	grpcLoggerOnce sync.Once //nolint:gochecknoglobals
+)
+
 // New returns a pointer to a new RootModule instance.
 func New() *RootModule {
 	return &RootModule{}
@@ -37,29 +83,111 @@ func New() *RootModule {
 
 // NewModuleInstance implements the modules.Module interface to return
 // a new instance for each VU.
-func (*RootModule) NewModuleInstance(vu modules.VU) modules.Instance {
+func (r *RootModule) NewModuleInstance(vu modules.VU) modules.Instance {
 	metrics, err := registerMetrics(vu.InitEnv().Registry)
 	if err != nil {
 		common.Throw(vu.Runtime(), fmt.Errorf("failed to register GRPC module metrics: %w", err))
 	}
 
+	grpcLoggerOnce.Do(func() {
+		grpcLogger.SetOutput(vu.InitEnv().Logger)
+		grpclog.SetLoggerV2(grpcLogger)
+	})
+
+	if events := vu.Events().Global; events != nil {
+		r.closeOnce.Do(func() { r.closeAtTestEnd(events, vu.InitEnv().Logger) })
+	}
+
 	mi := &ModuleInstance{
 		vu:      vu,
 		exports: make(map[string]interface{}),
 		metrics: metrics,
+
+		latencies:   &r.latencies,
+		reflections: &r.reflections,
+		duplicates:  &r.duplicates,
+		pool:        &r.pool,
+		prewarmed:   &r.prewarmed,
+		xds:         &r.xds,
+		captured:    &r.captured,
+		files:       &r.files,
+
+		reflectionCache: &r.reflectionCache,
+		statusCallback:  &statusCallback{},
+		breakers:        &circuitBreakers{},
+		extOptions:      &extOptionsCache{},
+		descriptors:     &r.descriptors,
+		registryImages:  &r.registryImages,
+		mocks:           newMockJobs(),
 	}
 
 	mi.exports["Client"] = mi.NewClient
 	mi.defineConstants()
+	mi.defineFeatures()
 	mi.exports["Stream"] = mi.stream
+	mi.exports["Server"] = mi.newServer
+	mi.exports["AbortController"] = mi.newAbortController
+	mi.exports["latencySnapshot"] = mi.latencySnapshot
+	mi.exports["captures"] = mi.captures
+	mi.exports["openFile"] = mi.openFile
+	mi.exports["version"] = mi.version
+	mi.exports["expectedStatuses"] = mi.expectedStatuses
+	mi.exports["setStatusCallback"] = mi.setStatusCallback
+	mi.exports["checkStatus"] = mi.checkStatus
 
 	return mi
 }
 
+// closeAtTestEnd closes the connections shared by the VUs (the pooled ones and the prewarmed ones
+// which haven't been taken) and stops observing the xDS client once the test ends
+func (r *RootModule) closeAtTestEnd(events event.Subscriber, logger logrus.FieldLogger) {
+	id, ch := events.Subscribe(event.TestEnd, event.Exit)
+
+	go func() {
+		e := <-ch
+		events.Unsubscribe(id)
+
+		if err := r.pool.close(); err != nil {
+			logger.WithError(err).Warn("failed to close the pooled gRPC connections")
+		}
+		if err := r.prewarmed.close(); err != nil {
+			logger.WithError(err).Warn("failed to close the prewarmed gRPC connections")
+		}
+		r.xds.close()
+		e.Done()
+	}()
+}
+
 // NewClient is the JS constructor for the grpc Client.
 func (mi *ModuleInstance) NewClient(_ goja.ConstructorCall) *goja.Object {
 	rt := mi.vu.Runtime()
-	return rt.ToValue(&Client{vu: mi.vu}).ToObject(rt)
+	return rt.ToValue(&Client{
+		vu:          mi.vu,
+		metrics:     mi.metrics,
+		listeners:   newClientEventListeners(),
+		events:      newClientEvents(mi.vu),
+		latencies:   mi.latencies,
+		reflections: mi.reflections,
+		duplicates:  mi.duplicates,
+		pool:        mi.pool,
+		prewarmed:   mi.prewarmed,
+		xds:         mi.xds,
+		captured:    mi.captured,
+
+		reflectionCache: mi.reflectionCache,
+		statusCallback:  mi.statusCallback,
+		breakers:        mi.breakers,
+		extOptions:      mi.extOptions,
+		descriptors:     mi.descriptors,
+		registryImages:  mi.registryImages,
+		mocks:           mi.mocks,
+	}).ToObject(rt)
+}
+
+// latencySnapshot returns the percentile snapshots of the RPCs' latencies per method,
+// recorded by all the VUs in HDR histograms, e.g. to include them in the handleSummary.
+func (mi *ModuleInstance) latencySnapshot() map[string]latencySnapshot {
+	return mi.latencies.snapshot()
 }
 
 // defineConstants defines the constant variables of the module.
@@ -99,7 +227,7 @@ func (mi *ModuleInstance) Exports() modules.Exports {
 func (mi *ModuleInstance) stream(c goja.ConstructorCall) *goja.Object {
 	rt := mi.vu.Runtime()
 
-	client, err := extractClient(c.Argument(0), rt)
+	client, err := extractClient(c.Argument(0), c.Argument(2), rt)
 	if err != nil {
 		common.Throw(rt, fmt.Errorf("invalid GRPC Stream's client: %w", err))
 	}
@@ -110,7 +238,7 @@ func (mi *ModuleInstance) stream(c goja.ConstructorCall) *goja.Object {
 		common.Throw(rt, fmt.Errorf("invalid GRPC Stream's method: %w", err))
 	}
 
-	p, err := newCallParams(mi.vu, c.Argument(2))
+	p, err := newCallParams(mi.vu, client.extOptions, client.tags, c.Argument(2))
 	if err != nil {
 		common.Throw(rt, fmt.Errorf("invalid GRPC Stream's parameters: %w", err))
 	}
@@ -134,6 +262,7 @@ func (mi *ModuleInstance) stream(c goja.ConstructorCall) *goja.Object {
 		writingState:    opened,
 
 		writeQueueCh: make(chan message),
+		ended:        make(chan struct{}),
 
 		eventListeners: newEventListeners(),
 		obj:            rt.NewObject(),
@@ -152,8 +281,9 @@ func (mi *ModuleInstance) stream(c goja.ConstructorCall) *goja.Object {
 	return s.obj
 }
 
-// extractClient extracts & validates a grpc.Client from a goja.Value.
-func extractClient(v goja.Value, rt *goja.Runtime) (*Client, error) {
+// extractClient extracts & validates a grpc.Client from a goja.Value,
+// it's the client of the connection picked by the params.
+func extractClient(v goja.Value, params goja.Value, rt *goja.Runtime) (*Client, error) {
 	if common.IsNullish(v) {
 		return nil, errors.New("empty gRPC client")
 	}
@@ -163,6 +293,11 @@ func extractClient(v goja.Value, rt *goja.Runtime) (*Client, error) {
 		return nil, errors.New("not a gRPC client")
 	}
 
+	client, err := client.connection(params)
+	if err != nil {
+		return nil, err
+	}
+
 	if client.conn == nil {
 		return nil, errors.New("no gRPC connection, you must call connect first")
 	}
@@ -0,0 +1,191 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// errNotServing is the error message of the calls rejected by the health gating
+var errNotServing = errors.New("the target isn't serving according to its health checks") //nolint:gochecknoglobals
+
+// healthWatchRetryInterval is the interval of re-establishing the failed health watch
+const healthWatchRetryInterval = time.Second
+
+// healthGatingParams is the parameters of the health-based load gating.
+type healthGatingParams struct {
+	Service  string
+	MaxPause time.Duration
+}
+
+// newHealthGatingParams constructs the health gating parameters from the input value.
+func newHealthGatingParams(rt *goja.Runtime, input goja.Value) (*healthGatingParams, error) {
+	result := &healthGatingParams{
+		MaxPause: time.Minute,
+	}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	if v, ok := input.Export().(bool); ok {
+		if !v {
+			return nil, nil //nolint:nilnil
+		}
+
+		return result, nil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf("invalid healthGating value: '%#v', it needs to be boolean or an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "service":
+			var ok bool
+			result.Service, ok = v.(string)
+			if !ok {
+				return result, fmt.Errorf("invalid healthGating service value: '%#v', it needs to be a string", v)
+			}
+		case "maxPause":
+			var err error
+			result.MaxPause, err = types.GetDurationValue(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid healthGating maxPause value: %w", err)
+			}
+			if result.MaxPause <= 0 {
+				return result, fmt.Errorf("invalid healthGating maxPause value: '%#v', it needs to be a positive duration", v)
+			}
+		default:
+			return result, fmt.Errorf("unknown healthGating param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// healthGate pauses the client's traffic while the target isn't serving
+// according to the gRPC health checking protocol, the traffic is also paused
+// until the first serving status is received.
+type healthGate struct {
+	mu      sync.Mutex
+	serving bool
+	// changed is closed and replaced on each serving state change
+	changed chan struct{}
+
+	maxPause time.Duration
+	cancel   context.CancelFunc
+}
+
+func newHealthGate(p *healthGatingParams) *healthGate {
+	return &healthGate{
+		serving:  false,
+		changed:  make(chan struct{}),
+		maxPause: p.MaxPause,
+		cancel:   func() {},
+	}
+}
+
+// set updates the serving state, waking up the paused calls
+func (g *healthGate) set(serving bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.serving == serving {
+		return
+	}
+
+	g.serving = serving
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// wait blocks while the target isn't serving, it returns the duration of the pause
+// and whether the target is serving after it.
+func (g *healthGate) wait(ctx context.Context) (time.Duration, bool) {
+	var (
+		start = time.Now()
+		timer *time.Timer
+	)
+
+	for {
+		g.mu.Lock()
+		serving, changed := g.serving, g.changed
+		g.mu.Unlock()
+
+		if serving {
+			if timer != nil {
+				timer.Stop()
+			}
+
+			return time.Since(start), true
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(g.maxPause)
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return time.Since(start), false
+		case <-ctx.Done():
+			timer.Stop()
+
+			return time.Since(start), false
+		}
+	}
+}
+
+// watch keeps the serving state up to date until the gate is stopped.
+// A target which doesn't implement the health checking is considered serving,
+// while a failed watch pauses the traffic until it's re-established.
+func (g *healthGate) watch(ctx context.Context, conn *grpcext.Conn, service string) {
+	ctx, g.cancel = context.WithCancel(ctx)
+
+	go func() {
+		for {
+			err := conn.WatchHealth(ctx, service, func(s healthpb.HealthCheckResponse_ServingStatus) {
+				g.set(s == healthpb.HealthCheckResponse_SERVING)
+			})
+
+			if status.Code(err) == codes.Unimplemented {
+				g.set(true)
+
+				return
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			g.set(false)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(healthWatchRetryInterval):
+			}
+		}
+	}()
+}
+
+// stop stops watching the target's health
+func (g *healthGate) stop() {
+	g.cancel()
+}
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthGate_Wait(t *testing.T) {
+	t.Parallel()
+
+	g := newHealthGate(&healthGatingParams{MaxPause: time.Minute})
+	g.set(true)
+
+	paused, serving := g.wait(context.Background())
+	assert.True(t, serving)
+	assert.Less(t, paused, 10*time.Millisecond)
+
+	g.set(false)
+	time.AfterFunc(50*time.Millisecond, func() { g.set(true) })
+
+	paused, serving = g.wait(context.Background())
+	assert.True(t, serving)
+	assert.GreaterOrEqual(t, paused, 50*time.Millisecond)
+}
+
+func TestHealthGate_MaxPause(t *testing.T) {
+	t.Parallel()
+
+	g := newHealthGate(&healthGatingParams{MaxPause: 50 * time.Millisecond})
+
+	paused, serving := g.wait(context.Background())
+	assert.False(t, serving)
+	assert.GreaterOrEqual(t, paused, 50*time.Millisecond)
+}
+
+func TestHealthGate_ContextDone(t *testing.T) {
+	t.Parallel()
+
+	g := newHealthGate(&healthGatingParams{MaxPause: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, serving := g.wait(ctx)
+	assert.False(t, serving)
+}
@@ -0,0 +1,123 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// HealthWatch watches the serving status of a service using the Health/Watch stream,
+// the status changes are passed to the callback on the VU's event loop, by the client's events.
+type HealthWatch struct {
+	service  string
+	callback goja.Callable
+	cancel   context.CancelFunc
+	events   *clientEvents
+	rt       *goja.Runtime
+
+	mu     sync.Mutex
+	status string
+}
+
+// HealthWatch starts watching the serving status of the service ("" is the whole server),
+// the callback is called with the status (e.g. NOT_SERVING) on each change.
+func (c *Client) HealthWatch(service string, callback goja.Callable) (*HealthWatch, error) {
+	if c.conn == nil {
+		return nil, errors.New("no gRPC connection, you must call connect first")
+	}
+
+	if callback == nil {
+		return nil, errors.New("the healthWatch's callback needs to be a function")
+	}
+
+	w := &HealthWatch{
+		service:  service,
+		callback: callback,
+		events:   c.events,
+		rt:       c.vu.Runtime(),
+		status:   healthpb.HealthCheckResponse_UNKNOWN.String(),
+	}
+	w.watch(c.vu.Context(), c.conn)
+	c.healthWatches = append(c.healthWatches, w)
+
+	return w, nil
+}
+
+// Status returns the last status received
+func (w *HealthWatch) Status() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.status
+}
+
+// Close stops the watch
+func (w *HealthWatch) Close() {
+	w.cancel()
+}
+
+// set records the status if it's changed
+func (w *HealthWatch) set(s string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.status == s {
+		return
+	}
+
+	w.status = s
+	w.events.post(func() error {
+		_, err := w.callback(goja.Undefined(), w.rt.ToValue(s))
+
+		return err
+	})
+}
+
+// watch keeps the status up to date until the watch is closed, like the health gating does.
+// A target which doesn't implement the health checking is considered serving,
+// while a failed watch makes the status UNKNOWN until it's re-established.
+func (w *HealthWatch) watch(ctx context.Context, conn *grpcext.Conn) {
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	go func() {
+		for {
+			err := conn.WatchHealth(ctx, w.service, func(s healthpb.HealthCheckResponse_ServingStatus) {
+				w.set(s.String())
+			})
+
+			if status.Code(err) == codes.Unimplemented {
+				w.set(healthpb.HealthCheckResponse_SERVING.String())
+
+				return
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			w.set(healthpb.HealthCheckResponse_UNKNOWN.String())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(healthWatchRetryInterval):
+			}
+		}
+	}()
+}
+
+// stopHealthWatches stops the watches of the client's connection
+func (c *Client) stopHealthWatches() {
+	for _, w := range c.healthWatches {
+		w.Close()
+	}
+
+	c.healthWatches = nil
+}
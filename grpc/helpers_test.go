@@ -6,9 +6,15 @@ import (
 	"testing"
 
 	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
 )
 
 func assertResponse(t *testing.T, cb codeBlock, err error, val goja.Value, ts testState) {
@@ -40,14 +46,87 @@ func assertMetricEmitted(
 
 	for _, sampleContainer := range sampleContainers {
 		for _, sample := range sampleContainer.GetSamples() {
+			// the other metrics could be the connections' ones, which aren't tagged with the url
+			if sample.Metric.Name != metricName {
+				continue
+			}
+
 			surl, ok := sample.Tags.Get("url")
-			assert.True(t, ok)
+			assert.True(t, ok, "%s sample isn't tagged with the url", metricName)
 			if surl == url {
-				if sample.Metric.Name == metricName {
-					seenMetric = true
-				}
+				seenMetric = true
 			}
 		}
 	}
 	assert.True(t, seenMetric, "url %s didn't emit %s", url, metricName)
 }
+
+// metricValues returns the values of the metric's samples
+func metricValues(sampleContainers []metrics.SampleContainer, metricName string) []float64 {
+	var values []float64
+
+	for _, sampleContainer := range sampleContainers {
+		for _, sample := range sampleContainer.GetSamples() {
+			if sample.Metric.Name == metricName {
+				values = append(values, sample.Value)
+			}
+		}
+	}
+
+	return values
+}
+
+// metricTagValues returns the tag's values of the metric's samples, keyed by the samples' urls
+func metricTagValues(sampleContainers []metrics.SampleContainer, metricName, tag string) map[string]string {
+	values := make(map[string]string)
+
+	for _, sampleContainer := range sampleContainers {
+		for _, sample := range sampleContainer.GetSamples() {
+			if sample.Metric.Name != metricName {
+				continue
+			}
+
+			url, _ := sample.Tags.Get("url")
+			values[url], _ = sample.Tags.Get(tag)
+		}
+	}
+
+	return values
+}
+
+// xdsCircuitBreakerPolicy is the load balancing policy rejecting the calls like the xDS cluster's circuit breaking
+const xdsCircuitBreakerPolicy = "k6_test_xds_circuit_breaker"
+
+func init() { //nolint:gochecknoinits
+	grpcext.RegisterBalancer(xdsCircuitBreakerBuilder{})
+}
+
+type xdsCircuitBreakerBuilder struct{}
+
+func (xdsCircuitBreakerBuilder) Build(cc balancer.ClientConn, _ balancer.BuildOptions) balancer.Balancer {
+	return &xdsCircuitBreakerBalancer{cc: cc}
+}
+
+func (xdsCircuitBreakerBuilder) Name() string {
+	return xdsCircuitBreakerPolicy
+}
+
+type xdsCircuitBreakerBalancer struct {
+	cc balancer.ClientConn
+}
+
+func (b *xdsCircuitBreakerBalancer) UpdateClientConnState(balancer.ClientConnState) error {
+	b.cc.UpdateState(balancer.State{
+		ConnectivityState: connectivity.Ready,
+		Picker: base.NewErrPicker(
+			status.Error(codes.Unavailable, "max requests 1 exceeded on service cluster-a")),
+	})
+
+	return nil
+}
+
+func (b *xdsCircuitBreakerBalancer) ResolverError(error) {}
+
+func (b *xdsCircuitBreakerBalancer) UpdateSubConnState(balancer.SubConn, balancer.SubConnState) {}
+
+func (b *xdsCircuitBreakerBalancer) Close() {}
@@ -0,0 +1,201 @@
+package grpc
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// hdrSubBucketBits is the number of the bits of the linear sub-buckets,
+	// 2048 sub-buckets keep 3 significant decimal digits of the recorded values.
+	hdrSubBucketBits  = 11
+	hdrSubBucketCount = 1 << hdrSubBucketBits
+	hdrSubBucketHalf  = hdrSubBucketCount / 2
+)
+
+// hdrPercentiles is the list of the percentiles included in the snapshots.
+var hdrPercentiles = []float64{50, 90, 95, 99, 99.9, 99.99} //nolint:gochecknoglobals
+
+// hdrHistogram is a high dynamic range histogram of the values in microseconds,
+// the values are counted in log-linear buckets with the constant relative precision.
+// It's safe for the concurrent use, each histogram is locked on its own,
+// so the RPCs of the different methods don't wait for each other.
+type hdrHistogram struct {
+	mu sync.Mutex
+
+	counts []int64
+	total  int64
+	sum    float64
+	min    int64
+	max    int64
+}
+
+// hdrIndex returns the index of the bucket counting the value.
+func hdrIndex(v int64) int {
+	if v < hdrSubBucketCount {
+		return int(v)
+	}
+
+	shift := bits.Len64(uint64(v)) - hdrSubBucketBits
+
+	return hdrSubBucketCount + (shift-1)*hdrSubBucketHalf + int(v>>shift) - hdrSubBucketHalf
+}
+
+// hdrHighestValue returns the highest value counted by the bucket with the index.
+func hdrHighestValue(i int) int64 {
+	if i < hdrSubBucketCount {
+		return int64(i)
+	}
+
+	shift := (i-hdrSubBucketCount)/hdrSubBucketHalf + 1
+	mantissa := int64((i-hdrSubBucketCount)%hdrSubBucketHalf + hdrSubBucketHalf)
+
+	return (mantissa+1)<<shift - 1
+}
+
+func (h *hdrHistogram) record(d time.Duration) {
+	v := d.Microseconds()
+	if v < 0 {
+		v = 0
+	}
+	i := hdrIndex(v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if i >= len(h.counts) {
+		counts := make([]int64, i+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+
+	h.counts[i]++
+
+	if h.total == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+
+	h.total++
+	h.sum += float64(v)
+}
+
+// percentile returns the value (in microseconds) at the given percentile,
+// the caller needs to hold the histogram's lock.
+func (h *hdrHistogram) percentile(p float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+
+	target := int64(math.Ceil(p / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+
+	var count int64
+	for i, c := range h.counts {
+		count += c
+		if count >= target {
+			v := hdrHighestValue(i)
+			if v > h.max {
+				return h.max
+			}
+
+			return v
+		}
+	}
+
+	return h.max
+}
+
+// latencySnapshot is the snapshot of a method's latency histogram,
+// all the durations are in milliseconds.
+type latencySnapshot struct {
+	Count       int64              `js:"count"`
+	Min         float64            `js:"min"`
+	Max         float64            `js:"max"`
+	Mean        float64            `js:"mean"`
+	Percentiles map[string]float64 `js:"percentiles"`
+}
+
+func (h *hdrHistogram) snapshot() latencySnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := latencySnapshot{
+		Count:       h.total,
+		Min:         float64(h.min) / 1000,
+		Max:         float64(h.max) / 1000,
+		Percentiles: make(map[string]float64, len(hdrPercentiles)),
+	}
+
+	if h.total > 0 {
+		s.Mean = h.sum / float64(h.total) / 1000
+	}
+
+	for _, p := range hdrPercentiles {
+		s.Percentiles["p("+strconv.FormatFloat(p, 'f', -1, 64)+")"] = float64(h.percentile(p)) / 1000
+	}
+
+	return s
+}
+
+// latencyHistograms keeps the latency histograms of the RPCs per method,
+// it's shared by all the VUs, so the snapshots cover the whole test run.
+// The zero value is ready to use.
+type latencyHistograms struct {
+	mu         sync.RWMutex
+	histograms map[string]*hdrHistogram
+}
+
+// record counts the latency of the method's RPC
+func (lh *latencyHistograms) record(method string, d time.Duration) {
+	lh.histogram(method).record(d)
+}
+
+// histogram returns the method's histogram, it's created on the method's first RPC
+func (lh *latencyHistograms) histogram(method string) *hdrHistogram {
+	lh.mu.RLock()
+	h, ok := lh.histograms[method]
+	lh.mu.RUnlock()
+	if ok {
+		return h
+	}
+
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+
+	if lh.histograms == nil {
+		lh.histograms = make(map[string]*hdrHistogram)
+	}
+
+	h, ok = lh.histograms[method]
+	if !ok {
+		h = &hdrHistogram{}
+		lh.histograms[method] = h
+	}
+
+	return h
+}
+
+// snapshot returns the snapshots of all the methods' histograms
+func (lh *latencyHistograms) snapshot() map[string]latencySnapshot {
+	lh.mu.RLock()
+	histograms := make(map[string]*hdrHistogram, len(lh.histograms))
+	for method, h := range lh.histograms {
+		histograms[method] = h
+	}
+	lh.mu.RUnlock()
+
+	result := make(map[string]latencySnapshot, len(histograms))
+	for method, h := range histograms {
+		result[method] = h.snapshot()
+	}
+
+	return result
+}
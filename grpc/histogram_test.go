@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHDRHistogram_Index(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []int64{0, 1, 2047, 2048, 2049, 4095, 4096, 123456, 987654321} {
+		i := hdrIndex(v)
+		highest := hdrHighestValue(i)
+
+		assert.GreaterOrEqual(t, highest, v)
+		// 3 significant decimal digits
+		assert.LessOrEqual(t, float64(highest-v), float64(v)/1000+1, "value %d", v)
+		assert.Equal(t, i, hdrIndex(highest), "value %d", v)
+	}
+}
+
+func TestHDRHistogram_Percentiles(t *testing.T) {
+	t.Parallel()
+
+	h := &hdrHistogram{}
+	for i := 1; i <= 10000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	s := h.snapshot()
+
+	require.Equal(t, int64(10000), s.Count)
+	assert.Equal(t, 1.0, s.Min)
+	assert.Equal(t, 10000.0, s.Max)
+	assert.InDelta(t, 5000.5, s.Mean, 0.001)
+	assert.InEpsilon(t, 5000, s.Percentiles["p(50)"], 0.001)
+	assert.InEpsilon(t, 9900, s.Percentiles["p(99)"], 0.001)
+	assert.InEpsilon(t, 9990, s.Percentiles["p(99.9)"], 0.001)
+}
+
+func TestLatencyHistograms_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	var lh latencyHistograms
+	lh.record("/foo/Bar", time.Millisecond)
+	lh.record("/foo/Bar", 3*time.Millisecond)
+	lh.record("/foo/Baz", 2*time.Millisecond)
+
+	s := lh.snapshot()
+
+	require.Len(t, s, 2)
+	assert.Equal(t, int64(2), s["/foo/Bar"].Count)
+	assert.Equal(t, 3.0, s["/foo/Bar"].Max)
+	assert.Equal(t, int64(1), s["/foo/Baz"].Count)
+}
+
+func TestLatencyHistograms_RecordConcurrently(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lh latencyHistograms
+		wg sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		method := "/foo/Bar"
+		if i%2 == 1 {
+			method = "/foo/Baz"
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				lh.record(method, time.Millisecond)
+				_ = lh.snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := lh.snapshot()
+
+	require.Len(t, s, 2)
+	assert.Equal(t, int64(4000), s["/foo/Bar"].Count)
+	assert.Equal(t, int64(4000), s["/foo/Baz"].Count)
+}
@@ -0,0 +1,108 @@
+package grpc
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// importPaths is the import paths of the load, the mappings map the import prefixes
+// (e.g. github.com/acme/apis/) to the local directories the imported files are read from.
+type importPaths struct {
+	Paths    []string
+	Mappings map[string]string
+}
+
+// newImportPaths constructs the import paths from the input value,
+// either an array of the paths or an object with the paths and the mappings.
+func newImportPaths(rt *goja.Runtime, input goja.Value) (*importPaths, error) {
+	result := &importPaths{}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	if _, ok := input.Export().([]interface{}); ok {
+		if err := rt.ExportTo(input, &result.Paths); err != nil {
+			return result, fmt.Errorf("invalid import paths value: %w", err)
+		}
+
+		return result, nil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf("invalid import paths value: '%#v', it needs to be an array or an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k)
+
+		switch k {
+		case "paths":
+			if err := rt.ExportTo(v, &result.Paths); err != nil {
+				return result, fmt.Errorf("invalid paths value: %w", err)
+			}
+		case "mappings":
+			if err := rt.ExportTo(v, &result.Mappings); err != nil {
+				return result, fmt.Errorf("invalid mappings value: %w", err)
+			}
+		default:
+			return result, fmt.Errorf("unknown import paths param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// mappedAccessor returns the accessor of the proto files which reads the imports with the mapped prefixes
+// from their directories (the longest prefix first), and the other files from the import paths.
+func mappedAccessor(
+	open func(string) (io.ReadCloser, error), paths []string, mappings map[string]string,
+) func(string) (io.ReadCloser, error) {
+	prefixes := make([]string, 0, len(mappings))
+	for prefix := range mappings {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	return func(filename string) (io.ReadCloser, error) {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(filename, prefix) {
+				return open(filepath.Join(mappings[prefix], strings.TrimPrefix(filename, prefix)))
+			}
+		}
+
+		var err error
+		for _, importPath := range paths {
+			var r io.ReadCloser
+			if r, err = open(filepath.Join(importPath, filename)); err == nil {
+				return r, nil
+			}
+		}
+
+		return nil, err
+	}
+}
+
+// lookupEmbeddedImport returns the descriptor of the import compiled into the binary,
+// e.g. the google/rpc/status.proto, so the googleapis tree doesn't need to be vendored.
+func lookupEmbeddedImport(filename string) (*descriptorpb.FileDescriptorProto, error) {
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return protodesc.ToFileDescriptorProto(fd), nil
+}
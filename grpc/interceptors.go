@@ -0,0 +1,117 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"google.golang.org/grpc/metadata"
+)
+
+// interceptor is called with the unary call's { method, metadata, message } before it's sent and
+// could mutate them, if it returns a function, it's called with the response once it's received.
+//
+// this return goja.value *and* error in order to return error on exception instead of panic
+// https://pkg.go.dev/github.com/dop251/goja#hdr-Functions
+type interceptor func(goja.Value) (goja.Value, error)
+
+// AddInterceptor adds an interceptor of the client's unary calls, the interceptors are called
+// in the order of their addition and their response callbacks in the reverse order.
+func (c *Client) AddInterceptor(fn interceptor) error {
+	if fn == nil {
+		return errors.New("the interceptor needs to be a function")
+	}
+
+	c.interceptors = append(c.interceptors, fn)
+
+	return nil
+}
+
+// responseCallbacks are the callbacks returned by the interceptors
+type responseCallbacks []goja.Callable
+
+// call calls the callbacks with the response in the reverse order
+func (rc responseCallbacks) call(rt *goja.Runtime, resp *grpcext.Response) error {
+	v := rt.ToValue(resp)
+	for i := len(rc) - 1; i >= 0; i-- {
+		if _, err := rc[i](goja.Undefined(), v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// intercept passes the call through the interceptors, it returns the possibly mutated method
+// and message, the metadata is replaced in the params.
+func (c *Client) intercept(
+	method string, message goja.Value, p *callParams,
+) (string, goja.Value, responseCallbacks, error) {
+	if len(c.interceptors) == 0 {
+		return method, message, nil, nil
+	}
+
+	rt := c.vu.Runtime()
+
+	call := rt.NewObject()
+	for k, v := range map[string]interface{}{
+		"method":   method,
+		"metadata": metadataObject(rt, p.Metadata),
+		"message":  message,
+	} {
+		if err := call.Set(k, v); err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	var callbacks responseCallbacks
+	for _, fn := range c.interceptors {
+		v, err := fn(call)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		if cb, ok := goja.AssertFunction(v); ok {
+			callbacks = append(callbacks, cb)
+		}
+	}
+
+	method = call.Get("method").String()
+	if method == "" {
+		return "", nil, nil, errors.New("the intercepted method cannot be empty")
+	}
+	if method[0] != '/' {
+		method = "/" + method
+	}
+
+	md, err := newMetadata(call.Get("metadata"))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("invalid intercepted metadata: %w", err)
+	}
+	p.Metadata = md
+
+	return method, call.Get("message"), callbacks, nil
+}
+
+// metadataObject converts the metadata into the object accepted by the newMetadata,
+// the binary values stay the bytes.
+func metadataObject(rt *goja.Runtime, md metadata.MD) *goja.Object {
+	obj := rt.NewObject()
+	for k, values := range md {
+		if len(values) == 0 {
+			continue
+		}
+
+		// the metadata parsed from the params has a value per key
+		var v interface{} = values[len(values)-1]
+		if strings.HasSuffix(k, "-bin") {
+			v = []byte(values[len(values)-1])
+		}
+
+		_ = obj.Set(k, v)
+	}
+
+	return obj
+}
@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ListServices returns the sorted fully-qualified names of the services of the loaded methods.
+func (c *Client) ListServices() []string {
+	seen := make(map[string]struct{})
+	services := make([]string, 0)
+
+	for _, md := range c.mds {
+		name := string(md.Parent().FullName())
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		seen[name] = struct{}{}
+		services = append(services, name)
+	}
+	sort.Strings(services)
+
+	return services
+}
+
+// ListMethods returns the loaded methods of the service (e.g. "pkg.Service") sorted by their full names,
+// or all the loaded methods if the service is empty.
+func (c *Client) ListMethods(service string) ([]MethodInfo, error) {
+	methods := make([]MethodInfo, 0)
+
+	for _, name := range c.methodNames() {
+		md := c.mds[name]
+		if service != "" && string(md.Parent().FullName()) != service {
+			continue
+		}
+
+		methods = append(methods, newMethodInfo(md))
+	}
+
+	if service != "" && len(methods) == 0 {
+		return nil, fmt.Errorf("service %q not found in the loaded file descriptors", service)
+	}
+
+	return methods, nil
+}
+
+// newMethodInfo returns the information of the method
+func newMethodInfo(md protoreflect.MethodDescriptor) MethodInfo {
+	sd := md.Parent()
+
+	return MethodInfo{
+		MethodInfo: grpc.MethodInfo{
+			Name:           string(md.Name()),
+			IsClientStream: md.IsStreamingClient(),
+			IsServerStream: md.IsStreamingServer(),
+		},
+		Package:    string(md.ParentFile().Package()),
+		Service:    string(sd.Name()),
+		FullMethod: fmt.Sprintf("/%s/%s", sd.FullName(), md.Name()),
+	}
+}
+
+// Channelz returns the channelz view (the states, the subchannels' addresses and the calls) of the channels
+// connected to the client's target, e.g. to dump the connections' topology in the teardown.
+// The channels of the other VUs connected to the same target are included too. The channelz
+// is opt-in, it's enabled by the connect's channelz param.
+func (c *Client) Channelz() ([]grpcext.ChannelInfo, error) {
+	if c.conn == nil {
+		return nil, errors.New("no gRPC connection, you must call connect first")
+	}
+
+	if !c.channelz {
+		return nil, errors.New("the channelz isn't enabled, you must connect with the channelz param")
+	}
+
+	return c.conn.Channelz(c.vu.Context())
+}
+
+// connectivityStates are the connectivity states by their names
+var connectivityStates = map[string]connectivity.State{ //nolint:gochecknoglobals
+	connectivity.Idle.String():             connectivity.Idle,
+	connectivity.Connecting.String():       connectivity.Connecting,
+	connectivity.Ready.String():            connectivity.Ready,
+	connectivity.TransientFailure.String(): connectivity.TransientFailure,
+	connectivity.Shutdown.String():         connectivity.Shutdown,
+}
+
+// GetState returns the name of the connection's connectivity state, e.g. "READY".
+func (c *Client) GetState() (string, error) {
+	if c.conn == nil {
+		return "", errors.New("no gRPC connection, you must call connect first")
+	}
+
+	return c.conn.State().String(), nil
+}
+
+// WaitForState waits until the connection gets into the state (e.g. "READY" once the xDS resources
+// have converged), it returns false if the timeout (a minute by default) has been exceeded before.
+func (c *Client) WaitForState(state string, timeout goja.Value) (bool, error) {
+	if c.conn == nil {
+		return false, errors.New("no gRPC connection, you must call connect first")
+	}
+
+	target, ok := connectivityStates[state]
+	if !ok {
+		return false, fmt.Errorf("invalid state value: %q, it needs to be one of IDLE, CONNECTING, READY, "+
+			"TRANSIENT_FAILURE or SHUTDOWN", state)
+	}
+
+	d := time.Minute
+	if !common.IsNullish(timeout) {
+		var err error
+		if d, err = types.GetDurationValue(timeout.Export()); err != nil {
+			return false, fmt.Errorf("invalid timeout value: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.vu.Context(), d)
+	defer cancel()
+
+	return c.conn.WaitForState(ctx, target), nil
+}
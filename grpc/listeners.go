@@ -11,14 +11,18 @@ const (
 	eventError  = "error"
 	eventEnd    = "end"
 	eventStatus = "status"
+	eventDrain  = "drain"
+
+	eventCircuitBreaker = "circuitBreaker"
+	eventConnection     = "connection"
+	eventRotation       = "rotation"
+	eventXDSUpdate      = "xdsUpdate"
 )
 
 // eventListeners keeps track of the eventListeners for each event type
 type eventListeners struct {
-	data   *eventListener
-	error  *eventListener
-	end    *eventListener
-	status *eventListener
+	owner string
+	list  map[string]*eventListener
 }
 
 // eventListener keeps listeners of a certain type
@@ -44,18 +48,7 @@ func (l *eventListener) add(fn func(goja.Value) (goja.Value, error)) {
 
 // getTypes return event listener of a certain type
 func (l *eventListeners) getType(t string) *eventListener {
-	switch t {
-	case eventData:
-		return l.data
-	case eventError:
-		return l.error
-	case eventStatus:
-		return l.status
-	case eventEnd:
-		return l.end
-	default:
-		return nil
-	}
+	return l.list[t]
 }
 
 // add adds a listener to the listeners
@@ -63,7 +56,7 @@ func (l *eventListeners) add(t string, f func(goja.Value) (goja.Value, error)) e
 	list := l.getType(t)
 
 	if list == nil {
-		return fmt.Errorf("unknown GRPC stream's event type: %s", t)
+		return fmt.Errorf("unknown GRPC %s's event type: %s", l.owner, t)
 	}
 
 	list.add(f)
@@ -82,11 +75,41 @@ func (l *eventListeners) all(t string) []func(goja.Value) (goja.Value, error) {
 	return list.list
 }
 
-func newEventListeners() *eventListeners {
-	return &eventListeners{
-		data:   newListener(eventData),
-		error:  newListener(eventError),
-		status: newListener(eventStatus),
-		end:    newListener(eventEnd),
+// has tells whether there are the listeners of the event type
+func (l *eventListeners) has(t string) bool {
+	return len(l.all(t)) > 0
+}
+
+// call calls all listeners of a certain event type with the given value
+func (l *eventListeners) call(t string, v goja.Value) error {
+	for _, listener := range l.all(t) {
+		if _, err := listener(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newEventListenersOf creates listeners of the given event types
+func newEventListenersOf(owner string, types ...string) *eventListeners {
+	l := &eventListeners{
+		owner: owner,
+		list:  make(map[string]*eventListener, len(types)),
+	}
+
+	for _, t := range types {
+		l.list[t] = newListener(t)
 	}
+
+	return l
+}
+
+func newEventListeners() *eventListeners {
+	return newEventListenersOf("stream", eventData, eventError, eventStatus, eventEnd, eventDrain)
+}
+
+func newClientEventListeners() *eventListeners {
+	return newEventListenersOf("client",
+		eventCircuitBreaker, eventConnection, eventRotation, eventXDSUpdate)
 }
@@ -0,0 +1,253 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"github.com/mstoykov/k6-taskqueue-lib/taskqueue"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+	"google.golang.org/grpc"
+)
+
+// loadParams is the parameters of the open-loop load started by the client.startLoad().
+type loadParams struct {
+	Method   string
+	Request  goja.Value
+	Rate     float64
+	Duration time.Duration
+
+	Call *callParams
+}
+
+// newLoadParams constructs the load parameters from the input value,
+// the keys other than the load ones are parsed as the call parameters.
+func newLoadParams(c *Client, input goja.Value) (*loadParams, error) {
+	if common.IsNullish(input) {
+		return nil, errors.New("method, rps and duration are required")
+	}
+
+	rt := c.vu.Runtime()
+	params := input.ToObject(rt)
+	callInput := rt.NewObject()
+	result := &loadParams{}
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "method":
+			var ok bool
+			result.Method, ok = v.(string)
+			if m, isInfo := v.(MethodInfo); isInfo {
+				result.Method, ok = m.FullMethod, true
+			}
+			if !ok || result.Method == "" {
+				return nil, fmt.Errorf("invalid method value: '%#v', it needs to be a non-empty string", v)
+			}
+		case "req":
+			result.Request = params.Get(k)
+		case "rps":
+			var ok bool
+			result.Rate, ok = toFloat64(v)
+			if !ok || !(result.Rate > 0 && result.Rate <= maxRate) {
+				return nil, fmt.Errorf("invalid rps value: '%#v', it needs to be a positive number up to %g", v, maxRate)
+			}
+		case "duration":
+			var err error
+			result.Duration, err = types.GetDurationValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration value: %w", err)
+			}
+			if result.Duration <= 0 {
+				return nil, fmt.Errorf("invalid duration value: '%#v', it needs to be a positive duration", v)
+			}
+		default:
+			if err := callInput.Set(k, params.Get(k)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch {
+	case result.Method == "":
+		return nil, errors.New("method is required")
+	case result.Rate == 0:
+		return nil, errors.New("rps is required")
+	case result.Duration == 0:
+		return nil, errors.New("duration is required")
+	}
+
+	var err error
+	if result.Call, err = newCallParams(c.vu, c.extOptions, c.tags, callInput); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// loadResult is the summary of the load started by the client.startLoad().
+type loadResult struct {
+	Requests int64 `js:"requests"`
+	Failures int64 `js:"failures"`
+}
+
+// StartLoad fires unary RPCs at the fixed open-loop rate for the given duration,
+// independently of the VU iteration pacing. The responses are only reported as metrics,
+// the returned promise is resolved with the summary of the load once all the RPCs are done.
+//
+// The client-side circuit breaker, the adaptive throttling and the health gating aren't applied to these RPCs,
+// and a token of the per-RPC credentials callback is fetched only once when the load starts.
+// It can't be started while the VU's mock servers are, since nothing would call their handlers.
+func (c *Client) StartLoad(params goja.Value) (*goja.Promise, error) {
+	state := c.vu.State()
+	if state == nil {
+		return nil, common.NewInitContextError("starting a load in the init context is not supported")
+	}
+	c, err := c.connection(params)
+	if err != nil {
+		return nil, err
+	}
+	if c.conn == nil {
+		return nil, errors.New("no gRPC connection, you must call connect first")
+	}
+	// the mock servers' handlers are run by the VU while it waits for a call, which the load's RPCs don't do
+	if c.mocks.running() {
+		return nil, errors.New("starting a load while a mock server is started is not supported, " +
+			"its handlers are only called while the VU waits for an invoke")
+	}
+
+	p, err := newLoadParams(c, params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GRPC's client.startLoad() parameters: %w", err)
+	}
+
+	method := p.Method
+	if method[0] != '/' {
+		method = "/" + method
+	}
+	methodDesc := c.mds[method]
+	if methodDesc == nil {
+		return nil, c.methodNotFound(method)
+	}
+
+	// k6 GRPC Invoke's default timeout is 2 minutes
+	if p.Call.Timeout == time.Duration(0) && p.Call.Deadline.IsZero() {
+		p.Call.Timeout = 2 * time.Minute
+	}
+
+	rt := c.vu.Runtime()
+	req := p.Request
+	if common.IsNullish(req) {
+		req = rt.NewObject()
+	}
+	b, marshaled, err := c.requestMessage(methodDesc, req)
+	if err != nil {
+		return nil, err
+	}
+
+	copts, err := c.callOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	p.Call.SetSystemTags(state, c.addr, method)
+	if p.Call.ExpectedStatuses == nil {
+		p.Call.ExpectedStatuses = c.statusCallback.expected
+	}
+
+	reqmsg := grpcext.Request{
+		MethodDescriptor: methodDesc,
+		Message:          b,
+		Marshaled:        marshaled,
+		DiscardUnknown:   !p.Call.StrictRequest,
+		TagsAndMeta:      &p.Call.TagsAndMeta,
+		ExpectedStatus:   p.Call.ExpectedStatuses.callback(),
+	}
+
+	tq := taskqueue.New(c.vu.RegisterCallback)
+	promise, resolve, _ := rt.NewPromise()
+
+	conn := c.conn
+	c.events.hold()
+	go func() {
+		result := c.runLoad(conn, p, method, reqmsg, copts)
+
+		tq.Queue(func() error {
+			defer tq.Close()
+			c.events.release()
+			resolve(result)
+
+			return nil
+		})
+	}()
+
+	return promise, nil
+}
+
+// runLoad schedules the RPCs at the open-loop rate, each one is fired
+// in its own goroutine so slow responses don't delay the following ones.
+func (c *Client) runLoad(
+	conn *grpcext.Conn, p *loadParams, method string, req grpcext.Request, copts []grpc.CallOption,
+) *loadResult {
+	var (
+		wg       sync.WaitGroup
+		requests int64
+		failures int64
+	)
+
+	ctx := c.vuContext()
+	interval := time.Duration(float64(time.Second) / p.Rate)
+	start := time.Now()
+
+loop:
+	for i := 0; ; i++ {
+		next := start.Add(time.Duration(i) * interval)
+		if next.Sub(start) >= p.Duration {
+			break
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			break loop
+		case <-timer.C:
+		}
+
+		requests++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			callCtx, cancel := p.Call.withDeadline(ctx)
+			defer cancel()
+			callCtx = p.Call.withDebug(callCtx)
+
+			// the call isn't admitted by the client-side policies (e.g. the circuit breaker or the health gating),
+			// it's sent at the load's rate regardless of the previous calls' results
+			start := time.Now()
+			resp, err := conn.Invoke(callCtx, method, p.Call.Metadata, req, copts...)
+			if err != nil {
+				atomic.AddInt64(&failures, 1)
+
+				return
+			}
+			c.latencies.record(method, time.Since(start))
+
+			if p.Call.ExpectedStatuses.failed(resp.Status) {
+				atomic.AddInt64(&failures, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return &loadResult{Requests: requests, Failures: atomic.LoadInt64(&failures)}
+}
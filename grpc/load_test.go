@@ -0,0 +1,156 @@
+package grpc_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/lib/testutils/httpmultibin/grpc_testing"
+	"go.k6.io/k6/metrics"
+)
+
+func TestClient_StartLoad(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	var calls int64
+	ts.httpBin.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+		atomic.AddInt64(&calls, 1)
+
+		return &grpc_testing.Empty{}, nil
+	}
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect("GRPCBIN_ADDR");
+		client.startLoad({
+			method: "grpc.testing.TestService/EmptyCall",
+			req: {},
+			rps: 20,
+			duration: "500ms",
+		}).then(function (result) {
+			call('Requests: ' + result.requests + ' Failures: ' + result.failures);
+		});
+		`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+
+	assert.Equal(t, []string{"Requests: 10 Failures: 0"}, ts.callRecorder.Recorded())
+	assert.Equal(t, int64(10), atomic.LoadInt64(&calls))
+
+	samplesBuf := metrics.GetBufferedSamples(ts.samples)
+	assertMetricEmitted(t, metrics.GRPCReqDurationName, samplesBuf,
+		ts.httpBin.Replacer.Replace("GRPCBIN_ADDR/grpc.testing.TestService/EmptyCall"))
+}
+
+func TestClient_StartLoadDeadline(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+	ts.httpBin.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+		return &grpc_testing.Empty{}, nil
+	}
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect("GRPCBIN_ADDR");
+		client.startLoad({
+			method: "grpc.testing.TestService/EmptyCall",
+			req: {},
+			rps: 20,
+			duration: "250ms",
+			deadline: new Date(Date.now() + 60000),
+		}).then(function (result) {
+			call('Requests: ' + result.requests + ' Failures: ' + result.failures);
+		});
+		`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+
+	assert.Equal(t, []string{"Requests: 5 Failures: 0"}, ts.callRecorder.Recorded())
+}
+
+func TestClient_StartLoadInvalidParams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		params string
+		err    string
+	}{
+		{
+			name:   "MissingRate",
+			params: `{ method: "grpc.testing.TestService/EmptyCall", duration: "1s" }`,
+			err:    "rps is required",
+		},
+		{
+			name:   "TooHighRate",
+			params: `{ method: "grpc.testing.TestService/EmptyCall", rps: 1e10, duration: "1s" }`,
+			err:    "invalid rps value",
+		},
+		{
+			name:   "InvalidDuration",
+			params: `{ method: "grpc.testing.TestService/EmptyCall", rps: 10, duration: "foo" }`,
+			err:    "invalid duration value",
+		},
+		{
+			name:   "UnknownParam",
+			params: `{ method: "grpc.testing.TestService/EmptyCall", rps: 10, duration: "1s", foo: "bar" }`,
+			err:    `unknown param: "foo"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestState(t)
+
+			initString := codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testdata/grpc_testing/test.proto");`,
+			}
+
+			val, err := ts.Run(initString.code)
+			assertResponse(t, initString, err, val, ts)
+
+			ts.ToVUContext()
+
+			_, err = ts.Run(`
+			client.connect("GRPCBIN_ADDR");
+			client.startLoad(` + tt.params + `);`)
+
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dop251/goja"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// MarshaledMessage is the request marshaled into the protobuf once by the client.marshal,
+// so the constant payloads skip the conversion on each call.
+type MarshaledMessage struct {
+	input protoreflect.FullName
+	data  []byte
+}
+
+// Marshal converts the method's request into the protobuf, the result could be passed to the invoke
+// and the startLoad instead of the request object. It could be called in the init context too.
+func (c *Client) Marshal(method string, req goja.Value) (*MarshaledMessage, error) {
+	if method == "" {
+		return nil, errors.New("method to marshal for cannot be empty")
+	}
+	if method[0] != '/' {
+		method = "/" + method
+	}
+	methodDesc := c.mds[method]
+	if methodDesc == nil {
+		return nil, c.methodNotFound(method)
+	}
+
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+	b, err := requestJSON(c.vu.Runtime(), methodDesc.Input(), methodDesc.Output(), req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialise request object: %w", err)
+	}
+
+	msg := dynamicpb.NewMessage(methodDesc.Input())
+	if err = protojson.Unmarshal(b, msg); err != nil {
+		return nil, fmt.Errorf("unable to serialise request object to protocol buffer: %w", err)
+	}
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal request object: %w", err)
+	}
+
+	return &MarshaledMessage{input: methodDesc.Input().FullName(), data: data}, nil
+}
+
+// requestMessage returns the method's request message, either the JSON of the request object
+// or the protobuf of the marshaled one, which is reported by the marshaled.
+func (c *Client) requestMessage(
+	methodDesc protoreflect.MethodDescriptor, req goja.Value,
+) (b []byte, marshaled bool, err error) {
+	if m, ok := req.Export().(*MarshaledMessage); ok {
+		if m.input != methodDesc.Input().FullName() {
+			return nil, false, fmt.Errorf("the request is marshaled as %s, but the method's input is %s",
+				m.input, methodDesc.Input().FullName())
+		}
+
+		return m.data, true, nil
+	}
+
+	b, err = requestJSON(c.vu.Runtime(), methodDesc.Input(), methodDesc.Output(), req)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to serialise request object: %w", err)
+	}
+
+	return b, false, nil
+}
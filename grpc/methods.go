@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxMethodSuggestions is the number of the closest methods suggested for the method which isn't found
+const maxMethodSuggestions = 3
+
+// String returns the full method, so the MethodInfo could be passed in place of the method's name
+func (m MethodInfo) String() string {
+	return m.FullMethod
+}
+
+// methodNotFound returns the error of the method which isn't loaded, suggesting the closest ones
+func (c *Client) methodNotFound(method string) error {
+	suggestions := suggestMethods(method, c.mds)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("method %q not found in file descriptors", method)
+	}
+
+	return fmt.Errorf("method %q not found in file descriptors, did you mean %s?",
+		method, strings.Join(suggestions, ", "))
+}
+
+// suggestMethods returns the quoted known methods closest to the method by the case-insensitive edit distance,
+// the ones differing by more than a third of the method's name (without the service) aren't suggested
+func suggestMethods(method string, known map[string]protoreflect.MethodDescriptor) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	lower := strings.ToLower(method)
+	maxDistance := len(method[strings.LastIndex(method, "/")+1:])/3 + 1
+
+	var candidates []candidate
+	for name := range known {
+		if d := editDistance(lower, strings.ToLower(name)); d <= maxDistance {
+			candidates = append(candidates, candidate{name: name, distance: d})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+
+		return candidates[i].name < candidates[j].name
+	})
+
+	if len(candidates) > maxMethodSuggestions {
+		candidates = candidates[:maxMethodSuggestions]
+	}
+
+	suggestions := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		suggestions = append(suggestions, fmt.Sprintf("%q", c.name))
+	}
+
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance of the strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestSuggestMethods(t *testing.T) {
+	t.Parallel()
+
+	known := map[string]protoreflect.MethodDescriptor{
+		"/main.RouteGuide/GetFeature":   nil,
+		"/main.RouteGuide/ListFeatures": nil,
+		"/main.RouteGuide/RouteChat":    nil,
+		"/grpc.health.v1.Health/Check":  nil,
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		expected []string
+	}{
+		{name: "Typo", method: "/main.RouteGuide/GetFeatrue", expected: []string{`"/main.RouteGuide/GetFeature"`}},
+		{name: "Case", method: "/main.routeguide/routechat", expected: []string{`"/main.RouteGuide/RouteChat"`}},
+		{name: "Service", method: "/main.RoutGuide/RouteChat", expected: []string{`"/main.RouteGuide/RouteChat"`}},
+		{name: "Unrelated", method: "/foo/bar", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, suggestMethods(tt.method, known))
+		})
+	}
+}
@@ -1,12 +1,69 @@
 package grpc
 
-import "go.k6.io/k6/metrics"
+import (
+	"strconv"
+	"time"
+
+	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc/codes"
+)
 
 // instanceMetrics contains the metrics for the grpc extension.
 type instanceMetrics struct {
 	Streams                 *metrics.Metric
 	StreamsMessagesSent     *metrics.Metric
 	StreamsMessagesReceived *metrics.Metric
+
+	StreamDuration         *metrics.Metric
+	StreamMessagesSent     *metrics.Metric
+	StreamMessagesReceived *metrics.Metric
+	StreamTimeToFirstMsg   *metrics.Metric
+
+	CircuitBreakerRejections   *metrics.Metric
+	CircuitBreakerStateChanges *metrics.Metric
+	ThrottlingRejections       *metrics.Metric
+
+	HandshakeDuration *metrics.Metric
+	ConnDuration      *metrics.Metric
+	ConnEvents        *metrics.Metric
+
+	ConnRotations        *metrics.Metric
+	ConnRotationDowntime *metrics.Metric
+
+	IdleReactivationDuration *metrics.Metric
+
+	XDSUpdates *metrics.Metric
+	XDSAcks    *metrics.Metric
+	XDSNacks   *metrics.Metric
+
+	XDSCircuitBreakerRejections *metrics.Metric
+
+	HealthGatingPauseDuration *metrics.Metric
+
+	ReflectionQueueDuration *metrics.Metric
+
+	StreamLimitQueueDuration *metrics.Metric
+	StreamLimitRejections    *metrics.Metric
+
+	RateLimitDuration *metrics.Metric
+
+	BudgetExceededAborts *metrics.Metric
+
+	DuplicateRequests *metrics.Metric
+
+	ReqFailed *metrics.Metric
+
+	MessagesSent     *metrics.Metric
+	MessagesReceived *metrics.Metric
+	DataSent         *metrics.Metric
+	DataReceived     *metrics.Metric
+
+	ReqBlocked   *metrics.Metric
+	ReqSending   *metrics.Metric
+	ReqWaiting   *metrics.Metric
+	ReqReceiving *metrics.Metric
+
+	ChannelsReady *metrics.Metric
 }
 
 // registerMetrics registers and returns the metrics in the provided registry
@@ -26,5 +83,198 @@ func registerMetrics(registry *metrics.Registry) (*instanceMetrics, error) {
 		return nil, err
 	}
 
+	if m.StreamDuration, err = registry.NewMetric("grpc_stream_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.StreamMessagesSent, err = registry.NewMetric("grpc_stream_msgs_sent", metrics.Trend); err != nil {
+		return nil, err
+	}
+
+	if m.StreamMessagesReceived, err = registry.NewMetric("grpc_stream_msgs_received", metrics.Trend); err != nil {
+		return nil, err
+	}
+
+	if m.StreamTimeToFirstMsg, err = registry.NewMetric(
+		"grpc_stream_time_to_first_msg", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.CircuitBreakerRejections, err = registry.NewMetric(
+		"grpc_circuit_breaker_rejections", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.CircuitBreakerStateChanges, err = registry.NewMetric(
+		"grpc_circuit_breaker_state_changes", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.ThrottlingRejections, err = registry.NewMetric(
+		"grpc_adaptive_throttling_rejections", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.HandshakeDuration, err = registry.NewMetric(
+		"grpc_handshake_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.ConnDuration, err = registry.NewMetric("grpc_conn_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.ConnEvents, err = registry.NewMetric("grpc_conn_events", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.ConnRotations, err = registry.NewMetric("grpc_conn_rotations", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.ConnRotationDowntime, err = registry.NewMetric(
+		"grpc_conn_rotation_downtime", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.HealthGatingPauseDuration, err = registry.NewMetric(
+		"grpc_health_gating_pause_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.IdleReactivationDuration, err = registry.NewMetric(
+		"grpc_idle_reactivation_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.XDSUpdates, err = registry.NewMetric("grpc_xds_updates", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.XDSAcks, err = registry.NewMetric("grpc_xds_acks", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.XDSNacks, err = registry.NewMetric("grpc_xds_nacks", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.XDSCircuitBreakerRejections, err = registry.NewMetric(
+		"grpc_xds_circuit_breaker_rejections", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.ReflectionQueueDuration, err = registry.NewMetric(
+		"grpc_reflection_queue_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.StreamLimitQueueDuration, err = registry.NewMetric(
+		"grpc_stream_limit_queue_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.StreamLimitRejections, err = registry.NewMetric("grpc_stream_limit_rejections", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.RateLimitDuration, err = registry.NewMetric("grpc_rate_limit_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.BudgetExceededAborts, err = registry.NewMetric(
+		"grpc_budget_exceeded_aborts", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.DuplicateRequests, err = registry.NewMetric(
+		"grpc_duplicate_requests", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.ReqFailed, err = registry.NewMetric("grpc_req_failed", metrics.Rate); err != nil {
+		return nil, err
+	}
+
+	if m.MessagesSent, err = registry.NewMetric("grpc_msgs_sent", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.MessagesReceived, err = registry.NewMetric("grpc_msgs_received", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.DataSent, err = registry.NewMetric("grpc_data_sent", metrics.Counter, metrics.Data); err != nil {
+		return nil, err
+	}
+
+	if m.DataReceived, err = registry.NewMetric("grpc_data_received", metrics.Counter, metrics.Data); err != nil {
+		return nil, err
+	}
+
+	if m.ReqBlocked, err = registry.NewMetric("grpc_req_blocked", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.ReqSending, err = registry.NewMetric("grpc_req_sending", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.ReqWaiting, err = registry.NewMetric("grpc_req_waiting", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.ReqReceiving, err = registry.NewMetric("grpc_req_receiving", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.ChannelsReady, err = registry.NewMetric("grpc_channels_ready", metrics.Gauge); err != nil {
+		return nil, err
+	}
+
 	return m, nil
 }
+
+// pushMetric pushes a sample of the metric with the given tags and metadata
+func (c *Client) pushMetric(metric *metrics.Metric, tagsAndMeta *metrics.TagsAndMeta, value float64) {
+	metrics.PushIfNotDone(c.vu.Context(), c.vu.State().Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   tagsAndMeta.Tags,
+		},
+		Time:     time.Now(),
+		Metadata: tagsAndMeta.Metadata,
+		Value:    value,
+	})
+}
+
+// connTagsAndMeta returns the VU's current tags and metadata with the connection's tags,
+// for the samples of the connection which aren't emitted by the calls
+func (c *Client) connTagsAndMeta() metrics.TagsAndMeta {
+	tagsAndMeta := c.vu.State().Tags.GetCurrentValues()
+	for k, v := range c.tags {
+		tagsAndMeta.SetTag(k, v)
+	}
+
+	return tagsAndMeta
+}
+
+// setStatusTag sets the status tag of the call finished with the code, if the tag is enabled.
+// The stats handler sets it for the calls sent to the server.
+func (c *Client) setStatusTag(tagsAndMeta *metrics.TagsAndMeta, code codes.Code) {
+	tagsAndMeta.SetSystemTagOrMetaIfEnabled(c.vu.State().Options.SystemTags, metrics.TagStatus, strconv.Itoa(int(code)))
+}
+
+// pushReqFailed pushes the grpc_req_failed sample of the call if there are the expected statuses
+func (c *Client) pushReqFailed(expected *expectedStatuses, code codes.Code, tagsAndMeta *metrics.TagsAndMeta) {
+	if expected == nil {
+		return
+	}
+
+	var failed float64
+	if expected.failed(code) {
+		failed = 1
+	}
+
+	c.pushMetric(c.metrics.ReqFailed, tagsAndMeta, failed)
+}
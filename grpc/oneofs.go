@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// validateOneofs checks that the request's oneofs (of the message and of the nested ones) have a member set at most,
+// so a script setting several of them fails instead of the one of them being picked.
+func validateOneofs(md protoreflect.MessageDescriptor, v interface{}) error {
+	err := checkOneofs(md, v)
+
+	grpcext.ConvertFields(md, v, func(fd protoreflect.FieldDescriptor, v interface{}) (interface{}, bool) {
+		if fd.Message() == nil || err != nil {
+			return v, err != nil
+		}
+
+		// the well-known types' JSON isn't their fields, e.g. the Value's
+		if strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf.") {
+			return v, true
+		}
+
+		err = checkOneofs(fd.Message(), v)
+
+		return v, false
+	})
+
+	return err
+}
+
+// checkOneofs checks the oneofs of the message's JSON value, the null members aren't set
+func checkOneofs(md protoreflect.MessageDescriptor, v interface{}) error {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	ods := md.Oneofs()
+	for i := 0; i < ods.Len(); i++ {
+		od := ods.Get(i)
+		// the proto3 optional fields are the synthetic oneofs
+		if od.IsSynthetic() {
+			continue
+		}
+
+		var set []string
+
+		fields := od.Fields()
+		for j := 0; j < fields.Len(); j++ {
+			fd := fields.Get(j)
+			if obj[fd.JSONName()] != nil || obj[string(fd.Name())] != nil {
+				set = append(set, fmt.Sprintf("%q", fd.Name()))
+			}
+		}
+
+		if len(set) > 1 {
+			return fmt.Errorf("oneof %s has multiple members set: %s, only one of them can be set",
+				od.FullName(), strings.Join(set, ", "))
+		}
+	}
+
+	return nil
+}
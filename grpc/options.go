@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules"
+)
+
+// extOptionsKey is the key of the module's defaults in the k6 options' ext, e.g.
+//
+//	export const options = { ext: { grpc: { target: "localhost:8080", connect: { plaintext: true } } } }
+const extOptionsKey = "grpc"
+
+// extOptions are the scenario-level defaults of the clients, so the scripts could switch
+// the environments with the config files or --env without editing every connect call.
+type extOptions struct {
+	// Target is the address connected to when the connect's one is empty
+	Target string `json:"target"`
+	// Connect and Params are the defaults of the connect's and the calls' params,
+	// the params given override them, except the metadata merged key by key.
+	Connect map[string]interface{} `json:"connect"`
+	Params  map[string]interface{} `json:"params"`
+	// ConnectionReuse tells whether the VU's connections are reused across its iterations ("vu", the default)
+	// or reconnected in each iteration ("iteration")
+	ConnectionReuse string `json:"connectionReuse"`
+}
+
+// extOptionsCache is the VU's options' ext parsed once, by the first call in the VU context,
+// since the options don't change during the test.
+type extOptionsCache struct {
+	loaded bool
+	opts   *extOptions
+	err    error
+}
+
+// load returns the defaults set in the options' ext, nil if there are none
+func (e *extOptionsCache) load(vu modules.VU) (*extOptions, error) {
+	state := vu.State()
+	if state == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	if !e.loaded {
+		e.opts, e.err = parseExtOptions(state.Options.External)
+		e.loaded = true
+	}
+
+	return e.opts, e.err
+}
+
+// parseExtOptions parses the defaults set in the options' ext, nil if there are none
+func parseExtOptions(external map[string]json.RawMessage) (*extOptions, error) {
+	raw, ok := external[extOptionsKey]
+	if !ok || len(raw) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	opts := &extOptions{}
+	if err := json.Unmarshal(raw, opts); err != nil {
+		return nil, fmt.Errorf("invalid options.ext.%s: %w", extOptionsKey, err)
+	}
+
+	switch opts.ConnectionReuse {
+	case "", connectionReuseVU, connectionReuseIteration:
+	default:
+		return nil, fmt.Errorf("invalid options.ext.%s.connectionReuse value: %q, it needs to be %q or %q",
+			extOptionsKey, opts.ConnectionReuse, connectionReuseVU, connectionReuseIteration)
+	}
+
+	return opts, nil
+}
+
+// withDefaults returns the params with the defaults set for the keys they don't have
+func withDefaults(rt *goja.Runtime, defaults map[string]interface{}, input goja.Value) goja.Value {
+	if len(defaults) == 0 {
+		return input
+	}
+
+	if _, ok := input.(*goja.Object); !ok && !common.IsNullish(input) {
+		// the params' parsing reports the invalid ones
+		return input
+	}
+
+	result := rt.NewObject()
+	for k, v := range defaults {
+		_ = result.Set(k, v)
+	}
+
+	if common.IsNullish(input) {
+		return result
+	}
+
+	params := input.ToObject(rt)
+	for _, k := range params.Keys() {
+		v := params.Get(k)
+
+		if k == "metadata" {
+			v = mergeMetadata(rt, result.Get(k), v)
+		}
+
+		_ = result.Set(k, v)
+	}
+
+	return result
+}
+
+// mergeMetadata returns the default metadata overridden by the given one, key by key
+func mergeMetadata(rt *goja.Runtime, defaults, md goja.Value) goja.Value {
+	if common.IsNullish(defaults) || common.IsNullish(md) {
+		return md
+	}
+
+	dobj, ok := defaults.(*goja.Object)
+	if !ok {
+		return md
+	}
+
+	mobj, ok := md.(*goja.Object)
+	if !ok {
+		return md
+	}
+
+	merged := rt.NewObject()
+	for _, k := range dobj.Keys() {
+		_ = merged.Set(k, dobj.Get(k))
+	}
+
+	for _, k := range mobj.Keys() {
+		_ = merged.Set(k, mobj.Get(k))
+	}
+
+	return merged
+}
@@ -3,15 +3,20 @@ package grpc
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules"
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -21,14 +26,63 @@ type callParams struct {
 	Metadata    metadata.MD
 	TagsAndMeta metrics.TagsAndMeta
 	Timeout     time.Duration
+	// Deadline is the absolute deadline of the call, the earliest of it and the timeout applies
+	Deadline time.Time
+	// AbortOnBudgetExceeded fails the unary call locally if its deadline has been exceeded
+	// before sending it, e.g. while waiting for the client-side policies or the connection.
+	AbortOnBudgetExceeded bool
+	// ExpectedStatuses overrides the VU's default set by the grpc.setStatusCallback
+	ExpectedStatuses *expectedStatuses
+	// LazyMessage leaves the unary call's response message unconverted until the resp.json() or resp.binary()
+	LazyMessage bool
+	// Int64Numbers returns the responses' 64-bit integers as the numbers if they're represented exactly,
+	// set by the int64: "number", the default "string" keeps them as the protojson's strings
+	Int64Numbers bool
+	// StrictRequest fails the call if the request object has the fields unknown to the method's input message
+	// (or to the nested messages), otherwise they're ignored
+	StrictRequest bool
+	// TimestampDates returns the responses' Timestamps as the Dates, set by the timestamp: "date",
+	// the default "string" keeps them as the RFC 3339 strings
+	TimestampDates bool
+	// Signal and CancelAfter cancel the call, unlike the timeout, it ends with the Canceled status
+	Signal      *AbortSignal
+	CancelAfter time.Duration
+	// MaxSendSize and MaxReceiveSize override the connection's message size limits, zero keeps them
+	MaxSendSize    int64
+	MaxReceiveSize int64
+	// Debug overrides the --http-debug logging of the call, "headers" or "full"
+	Debug string
+	// DebugSampleRate is the share of the calls logged with the debug logging, zero logs all of them
+	DebugSampleRate float64
+	// MaxBufferedWrites and MaxBufferedReads limit the stream's messages waiting to be sent to the server
+	// and to be handled by the event loop, zero keeps them unlimited
+	MaxBufferedWrites int64
+	MaxBufferedReads  int64
+	// RawMessages delivers the stream's received messages as the marshaled protobuf, skipping the conversion
+	RawMessages bool
 }
 
 // newCallParams constructs the call parameters from the input value.
 // if no input is given, the default values are used.
-func newCallParams(vu modules.VU, input goja.Value) (*callParams, error) {
+// The call's tags override the tags of the connection.
+func newCallParams(
+	vu modules.VU, ext *extOptionsCache, connTags map[string]string, input goja.Value,
+) (*callParams, error) {
 	result := &callParams{
-		Metadata:    metadata.New(nil),
-		TagsAndMeta: vu.State().Tags.GetCurrentValues(),
+		Metadata:      metadata.New(nil),
+		TagsAndMeta:   vu.State().Tags.GetCurrentValues(),
+		StrictRequest: true,
+	}
+	for k, v := range connTags {
+		result.TagsAndMeta.SetTag(k, v)
+	}
+
+	opts, err := ext.load(vu)
+	if err != nil {
+		return result, err
+	}
+	if opts != nil {
+		input = withDefaults(vu.Runtime(), opts.Params, input)
 	}
 
 	if common.IsNullish(input) {
@@ -58,6 +112,118 @@ func newCallParams(vu modules.VU, input goja.Value) (*callParams, error) {
 			if err != nil {
 				return result, fmt.Errorf("invalid timeout value: %w", err)
 			}
+		case "deadline":
+			var err error
+			result.Deadline, err = parseDeadline(params.Get(k).Export())
+			if err != nil {
+				return result, err
+			}
+		case "abortOnBudgetExceeded":
+			v := params.Get(k).Export()
+			var ok bool
+			result.AbortOnBudgetExceeded, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid abortOnBudgetExceeded value: '%#v', it needs to be boolean", v)
+			}
+		case "lazyMessage":
+			v := params.Get(k).Export()
+			var ok bool
+			result.LazyMessage, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid lazyMessage value: '%#v', it needs to be boolean", v)
+			}
+		case "int64":
+			v := params.Get(k).Export()
+			switch v {
+			case "string":
+				result.Int64Numbers = false
+			case "number":
+				result.Int64Numbers = true
+			default:
+				return result, fmt.Errorf("invalid int64 value: '%#v', it needs to be \"string\" or \"number\"", v)
+			}
+		case "strictRequest":
+			v := params.Get(k).Export()
+			var ok bool
+			result.StrictRequest, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid strictRequest value: '%#v', it needs to be boolean", v)
+			}
+		case "timestamp":
+			v := params.Get(k).Export()
+			switch v {
+			case "string":
+				result.TimestampDates = false
+			case "date":
+				result.TimestampDates = true
+			default:
+				return result, fmt.Errorf("invalid timestamp value: '%#v', it needs to be \"string\" or \"date\"", v)
+			}
+		case "signal":
+			v := params.Get(k).Export()
+			var ok bool
+			result.Signal, ok = v.(*AbortSignal)
+			if !ok {
+				return result, fmt.Errorf("invalid signal value: '%#v', it needs to be an AbortController's signal", v)
+			}
+		case "cancelAfter":
+			v := params.Get(k).Export()
+			var err error
+			result.CancelAfter, err = types.GetDurationValue(v)
+			if err != nil || result.CancelAfter <= 0 {
+				return result, fmt.Errorf("invalid cancelAfter value: '%#v', it needs to be a positive duration", v)
+			}
+		case "maxSendSize", "maxReceiveSize":
+			v := params.Get(k).Export()
+			size, ok := v.(int64)
+			if !ok || size <= 0 || size > math.MaxInt32 {
+				return result, fmt.Errorf("invalid %s value: '%#v', it needs to be a positive integer", k, v)
+			}
+
+			if k == "maxSendSize" {
+				result.MaxSendSize = size
+			} else {
+				result.MaxReceiveSize = size
+			}
+		case "maxBufferedWrites", "maxBufferedReads":
+			v := params.Get(k).Export()
+			n, ok := v.(int64)
+			if !ok || n <= 0 || n > math.MaxInt32 {
+				return result, fmt.Errorf("invalid %s value: '%#v', it needs to be a positive integer", k, v)
+			}
+
+			if k == "maxBufferedWrites" {
+				result.MaxBufferedWrites = n
+			} else {
+				result.MaxBufferedReads = n
+			}
+		case "rawMessages":
+			v := params.Get(k).Export()
+			var ok bool
+			result.RawMessages, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid rawMessages value: '%#v', it needs to be boolean", v)
+			}
+		case "debug":
+			var err error
+			result.Debug, err = parseDebug(params.Get(k).Export())
+			if err != nil {
+				return result, err
+			}
+		case "debugSampleRate":
+			var err error
+			result.DebugSampleRate, err = parseDebugSampleRate(params.Get(k).Export())
+			if err != nil {
+				return result, err
+			}
+		case "expectedStatuses":
+			var err error
+			result.ExpectedStatuses, err = parseExpectedStatuses(params.Get(k).Export())
+			if err != nil {
+				return result, fmt.Errorf("invalid expectedStatuses value: %w", err)
+			}
+		case "connection", "authority":
+			// the call's connection is picked by the client before its params are parsed
 		default:
 			return result, fmt.Errorf("unknown param: %q", k)
 		}
@@ -66,6 +232,21 @@ func newCallParams(vu modules.VU, input goja.Value) (*callParams, error) {
 	return result, nil
 }
 
+// callOptions returns the call options overriding the connection's ones
+func (p *callParams) callOptions() []grpc.CallOption {
+	var opts []grpc.CallOption
+
+	if p.MaxSendSize > 0 {
+		opts = append(opts, grpc.MaxCallSendMsgSize(int(p.MaxSendSize)))
+	}
+
+	if p.MaxReceiveSize > 0 {
+		opts = append(opts, grpc.MaxCallRecvMsgSize(int(p.MaxReceiveSize)))
+	}
+
+	return opts
+}
+
 // newMetadata constructs a metadata.MD from the input value.
 func newMetadata(input goja.Value) (metadata.MD, error) {
 	md := metadata.New(nil)
@@ -103,6 +284,23 @@ func newMetadata(input goja.Value) (metadata.MD, error) {
 	return md, nil
 }
 
+// parseConnTags parses the connection's tags, an object with the string values
+func parseConnTags(v interface{}) (map[string]string, error) {
+	raw, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("must be an object with key-value pairs")
+	}
+
+	tags := make(map[string]string, len(raw))
+	for k, tv := range raw {
+		if tags[k], ok = tv.(string); !ok {
+			return nil, fmt.Errorf("%q value must be a string", k)
+		}
+	}
+
+	return tags, nil
+}
+
 // SetSystemTags sets the system tags for the call.
 func (p *callParams) SetSystemTags(state *lib.State, addr string, methodName string) {
 	if state.Options.SystemTags.Has(metrics.TagURL) {
@@ -128,6 +326,54 @@ type connectParams struct {
 	MaxReceiveSize        int64
 	MaxSendSize           int64
 	TLS                   map[string]interface{}
+	Authority             string
+	CircuitBreaker        *circuitBreakerParams
+	AdaptiveThrottling    *adaptiveThrottlingParams
+	CallCredentials       *callCredentialsParams
+	Credentials           *credentialsParams
+	HealthGating          *healthGatingParams
+	Proxy                 *url.URL
+	Endpoints             []string
+	DuplicateDetection    *duplicateDetectionParams
+	Capture               *captureParams
+	Pool                  bool
+	LogLevel              string
+	ReflectCache          bool
+	ReflectionSymbols     []string
+	AnyTypes              []string
+	TracePropagation      *tracePropagationParams
+	Channels              int64
+	// the HTTP/2 flow control windows and the transport's buffers, zero means the gRPC's default
+	InitialWindowSize     int64
+	InitialConnWindowSize int64
+	WriteBufferSize       int64
+	ReadBufferSize        int64
+	Socket                *grpcext.SocketOptions
+	Backoff               *backoffParams
+	DNSRefresh            *grpcext.DNSRefresh
+	LoadBalancing         *grpcext.LoadBalancing
+	// IdleTimeout is the inactivity after which the channels go idle, zero disables the idleness
+	IdleTimeout time.Duration
+	// Channelz enables the gRPC's channelz before the dial, so the connection's channels are tracked
+	Channelz bool
+
+	// MaxConcurrentReflections limits the reflection exchanges made concurrently by all the VUs
+	MaxConcurrentReflections int64
+
+	// MaxConcurrentStreams limits the connection's concurrent RPCs on the client side
+	MaxConcurrentStreams *streamLimitParams
+
+	// RateLimit paces the client's unary calls independent of the iterations
+	RateLimit *rateLimitParams
+
+	// ReflectOnReconnect is whether the reconnect without the reflect repeats the client's reflection
+	ReflectOnReconnect string
+
+	// Name is the name of the client's named connection, the calls pick it by their connection param
+	Name string
+
+	// Tags are attached to all the samples emitted through the connection
+	Tags map[string]string
 }
 
 func newConnectParams(vu modules.VU, input goja.Value) (*connectParams, error) { //nolint:gocognit
@@ -138,6 +384,8 @@ func newConnectParams(vu modules.VU, input goja.Value) (*connectParams, error) {
 		MaxReceiveSize:        0,
 		MaxSendSize:           0,
 		ReflectionMetadata:    metadata.New(nil),
+		ReflectCache:          true,
+		ReflectOnReconnect:    reflectOnReconnectAuto,
 	}
 
 	if common.IsNullish(input) {
@@ -176,6 +424,118 @@ func newConnectParams(vu modules.VU, input goja.Value) (*connectParams, error) {
 			}
 
 			result.ReflectionMetadata = md
+		case "reflectSymbols":
+			var err error
+			result.ReflectionSymbols, err = parseReflectionSymbols(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid reflectSymbols value: %w", err)
+			}
+		case "anyTypes":
+			var err error
+			result.AnyTypes, err = parseReflectionSymbols(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid anyTypes value: %w", err)
+			}
+		case "initialWindowSize", "initialConnWindowSize":
+			n, ok := v.(int64)
+			if !ok || n < minWindowSize || n > math.MaxInt32 {
+				return result, fmt.Errorf("invalid %s value: '%#v', it needs to be an integer between %d and %d",
+					k, v, minWindowSize, math.MaxInt32)
+			}
+			if k == "initialWindowSize" {
+				result.InitialWindowSize = n
+			} else {
+				result.InitialConnWindowSize = n
+			}
+		case "idleTimeout":
+			var err error
+			result.IdleTimeout, err = types.GetDurationValue(v)
+			if err != nil || result.IdleTimeout < 0 {
+				return result, fmt.Errorf("invalid idleTimeout value: '%#v', it needs to be a non-negative duration", v)
+			}
+		case "writeBufferSize", "readBufferSize":
+			n, ok := v.(int64)
+			if !ok || n <= 0 || n > math.MaxInt32 {
+				return result, fmt.Errorf("invalid %s value: '%#v', it needs to be a positive integer", k, v)
+			}
+			if k == "writeBufferSize" {
+				result.WriteBufferSize = n
+			} else {
+				result.ReadBufferSize = n
+			}
+		case "tcpKeepAlive", "tcpNoDelay", "localAddress":
+			if result.Socket == nil {
+				result.Socket = &grpcext.SocketOptions{}
+			}
+			if err := parseSocketParam(result.Socket, k, v); err != nil {
+				return result, err
+			}
+		case "backoff":
+			var err error
+			result.Backoff, err = newBackoffParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "dnsRefresh":
+			var err error
+			result.DNSRefresh, err = newDNSRefreshParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "loadBalancing":
+			var err error
+			result.LoadBalancing, err = newLoadBalancingParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "channels":
+			var ok bool
+			result.Channels, ok = v.(int64)
+			if !ok || result.Channels <= 0 {
+				return result, fmt.Errorf("invalid channels value: '%#v', it needs to be a positive integer", v)
+			}
+		case "tracePropagation":
+			var err error
+			result.TracePropagation, err = parseTracePropagation(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid tracePropagation value: %w", err)
+			}
+		case "reflectCache":
+			var ok bool
+			result.ReflectCache, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid reflectCache value: '%#v', it needs to be boolean", v)
+			}
+		case "tags":
+			var err error
+			result.Tags, err = parseConnTags(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid tags value: %w", err)
+			}
+		case "sharding":
+			// the sharding picks the target before the params are parsed, see the pickTarget
+		case "name":
+			var ok bool
+			result.Name, ok = v.(string)
+			if !ok || result.Name == "" {
+				return result, fmt.Errorf("invalid name value: '%#v', it needs to be a non-empty string", v)
+			}
+		case "reflectOnReconnect":
+			mode, _ := v.(string)
+			switch mode {
+			case reflectOnReconnectAuto, reflectOnReconnectAlways, reflectOnReconnectNever:
+				result.ReflectOnReconnect = mode
+			default:
+				return result, fmt.Errorf("invalid reflectOnReconnect value: '%#v', it needs to be %q, %q or %q",
+					v, reflectOnReconnectAuto, reflectOnReconnectAlways, reflectOnReconnectNever)
+			}
+		case "maxConcurrentReflections":
+			var ok bool
+			result.MaxConcurrentReflections, ok = v.(int64)
+			if !ok || result.MaxConcurrentReflections <= 0 {
+				return result, fmt.Errorf(
+					"invalid maxConcurrentReflections value: '%#v', it needs to be a positive integer", v)
+			}
 		case "maxReceiveSize":
 			var ok bool
 			result.MaxReceiveSize, ok = v.(int64)
@@ -198,14 +558,237 @@ func newConnectParams(vu modules.VU, input goja.Value) (*connectParams, error) {
 			if err := parseConnectTLSParam(result, v); err != nil {
 				return result, err
 			}
+		case "authority":
+			var ok bool
+			result.Authority, ok = v.(string)
+			if !ok || result.Authority == "" {
+				return result, fmt.Errorf("invalid authority value: '%#v', it needs to be a non-empty string", v)
+			}
+		case "circuitBreaker":
+			var err error
+			result.CircuitBreaker, err = newCircuitBreakerParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "adaptiveThrottling":
+			var err error
+			result.AdaptiveThrottling, err = newAdaptiveThrottlingParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "callCredentials":
+			var err error
+			result.CallCredentials, err = newCallCredentialsParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "healthGating":
+			var err error
+			result.HealthGating, err = newHealthGatingParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "credentials":
+			var err error
+			result.Credentials, err = newCredentialsParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "logLevel":
+			var ok bool
+			result.LogLevel, ok = v.(string)
+			if _, known := grpcLogLevels[result.LogLevel]; !ok || !known {
+				return result, fmt.Errorf(
+					"invalid logLevel value: '%#v', it needs to be one of \"error\", \"warning\", \"info\" or \"debug\"", v)
+			}
+		case "channelz":
+			var ok bool
+			result.Channelz, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid channelz value: '%#v', it needs to be boolean", v)
+			}
+		case "pool":
+			var ok bool
+			result.Pool, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid pool value: '%#v', it needs to be boolean", v)
+			}
+		case "duplicateDetection":
+			var err error
+			result.DuplicateDetection, err = newDuplicateDetectionParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "rateLimit":
+			var err error
+			result.RateLimit, err = newRateLimitParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "maxConcurrentStreams":
+			var err error
+			result.MaxConcurrentStreams, err = newStreamLimitParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "capture":
+			var err error
+			result.Capture, err = newCaptureParams(rt, params.Get(k))
+			if err != nil {
+				return result, err
+			}
+		case "endpoints":
+			var err error
+			result.Endpoints, err = parseEndpoints(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid endpoints value: %w", err)
+			}
+		case "proxy":
+			var err error
+			result.Proxy, err = parseProxyURL(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid proxy value: %w", err)
+			}
 		default:
 			return result, fmt.Errorf("unknown connect param: %q", k)
 		}
 	}
 
+	// the xds credentials use the plaintext or tls params as the fallback
+	if result.Credentials != nil && result.Credentials.Type != credentialsXDS &&
+		(result.IsPlaintext || len(result.TLS) > 0) {
+		return result, fmt.Errorf("the %q credentials can't be combined with the plaintext or tls params",
+			result.Credentials.Type)
+	}
+
 	return result, nil
 }
 
+// parseSocketParam parses the TCP-level tuning param into the socket options
+func parseSocketParam(socket *grpcext.SocketOptions, k string, v interface{}) error {
+	switch k {
+	case "tcpKeepAlive":
+		// false disables the keep-alive probes
+		if v == false {
+			socket.KeepAlive = -1
+			return nil
+		}
+
+		d, err := types.GetDurationValue(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid tcpKeepAlive value: '%#v', it needs to be a positive duration or false", v)
+		}
+		socket.KeepAlive = d
+	case "tcpNoDelay":
+		noDelay, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("invalid tcpNoDelay value: '%#v', it needs to be boolean", v)
+		}
+		socket.NoDelay = &noDelay
+	case "localAddress":
+		list, ok := v.([]interface{})
+		if !ok {
+			list = []interface{}{v}
+		}
+
+		if len(list) == 0 {
+			return fmt.Errorf("invalid localAddress value: '%#v', it needs to be a non-empty list", v)
+		}
+
+		for _, a := range list {
+			s, _ := a.(string)
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return fmt.Errorf("invalid localAddress value: '%#v', it needs to be an IP address or a list of them", a)
+			}
+			socket.LocalAddrs = append(socket.LocalAddrs, ip)
+		}
+	}
+
+	return nil
+}
+
+// minWindowSize is the minimal HTTP/2 flow control window, the gRPC ignores the smaller ones
+const minWindowSize = 64 * 1024
+
+// grpcLogLevel is the minimal severity and the verbosity of the gRPC internal logs
+type grpcLogLevel struct {
+	severity  int
+	verbosity int
+}
+
+// grpcLogLevels is the levels of the gRPC internal logs by their names
+var grpcLogLevels = map[string]grpcLogLevel{ //nolint:gochecknoglobals
+	"error":   {severity: grpcext.LogSeverityError},
+	"warning": {severity: grpcext.LogSeverityWarning},
+	"info":    {severity: grpcext.LogSeverityInfo},
+	"debug":   {severity: grpcext.LogSeverityInfo, verbosity: 2},
+}
+
+// parseReflectionSymbols parses the fully-qualified names of the symbols (e.g. the services)
+// the reflection is limited to
+func parseReflectionSymbols(v interface{}) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("'%#v', it needs to be a non-empty array of strings", v)
+	}
+
+	symbols := make([]string, 0, len(list))
+	for _, s := range list {
+		symbol, ok := s.(string)
+		if !ok || symbol == "" {
+			return nil, fmt.Errorf("'%#v', it needs to be a non-empty string", s)
+		}
+
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols, nil
+}
+
+// parseEndpoints parses the static list of the host:port endpoints
+func parseEndpoints(v interface{}) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("'%#v', it needs to be a non-empty array of host:port strings", v)
+	}
+
+	endpoints := make([]string, 0, len(list))
+	for _, e := range list {
+		s, ok := e.(string)
+		if !ok {
+			return nil, fmt.Errorf("'%#v', it needs to be a host:port string", e)
+		}
+
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			return nil, fmt.Errorf("%q, it needs to be a host:port string", s)
+		}
+
+		endpoints = append(endpoints, s)
+	}
+
+	return endpoints, nil
+}
+
+// parseProxyURL parses the URL of the HTTP proxy, the user info is used as the proxy's credentials
+func parseProxyURL(v interface{}) (*url.URL, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("'%#v', it needs to be a string", v)
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" || u.Hostname() == "" {
+		return nil, fmt.Errorf("%q, it needs to be a URL like http://[user:password@]host[:port]", s)
+	}
+
+	return u, nil
+}
+
 func parseConnectTLSParam(params *connectParams, v interface{}) error {
 	var ok bool
 	params.TLS, ok = v.(map[string]interface{})
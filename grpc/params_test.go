@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -45,6 +46,21 @@ func TestCallParamsInvalidInput(t *testing.T) {
 			JSON:        `{ metadata: "lorem" }`,
 			ErrContains: `invalid metadata param: must be an object with key-value pairs`,
 		},
+		{
+			Name:        "InvalidDebug",
+			JSON:        `{ debug: "body" }`,
+			ErrContains: `invalid debug value: '"body"', it needs to be "headers" or "full"`,
+		},
+		{
+			Name:        "InvalidDebugSampleRate",
+			JSON:        `{ debugSampleRate: 1.5 }`,
+			ErrContains: `invalid debugSampleRate value: '1.5', it needs to be a number between 0 and 1`,
+		},
+		{
+			Name:        "InvalidMaxBufferedWrites",
+			JSON:        `{ maxBufferedWrites: 0 }`,
+			ErrContains: `invalid maxBufferedWrites value: '0', it needs to be a positive integer`,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -55,7 +71,7 @@ func TestCallParamsInvalidInput(t *testing.T) {
 
 			testRuntime, params := newParamsTestRuntime(t, tc.JSON)
 
-			_, err := newCallParams(testRuntime.VU, params)
+			_, err := newCallParams(testRuntime.VU, &extOptionsCache{}, nil, params)
 
 			assert.ErrorContains(t, err, tc.ErrContains)
 		})
@@ -90,7 +106,7 @@ func TestCallParamsMetadata(t *testing.T) {
 
 			testRuntime, params := newParamsTestRuntime(t, tc.JSON)
 
-			p, err := newCallParams(testRuntime.VU, params)
+			p, err := newCallParams(testRuntime.VU, &extOptionsCache{}, nil, params)
 
 			require.NoError(t, err)
 			assert.Equal(t, tc.ExpectedMetadata, p.Metadata)
@@ -131,7 +147,7 @@ func TestCallParamsTimeOutParse(t *testing.T) {
 
 			testRuntime, params := newParamsTestRuntime(t, tc.JSON)
 
-			p, err := newCallParams(testRuntime.VU, params)
+			p, err := newCallParams(testRuntime.VU, &extOptionsCache{}, nil, params)
 			require.NoError(t, err)
 
 			assert.Equal(t, tc.Timeout, p.Timeout)
@@ -179,3 +195,105 @@ func newParamsTestRuntime(t *testing.T, paramsJSON string) (*modulestest.Runtime
 
 	return testRuntime, params
 }
+
+func TestConnectParamsFlowControl(t *testing.T) {
+	t.Parallel()
+
+	testRuntime, params := newParamsTestRuntime(t,
+		`{ initialWindowSize: 1048576, initialConnWindowSize: 4194304, writeBufferSize: 65536, readBufferSize: 131072 }`)
+
+	p, err := newConnectParams(testRuntime.VU, params)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1048576), p.InitialWindowSize)
+	assert.Equal(t, int64(4194304), p.InitialConnWindowSize)
+	assert.Equal(t, int64(65536), p.WriteBufferSize)
+	assert.Equal(t, int64(131072), p.ReadBufferSize)
+	assert.Len(t, flowControlDialOptions(p), 4)
+
+	testCases := []struct {
+		Name        string
+		JSON        string
+		ErrContains string
+	}{
+		{
+			Name:        "SmallWindow",
+			JSON:        `{ initialWindowSize: 1024 }`,
+			ErrContains: `invalid initialWindowSize value: '1024', it needs to be an integer between 65536 and 2147483647`,
+		},
+		{
+			Name:        "NonIntegerConnWindow",
+			JSON:        `{ initialConnWindowSize: "1MB" }`,
+			ErrContains: `invalid initialConnWindowSize value`,
+		},
+		{
+			Name:        "NegativeBuffer",
+			JSON:        `{ writeBufferSize: -1 }`,
+			ErrContains: `invalid writeBufferSize value: '-1', it needs to be a positive integer`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			testRuntime, params := newParamsTestRuntime(t, tc.JSON)
+
+			_, err := newConnectParams(testRuntime.VU, params)
+
+			assert.ErrorContains(t, err, tc.ErrContains)
+		})
+	}
+}
+
+func TestConnectParamsSocket(t *testing.T) {
+	t.Parallel()
+
+	testRuntime, params := newParamsTestRuntime(t,
+		`{ tcpKeepAlive: "15s", tcpNoDelay: false, localAddress: ["10.0.0.1", "10.0.0.2"] }`)
+
+	p, err := newConnectParams(testRuntime.VU, params)
+	require.NoError(t, err)
+
+	require.NotNil(t, p.Socket)
+	assert.Equal(t, 15*time.Second, p.Socket.KeepAlive)
+	require.NotNil(t, p.Socket.NoDelay)
+	assert.False(t, *p.Socket.NoDelay)
+	assert.Len(t, p.Socket.LocalAddrs, 2)
+
+	testRuntime, params = newParamsTestRuntime(t, `{ tcpKeepAlive: false, localAddress: "::1" }`)
+
+	p, err = newConnectParams(testRuntime.VU, params)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), p.Socket.KeepAlive)
+	assert.Equal(t, "::1", p.Socket.LocalAddrs[0].String())
+
+	testRuntime, params = newParamsTestRuntime(t, `{ localAddress: "eth0" }`)
+
+	_, err = newConnectParams(testRuntime.VU, params)
+	assert.ErrorContains(t, err, `invalid localAddress value: '"eth0"', it needs to be an IP address or a list of them`)
+}
+
+func TestConnectParamsDNSRefresh(t *testing.T) {
+	t.Parallel()
+
+	testRuntime, params := newParamsTestRuntime(t, `{ dnsRefresh: { interval: "10s", onError: true } }`)
+
+	p, err := newConnectParams(testRuntime.VU, params)
+	require.NoError(t, err)
+	assert.Equal(t, &grpcext.DNSRefresh{Interval: 10 * time.Second, OnError: true}, p.DNSRefresh)
+
+	for json, errContains := range map[string]string{
+		`{ dnsRefresh: {} }`:                  `invalid dnsRefresh value: it needs an interval or onError`,
+		`{ dnsRefresh: { interval: "-1s" } }`: `invalid dnsRefresh interval value`,
+		`{ dnsRefresh: { onError: "yes" } }`:  `invalid dnsRefresh onError value`,
+		`{ dnsRefresh: { ttl: "10s" } }`:      `unknown dnsRefresh param: "ttl"`,
+	} {
+		testRuntime, params := newParamsTestRuntime(t, json)
+
+		_, err := newConnectParams(testRuntime.VU, params)
+		assert.ErrorContains(t, err, errContains)
+	}
+}
@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"errors"
+
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errBudgetExceeded is the error of the calls which deadline has been exceeded before sending them
+var errBudgetExceeded = errors.New("the call's deadline has been exceeded before sending it") //nolint:gochecknoglobals
+
+// admit applies the client-side policies (health gating, circuit breaking, throttling) to the call,
+// it returns a locally finished response if the call has been rejected.
+func (c *Client) admit(tagsAndMeta *metrics.TagsAndMeta) (*grpcext.Response, error) {
+	if c.health != nil {
+		paused, serving := c.health.wait(c.vu.Context())
+		if paused > 0 {
+			c.pushMetric(c.metrics.HealthGatingPauseDuration, tagsAndMeta, metrics.D(paused))
+		}
+
+		if !serving {
+			return grpcext.NewStatusResponse(status.New(codes.Unavailable, errNotServing.Error())), nil
+		}
+	}
+
+	if c.breaker != nil {
+		allowed, transition := c.breaker.allow()
+		if err := c.circuitTransited(transition, tagsAndMeta); err != nil {
+			return nil, err
+		}
+
+		if !allowed {
+			c.setStatusTag(tagsAndMeta, codes.Unavailable)
+			tagsAndMeta.SetTag(grpcext.ErrorKindTag, grpcext.ErrorKindCircuitBreaker)
+			c.pushMetric(c.metrics.CircuitBreakerRejections, tagsAndMeta, 1)
+
+			resp := grpcext.NewStatusResponse(status.New(codes.Unavailable, errCircuitOpen.Error()))
+			resp.ErrorKind = grpcext.ErrorKindCircuitBreaker
+
+			return resp, nil
+		}
+	}
+
+	if c.throttler != nil && !c.throttler.allow() {
+		c.releaseBreaker()
+		c.setStatusTag(tagsAndMeta, codes.Unavailable)
+		c.pushMetric(c.metrics.ThrottlingRejections, tagsAndMeta, 1)
+
+		return grpcext.NewStatusResponse(status.New(codes.Unavailable, errThrottled.Error())), nil
+	}
+
+	return nil, nil //nolint:nilnil
+}
+
+// observe records the result of the call in the client-side policies.
+func (c *Client) observe(code codes.Code, tagsAndMeta *metrics.TagsAndMeta) error {
+	if c.throttler != nil {
+		c.throttler.record(code)
+	}
+
+	if c.breaker != nil {
+		return c.circuitTransited(c.breaker.record(code), tagsAndMeta)
+	}
+
+	return nil
+}
+
+// releaseBreaker gives up the call allowed by the circuit breaker, when it isn't sent
+func (c *Client) releaseBreaker() {
+	if c.breaker != nil {
+		c.breaker.release()
+	}
+}
+
+// stopHealthGate stops the health gating of the client if it's enabled
+func (c *Client) stopHealthGate() {
+	if c.health == nil {
+		return
+	}
+
+	c.health.stop()
+	c.health = nil
+}
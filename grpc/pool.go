@@ -0,0 +1,237 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+)
+
+// errPoolClosed is returned by the pool once the test has ended
+var errPoolClosed = errors.New("the connection pool is closed, the test has ended")
+
+// connPool is the pool of the connections shared by the clients of all the VUs connected with pool: true,
+// so the clients created per iteration don't dial (and leak) a new connection each time, and to model
+// the "few connections, many streams" production patterns. The connections are keyed by the target
+// and the params they're dialed with, and stay open until the test ends. The zero value is ready to use.
+type connPool struct {
+	mu      sync.Mutex
+	entries map[string]*pooledConn
+	closed  bool
+}
+
+// pooledConn is the pooled connection, done is closed once it's dialed
+type pooledConn struct {
+	done chan struct{}
+	conn dialedConn
+	err  error
+}
+
+// dialedConn is the connection dialed for the other clients than the dialing one (e.g. the pooled
+// or the prewarmed ones) with its owner and its SPIFFE source if any, which are handed over with it.
+type dialedConn struct {
+	conn       *grpcext.Conn
+	owner      *connOwner
+	svidSource *workloadapi.X509Source
+}
+
+// close closes the connection and its SPIFFE source
+func (d dialedConn) close() error {
+	err := d.conn.Close()
+	if d.svidSource != nil {
+		if serr := d.svidSource.Close(); err == nil {
+			err = serr
+		}
+	}
+
+	return err
+}
+
+// dialFunc dials a new connection
+type dialFunc func() (dialedConn, error)
+
+// get returns the pooled connection with the key or dials a new one, the concurrent calls for the same
+// connection wait for the single dial. The failed dials aren't kept. The connection's own samples and events
+// are reported by the client taking it, as the last one using it.
+func (p *connPool) get(ctx context.Context, key string, c *Client, dial dialFunc) (*grpcext.Conn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+
+		return nil, errPoolClosed
+	}
+	if p.entries == nil {
+		p.entries = make(map[string]*pooledConn)
+	}
+
+	e, ok := p.entries[key]
+	if !ok {
+		e = &pooledConn{done: make(chan struct{})}
+		p.entries[key] = e
+	}
+	p.mu.Unlock()
+
+	if ok {
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else {
+		e.conn, e.err = dial()
+		if e.err != nil {
+			p.mu.Lock()
+			delete(p.entries, key)
+			p.mu.Unlock()
+		}
+		close(e.done)
+	}
+
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.conn.owner.set(c)
+
+	return e.conn.conn, nil
+}
+
+// close closes the pooled connections, the following gets fail. It returns the first error.
+func (p *connPool) close() error {
+	p.mu.Lock()
+	p.closed = true
+	entries := p.entries
+	p.entries = nil
+	p.mu.Unlock()
+
+	var err error
+	for _, e := range entries {
+		<-e.done
+		if e.err != nil {
+			continue
+		}
+		if cerr := e.conn.close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// connOwner is the client reporting a connection's own samples and events (its dials, handshakes,
+// GOAWAYs and reconnects), the calls' samples are bound to the VUs making them by their context instead.
+// The pooled and the prewarmed connections are handed over to the clients taking them.
+type connOwner struct {
+	client atomic.Pointer[ownerClient]
+	// connEvents observes the connection's events if the client dialing it listens to them
+	connEvents *grpcext.ConnEvents
+}
+
+// ownerClient is the connection's owner with the tags of the connection's samples,
+// they're captured on the owner VU's event loop
+type ownerClient struct {
+	*Client
+	tagsAndMeta metrics.TagsAndMeta
+}
+
+// newConnOwner returns the owner of the connection dialed by the client
+func newConnOwner(c *Client) *connOwner {
+	o := &connOwner{}
+	o.set(c)
+
+	return o
+}
+
+// set hands the connection over to the client
+func (o *connOwner) set(c *Client) {
+	tagsAndMeta := c.connTagsAndMeta()
+	tagsAndMeta.Tags = tagsAndMeta.Tags.With("target", c.addr)
+
+	o.client.Store(&ownerClient{Client: c, tagsAndMeta: tagsAndMeta})
+}
+
+// get returns the connection's current owner
+func (o *connOwner) get() *ownerClient {
+	return o.client.Load()
+}
+
+// state returns the state of the owner's VU
+func (o *connOwner) state() *lib.State {
+	return o.get().vu.State()
+}
+
+// vuContext returns the VU's context the calls are made with, it binds their samples to the VU,
+// since the connection could be owned by a client of another VU.
+func (c *Client) vuContext() context.Context {
+	return grpcext.WithVUState(c.vu.Context(), c.vu.State())
+}
+
+// connKey returns the key of the pooled and the prewarmed connections to the target, made of the params
+// the connections are dialed with. The ones the clients apply to their calls (e.g. the callCredentials'
+// callback, the policies or the tags) don't split the connections.
+func (p *connectParams) connKey(addr string) (string, error) {
+	dialed := struct {
+		Addr                  string
+		Plaintext             bool
+		TLS                   map[string]interface{}
+		Authority             string
+		Token                 string
+		Credentials           *credentialsParams
+		Proxy                 string
+		Endpoints             []string
+		Channels              int64
+		MaxReceiveSize        int64
+		MaxSendSize           int64
+		InitialWindowSize     int64
+		InitialConnWindowSize int64
+		WriteBufferSize       int64
+		ReadBufferSize        int64
+		Socket                *grpcext.SocketOptions
+		Backoff               *backoffParams
+		DNSRefresh            *grpcext.DNSRefresh
+		LoadBalancing         *grpcext.LoadBalancing
+		IdleTimeout           int64
+		MaxConcurrentStreams  *streamLimitParams
+		Channelz              bool
+	}{
+		Addr:                  addr,
+		Plaintext:             p.IsPlaintext,
+		TLS:                   p.TLS,
+		Authority:             p.Authority,
+		Credentials:           p.Credentials,
+		Endpoints:             p.Endpoints,
+		Channels:              p.Channels,
+		MaxReceiveSize:        p.MaxReceiveSize,
+		MaxSendSize:           p.MaxSendSize,
+		InitialWindowSize:     p.InitialWindowSize,
+		InitialConnWindowSize: p.InitialConnWindowSize,
+		WriteBufferSize:       p.WriteBufferSize,
+		ReadBufferSize:        p.ReadBufferSize,
+		Socket:                p.Socket,
+		Backoff:               p.Backoff,
+		DNSRefresh:            p.DNSRefresh,
+		LoadBalancing:         p.LoadBalancing,
+		IdleTimeout:           int64(p.IdleTimeout),
+		MaxConcurrentStreams:  p.MaxConcurrentStreams,
+		Channelz:              p.Channelz,
+	}
+	if p.CallCredentials != nil {
+		dialed.Token = p.CallCredentials.Token
+	}
+	if p.Proxy != nil {
+		dialed.Proxy = p.Proxy.String()
+	}
+
+	b, err := json.Marshal(dialed)
+	if err != nil {
+		return "", fmt.Errorf("the pooled connection's params need to be serializable: %w", err)
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnKey(t *testing.T) {
+	t.Parallel()
+
+	key := func(addr string, code string) string {
+		t.Helper()
+
+		testRuntime, params := newParamsTestRuntime(t, code)
+		p, err := newConnectParams(testRuntime.VU, params)
+		require.NoError(t, err)
+
+		k, err := p.connKey(addr)
+		require.NoError(t, err)
+
+		return k
+	}
+
+	assert.Equal(t, key("a:1", `{plaintext: true, timeout: "5s"}`), key("a:1", `{timeout: "5s", plaintext: true}`))
+	assert.NotEqual(t, key("a:1", `{plaintext: true}`), key("b:1", `{plaintext: true}`))
+	assert.NotEqual(t, key("a:1", `{plaintext: true}`), key("a:1", `{plaintext: false}`))
+
+	// the params applied to the calls don't split the connections
+	assert.Equal(t, key("a:1", `{plaintext: true}`), key("a:1", `{plaintext: true, tags: {region: "eu"}}`))
+	assert.Equal(t,
+		key("a:1", `{callCredentials: {type: "bearer", token: () => "a"}}`),
+		key("a:1", `{callCredentials: {type: "bearer", token: () => "b"}}`))
+	assert.NotEqual(t,
+		key("a:1", `{callCredentials: {type: "bearer", token: "a"}}`),
+		key("a:1", `{callCredentials: {type: "bearer", token: "b"}}`))
+}
+
+func TestConnPool(t *testing.T) {
+	t.Parallel()
+
+	testRuntime, _ := newParamsTestRuntime(t, `{}`)
+	c := &Client{vu: testRuntime.VU}
+
+	var (
+		pool  connPool
+		dials int32
+	)
+
+	conn := &grpcext.Conn{}
+	dial := func() (dialedConn, error) {
+		atomic.AddInt32(&dials, 1)
+		time.Sleep(10 * time.Millisecond)
+
+		return dialedConn{conn: conn, owner: &connOwner{}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			got, err := pool.get(context.Background(), "a:1", c, dial)
+			assert.NoError(t, err)
+			assert.Same(t, conn, got)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dials))
+
+	// the failed dials aren't kept
+	failed := func() (dialedConn, error) {
+		return dialedConn{}, errors.New("refused")
+	}
+	_, err := pool.get(context.Background(), "b:1", c, failed)
+	require.EqualError(t, err, "refused")
+
+	got, err := pool.get(context.Background(), "b:1", c, dial)
+	require.NoError(t, err)
+	assert.Same(t, conn, got)
+
+	// the connection is handed over to the client taking it
+	other := &Client{vu: testRuntime.VU, addr: "b:1"}
+	_, err = pool.get(context.Background(), "b:1", other, dial)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&dials))
+
+	pool.mu.Lock()
+	owner := pool.entries["b:1"].conn.owner.get()
+	pool.mu.Unlock()
+	assert.Same(t, other, owner.Client)
+}
@@ -0,0 +1,157 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+// errPrewarmedClosed is returned by the prewarm once the test has ended
+var errPrewarmedClosed = errors.New("the prewarmed connections are closed, the test has ended")
+
+// prewarmedConns are the connections dialed in advance by the client.prewarm (e.g. in the setup),
+// each of them is handed over to a single client connecting to the target with the same params.
+// They're keyed like the pooled connections, and the ones which aren't taken are closed once the test ends.
+// The zero value is ready to use.
+type prewarmedConns struct {
+	mu     sync.Mutex
+	conns  map[string][]dialedConn
+	closed bool
+}
+
+// put adds the prewarmed connection, it's closed right away if the test has ended
+func (p *prewarmedConns) put(key string, conn dialedConn) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		_ = conn.close()
+
+		return errPrewarmedClosed
+	}
+
+	if p.conns == nil {
+		p.conns = make(map[string][]dialedConn)
+	}
+	p.conns[key] = append(p.conns[key], conn)
+
+	return nil
+}
+
+// take removes a prewarmed connection with the key and hands it over to the client,
+// ok is false if there's none
+func (p *prewarmedConns) take(key string, c *Client) (dialedConn, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.conns[key]
+	if len(conns) == 0 {
+		return dialedConn{}, false
+	}
+
+	conn := conns[len(conns)-1]
+	if len(conns) == 1 {
+		delete(p.conns, key)
+	} else {
+		p.conns[key] = conns[:len(conns)-1]
+	}
+	conn.owner.set(c)
+
+	return conn, true
+}
+
+// close closes the prewarmed connections which haven't been taken, it returns the first error
+func (p *prewarmedConns) close() error {
+	p.mu.Lock()
+	p.closed = true
+	conns := p.conns
+	p.conns = nil
+	p.mu.Unlock()
+
+	var err error
+	for _, keyed := range conns {
+		for _, conn := range keyed {
+			if cerr := conn.close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+
+	return err
+}
+
+// Prewarm dials the count of the connections to the target in advance, e.g. in the setup, so the ramp-up's latencies
+// don't include the connection storm. The connections are validated like the connect's ones (the xDS resolution
+// and the TLS handshakes included) each within the timeout, and each of them is handed over to a client connecting
+// (in any VU) to the target with the same params instead of dialing. It returns the count of the prewarmed connections.
+func (c *Client) Prewarm(addr string, count int64, params goja.Value) (int64, error) {
+	state := c.vu.State()
+	if state == nil {
+		return 0, common.NewInitContextError("prewarming the connections in the init context is not supported")
+	}
+	if count < 1 {
+		return 0, fmt.Errorf("invalid prewarm count: %d, it needs to be positive", count)
+	}
+
+	opts, err := c.extOptions.load(c.vu)
+	if err != nil {
+		return 0, err
+	}
+	if opts != nil {
+		params = withDefaults(c.vu.Runtime(), opts.Connect, params)
+		if addr == "" {
+			addr = opts.Target
+		}
+	}
+
+	p, err := newConnectParams(c.vu, params)
+	if err != nil {
+		return 0, fmt.Errorf("invalid grpc.prewarm() parameters: %w", err)
+	}
+	if p.Pool {
+		return 0, errors.New("the pooled connections can't be prewarmed, they're dialed once anyway")
+	}
+
+	key, err := c.connKey(p, addr)
+	if err != nil {
+		return 0, err
+	}
+
+	// the dial sets the SPIFFE source of the connection as the client's one, the client's own one is kept
+	own := c.svidSource
+	defer func() { c.svidSource = own }()
+
+	for i := int64(0); i < count; i++ {
+		c.svidSource = nil
+
+		conn, err := c.prewarm(state, addr, p)
+		if err != nil {
+			return i, fmt.Errorf("can't prewarm the connection to %s: %w", addr, err)
+		}
+
+		if err = c.prewarmed.put(key, conn); err != nil {
+			return i, err
+		}
+	}
+
+	return count, nil
+}
+
+// prewarm dials a prewarmed connection, each one within the connect's timeout
+func (c *Client) prewarm(state *lib.State, addr string, p *connectParams) (dialedConn, error) {
+	ctx, cancel := context.WithTimeout(c.vuContext(), p.Timeout)
+	defer cancel()
+
+	owner := newConnOwner(c)
+	conn, err := c.dial(ctx, state, addr, p, owner)
+	if err != nil {
+		return dialedConn{}, err
+	}
+
+	return dialedConn{conn: conn, owner: owner, svidSource: c.svidSource}, nil
+}
@@ -0,0 +1,121 @@
+package grpc
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.k6.io/k6/lib/fsext"
+)
+
+// protoExt is the extension of the proto files
+const protoExt = ".proto"
+
+// expandProtoFiles expands the glob patterns (with the ** matching any number of the directories)
+// and the directories into the proto files found under the import paths, the other file names are kept as is.
+// The found files are named relative to their import path, as the parser expects them.
+func expandProtoFiles(
+	fileSystem fsext.Fs, absPath func(string) string, importPaths []string, filenames []string,
+) ([]string, error) {
+	result := make([]string, 0, len(filenames))
+	seen := make(map[string]struct{})
+	add := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			result = append(result, name)
+		}
+	}
+
+	for _, filename := range filenames {
+		isGlob := strings.ContainsAny(filename, "*?[")
+		pattern := strings.Split(path.Clean(filepath.ToSlash(filename)), "/")
+
+		var found []string
+		isDir := false
+
+		for _, importPath := range importPaths {
+			root := absPath(importPath)
+
+			// the directories are walked alone, the patterns need the whole import path
+			walkRoot := root
+			if !isGlob {
+				walkRoot = filepath.Join(root, filepath.FromSlash(filename))
+				if ok, _ := fsext.IsDir(fileSystem, walkRoot); !ok {
+					continue
+				}
+				isDir = true
+			}
+
+			err := fsext.Walk(fileSystem, walkRoot, func(p string, info fs.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.IsDir() || !strings.HasSuffix(p, protoExt) {
+					return nil
+				}
+
+				rel, err := filepath.Rel(root, p)
+				if err != nil {
+					return err
+				}
+				name := strings.Split(filepath.ToSlash(rel), "/")
+
+				if !isGlob || matchGlob(pattern, name) {
+					found = append(found, strings.Join(name, "/"))
+				}
+
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("can't walk the import path %q: %w", importPath, err)
+			}
+		}
+
+		if !isGlob && !isDir {
+			add(filename)
+			continue
+		}
+
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no proto files match %q in the import paths", filename)
+		}
+
+		sort.Strings(found)
+		for _, name := range found {
+			add(name)
+		}
+	}
+
+	return result, nil
+}
+
+// matchGlob reports whether the path's segments match the pattern's segments,
+// the ** segment matches any number of the segments
+func matchGlob(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlob(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
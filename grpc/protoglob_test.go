@@ -0,0 +1,33 @@
+package grpc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{pattern: "*.proto", name: "svc.proto", match: true},
+		{pattern: "*.proto", name: "api/svc.proto", match: false},
+		{pattern: "api/*.proto", name: "api/svc.proto", match: true},
+		{pattern: "**/*.proto", name: "svc.proto", match: true},
+		{pattern: "**/*.proto", name: "api/v1/svc.proto", match: true},
+		{pattern: "api/**/svc.proto", name: "api/svc.proto", match: true},
+		{pattern: "api/**/svc.proto", name: "api/v1/v2/svc.proto", match: true},
+		{pattern: "api/**/svc.proto", name: "other/v1/svc.proto", match: false},
+		{pattern: "api/v?/*.proto", name: "api/v1/svc.proto", match: true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, matchGlob(strings.Split(tt.pattern, "/"), strings.Split(tt.name, "/")),
+			"%s ~ %s", tt.pattern, tt.name)
+	}
+}
@@ -0,0 +1,139 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/metrics"
+)
+
+// rateLimitParams is the parameters of the client's pacing of the unary calls.
+type rateLimitParams struct {
+	RPS float64
+	// Burst is the number of the calls which could be sent at once after an inactivity
+	Burst int64
+}
+
+// newRateLimitParams constructs the rate limit parameters from the input value,
+// either the rps or an object with the rps and the burst.
+func newRateLimitParams(rt *goja.Runtime, input goja.Value) (*rateLimitParams, error) {
+	result := &rateLimitParams{Burst: 1}
+
+	if common.IsNullish(input) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if rps, ok := toFloat64(input.Export()); ok {
+		if rps <= 0 {
+			return result, fmt.Errorf("invalid rateLimit value: '%#v', it needs to be a positive number", input.Export())
+		}
+		result.RPS = rps
+
+		return result, nil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf(
+			"invalid rateLimit value: '%#v', it needs to be a positive number or an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "rps":
+			var ok bool
+			result.RPS, ok = toFloat64(v)
+			if !ok || result.RPS <= 0 {
+				return result, fmt.Errorf("invalid rateLimit rps value: '%#v', it needs to be a positive number", v)
+			}
+		case "burst":
+			var ok bool
+			result.Burst, ok = v.(int64)
+			if !ok || result.Burst <= 0 {
+				return result, fmt.Errorf("invalid rateLimit burst value: '%#v', it needs to be a positive integer", v)
+			}
+		default:
+			return result, fmt.Errorf("unknown rateLimit param: %q", k)
+		}
+	}
+
+	if result.RPS == 0 {
+		return result, fmt.Errorf("invalid rateLimit value: the rps is required")
+	}
+
+	return result, nil
+}
+
+// rateLimiter paces the client's calls with the token bucket, implemented as
+// the generic cell rate algorithm: a call conforms if it isn't earlier than
+// the theoretical arrival time by more than the burst allows.
+type rateLimiter struct {
+	mu sync.Mutex
+
+	interval  time.Duration
+	tolerance time.Duration
+	// tat is the theoretical arrival time of the next call
+	tat time.Time
+
+	now func() time.Time
+}
+
+func newRateLimiter(p *rateLimitParams) *rateLimiter {
+	interval := time.Duration(float64(time.Second) / p.RPS)
+
+	return &rateLimiter{
+		interval:  interval,
+		tolerance: time.Duration(p.Burst-1) * interval,
+		now:       time.Now,
+	}
+}
+
+// reserve reserves the slot of the call, it returns the delay the call needs to wait for
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if l.tat.Before(now) {
+		l.tat = now
+	}
+
+	delay := l.tat.Sub(now) - l.tolerance
+	l.tat = l.tat.Add(l.interval)
+
+	if delay < 0 {
+		return 0
+	}
+
+	return delay
+}
+
+// pace waits for the call's slot, the time spent waiting is reported as the grpc_rate_limit_duration
+func (c *Client) pace(ctx context.Context, tagsAndMeta *metrics.TagsAndMeta) {
+	if c.rateLimiter == nil {
+		return
+	}
+
+	delay := c.rateLimiter.reserve()
+	if delay == 0 {
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	start := time.Now()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	c.pushMetric(c.metrics.RateLimitDuration, tagsAndMeta, metrics.D(time.Since(start)))
+}
@@ -0,0 +1,28 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	l := newRateLimiter(&rateLimitParams{RPS: 10, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	// the burst is sent at once, the following calls are paced by the rate
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, 100*time.Millisecond, l.reserve())
+	assert.Equal(t, 200*time.Millisecond, l.reserve())
+
+	// the inactivity refills the bucket, but not over the burst
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, 100*time.Millisecond, l.reserve())
+}
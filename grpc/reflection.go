@@ -0,0 +1,260 @@
+package grpc
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// reflectOnReconnectAuto repeats the reflection if the client reconnects to another target
+	reflectOnReconnectAuto = "auto"
+	// reflectOnReconnectAlways repeats the reflection on each reconnect
+	reflectOnReconnectAlways = "always"
+	// reflectOnReconnectNever reuses the descriptors reflected by the previous connections
+	reflectOnReconnectNever = "never"
+)
+
+// reflectionLimiter limits the number of the concurrent reflection exchanges made
+// by all the VUs, so many VUs connecting with reflect: true at the ramp-up
+// don't dominate the server's CPU. The zero value is ready to use.
+type reflectionLimiter struct {
+	mu     sync.Mutex
+	active int
+	// released is closed and replaced on each release, waking up the queued exchanges
+	released chan struct{}
+}
+
+// acquire waits until there are less than the limit of the active exchanges and
+// starts a new one, it returns the time spent in the queue. A non-positive limit
+// means no limit.
+func (l *reflectionLimiter) acquire(ctx context.Context, limit int64) (time.Duration, error) {
+	start := time.Now()
+
+	for {
+		l.mu.Lock()
+		if limit <= 0 || int64(l.active) < limit {
+			l.active++
+			l.mu.Unlock()
+
+			return time.Since(start), nil
+		}
+
+		if l.released == nil {
+			l.released = make(chan struct{})
+		}
+		released := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return time.Since(start), ctx.Err()
+		}
+	}
+}
+
+// release finishes the exchange started by the acquire
+func (l *reflectionLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+
+	if l.released != nil {
+		close(l.released)
+		l.released = nil
+	}
+}
+
+// maxReflectionCacheEntries bounds the reflectionCache, the oldest entries are evicted
+const maxReflectionCacheEntries = 256
+
+// reflectionCache is the file descriptors fetched by all the VUs using the reflection, keyed by the target
+// and the connection's identity, so the reflection happens once per target. The zero value is ready to use.
+type reflectionCache struct {
+	mu      sync.Mutex
+	entries map[string]*reflectionCacheEntry
+	// order is the entries' keys from the oldest one
+	order *list.List
+}
+
+// reflectionCacheEntry is the result of the target's reflection, done is closed once it's fetched
+type reflectionCacheEntry struct {
+	done  chan struct{}
+	fdset *descriptorpb.FileDescriptorSet
+	err   error
+	elem  *list.Element
+}
+
+// get returns the cached file descriptors of the target, the concurrent calls for the same target
+// wait for the single fetch. The failed fetches aren't cached.
+func (rc *reflectionCache) get(
+	ctx context.Context, key string, fetch func() (*descriptorpb.FileDescriptorSet, error),
+) (*descriptorpb.FileDescriptorSet, error) {
+	rc.mu.Lock()
+	if rc.entries == nil {
+		rc.entries = make(map[string]*reflectionCacheEntry)
+		rc.order = list.New()
+	}
+
+	if e, ok := rc.entries[key]; ok {
+		rc.mu.Unlock()
+
+		select {
+		case <-e.done:
+			return e.fdset, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if rc.order.Len() >= maxReflectionCacheEntries {
+		oldest := rc.order.Front()
+		delete(rc.entries, rc.order.Remove(oldest).(string)) //nolint:forcetypeassert
+	}
+
+	e := &reflectionCacheEntry{done: make(chan struct{})}
+	e.elem = rc.order.PushBack(key)
+	rc.entries[key] = e
+	rc.mu.Unlock()
+
+	e.fdset, e.err = fetch()
+	if e.err != nil {
+		rc.mu.Lock()
+		if rc.entries[key] == e {
+			delete(rc.entries, key)
+			rc.order.Remove(e.elem)
+		}
+		rc.mu.Unlock()
+	}
+	close(e.done)
+
+	return e.fdset, e.err
+}
+
+// reflect fetches the server's file descriptors using the reflection protocol or takes them
+// from the cache shared by all the VUs, unless it's disabled. Without the symbols, the descriptors
+// of all the services are fetched.
+func (c *Client) reflect(
+	ctx context.Context, p *connectParams, symbols []string,
+) (*descriptorpb.FileDescriptorSet, error) {
+	if !p.ReflectCache {
+		return c.reflectLimited(ctx, p, symbols)
+	}
+
+	key, err := p.reflectionKey(c.addr, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.reflectionCache.get(ctx, key, func() (*descriptorpb.FileDescriptorSet, error) {
+		return c.reflectLimited(ctx, p, symbols)
+	})
+}
+
+// reflectionKey returns the key of the target's reflection in the reflectionCache, the servers could
+// expose the different descriptors depending on the client's identity (its TLS certificate, credentials
+// or the reflection's metadata), so they're a part of it.
+func (p *connectParams) reflectionKey(addr string, symbols []string) (string, error) {
+	sorted := append([]string(nil), symbols...)
+	sort.Strings(sorted)
+
+	reflected := struct {
+		Addr        string
+		Plaintext   bool
+		TLS         map[string]interface{}
+		Authority   string
+		Token       string
+		Credentials *credentialsParams
+		Proxy       string
+		Endpoints   []string
+		Metadata    metadata.MD
+		Symbols     []string
+	}{
+		Addr:        addr,
+		Plaintext:   p.IsPlaintext,
+		TLS:         p.TLS,
+		Authority:   p.Authority,
+		Credentials: p.Credentials,
+		Endpoints:   p.Endpoints,
+		Metadata:    p.ReflectionMetadata,
+		Symbols:     sorted,
+	}
+	if p.CallCredentials != nil {
+		reflected.Token = p.CallCredentials.Token
+	}
+	if p.Proxy != nil {
+		reflected.Proxy = p.Proxy.String()
+	}
+
+	b, err := json.Marshal(reflected)
+	if err != nil {
+		return "", fmt.Errorf("the cached reflection's params need to be serializable: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// reflectLimited fetches the server's file descriptors using the reflection protocol,
+// waiting for its turn if the number of the concurrent exchanges is limited.
+func (c *Client) reflectLimited(
+	ctx context.Context, p *connectParams, symbols []string,
+) (*descriptorpb.FileDescriptorSet, error) {
+	if p.MaxConcurrentReflections <= 0 {
+		return c.conn.Reflect(ctx, symbols...)
+	}
+
+	queued, err := c.reflections.acquire(ctx, p.MaxConcurrentReflections)
+	if err != nil {
+		return nil, fmt.Errorf("can't wait for the reflection: %w", err)
+	}
+	defer c.reflections.release()
+
+	tagsAndMeta := c.connTagsAndMeta()
+	c.pushMetric(c.metrics.ReflectionQueueDuration, &tagsAndMeta, metrics.D(queued))
+
+	return c.conn.Reflect(ctx, symbols...)
+}
+
+// reflectedSetup is the reflection the client's descriptors were populated with,
+// it's repeated by the reconnects which don't reflect on their own.
+type reflectedSetup struct {
+	addr     string
+	symbols  []string
+	metadata metadata.MD
+}
+
+// reflectOnReconnect makes the connection reflect the target like the client's previous one did,
+// unless its own reflection is set. The previous reflection's symbols and metadata apply
+// if the connection doesn't set them.
+func (c *Client) reflectOnReconnect(addr string, p *connectParams) {
+	if p.UseReflectionProtocol || c.reflected == nil {
+		return
+	}
+
+	switch p.ReflectOnReconnect {
+	case reflectOnReconnectNever:
+		return
+	case reflectOnReconnectAuto:
+		if addr == c.reflected.addr {
+			return
+		}
+	}
+
+	p.UseReflectionProtocol = true
+	if len(p.ReflectionSymbols) == 0 {
+		p.ReflectionSymbols = c.reflected.symbols
+	}
+	if p.ReflectionMetadata.Len() == 0 {
+		p.ReflectionMetadata = c.reflected.metadata
+	}
+}
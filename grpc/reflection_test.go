@@ -0,0 +1,141 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestReflectionLimiter(t *testing.T) {
+	t.Parallel()
+
+	var l reflectionLimiter
+
+	queued, err := l.acquire(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Less(t, queued, 10*time.Millisecond)
+
+	time.AfterFunc(50*time.Millisecond, l.release)
+
+	queued, err = l.acquire(context.Background(), 1)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, queued, 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = l.acquire(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// no limit
+	_, err = l.acquire(context.Background(), 0)
+	assert.NoError(t, err)
+}
+
+func TestReflectionCache(t *testing.T) {
+	t.Parallel()
+
+	var rc reflectionCache
+	var fetches int32
+	fetch := func() (*descriptorpb.FileDescriptorSet, error) {
+		atomic.AddInt32(&fetches, 1)
+		time.Sleep(20 * time.Millisecond)
+
+		return &descriptorpb.FileDescriptorSet{}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			fdset, err := rc.get(context.Background(), "localhost:8080", fetch)
+			assert.NoError(t, err)
+			assert.NotNil(t, fdset)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	_, err := rc.get(context.Background(), "localhost:8081", fetch)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func TestReflectionCache_Error(t *testing.T) {
+	t.Parallel()
+
+	var rc reflectionCache
+
+	_, err := rc.get(context.Background(), "localhost:8080", func() (*descriptorpb.FileDescriptorSet, error) {
+		return nil, errors.New("unavailable")
+	})
+	assert.EqualError(t, err, "unavailable")
+
+	fdset, err := rc.get(context.Background(), "localhost:8080", func() (*descriptorpb.FileDescriptorSet, error) {
+		return &descriptorpb.FileDescriptorSet{}, nil
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, fdset)
+}
+
+func TestReflectionCache_Bounded(t *testing.T) {
+	t.Parallel()
+
+	var rc reflectionCache
+	var fetches int32
+	fetch := func() (*descriptorpb.FileDescriptorSet, error) {
+		atomic.AddInt32(&fetches, 1)
+
+		return &descriptorpb.FileDescriptorSet{}, nil
+	}
+
+	for i := 0; i <= maxReflectionCacheEntries; i++ {
+		_, err := rc.get(context.Background(), fmt.Sprintf("localhost:%d", i), fetch)
+		require.NoError(t, err)
+	}
+	assert.Len(t, rc.entries, maxReflectionCacheEntries)
+
+	// the oldest entry has been evicted, the newest ones are cached
+	_, err := rc.get(context.Background(), fmt.Sprintf("localhost:%d", maxReflectionCacheEntries), fetch)
+	require.NoError(t, err)
+	assert.Equal(t, int32(maxReflectionCacheEntries+1), atomic.LoadInt32(&fetches))
+
+	_, err = rc.get(context.Background(), "localhost:0", fetch)
+	require.NoError(t, err)
+	assert.Equal(t, int32(maxReflectionCacheEntries+2), atomic.LoadInt32(&fetches))
+}
+
+func TestReflectionKey(t *testing.T) {
+	t.Parallel()
+
+	key := func(p *connectParams, symbols ...string) string {
+		k, err := p.reflectionKey("localhost:8080", symbols)
+		require.NoError(t, err)
+
+		return k
+	}
+
+	p := &connectParams{ReflectionMetadata: metadata.Pairs("tenant", "a")}
+	assert.Equal(t, key(p, "b", "a"), key(&connectParams{ReflectionMetadata: metadata.Pairs("tenant", "a")}, "a", "b"))
+	assert.NotEqual(t, key(p), key(&connectParams{ReflectionMetadata: metadata.Pairs("tenant", "b")}))
+	assert.NotEqual(t, key(p), key(&connectParams{
+		ReflectionMetadata: metadata.Pairs("tenant", "a"),
+		TLS:                map[string]interface{}{"cert": "client.pem"},
+	}))
+	assert.NotEqual(t, key(p), key(&connectParams{
+		ReflectionMetadata: metadata.Pairs("tenant", "a"),
+		CallCredentials:    &callCredentialsParams{Token: "secret"},
+	}))
+}
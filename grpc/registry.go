@@ -0,0 +1,218 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// registryGetImagePath is the path of the Buf Schema Registry's GetImage procedure (the Connect protocol)
+const registryGetImagePath = "/buf.alpha.registry.v1alpha1.ImageService/GetImage"
+
+// registryParams is the parameters of the client's loadFromRegistry.
+type registryParams struct {
+	// Token is the registry's API token, required by the private modules
+	Token string
+	// URL is the registry's API, https://api.<host> by default
+	URL     string
+	Timeout time.Duration
+}
+
+// newRegistryParams constructs the registry parameters from the input value
+func newRegistryParams(rt *goja.Runtime, input goja.Value) (*registryParams, error) {
+	result := &registryParams{Timeout: time.Minute}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "token":
+			var ok bool
+			result.Token, ok = v.(string)
+			if !ok {
+				return result, fmt.Errorf("invalid token value: '%#v', it needs to be a string", v)
+			}
+		case "url":
+			var ok bool
+			result.URL, ok = v.(string)
+			if !ok || result.URL == "" {
+				return result, fmt.Errorf("invalid url value: '%#v', it needs to be a non-empty string", v)
+			}
+		case "timeout":
+			var err error
+			result.Timeout, err = types.GetDurationValue(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid timeout value: %w", err)
+			}
+		default:
+			return result, fmt.Errorf("unknown registry param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// moduleRef is the registry's module reference, <host>/<owner>/<repository>[:<reference>]
+type moduleRef struct {
+	host       string
+	owner      string
+	repository string
+	// reference is the module's commit, tag or branch, the registry's default is the main
+	reference string
+}
+
+// parseModuleRef parses the registry's module reference
+func parseModuleRef(ref string) (moduleRef, error) {
+	var m moduleRef
+
+	name, reference, _ := strings.Cut(ref, ":")
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return m, fmt.Errorf("invalid module reference: %q, it needs to be <host>/<owner>/<repository>[:<reference>]", ref)
+	}
+
+	m.host, m.owner, m.repository, m.reference = parts[0], parts[1], parts[2], reference
+
+	return m, nil
+}
+
+// LoadFromRegistry downloads the module's image (its files with the dependencies) from the Buf Schema Registry
+// and makes the file descriptors available to request. The images are cached for all the VUs,
+// so the module is downloaded once.
+func (c *Client) LoadFromRegistry(ref string, params goja.Value) ([]MethodInfo, error) {
+	if c.vu.State() != nil {
+		return nil, errors.New("load must be called in the init context")
+	}
+
+	m, err := parseModuleRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newRegistryParams(c.vu.Runtime(), params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GRPC's client.loadFromRegistry() parameters: %w", err)
+	}
+	if p.URL == "" {
+		p.URL = "https://api." + m.host
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+
+	key := strings.Join([]string{p.URL, m.owner, m.repository, m.reference}, "\x00")
+	fdset, err := c.registryImages.get(ctx, key, func() (*descriptorpb.FileDescriptorSet, error) {
+		return fetchRegistryImage(ctx, m, p)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't load %q from the registry: %w", ref, err)
+	}
+
+	return c.convertToMethodInfo(fdset)
+}
+
+// fetchRegistryImage gets the module's image using the registry's GetImage procedure,
+// the image is wire compatible with the FileDescriptorSet.
+func fetchRegistryImage(ctx context.Context, m moduleRef, p *registryParams) (*descriptorpb.FileDescriptorSet, error) {
+	var body []byte
+	body = protowire.AppendTag(body, 1, protowire.BytesType)
+	body = protowire.AppendString(body, m.owner)
+	body = protowire.AppendTag(body, 2, protowire.BytesType)
+	body = protowire.AppendString(body, m.repository)
+	if m.reference != "" {
+		body = protowire.AppendTag(body, 3, protowire.BytesType)
+		body = protowire.AppendString(body, m.reference)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(p.URL, "/")+registryGetImagePath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/proto")
+	req.Header.Set("Connect-Protocol-Version", "1")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// the Connect protocol's errors are the JSON objects with the code and the message
+		var connectErr struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(b, &connectErr) == nil && connectErr.Code != "" {
+			return nil, fmt.Errorf("%s: %s", connectErr.Code, connectErr.Message)
+		}
+
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return unmarshalRegistryImage(b)
+}
+
+// unmarshalRegistryImage unmarshals the image of the GetImage's response
+func unmarshalRegistryImage(b []byte) (*descriptorpb.FileDescriptorSet, error) {
+	fdset := &descriptorpb.FileDescriptorSet{}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid image response: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if num == 1 && typ == protowire.BytesType {
+			image, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, fmt.Errorf("invalid image response: %w", protowire.ParseError(n))
+			}
+			b = b[n:]
+
+			// the repeated image's messages are merged
+			if err := (proto.UnmarshalOptions{Merge: true}).Unmarshal(image, fdset); err != nil {
+				return nil, fmt.Errorf("invalid image: %w", err)
+			}
+
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid image response: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+
+	return fdset, nil
+}
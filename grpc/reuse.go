@@ -0,0 +1,37 @@
+package grpc
+
+const (
+	// connectionReuseVU reuses the VU's connections across its iterations
+	connectionReuseVU = "vu"
+	// connectionReuseIteration reconnects the VU's connections in each iteration
+	connectionReuseIteration = "iteration"
+)
+
+// renewConnection reconnects the client connected in a previous iteration if the connections aren't reused
+// across the iterations, so each iteration dials (and handshakes) like a new client, e.g. a mobile app's session.
+// The pooled connections are shared, so they're reused anyway.
+func (c *Client) renewConnection() error {
+	state := c.vu.State()
+	if c.conn == nil || c.pooled || state == nil || c.connIteration == state.Iteration {
+		return nil
+	}
+
+	opts, err := c.extOptions.load(c.vu)
+	if err != nil || opts == nil || opts.ConnectionReuse != connectionReuseIteration {
+		return err
+	}
+
+	if err = c.close(); err != nil {
+		return err
+	}
+
+	// the target is picked again, so e.g. the random sharding spreads the renewed connections too
+	addr, err := c.pickTarget(c.connTarget, c.connParams)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.connectAddr(addr, c.connTarget, c.connParams)
+
+	return err
+}
@@ -0,0 +1,95 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// SampleRequest returns an example request object of the method with all the fields populated
+// with their default values, the repeated and map fields get a single element and the oneofs
+// their first field. The well-known types and the recursive messages are left null.
+func (c *Client) SampleRequest(method string) (interface{}, error) {
+	md, err := c.getMethodDescriptor(method)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamicpb.NewMessage(md.Input())
+	populateMessage(msg, make(map[protoreflect.FullName]bool))
+
+	b, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert the sample request to JSON: %w", err)
+	}
+
+	var sample interface{}
+	if err = json.Unmarshal(b, &sample); err != nil {
+		return nil, fmt.Errorf("unable to convert the sample request from JSON: %w", err)
+	}
+
+	return sample, nil
+}
+
+// populateMessage sets the message's fields to their default values, the path holds
+// the messages being populated to stop on the recursive ones
+func populateMessage(m protoreflect.Message, path map[protoreflect.FullName]bool) {
+	name := m.Descriptor().FullName()
+	path[name] = true
+	defer delete(path, name)
+
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() && od.Fields().Get(0) != fd {
+			continue
+		}
+
+		if fd.Message() != nil && !fd.IsMap() && !canPopulate(fd.Message(), path) {
+			continue
+		}
+
+		switch {
+		case fd.IsMap():
+			populateMap(m.Mutable(fd).Map(), fd, path)
+		case fd.IsList():
+			l := m.Mutable(fd).List()
+			v := l.NewElement()
+			if fd.Message() != nil {
+				populateMessage(v.Message(), path)
+			}
+			l.Append(v)
+		case fd.Message() != nil:
+			populateMessage(m.Mutable(fd).Message(), path)
+		default:
+			m.Set(fd, fd.Default())
+		}
+	}
+}
+
+// populateMap adds a single entry with the default key and value to the map
+func populateMap(mp protoreflect.Map, fd protoreflect.FieldDescriptor, path map[protoreflect.FullName]bool) {
+	key := fd.MapKey().Default().MapKey()
+
+	vd := fd.MapValue()
+	if vd.Message() != nil && !canPopulate(vd.Message(), path) {
+		return
+	}
+
+	v := mp.NewValue()
+	if vd.Message() != nil {
+		populateMessage(v.Message(), path)
+	}
+	mp.Set(key, v)
+}
+
+// canPopulate reports whether the message could be populated, i.e. it isn't
+// a well-known type (which has its own JSON format) or a recursive message
+func canPopulate(md protoreflect.MessageDescriptor, path map[protoreflect.FullName]bool) bool {
+	return !strings.HasPrefix(string(md.FullName()), "google.protobuf.") && !path[md.FullName()]
+}
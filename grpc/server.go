@@ -0,0 +1,296 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// defaultServerAddress is the address the mock servers listen on by default, on a random port
+const defaultServerAddress = "127.0.0.1:0"
+
+// mockJobs are the calls of the VU's mock servers' handlers. The handlers are JS functions,
+// so they're run on the VU's goroutine while its client waits for a call's response.
+type mockJobs struct {
+	jobs    chan func()
+	started int32
+}
+
+func newMockJobs() *mockJobs {
+	return &mockJobs{jobs: make(chan func())}
+}
+
+// running tells whether any of the VU's mock servers is started
+func (m *mockJobs) running() bool {
+	return m != nil && atomic.LoadInt32(&m.started) > 0
+}
+
+// pump sends the call, running the mock servers' handlers until it's done
+func (m *mockJobs) pump(send func() (*grpcext.Response, error)) (*grpcext.Response, error) {
+	if !m.running() {
+		return send()
+	}
+
+	var (
+		resp *grpcext.Response
+		err  error
+		done = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+		resp, err = send()
+	}()
+
+	for {
+		select {
+		case <-done:
+			return resp, err
+		case job := <-m.jobs:
+			job()
+		}
+	}
+}
+
+// mockHandler is a JS handler of a mocked method
+type mockHandler struct {
+	method protoreflect.MethodDescriptor
+	fn     goja.Callable
+}
+
+// Server is an in-process gRPC server serving the client's loaded definitions with the JS handlers,
+// so the scripts could be self-contained. The handlers are called while the VU's client waits for a call,
+// so the server could only be called by the same VU.
+type Server struct {
+	vu     modules.VU
+	client *Client
+	mocks  *mockJobs
+
+	mu       sync.Mutex
+	handlers map[string]*mockHandler
+	srv      *grpc.Server
+	addr     string
+	stopOnce *sync.Once
+}
+
+// newServer is the JS constructor of the mock server, serving the given client's loaded definitions
+func (mi *ModuleInstance) newServer(c goja.ConstructorCall) *goja.Object {
+	rt := mi.vu.Runtime()
+
+	client, ok := c.Argument(0).Export().(*Client)
+	if !ok {
+		common.Throw(rt, errors.New("invalid GRPC Server's client: it needs to be a gRPC client with the loaded definitions"))
+	}
+
+	return rt.ToValue(&Server{
+		vu:       mi.vu,
+		client:   client,
+		mocks:    mi.mocks,
+		handlers: make(map[string]*mockHandler),
+	}).ToObject(rt)
+}
+
+// Handle sets the handler of the unary method, it's called with the request and the metadata
+// and returns the response. A thrown { code, message } object is responded as the status.
+func (s *Server) Handle(method string, handler goja.Callable) error {
+	if handler == nil {
+		return errors.New("the handler needs to be a function")
+	}
+
+	md, err := s.client.getMethodDescriptor(method)
+	if err != nil {
+		return err
+	}
+
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return fmt.Errorf("the method %q is streaming, only the unary methods could be mocked", method)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[sanitizeMethodName(method)] = &mockHandler{method: md, fn: handler}
+
+	return nil
+}
+
+// Start starts serving on the address (127.0.0.1 on a random port by default), it returns the address listened on
+func (s *Server) Start(address goja.Value) (string, error) {
+	if s.vu.State() == nil {
+		return "", common.NewInitContextError("starting a mock server in the init context is not supported")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.srv != nil {
+		return "", errors.New("the mock server is already started")
+	}
+
+	addr := defaultServerAddress
+	if !common.IsNullish(address) {
+		addr = address.String()
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(s.serve))
+	go func() { _ = srv.Serve(l) }()
+	atomic.AddInt32(&s.mocks.started, 1)
+
+	s.srv, s.addr, s.stopOnce = srv, l.Addr().String(), &sync.Once{}
+
+	// the servers left running are stopped with the VU
+	stop := s.stopOnce
+	go func() {
+		<-s.vu.Context().Done()
+		stop.Do(func() { s.stop(srv) })
+	}()
+
+	return s.addr, nil
+}
+
+// Address returns the address the server listens on, it's empty until it's started
+func (s *Server) Address() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addr
+}
+
+// Stop stops the server, closing its connections
+func (s *Server) Stop() {
+	s.mu.Lock()
+	srv, stopOnce := s.srv, s.stopOnce
+	s.srv, s.addr = nil, ""
+	s.mu.Unlock()
+
+	if srv == nil {
+		return
+	}
+
+	stopOnce.Do(func() { s.stop(srv) })
+}
+
+func (s *Server) stop(srv *grpc.Server) {
+	srv.Stop()
+	atomic.AddInt32(&s.mocks.started, -1)
+}
+
+// serve serves all the methods, passing the calls of the handled ones to the VU
+func (s *Server) serve(_ interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+
+	s.mu.Lock()
+	h := s.handlers[method]
+	s.mu.Unlock()
+
+	if h == nil {
+		return status.Errorf(codes.Unimplemented, "the method %s isn't handled by the mock server", method)
+	}
+
+	req := dynamicpb.NewMessage(h.method.Input())
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+
+	md, _ := metadata.FromIncomingContext(stream.Context())
+
+	var (
+		resp *dynamicpb.Message
+		err  error
+		done = make(chan struct{})
+	)
+
+	job := func() {
+		defer close(done)
+		resp, err = s.handle(h, req, md)
+	}
+
+	select {
+	case s.mocks.jobs <- job:
+		<-done
+	case <-stream.Context().Done():
+		return status.FromContextError(stream.Context().Err()).Err()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return stream.SendMsg(resp)
+}
+
+// handle calls the JS handler on the VU's goroutine
+func (s *Server) handle(h *mockHandler, req *dynamicpb.Message, md metadata.MD) (*dynamicpb.Message, error) {
+	rt := s.vu.Runtime()
+
+	b, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to serialise the request: %s", err)
+	}
+
+	var reqv interface{}
+	if err = json.Unmarshal(b, &reqv); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to serialise the request: %s", err)
+	}
+
+	v, err := h.fn(goja.Undefined(), rt.ToValue(reqv), metadataObject(rt, md))
+	if err != nil {
+		return nil, thrownStatus(err)
+	}
+
+	resp := dynamicpb.NewMessage(h.method.Output())
+	if common.IsNullish(v) {
+		return resp, nil
+	}
+
+	b, err = requestJSON(rt, h.method.Output(), h.method.Output(), v)
+	if err == nil {
+		err = protojson.Unmarshal(b, resp)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid response of %s: %s", h.method.FullName(), err)
+	}
+
+	return resp, nil
+}
+
+// thrownStatus converts the handler's exception to the status, a thrown { code, message } object is kept
+func thrownStatus(err error) error {
+	var exc *goja.Exception
+	if !errors.As(err, &exc) {
+		return status.Error(codes.Unknown, err.Error())
+	}
+
+	if obj, ok := exc.Value().(*goja.Object); ok {
+		if code := obj.Get("code"); !common.IsNullish(code) {
+			msg := ""
+			if m := obj.Get("message"); !common.IsNullish(m) {
+				msg = m.String()
+			}
+
+			return status.Error(codes.Code(code.ToInteger()), msg)
+		}
+	}
+
+	return status.Error(codes.Unknown, exc.Value().ToString().String())
+}
@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+)
+
+// the strategies of picking the VU's target of the connect's targets
+const (
+	// shardingVUModulo picks the targets round-robin by the VU's ID
+	shardingVUModulo = "vu-modulo"
+	// shardingRandom picks a random target on each connect
+	shardingRandom = "random"
+	// shardingWeighted spreads the VUs by their IDs in the proportions of the targets' weights
+	shardingWeighted = "weighted"
+)
+
+// shardTarget is one of the connect's targets
+type shardTarget struct {
+	Target string `js:"target"`
+	Weight int64  `js:"weight"`
+}
+
+// pickTarget returns the connect's target, the VU's one of the targets if they're an array,
+// picked by the sharding strategy of the params (the vu-modulo by default).
+func (c *Client) pickTarget(target goja.Value, params goja.Value) (string, error) {
+	if common.IsNullish(target) {
+		return "", nil
+	}
+
+	if _, ok := target.Export().([]interface{}); !ok {
+		return target.String(), nil
+	}
+
+	targets, err := newShardTargets(c.vu.Runtime(), target)
+	if err != nil {
+		return "", err
+	}
+
+	strategy, err := c.shardingStrategy(params)
+	if err != nil {
+		return "", err
+	}
+
+	// the global ID, so the VUs of all the instances of a distributed test are spread across the targets
+	vuID := c.vu.State().VUIDGlobal
+	switch strategy {
+	case shardingRandom:
+		return targets[rand.Intn(len(targets))].Target, nil //nolint:gosec
+	case shardingWeighted:
+		var total int64
+		for _, t := range targets {
+			total += t.Weight
+		}
+
+		// the VUs are spread over the weights' sum, so each target gets its share of the consecutive IDs
+		position := int64((vuID - 1) % uint64(total))
+		for _, t := range targets {
+			if position < t.Weight {
+				return t.Target, nil
+			}
+			position -= t.Weight
+		}
+
+		return targets[len(targets)-1].Target, nil
+	default:
+		return targets[(vuID-1)%uint64(len(targets))].Target, nil
+	}
+}
+
+// newShardTargets constructs the targets from the array of the addresses or of the objects with their weights
+func newShardTargets(rt *goja.Runtime, input goja.Value) ([]shardTarget, error) {
+	items := input.ToObject(rt)
+	length := items.Get("length").ToInteger()
+	if length == 0 {
+		return nil, fmt.Errorf("invalid targets value: it needs to be a non-empty array")
+	}
+
+	targets := make([]shardTarget, 0, length)
+	for i := int64(0); i < length; i++ {
+		v := items.Get(fmt.Sprint(i))
+
+		t := shardTarget{Weight: 1}
+		switch item := v.Export().(type) {
+		case string:
+			t.Target = item
+		case map[string]interface{}:
+			if err := rt.ExportTo(v, &t); err != nil {
+				return nil, fmt.Errorf("invalid targets value: %w", err)
+			}
+		}
+
+		if t.Target == "" {
+			return nil, fmt.Errorf("invalid target value: '%#v', it needs to be an address or an object with the target",
+				v.Export())
+		}
+		if t.Weight < 1 {
+			return nil, fmt.Errorf("invalid weight value of %q: %d, it needs to be a positive integer", t.Target, t.Weight)
+		}
+
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// shardingStrategy returns the sharding strategy of the connect's params
+func (c *Client) shardingStrategy(params goja.Value) (string, error) {
+	if common.IsNullish(params) {
+		return shardingVUModulo, nil
+	}
+
+	v := params.ToObject(c.vu.Runtime()).Get("sharding")
+	if common.IsNullish(v) {
+		return shardingVUModulo, nil
+	}
+
+	switch strategy, _ := v.Export().(string); strategy {
+	case shardingVUModulo, shardingRandom, shardingWeighted:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("invalid sharding value: '%#v', it needs to be %q, %q or %q",
+			v.Export(), shardingVUModulo, shardingRandom, shardingWeighted)
+	}
+}
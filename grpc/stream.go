@@ -7,6 +7,7 @@ import (
 	"io"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dop251/goja"
@@ -25,6 +26,10 @@ import (
 type message struct {
 	isClosing bool
 	msg       []byte
+	// raw is set if the msg is the marshaled protobuf, not the JSON
+	raw bool
+	// sent is called once the message is sent, if it's set
+	sent func()
 }
 
 const (
@@ -46,6 +51,13 @@ type stream struct {
 	tagsAndMeta *metrics.TagsAndMeta
 	tq          *taskqueue.TaskQueue
 
+	expectedStatuses *expectedStatuses
+
+	// rawMessages delivers the received messages as the marshaled protobuf ArrayBuffers
+	rawMessages bool
+	// timestampDates converts the received messages' Timestamps into the Dates
+	timestampDates bool
+
 	instanceMetrics *instanceMetrics
 	builtinMetrics  *metrics.BuiltinMetrics
 
@@ -53,12 +65,28 @@ type stream struct {
 
 	writingState int8
 	done         chan struct{}
+	// ended is closed by the end, endMu orders it with the messages queued by the sendFromFile
+	ended chan struct{}
+	endMu sync.Mutex
 
 	writeQueueCh chan message
 
 	eventListeners *eventListeners
 
 	timeoutCancel context.CancelFunc
+
+	// started is the time of the stream's creation, the counters are updated by the reading and writing goroutines
+	started  time.Time
+	sent     atomic.Int64
+	received atomic.Int64
+	firstMsg atomic.Int64 // the time to the first received message in nanoseconds, zero until it's received
+
+	// writeSlots and readSlots limit the buffered messages, they're nil if the buffers are unlimited;
+	// buffered is the count of the messages waiting to be sent and drain is set by the rejected write
+	writeSlots chan struct{}
+	readSlots  chan struct{}
+	buffered   atomic.Int64
+	drain      atomic.Bool
 }
 
 // defineStream defines the goja.Object that is given to js to interact with the Stream
@@ -71,31 +99,68 @@ func defineStream(rt *goja.Runtime, s *stream) {
 
 	must(rt, s.obj.DefineDataProperty(
 		"end", rt.ToValue(s.end), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE))
+
+	must(rt, s.obj.DefineDataProperty(
+		"cancel", rt.ToValue(s.cancel), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE))
+
+	must(rt, s.obj.DefineAccessorProperty(
+		"writableState", rt.ToValue(s.getWritableState), nil, goja.FLAG_FALSE, goja.FLAG_TRUE))
+
+	must(rt, s.obj.DefineDataProperty(
+		"sendFromFile", rt.ToValue(s.sendFromFile), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE))
 }
 
 func (s *stream) beginStream(p *callParams) error {
-	tags := s.vu.State().Tags.GetCurrentValues()
+	s.expectedStatuses = p.ExpectedStatuses
+	if s.expectedStatuses == nil {
+		s.expectedStatuses = s.client.statusCallback.expected
+	}
+	s.limitBuffers(p)
+	s.rawMessages = p.RawMessages
+	s.timestampDates = p.TimestampDates
+
+	// the stats handler sets the tags of the stream's end, so they're copied
+	tags := s.tagsAndMeta.Clone()
 	req := &grpcext.StreamRequest{
 		Method:           s.method,
 		MethodDescriptor: s.methodDescriptor,
 		TagsAndMeta:      &tags,
 		Metadata:         p.Metadata,
+		ExpectedStatus:   s.expectedStatuses.callback(),
+		Int64Numbers:     p.Int64Numbers,
+		DiscardUnknown:   !p.StrictRequest,
 	}
 
-	ctx := s.vu.Context()
-	var cancel context.CancelFunc
-
-	if p.Timeout != time.Duration(0) {
-		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+	ctx, cancel := p.withDeadline(s.client.vuContext())
+	ctx, release := p.cancellable(ctx)
+	ctx, cancelStream := context.WithCancel(ctx)
+	s.timeoutCancel = func() {
+		cancelStream()
+		release()
+		cancel()
 	}
+	ctx = p.withDebug(ctx)
 
-	s.timeoutCancel = cancel
+	copts, err := s.client.callOptions()
+	if err != nil {
+		return err
+	}
+	copts = append(copts, p.callOptions()...)
 
-	stream, err := s.client.conn.NewStream(ctx, *req)
+	stream, err := s.client.conn.NewStream(ctx, *req, copts...)
 	if err != nil {
+		// the stream fails to be created before receiving anything from the server
+		if grpcext.ErrorKind(err, false) == grpcext.ErrorKindCircuitBreaker {
+			s.client.pushMetric(s.instanceMetrics.XDSCircuitBreakerRejections, req.TagsAndMeta, 1)
+		}
+
 		return fmt.Errorf("failed to create a new stream: %w", err)
 	}
 	s.stream = stream
+	s.started = time.Now()
+	s.client.inFlight.add()
+	// the client's events are delivered as soon as they're observed while the stream keeps the event loop running
+	s.client.events.hold()
 	metrics.PushIfNotDone(s.vu.Context(), s.vu.State().Samples, metrics.Sample{
 		TimeSeries: metrics.TimeSeries{
 			Metric: s.instanceMetrics.Streams,
@@ -142,6 +207,10 @@ func (s *stream) loop() {
 }
 
 func (s *stream) queueMessage(msg interface{}) {
+	if s.received.Add(1) == 1 {
+		s.firstMsg.Store(int64(time.Since(s.started)))
+	}
+
 	metrics.PushIfNotDone(s.vu.Context(), s.vu.State().Samples, metrics.Sample{
 		TimeSeries: metrics.TimeSeries{
 			Metric: s.instanceMetrics.StreamsMessagesReceived,
@@ -153,11 +222,21 @@ func (s *stream) queueMessage(msg interface{}) {
 	})
 
 	s.tq.Queue(func() error {
+		defer s.releaseRead()
+
 		rt := s.vu.Runtime()
 		listeners := s.eventListeners.all(eventData)
 
+		if s.timestampDates {
+			timestampDates(rt, s.methodDescriptor.Output(), msg)
+		}
+		value := rt.ToValue(msg)
+		if b, ok := msg.([]byte); ok {
+			value = rt.ToValue(rt.NewArrayBuffer(b))
+		}
+
 		for _, messageListener := range listeners {
-			if _, err := messageListener(rt.ToValue(msg)); err != nil {
+			if _, err := messageListener(value); err != nil {
 				// TODO(olegbespalov) consider logging the error
 				_ = s.closeWithError(err)
 
@@ -173,7 +252,7 @@ func (s *stream) readData(wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for {
-		msg, err := s.stream.ReceiveConverted()
+		msg, err := s.receive()
 
 		if err != nil && !isRegularClosing(err) {
 			s.logger.WithError(err).Debug("error while reading from the stream")
@@ -196,11 +275,29 @@ func (s *stream) readData(wg *sync.WaitGroup) {
 		}
 
 		if msg != nil || !reflect.ValueOf(msg).IsNil() {
+			if !s.bufferRead() {
+				return
+			}
+
 			s.queueMessage(msg)
 		}
 	}
 }
 
+// receive receives the converted message or the marshaled one if the stream's rawMessages is set
+func (s *stream) receive() (interface{}, error) {
+	if !s.rawMessages {
+		return s.stream.ReceiveConverted()
+	}
+
+	msg, err := s.stream.ReceiveRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
 func isRegularClosing(err error) bool {
 	return errors.Is(err, io.EOF) || errors.Is(err, grpcext.ErrCanceled)
 }
@@ -234,12 +331,22 @@ func (s *stream) writeData(wg *sync.WaitGroup) {
 					return
 				}
 
-				err := s.stream.Send(msg.msg)
+				send := s.stream.Send
+				if msg.raw {
+					send = s.stream.SendRaw
+				}
+
+				err := send(msg.msg)
 				if err != nil {
 					s.processSendError(err)
 					return
 				}
 
+				s.releaseWrite()
+				if msg.sent != nil {
+					msg.sent()
+				}
+				s.sent.Add(1)
 				metrics.PushIfNotDone(s.vu.Context(), s.vu.State().Samples, metrics.Sample{
 					TimeSeries: metrics.TimeSeries{
 						Metric: s.instanceMetrics.StreamsMessagesSent,
@@ -299,28 +406,87 @@ func (s *stream) on(event string, listener func(goja.Value) (goja.Value, error))
 	}
 }
 
-// write writes a message to the stream
-func (s *stream) write(input goja.Value) {
+// writeParams is the parameters that can be passed to the stream.write call.
+type writeParams struct {
+	// Raw writes the already marshaled protobuf message (e.g. an ArrayBuffer) as it is
+	Raw bool
+}
+
+// newWriteParams constructs the write parameters from the input value.
+// if no input is given, the default values are used.
+func newWriteParams(rt *goja.Runtime, input goja.Value) (*writeParams, error) {
+	result := &writeParams{}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "raw":
+			var ok bool
+			result.Raw, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid raw value: '%#v', it needs to be boolean", v)
+			}
+		default:
+			return result, fmt.Errorf("unknown write param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// write writes a message to the stream, it returns false if the message hasn't been written,
+// e.g. the stream's write buffer is full
+func (s *stream) write(input goja.Value, params goja.Value) bool {
 	if s.writingState != opened {
-		return
+		return false
 	}
 
 	if common.IsNullish(input) {
 		s.logger.Warnf("can't send empty message")
-		return
+		return false
 	}
 
 	rt := s.vu.Runtime()
 
-	b, err := input.ToObject(rt).MarshalJSON()
+	p, err := newWriteParams(rt, params)
 	if err != nil {
-		s.logger.WithError(err).Warnf("can't marshal message")
+		common.Throw(rt, fmt.Errorf("invalid GRPC Stream's write parameters: %w", err))
+	}
+
+	msg := message{raw: p.Raw}
+	if p.Raw {
+		msg.msg, err = common.ToBytes(input.Export())
+		if err != nil {
+			s.logger.WithError(err).Warnf("can't write the raw message")
+			return false
+		}
+	} else {
+		// the message marshaled by the client.marshal is written as it is too
+		msg.msg, msg.raw, err = s.client.requestMessage(s.methodDescriptor, input)
+		if err != nil {
+			s.logger.WithError(err).Warnf("can't marshal message")
+			return false
+		}
 	}
 
-	s.writeQueueCh <- message{msg: b}
+	if !s.tryBufferWrite() {
+		s.logger.Debugf("stream %s's write buffer is full", s.method)
+		return false
+	}
+
+	s.writeQueueCh <- msg
+
+	return true
 }
 
-// end closes client the stream
+// end half-closes the stream, the server could still send the data until it ends the stream
 func (s *stream) end() {
 	if s.writingState == closed {
 		return
@@ -329,9 +495,31 @@ func (s *stream) end() {
 	s.logger.Debugf("finishing stream %s writing", s.method)
 
 	s.writingState = closed
+
+	s.endMu.Lock()
+	defer s.endMu.Unlock()
+
+	close(s.ended)
 	s.writeQueueCh <- message{isClosing: true}
 }
 
+// cancel cancels the stream, unlike the end it doesn't wait for the server to end it,
+// so the stream ends with the Canceled status
+func (s *stream) cancel() {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	s.logger.Debugf("canceling stream %s", s.method)
+
+	s.writingState = closed
+	if s.timeoutCancel != nil {
+		s.timeoutCancel()
+	}
+}
+
 func (s *stream) closeWithError(err error) error {
 	s.close(err)
 
@@ -355,8 +543,45 @@ func (s *stream) close(err error) {
 	s.logger.Debugf("stream %s is closing", s.method)
 	close(s.done)
 
+	code, kind := status.Code(err), ""
+	switch {
+	case errors.Is(err, io.EOF):
+		code = codes.OK
+	case errors.Is(err, grpcext.ErrCanceled):
+		code = codes.Canceled
+	case s.stream != nil:
+		kind = s.stream.ErrorKind(err)
+	}
+	// the result's tags are set on a copy, the stream's reading and writing goroutines could still use its tags
+	tagsAndMeta := s.tagsAndMeta.Clone()
+	s.client.setStatusTag(&tagsAndMeta, code)
+	if kind != "" {
+		tagsAndMeta.SetTag(grpcext.ErrorKindTag, kind)
+	}
+	faultHeaders := s.stream != nil && code != codes.OK && grpcext.IsFaultResponse(s.stream.Trailer())
+	if grpcext.IsFaultInjected(err) || faultHeaders {
+		tagsAndMeta.SetTag(grpcext.FaultTag, grpcext.FaultInjected)
+	}
+	s.client.pushReqFailed(s.expectedStatuses, code, &tagsAndMeta)
+	s.pushStreamMetrics(&tagsAndMeta)
+
+	end := streamEnd{Status: code, Trailers: map[string][]string{}}
+	if code != codes.OK {
+		end.Message = extractError(err).Message
+	}
+	if s.stream != nil && s.stream.Trailer() != nil {
+		end.Trailers = s.stream.Trailer()
+	}
+
+	started := s.stream != nil
 	s.tq.Queue(func() error {
-		return s.callEventListeners(eventEnd)
+		// the stream is in-flight until its end is delivered
+		if started {
+			defer s.client.inFlight.done()
+			defer s.client.events.release()
+		}
+
+		return s.callEventListeners(eventEnd, end)
 	})
 
 	if s.timeoutCancel != nil {
@@ -364,6 +589,21 @@ func (s *stream) close(err error) {
 	}
 }
 
+// pushStreamMetrics pushes the duration and the messages of the closed stream, tagged with its status
+func (s *stream) pushStreamMetrics(tagsAndMeta *metrics.TagsAndMeta) {
+	if s.started.IsZero() {
+		return
+	}
+
+	s.client.pushMetric(s.instanceMetrics.StreamDuration, tagsAndMeta, metrics.D(time.Since(s.started)))
+	s.client.pushMetric(s.instanceMetrics.StreamMessagesSent, tagsAndMeta, float64(s.sent.Load()))
+	s.client.pushMetric(s.instanceMetrics.StreamMessagesReceived, tagsAndMeta, float64(s.received.Load()))
+
+	if first := s.firstMsg.Load(); first > 0 {
+		s.client.pushMetric(s.instanceMetrics.StreamTimeToFirstMsg, tagsAndMeta, metrics.D(time.Duration(first)))
+	}
+}
+
 func (s *stream) callErrorListeners(e error) error {
 	if e == nil || errors.Is(e, io.EOF) {
 		return nil
@@ -372,6 +612,7 @@ func (s *stream) callErrorListeners(e error) error {
 	rt := s.vu.Runtime()
 
 	obj := extractError(e)
+	obj.TrailersOnly = s.stream != nil && s.stream.TrailersOnly()
 
 	list := s.eventListeners.all(eventError)
 
@@ -394,6 +635,8 @@ type grpcError struct {
 	Details []interface{} `json:"details"`
 	// Message is the original error message.
 	Message string `json:"message"`
+	// TrailersOnly is set if the stream has ended with the status only, without the headers
+	TrailersOnly bool `json:"trailersOnly" js:"trailersOnly"`
 }
 
 // Error to satisfy the error interface.
@@ -420,11 +663,18 @@ func extractError(e error) grpcError {
 	return w
 }
 
-func (s *stream) callEventListeners(eventType string) error {
+// streamEnd is the end event's value, the stream's status and the trailers sent by the server
+type streamEnd struct {
+	Status   codes.Code          `js:"status"`
+	Message  string              `js:"message"`
+	Trailers map[string][]string `js:"trailers"`
+}
+
+func (s *stream) callEventListeners(eventType string, v interface{}) error {
 	rt := s.vu.Runtime()
 
 	for _, listener := range s.eventListeners.all(eventType) {
-		if _, err := listener(rt.ToValue(struct{}{})); err != nil {
+		if _, err := listener(rt.ToValue(v)); err != nil {
 			return err
 		}
 	}
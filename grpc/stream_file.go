@@ -0,0 +1,405 @@
+package grpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/fsext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	fileFormatJSONL = "jsonl"
+	fileFormatCSV   = "csv"
+
+	// maxFileRecordSize is the longest JSONL line that could be read,
+	// it's aligned with the gRPC's default max message size.
+	maxFileRecordSize = 4 * 1024 * 1024
+
+	// maxFileRecordsInFlight is the most records queued by the sendFromFile and not sent yet,
+	// so the records are read as fast as the stream sends them without a rate
+	maxFileRecordsInFlight = 16
+
+	// maxRate is the highest rate (per second) whose pacing interval is still at least a nanosecond
+	maxRate = float64(time.Second)
+)
+
+// recordFile is a file of the records opened by the grpc.openFile in the init context. Its content is read
+// once by the process and shared by all the VUs, so the sendFromFile reads the records from it
+// without copying the whole dataset into each VU's JS heap.
+type recordFile struct {
+	Path string `js:"path"`
+	Size int    `js:"size"`
+
+	data []byte
+}
+
+// recordFiles are the files opened by the grpc.openFile, keyed by their absolute paths.
+// The zero value is ready to use.
+type recordFiles struct {
+	mu    sync.Mutex
+	files map[string]*recordFile
+}
+
+// open returns the file at the absolute path, it's read by the first VU opening it
+func (r *recordFiles) open(fs fsext.Fs, path string) (*recordFile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.files[path]; ok {
+		return f, nil
+	}
+
+	data, err := fsext.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the file: %w", err)
+	}
+
+	if r.files == nil {
+		r.files = make(map[string]*recordFile)
+	}
+
+	f := &recordFile{Path: path, Size: len(data), data: data}
+	r.files[path] = f
+
+	return f, nil
+}
+
+// openFile opens the file of the records to be sent by the streams' sendFromFile. Like the open,
+// it needs to be called in the init context, so the file is included in the archives.
+func (mi *ModuleInstance) openFile(path string) (*recordFile, error) {
+	if mi.vu.State() != nil {
+		return nil, errors.New("openFile must be called in the init context")
+	}
+
+	initEnv := mi.vu.InitEnv()
+	if initEnv == nil {
+		return nil, errors.New("missing init environment")
+	}
+
+	return mi.files.open(initEnv.FileSystems["file"], initEnv.GetAbsFilePath(path))
+}
+
+// sendFromFileParams is the parameters that can be passed to the stream.sendFromFile call.
+type sendFromFileParams struct {
+	Format string
+	Rate   float64
+}
+
+// newSendFromFileParams constructs the sendFromFile parameters from the input value.
+// if no input is given, the default values are used.
+func newSendFromFileParams(rt *goja.Runtime, input goja.Value) (*sendFromFileParams, error) {
+	result := &sendFromFileParams{
+		Format: fileFormatJSONL,
+	}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "format":
+			format, ok := v.(string)
+			if !ok || (format != fileFormatJSONL && format != fileFormatCSV) {
+				return result, fmt.Errorf("invalid format value: '%#v', it needs to be %q or %q", v, fileFormatJSONL, fileFormatCSV)
+			}
+			result.Format = format
+		case "rate":
+			rate, ok := toFloat64(v)
+			if !ok || !(rate >= 0 && rate <= maxRate) {
+				return result, fmt.Errorf("invalid rate value: '%#v', it needs to be a non-negative number up to %g", v, maxRate)
+			}
+			result.Rate = rate
+		default:
+			return result, fmt.Errorf("unknown sendFromFile param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// toFloat64 converts an exported JS number to float64.
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// recordReader reads the records of a file one by one
+// and returns each of them as a JSON-encoded message.
+type recordReader interface {
+	Next() ([]byte, error)
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFileRecordSize)
+
+	return &jsonlReader{scanner: scanner}
+}
+
+// Next returns the next non-empty line or io.EOF
+func (r *jsonlReader) Next() ([]byte, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		record := make([]byte, len(line))
+		copy(record, line)
+
+		return record, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+// csvReader reads the CSV records, the first one is used as the header, that contains
+// the field names of the message. The values are typed by the message's fields.
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+	fields []protoreflect.FieldDescriptor
+	desc   protoreflect.MessageDescriptor
+}
+
+func newCSVReader(r io.Reader, desc protoreflect.MessageDescriptor) *csvReader {
+	return &csvReader{reader: csv.NewReader(r), desc: desc}
+}
+
+// Next returns the next CSV row as a JSON object or io.EOF
+func (r *csvReader) Next() ([]byte, error) {
+	if r.header == nil {
+		header, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		r.header = header
+		r.fields = make([]protoreflect.FieldDescriptor, len(header))
+		for i, name := range header {
+			if r.fields[i] = r.desc.Fields().ByJSONName(name); r.fields[i] == nil {
+				r.fields[i] = r.desc.Fields().ByName(protoreflect.Name(name))
+			}
+		}
+	}
+
+	row, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]interface{}, len(r.header))
+	for i, name := range r.header {
+		// the empty values are the fields' defaults
+		if row[i] == "" {
+			continue
+		}
+
+		if record[name], err = csvValue(r.fields[i], row[i]); err != nil {
+			line, _ := r.reader.FieldPos(i)
+
+			return nil, fmt.Errorf("invalid %s value on line %d: %w", name, line, err)
+		}
+	}
+
+	return json.Marshal(record)
+}
+
+// csvValue returns the JSON value of the field's CSV value, the values of the unknown
+// fields are the strings, while the messages, the lists and the maps are the JSON ones.
+func csvValue(fd protoreflect.FieldDescriptor, v string) (interface{}, error) {
+	if fd == nil {
+		return v, nil
+	}
+
+	if fd.IsList() || fd.IsMap() {
+		if !json.Valid([]byte(v)) {
+			return nil, errors.New("it needs to be a JSON array or object")
+		}
+
+		return json.RawMessage(v), nil
+	}
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.ParseBool(v)
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		// the well-known types (e.g. the Timestamp) are the JSON strings
+		if json.Valid([]byte(v)) {
+			return json.RawMessage(v), nil
+		}
+
+		return v, nil
+	case protoreflect.EnumKind:
+		// the enums are either their values' names or numbers
+		if _, err := strconv.ParseInt(v, 10, 32); err == nil {
+			return json.Number(v), nil
+		}
+
+		return v, nil
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return v, nil
+	default:
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return nil, errors.New("it needs to be a number")
+		}
+
+		return json.Number(v), nil
+	}
+}
+
+// sendFromFile reads the records from the file opened by the grpc.openFile and writes them
+// to the stream as messages with the given rate. The returned promise is resolved
+// with the number of the messages that were written, the stream's end stops the sending.
+func (s *stream) sendFromFile(file goja.Value, input goja.Value) *goja.Promise {
+	rt := s.vu.Runtime()
+
+	if s.writingState != opened {
+		common.Throw(rt, errors.New("can't send from a file, the stream is closed for writing"))
+	}
+
+	var f *recordFile
+	if !common.IsNullish(file) {
+		f, _ = file.Export().(*recordFile)
+	}
+	if f == nil {
+		common.Throw(rt, errors.New("invalid file, it needs to be opened by the grpc.openFile in the init context"))
+	}
+
+	p, err := newSendFromFileParams(rt, input)
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("invalid GRPC Stream's sendFromFile parameters: %w", err))
+	}
+
+	// the file's content is shared by the VUs, each reader only keeps its own offset
+	var reader recordReader = newJSONLReader(bytes.NewReader(f.data))
+	if p.Format == fileFormatCSV {
+		reader = newCSVReader(bytes.NewReader(f.data), s.methodDescriptor.Input())
+	}
+
+	promise, resolve, reject := rt.NewPromise()
+
+	go func() {
+		sent, err := s.sendRecords(reader, p.Rate)
+
+		s.tq.Queue(func() error {
+			if err != nil {
+				reject(rt.ToValue(fmt.Errorf("failed to send from the file: %w", err)))
+
+				return nil
+			}
+
+			resolve(sent)
+
+			return nil
+		})
+	}()
+
+	return promise
+}
+
+// sendRecords queues the records to be written, pacing them with the rate (messages per second).
+// At most maxFileRecordsInFlight records wait to be sent, so they aren't read ahead of the stream.
+func (s *stream) sendRecords(reader recordReader, rate float64) (int64, error) {
+	var tick <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	inFlight := make(chan struct{}, maxFileRecordsInFlight)
+
+	var sent int64
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return sent, nil
+		}
+
+		if err != nil {
+			return sent, err
+		}
+
+		if tick != nil && sent > 0 {
+			select {
+			case <-tick:
+			case <-s.ended:
+				return sent, nil
+			case <-s.done:
+				return sent, errors.New("the stream is closed")
+			}
+		}
+
+		select {
+		case inFlight <- struct{}{}:
+		case <-s.ended:
+			return sent, nil
+		case <-s.done:
+			return sent, errors.New("the stream is closed")
+		}
+
+		if !s.bufferWrite() {
+			return sent, errors.New("the stream is closed")
+		}
+
+		queued, err := s.queueRecord(message{msg: record, sent: func() { <-inFlight }})
+		if !queued {
+			return sent, err
+		}
+		sent++
+	}
+}
+
+// queueRecord queues the file's record to be written unless the stream is ended, it returns
+// false with no error if it's ended. The end's closing message is queued after the records.
+func (s *stream) queueRecord(msg message) (bool, error) {
+	s.endMu.Lock()
+	defer s.endMu.Unlock()
+
+	select {
+	case <-s.ended:
+		s.releaseWrite()
+
+		return false, nil
+	default:
+	}
+
+	select {
+	case s.writeQueueCh <- msg:
+		return true, nil
+	case <-s.done:
+		return false, errors.New("the stream is closed")
+	}
+}
@@ -1,9 +1,14 @@
 package grpc_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -11,7 +16,11 @@ import (
 	"github.com/dop251/goja"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
@@ -181,6 +190,64 @@ func TestStream_ErrorHandling(t *testing.T) {
 	)
 }
 
+func TestStream_AbortSignal(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	stub := &featureExplorerStub{}
+	stub.listFeatures = func(rect *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+		for {
+			if err := stream.Send(&grpcservice.Feature{Name: "foo"}); err != nil {
+				return err
+			}
+
+			select {
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	grpcservice.RegisterFeatureExplorerServer(ts.httpBin.ServerGRPC, stub)
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect("GRPCBIN_ADDR");
+		let controller = new grpc.AbortController();
+		let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures", { signal: controller.signal })
+		stream.write({ lo: { latitude: 1, longitude: 2 }, hi: { latitude: 1, longitude: 2 } });
+		stream.on('data', function (data) {
+			call('Feature:' + data.name);
+			controller.abort();
+		})
+		stream.on('end', function () {
+			call('End');
+		});
+		`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+
+	assertResponse(t, vuString, err, val, ts)
+
+	recorded := ts.callRecorder.Recorded()
+	require.NotEmpty(t, recorded)
+	assert.Equal(t, "Feature:foo", recorded[0])
+	assert.Equal(t, "End", recorded[len(recorded)-1])
+}
+
 // this test case is checking that everything that server sends
 // after the client finished (client.end called) is delivered to the client
 // and the end event is called
@@ -270,6 +337,610 @@ func TestStream_ReceiveAllServerResponsesAfterEnd(t *testing.T) {
 	)
 }
 
+func TestStream_Tags(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	stub := &featureExplorerStub{}
+	stub.listFeatures = func(*grpcservice.Rectangle, grpcservice.FeatureExplorer_ListFeaturesServer) error {
+		return nil
+	}
+
+	grpcservice.RegisterFeatureExplorerServer(ts.httpBin.ServerGRPC, stub)
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect("GRPCBIN_ADDR");
+		let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures", { tags: { tenant: "acme" } })
+		stream.on('end', function () {
+			call('End called');
+		});
+
+		stream.write({ lo: { latitude: 1, longitude: 2 }, hi: { latitude: 1, longitude: 2 } });
+		stream.end();
+		`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+
+	assert.Equal(t, []string{"End called"}, ts.callRecorder.Recorded())
+
+	seen := false
+	for _, sampleContainer := range metrics.GetBufferedSamples(ts.samples) {
+		for _, sample := range sampleContainer.GetSamples() {
+			if sample.Metric.Name != metrics.GRPCReqDurationName {
+				continue
+			}
+
+			seen = true
+			tenant, _ := sample.Tags.Get("tenant")
+			assert.Equal(t, "acme", tenant)
+		}
+	}
+	assert.True(t, seen, "grpc_req_duration hasn't been emitted")
+}
+
+func TestStream_Metrics(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	stub := &featureExplorerStub{}
+	stub.listFeatures = func(_ *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+		time.Sleep(50 * time.Millisecond)
+
+		for _, name := range []string{"foo", "bar"} {
+			if err := stream.Send(&grpcservice.Feature{Name: name}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	grpcservice.RegisterFeatureExplorerServer(ts.httpBin.ServerGRPC, stub)
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect("GRPCBIN_ADDR");
+		let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures")
+		stream.on('end', function () {
+			call('End called');
+		});
+
+		stream.write({ lo: { latitude: 1, longitude: 2 }, hi: { latitude: 1, longitude: 2 } });
+		stream.end();
+		`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+
+	assert.Equal(t, []string{"End called"}, ts.callRecorder.Recorded())
+
+	values := make(map[string][]float64)
+	for _, sampleContainer := range metrics.GetBufferedSamples(ts.samples) {
+		for _, sample := range sampleContainer.GetSamples() {
+			values[sample.Metric.Name] = append(values[sample.Metric.Name], sample.Value)
+		}
+	}
+
+	assert.Equal(t, []float64{1}, values["grpc_stream_msgs_sent"])
+	assert.Equal(t, []float64{2}, values["grpc_stream_msgs_received"])
+
+	require.Len(t, values["grpc_stream_duration"], 1)
+	require.Len(t, values["grpc_stream_time_to_first_msg"], 1)
+	assert.GreaterOrEqual(t, values["grpc_stream_time_to_first_msg"][0], float64(50))
+	assert.GreaterOrEqual(t, values["grpc_stream_duration"][0], values["grpc_stream_time_to_first_msg"][0])
+}
+
+func TestStream_Backpressure(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	stub := &featureExplorerStub{}
+	stub.listFeatures = func(_ *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+		for _, name := range []string{"foo", "bar", "baz"} {
+			if err := stream.Send(&grpcservice.Feature{Name: name}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	grpcservice.RegisterFeatureExplorerServer(ts.httpBin.ServerGRPC, stub)
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect("GRPCBIN_ADDR");
+		let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures", {
+			maxBufferedWrites: 1,
+			maxBufferedReads: 1,
+		})
+		let state = stream.writableState;
+		call('Writable: ' + state.writable + ' Limit: ' + state.limit);
+
+		stream.on('data', function (data) {
+			call('Feature:' + data.name);
+		});
+		stream.on('end', function () {
+			call('End called');
+		});
+
+		if (!stream.write({ lo: { latitude: 1, longitude: 2 }, hi: { latitude: 1, longitude: 2 } })) {
+			throw new Error('the first write has been rejected');
+		}
+		stream.end();
+		call('Writable: ' + stream.writableState.writable);
+		`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+
+	assert.Equal(t, []string{
+		"Writable: true Limit: 1",
+		"Writable: false",
+		"Feature:foo",
+		"Feature:bar",
+		"Feature:baz",
+		"End called",
+	}, ts.callRecorder.Recorded())
+}
+
+func TestStream_EndEvent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		listFeature func(*grpcservice.Rectangle, grpcservice.FeatureExplorer_ListFeaturesServer) error
+		vuString    string
+		expected    []string
+	}{
+		{
+			name: "HalfClose",
+			listFeature: func(_ *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+				stream.SetTrailer(metadata.Pairs("x-summary", "done"))
+
+				return stream.Send(&grpcservice.Feature{Name: "foo"})
+			},
+			vuString: `stream.end();`,
+			expected: []string{"Feature:foo", `End: 0 {"x-summary":["done"]}`},
+		},
+		{
+			name: "Error",
+			listFeature: func(_ *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+				stream.SetTrailer(metadata.Pairs("x-reason", "missing"))
+
+				return status.Error(codes.NotFound, "not found")
+			},
+			vuString: `stream.end();`,
+			expected: []string{`End: 5 not found {"x-reason":["missing"]}`},
+		},
+		{
+			name: "Cancel",
+			listFeature: func(_ *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+				if err := stream.Send(&grpcservice.Feature{Name: "foo"}); err != nil {
+					return err
+				}
+
+				<-stream.Context().Done()
+
+				return stream.Context().Err()
+			},
+			vuString: `stream.on('data', function () { stream.cancel(); });`,
+			expected: []string{"Feature:foo", "End: 1 canceled by client (k6) {}"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestState(t)
+
+			stub := &featureExplorerStub{listFeatures: tt.listFeature}
+			grpcservice.RegisterFeatureExplorerServer(ts.httpBin.ServerGRPC, stub)
+
+			initString := codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+			}
+			vuString := codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures")
+				stream.on('data', function (data) {
+					call('Feature:' + data.name);
+				});
+				stream.on('error', function () {});
+				stream.on('end', function (e) {
+					let msg = e.message ? ' ' + e.message : '';
+					call('End: ' + e.status + msg + ' ' + JSON.stringify(e.trailers));
+				});
+
+				stream.write({ lo: { latitude: 1, longitude: 2 }, hi: { latitude: 1, longitude: 2 } });
+				` + tt.vuString,
+			}
+
+			val, err := ts.Run(initString.code)
+			assertResponse(t, initString, err, val, ts)
+
+			ts.ToVUContext()
+
+			val, err = ts.RunOnEventLoop(vuString.code)
+			assertResponse(t, vuString, err, val, ts)
+
+			assert.Equal(t, tt.expected, ts.callRecorder.Recorded())
+		})
+	}
+}
+
+func TestStream_ConnEvents(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	// the server rotates the connection while the stream is open
+	srv := grpc.NewServer(grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionAge:      100 * time.Millisecond,
+		MaxConnectionAgeGrace: 10 * time.Second,
+	}))
+	grpcservice.RegisterFeatureExplorerServer(srv, &featureExplorerStub{
+		listFeatures: func(_ *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+			<-stream.Context().Done()
+
+			return stream.Context().Err()
+		},
+	})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		let goaway = false;
+		client.on('connection', function (e) {
+			if (e.type === 'goaway' && !goaway) {
+				goaway = true;
+				call('GoAway: ' + e.code);
+				stream.cancel();
+			}
+		});
+		client.connect("` + lis.Addr().String() + `", { plaintext: true });
+
+		// the connection's events are delivered while the stream is open, without another call
+		let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures")
+		stream.on('error', function () {});
+		stream.on('end', function (e) {
+			call('End: ' + e.status);
+		});
+		stream.write({ lo: { latitude: 1, longitude: 2 }, hi: { latitude: 1, longitude: 2 } });`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+
+	assert.Equal(t, []string{"GoAway: NO_ERROR", "End: 1"}, ts.callRecorder.Recorded())
+}
+
+func TestStream_GracefulClose(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		listFeature  func(*grpcservice.Rectangle, grpcservice.FeatureExplorer_ListFeaturesServer) error
+		streamParams string
+		closeParams  string
+		expected     []string
+	}{
+		{
+			name: "Drained",
+			listFeature: func(_ *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+				time.Sleep(200 * time.Millisecond)
+
+				return stream.Send(&grpcservice.Feature{Name: "foo"})
+			},
+			streamParams: `{}`,
+			closeParams:  `{ graceful: true, timeout: "5s" }`,
+			expected:     []string{"Feature:foo", "End: 0", "Closed"},
+		},
+		{
+			name: "TimedOut",
+			listFeature: func(_ *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+				<-stream.Context().Done()
+
+				return stream.Context().Err()
+			},
+			streamParams: `{}`,
+			closeParams:  `{ graceful: true, timeout: "100ms" }`,
+			expected:     []string{"Closed", "End: 1"},
+		},
+		{
+			// the buffered messages are handled by the event loop while the close waits
+			name: "BufferedReads",
+			listFeature: func(_ *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+				for _, name := range []string{"a", "b", "c"} {
+					if err := stream.Send(&grpcservice.Feature{Name: name}); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+			streamParams: `{ maxBufferedReads: 1 }`,
+			closeParams:  `{ graceful: true, timeout: "10s" }`,
+			expected:     []string{"Feature:a", "Feature:b", "Feature:c", "End: 0", "Closed"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestState(t)
+
+			stub := &featureExplorerStub{listFeatures: tt.listFeature}
+			grpcservice.RegisterFeatureExplorerServer(ts.httpBin.ServerGRPC, stub)
+
+			initString := codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+			}
+			vuString := codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures", ` + tt.streamParams + `);
+				stream.on('data', function (data) {
+					call('Feature:' + data.name);
+				});
+				stream.on('error', function () {});
+				stream.on('end', function (e) {
+					call('End: ' + e.status);
+				});
+
+				stream.write({ lo: { latitude: 1, longitude: 2 }, hi: { latitude: 1, longitude: 2 } });
+				stream.end();
+				client.close(` + tt.closeParams + `).then(function () {
+					call('Closed');
+				});`,
+			}
+
+			val, err := ts.Run(initString.code)
+			assertResponse(t, initString, err, val, ts)
+
+			ts.ToVUContext()
+
+			start := time.Now()
+			val, err = ts.RunOnEventLoop(vuString.code)
+			assertResponse(t, vuString, err, val, ts)
+			assert.Less(t, time.Since(start), 5*time.Second)
+
+			assert.Equal(t, tt.expected, ts.callRecorder.Recorded())
+		})
+	}
+}
+
+func TestStream_RawMessages(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	stub := &featureExplorerStub{}
+	stub.listFeatures = func(rect *grpcservice.Rectangle, stream grpcservice.FeatureExplorer_ListFeaturesServer) error {
+		if rect.GetLo().GetLatitude() != 1 || rect.GetLo().GetLongitude() != 2 {
+			return status.Error(codes.InvalidArgument, "unexpected rectangle")
+		}
+
+		return stream.Send(&grpcservice.Feature{Name: "k6"})
+	}
+
+	grpcservice.RegisterFeatureExplorerServer(ts.httpBin.ServerGRPC, stub)
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+	}
+	vuString := codeBlock{
+		code: `
+		client.connect("GRPCBIN_ADDR");
+		let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures", { rawMessages: true })
+		stream.on('data', function (data) {
+			var msg = new Uint8Array(data);
+			call('Feature: ' + msg.length + ' ' + String.fromCharCode(msg[2], msg[3]));
+		});
+		stream.on('error', function (e) {
+			call('Error: ' + e.message);
+		});
+		stream.on('end', function () {
+			call('End called');
+		});
+
+		// { lo: { latitude: 1, longitude: 2 } }
+		stream.write(new Uint8Array([0x0a, 0x04, 0x08, 0x01, 0x10, 0x02]).buffer, { raw: true });
+		stream.end();
+		`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+
+	assert.Equal(t, []string{"Feature: 4 k6", "End called"}, ts.callRecorder.Recorded())
+}
+
+func TestStream_WriteInvalidParams(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	grpcservice.RegisterFeatureExplorerServer(ts.httpBin.ServerGRPC, &featureExplorerStub{})
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	_, err = ts.RunOnEventLoop(`
+	client.connect("GRPCBIN_ADDR");
+	let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures");
+	stream.on('error', function () {});
+	try {
+		stream.write({}, { binary: true });
+	} catch (e) {
+		call(e.message);
+	}
+	stream.end();`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`invalid GRPC Stream's write parameters: unknown write param: "binary"`}, ts.callRecorder.Recorded())
+}
+
+func TestStream_MaxConcurrentStreams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		params   string
+		expected []string
+		metric   string
+	}{
+		{
+			name:     "Queue",
+			params:   `{ maxConcurrentStreams: 1 }`,
+			expected: []string{"Status: 0", "End called"},
+			metric:   "grpc_stream_limit_queue_duration",
+		},
+		{
+			name:     "Reject",
+			params:   `{ maxConcurrentStreams: { limit: 1, reject: true } }`,
+			expected: []string{"Status: 8", "End called"},
+			metric:   "grpc_stream_limit_rejections",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestState(t)
+
+			stub := &featureExplorerStub{}
+			stub.listFeatures = func(*grpcservice.Rectangle, grpcservice.FeatureExplorer_ListFeaturesServer) error {
+				time.Sleep(100 * time.Millisecond)
+
+				return nil
+			}
+			stub.getFeature = func(context.Context, *grpcservice.Point) (*grpcservice.Feature, error) {
+				return &grpcservice.Feature{Name: "foo"}, nil
+			}
+
+			grpcservice.RegisterFeatureExplorerServer(ts.httpBin.ServerGRPC, stub)
+
+			initString := codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testutils/grpcservice/route_guide.proto");`,
+			}
+			vuString := codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR", ` + tt.params + `);
+				let stream = new grpc.Stream(client, "main.FeatureExplorer/ListFeatures")
+				stream.on('end', function () {
+					call('End called');
+				});
+				stream.write({ lo: { latitude: 1, longitude: 2 }, hi: { latitude: 1, longitude: 2 } });
+
+				// the stream takes the only slot until it ends
+				let resp = client.invoke("main.FeatureExplorer/GetFeature", { latitude: 1, longitude: 2 });
+				call('Status: ' + resp.status);
+				stream.end();
+				`,
+			}
+
+			val, err := ts.Run(initString.code)
+			assertResponse(t, initString, err, val, ts)
+
+			ts.ToVUContext()
+
+			val, err = ts.RunOnEventLoop(vuString.code)
+			assertResponse(t, vuString, err, val, ts)
+
+			assert.Equal(t, tt.expected, ts.callRecorder.Recorded())
+
+			values := make(map[string][]float64)
+			for _, sampleContainer := range metrics.GetBufferedSamples(ts.samples) {
+				for _, sample := range sampleContainer.GetSamples() {
+					values[sample.Metric.Name] = append(values[sample.Metric.Name], sample.Value)
+				}
+			}
+			assert.Len(t, values[tt.metric], 1)
+		})
+	}
+}
+
 // featureExplorerStub is a stub for FeatureExplorerServer
 // it has ability to override methods
 type featureExplorerStub struct {
@@ -363,3 +1034,196 @@ func TestStream_Wrappers(t *testing.T) {
 	},
 	)
 }
+
+func TestStream_SendFromFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		file     string
+		data     string
+		params   string
+		expected []string
+	}{
+		{
+			name:     "JSONL",
+			file:     "testdata/stream_records/points.jsonl",
+			params:   `{ rate: 100 }`,
+			expected: []string{"Sent: 3", "Points: 3"},
+		},
+		{
+			name:     "CSV",
+			file:     "testdata/stream_records/points.csv",
+			params:   `{ format: "csv" }`,
+			expected: []string{"Sent: 2", "Points: 2"},
+		},
+		{
+			name:     "CSVEmptyValues",
+			data:     "latitude,longitude,unknown\n407838351,,\n",
+			params:   `{ format: "csv" }`,
+			expected: []string{"Sent: 1", "Points: 1"},
+		},
+		{
+			name:   "CSVInvalidNumber",
+			data:   "latitude,longitude\n407838351,-746143763\nnorth,-743999179\n",
+			params: `{ format: "csv" }`,
+			expected: []string{
+				"Error: failed to send from the file: invalid latitude value on line 3: it needs to be a number",
+				"Points: 1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := newTestState(t)
+
+			grpcservice.RegisterRouteGuideServer(ts.httpBin.ServerGRPC, grpcservice.NewRouteGuideServer())
+
+			// the data is read in the init context, like the open does
+			file := tt.file
+			if file == "" {
+				file = filepath.Join(t.TempDir(), "records")
+				require.NoError(t, os.WriteFile(file, []byte(tt.data), 0o600))
+			}
+			quoted, err := json.Marshal(file)
+			require.NoError(t, err)
+
+			initString := codeBlock{
+				code: `
+				var client = new grpc.Client();
+				client.load([], "../grpc/testutils/grpcservice/route_guide.proto");
+				var data = grpc.openFile(` + string(quoted) + `);`,
+			}
+			vuString := codeBlock{
+				code: `
+				client.connect("GRPCBIN_ADDR");
+				let stream = new grpc.Stream(client, "main.RouteGuide/RecordRoute");
+				stream.on('data', function (summary) {
+					call('Points: ' + summary.pointCount);
+				});
+				stream.on('error', function (e) {
+					call('Code: ' + e.code + ' Message: ' + e.message);
+				});
+				stream.sendFromFile(data, ` + tt.params + `).then(function (sent) {
+					call('Sent: ' + sent);
+					stream.end();
+				}, function (e) {
+					call('Error: ' + e);
+					stream.end();
+				});
+				`,
+			}
+
+			val, err := ts.Run(initString.code)
+			assertResponse(t, initString, err, val, ts)
+
+			ts.ToVUContext()
+
+			val, err = ts.RunOnEventLoop(vuString.code)
+			assertResponse(t, vuString, err, val, ts)
+
+			assert.Equal(t, tt.expected, ts.callRecorder.Recorded())
+		})
+	}
+}
+
+func TestStream_SendFromFileEnd(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	grpcservice.RegisterRouteGuideServer(ts.httpBin.ServerGRPC, grpcservice.NewRouteGuideServer())
+
+	file := filepath.Join(t.TempDir(), "points.jsonl")
+	require.NoError(t, os.WriteFile(file,
+		bytes.Repeat([]byte(`{"latitude": 407838351, "longitude": -746143763}`+"\n"), 10000), 0o600))
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testutils/grpcservice/route_guide.proto");
+		var data = grpc.openFile("` + filepath.ToSlash(file) + `");`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	// the end stops the sending, the records sent before it are the ones received by the server
+	vuString := codeBlock{
+		code: `
+		client.connect("GRPCBIN_ADDR");
+		let stream = new grpc.Stream(client, "main.RouteGuide/RecordRoute");
+		let points = new Promise(function (resolve) {
+			stream.on('data', function (summary) { resolve(summary.pointCount || 0); });
+		});
+		let sent = stream.sendFromFile(data);
+		stream.end();
+		Promise.all([sent, points]).then(function (counts) {
+			call('Stopped: ' + (counts[0] < 10000));
+			call('Received: ' + (counts[0] === counts[1]));
+		});`,
+	}
+
+	val, err = ts.RunOnEventLoop(vuString.code)
+	assertResponse(t, vuString, err, val, ts)
+
+	assert.Equal(t, []string{"Stopped: true", "Received: true"}, ts.callRecorder.Recorded())
+}
+
+func TestStream_SendFromFileInvalidParams(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestState(t)
+
+	grpcservice.RegisterRouteGuideServer(ts.httpBin.ServerGRPC, grpcservice.NewRouteGuideServer())
+
+	initString := codeBlock{
+		code: `
+		var client = new grpc.Client();
+		client.load([], "../grpc/testutils/grpcservice/route_guide.proto");
+		var data = grpc.openFile("testdata/stream_records/points.jsonl");`,
+	}
+
+	val, err := ts.Run(initString.code)
+	assertResponse(t, initString, err, val, ts)
+
+	ts.ToVUContext()
+
+	_, err = ts.RunOnEventLoop(`
+	client.connect("GRPCBIN_ADDR");
+	let stream = new grpc.Stream(client, "main.RouteGuide/RecordRoute");
+	try {
+		stream.sendFromFile(data, { format: "xml" });
+	} catch (e) {
+		call(e.message);
+	}
+	try {
+		stream.sendFromFile(data, { rate: 1e10 });
+	} catch (e) {
+		call(e.message);
+	}
+	try {
+		stream.sendFromFile("{}");
+	} catch (e) {
+		call(e.message);
+	}
+	try {
+		grpc.openFile("testdata/stream_records/points.jsonl");
+	} catch (e) {
+		call(e.message);
+	}
+	stream.end();`)
+
+	assert.NoError(t, err)
+	assert.Len(t, ts.callRecorder.Recorded(), 4)
+	assert.Contains(t, ts.callRecorder.Recorded()[0], `invalid format value`)
+	assert.Contains(t, ts.callRecorder.Recorded()[1], `invalid rate value`)
+	assert.Contains(t, ts.callRecorder.Recorded()[2], `it needs to be opened by the grpc.openFile`)
+	assert.Contains(t, ts.callRecorder.Recorded()[3], `openFile must be called in the init context`)
+}
@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/js/common"
+	"google.golang.org/grpc"
+)
+
+// streamLimitParams is the parameters of the client-side limit of the connection's concurrent streams.
+type streamLimitParams struct {
+	Limit int64
+	// Reject rejects the RPCs above the limit instead of queueing them until their deadline
+	Reject bool
+}
+
+// newStreamLimitParams constructs the concurrent streams' limit parameters from the input value,
+// either the limit or an object with the limit and the reject.
+func newStreamLimitParams(rt *goja.Runtime, input goja.Value) (*streamLimitParams, error) {
+	result := &streamLimitParams{}
+
+	if common.IsNullish(input) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if v, ok := input.Export().(int64); ok {
+		result.Limit = v
+		if result.Limit <= 0 || result.Limit > math.MaxInt32 {
+			return result, fmt.Errorf("invalid maxConcurrentStreams value: '%#v', it needs to be a positive integer", v)
+		}
+
+		return result, nil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf(
+			"invalid maxConcurrentStreams value: '%#v', it needs to be a positive integer or an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "limit":
+			var ok bool
+			result.Limit, ok = v.(int64)
+			if !ok || result.Limit <= 0 || result.Limit > math.MaxInt32 {
+				return result, fmt.Errorf(
+					"invalid maxConcurrentStreams limit value: '%#v', it needs to be a positive integer", v)
+			}
+		case "reject":
+			var ok bool
+			result.Reject, ok = v.(bool)
+			if !ok {
+				return result, fmt.Errorf("invalid maxConcurrentStreams reject value: '%#v', it needs to be boolean", v)
+			}
+		default:
+			return result, fmt.Errorf("unknown maxConcurrentStreams param: %q", k)
+		}
+	}
+
+	if result.Limit == 0 {
+		return result, errors.New("invalid maxConcurrentStreams value: the limit is required")
+	}
+
+	return result, nil
+}
+
+// streamLimitDialOptions returns the dial options limiting the connection's concurrent streams, if it's set
+func (o *connOwner) streamLimitDialOptions(p *connectParams) []grpc.DialOption {
+	if p.MaxConcurrentStreams == nil {
+		return nil
+	}
+
+	m := o.get().metrics
+
+	return grpcext.WithStreamLimit(o.state, grpcext.StreamLimit{
+		Max:           p.MaxConcurrentStreams.Limit,
+		Reject:        p.MaxConcurrentStreams.Reject,
+		QueueDuration: m.StreamLimitQueueDuration,
+		Rejections:    m.StreamLimitRejections,
+	})
+}
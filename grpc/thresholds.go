@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+)
+
+// thresholdsDefaultMetric is the metric of the per-method thresholds if it isn't set explicitly
+const thresholdsDefaultMetric = "grpc_req_duration"
+
+// thresholdsAllMethods is the key of the thresholds applied to all the loaded methods
+const thresholdsAllMethods = "*"
+
+// submetricsMu guards the submetrics of the registry's metrics, which are added by all the VUs
+var submetricsMu sync.Mutex //nolint:gochecknoglobals
+
+// MethodThresholds returns the thresholds definitions for the options, keyed by the per-method
+// sub-metrics (e.g. grpc_req_duration{name:/pkg.Svc/Get}) of the loaded methods, and registers
+// the sub-metrics. The spec maps the methods (or "*" for all of them) to their thresholds,
+// the unknown methods and metrics are reported at the init time instead of the silently passing thresholds.
+func (c *Client) MethodThresholds(spec goja.Value, metric goja.Value) (map[string]interface{}, error) {
+	if c.vu.State() != nil {
+		return nil, errors.New("methodThresholds must be called in the init context")
+	}
+
+	initEnv := c.vu.InitEnv()
+	if initEnv == nil {
+		return nil, errors.New("missing init environment")
+	}
+
+	if len(c.mds) == 0 {
+		return nil, errors.New("no loaded methods, you must call load first")
+	}
+
+	name := thresholdsDefaultMetric
+	if !common.IsNullish(metric) {
+		name = metric.String()
+	}
+
+	m := initEnv.Registry.Get(name)
+	if m == nil {
+		return nil, fmt.Errorf("unknown metric %q", name)
+	}
+
+	if common.IsNullish(spec) {
+		return nil, errors.New("thresholds spec is required")
+	}
+
+	rt := c.vu.Runtime()
+	specObj := spec.ToObject(rt)
+	thresholds := make(map[string]interface{})
+
+	submetricsMu.Lock()
+	defer submetricsMu.Unlock()
+
+	// the thresholds of all the methods go first, so the specific ones override them
+	keys := specObj.Keys()
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i] == thresholdsAllMethods && keys[j] != thresholdsAllMethods
+	})
+
+	for _, k := range keys {
+		methods := []string{sanitizeMethodName(k)}
+		if k == thresholdsAllMethods {
+			methods = c.methodNames()
+		} else if _, ok := c.mds[methods[0]]; !ok {
+			return nil, fmt.Errorf("method %q not found in the loaded file descriptors", methods[0])
+		}
+
+		for _, method := range methods {
+			submetric := "name:" + method
+			if _, err := m.AddSubmetric(submetric); err != nil {
+				return nil, err
+			}
+
+			thresholds[name+"{"+submetric+"}"] = specObj.Get(k).Export()
+		}
+	}
+
+	return thresholds, nil
+}
+
+// methodNames returns the sorted names of the loaded methods
+func (c *Client) methodNames() []string {
+	names := make([]string, 0, len(c.mds))
+	for name := range c.mds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,163 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+	"google.golang.org/grpc/codes"
+)
+
+// errThrottled is the error message of the calls rejected by the adaptive throttling
+var errThrottled = errors.New("rejected by the client-side adaptive throttling") //nolint:gochecknoglobals
+
+// throttlingBucketsCount is the number of buckets the throttling window is split to.
+const throttlingBucketsCount = 16
+
+// adaptiveThrottlingParams is the parameters of the client-side adaptive throttling.
+type adaptiveThrottlingParams struct {
+	K      float64
+	Window time.Duration
+}
+
+// newAdaptiveThrottlingParams constructs the adaptive throttling parameters from the input value.
+func newAdaptiveThrottlingParams(rt *goja.Runtime, input goja.Value) (*adaptiveThrottlingParams, error) {
+	result := &adaptiveThrottlingParams{
+		K:      2,
+		Window: 2 * time.Minute,
+	}
+
+	if common.IsNullish(input) {
+		return result, nil
+	}
+
+	if v, ok := input.Export().(bool); ok {
+		if !v {
+			return nil, nil //nolint:nilnil
+		}
+
+		return result, nil
+	}
+
+	if _, ok := input.Export().(map[string]interface{}); !ok {
+		return result, fmt.Errorf("invalid adaptiveThrottling value: '%#v', it needs to be boolean or an object", input.Export())
+	}
+
+	params := input.ToObject(rt)
+
+	for _, k := range params.Keys() {
+		v := params.Get(k).Export()
+
+		switch k {
+		case "k":
+			var ok bool
+			result.K, ok = toFloat64(v)
+			if !ok || result.K < 1 {
+				return result, fmt.Errorf("invalid adaptiveThrottling k value: '%#v', it needs to be a number >= 1", v)
+			}
+		case "window":
+			var err error
+			result.Window, err = types.GetDurationValue(v)
+			if err != nil {
+				return result, fmt.Errorf("invalid adaptiveThrottling window value: %w", err)
+			}
+			if result.Window < time.Second {
+				return result, fmt.Errorf("invalid adaptiveThrottling window value: '%#v', it needs to be at least 1s", v)
+			}
+		default:
+			return result, fmt.Errorf("unknown adaptiveThrottling param: %q", k)
+		}
+	}
+
+	return result, nil
+}
+
+// throttlingBucket keeps the counters for a time slot of the window
+type throttlingBucket struct {
+	start    time.Time
+	requests int64
+	accepts  int64
+}
+
+// adaptiveThrottler implements the client-side throttling as described
+// in the Google SRE book (https://sre.google/sre-book/handling-overload/),
+// a request is rejected locally with the probability
+// max(0, (requests - K * accepts) / (requests + 1))
+// where requests and accepts are counted over the window.
+type adaptiveThrottler struct {
+	mu sync.Mutex
+
+	k          float64
+	bucketSize time.Duration
+	buckets    [throttlingBucketsCount]throttlingBucket
+
+	now  func() time.Time
+	rand func() float64
+}
+
+func newAdaptiveThrottler(p *adaptiveThrottlingParams) *adaptiveThrottler {
+	return &adaptiveThrottler{
+		k:          p.K,
+		bucketSize: p.Window / throttlingBucketsCount,
+		now:        time.Now,
+		rand:       rand.Float64, //nolint:gosec
+	}
+}
+
+// bucket returns the bucket for the current time slot, resetting it if it's outdated
+func (at *adaptiveThrottler) bucket(now time.Time) *throttlingBucket {
+	slot := now.Truncate(at.bucketSize)
+	b := &at.buckets[(slot.UnixNano()/int64(at.bucketSize))%throttlingBucketsCount]
+
+	if !b.start.Equal(slot) {
+		*b = throttlingBucket{start: slot}
+	}
+
+	return b
+}
+
+// totals returns the sum of requests and accepts over the window
+func (at *adaptiveThrottler) totals(now time.Time) (requests, accepts int64) {
+	windowStart := now.Add(-at.bucketSize * throttlingBucketsCount)
+
+	for _, b := range at.buckets {
+		if b.start.After(windowStart) {
+			requests += b.requests
+			accepts += b.accepts
+		}
+	}
+
+	return requests, accepts
+}
+
+// allow counts the request and reports whether it should be sent
+func (at *adaptiveThrottler) allow() bool {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	now := at.now()
+	requests, accepts := at.totals(now)
+	at.bucket(now).requests++
+
+	probability := math.Max(0, (float64(requests)-at.k*float64(accepts))/float64(requests+1))
+
+	return at.rand() >= probability
+}
+
+// record counts the request as accepted if the backend didn't reject it
+func (at *adaptiveThrottler) record(code codes.Code) {
+	if code == codes.ResourceExhausted || code == codes.Unavailable {
+		return
+	}
+
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	at.bucket(at.now()).accepts++
+}
@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestAdaptiveThrottler(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	at := newAdaptiveThrottler(&adaptiveThrottlingParams{K: 2, Window: 16 * time.Second})
+	at.now = func() time.Time { return now }
+	at.rand = func() float64 { return 0.5 }
+
+	// the backend accepts everything, nothing is throttled
+	for i := 0; i < 10; i++ {
+		assert.True(t, at.allow())
+		at.record(codes.OK)
+	}
+
+	// the backend starts rejecting, the rejection probability grows
+	// while requests exceed K * accepts
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if at.allow() {
+			allowed++
+			at.record(codes.ResourceExhausted)
+		}
+	}
+	assert.Less(t, allowed, 100)
+
+	// the window has passed, the history is forgotten
+	now = now.Add(17 * time.Second)
+	assert.True(t, at.allow())
+}
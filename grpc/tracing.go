@@ -0,0 +1,61 @@
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"google.golang.org/grpc/metadata"
+)
+
+// the trace context propagation formats
+const (
+	tracePropagationW3C = "w3c"
+	tracePropagationB3  = "b3"
+)
+
+// tracePropagationParams is the trace context's formats injected into the invoked calls' metadata
+type tracePropagationParams struct {
+	// B3 adds the single b3 header to the always injected W3C traceparent
+	B3 bool
+}
+
+// parseTracePropagation parses either a format or the list of the formats
+func parseTracePropagation(v interface{}) (*tracePropagationParams, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("'%#v', it needs to be a non-empty list", v)
+	}
+
+	result := &tracePropagationParams{}
+	for _, f := range list {
+		switch f {
+		case tracePropagationW3C:
+		case tracePropagationB3:
+			result.B3 = true
+		default:
+			return nil, fmt.Errorf("'%#v', it needs to be %q, %q or a list of them",
+				f, tracePropagationW3C, tracePropagationB3)
+		}
+	}
+
+	return result, nil
+}
+
+// injectTrace starts a new trace and injects its context into the metadata, if the propagation is enabled.
+func (c *Client) injectTrace(md metadata.MD) (*grpcext.TraceContext, error) {
+	if c.tracePropagation == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	tc, err := grpcext.NewTraceContext()
+	if err != nil {
+		return nil, err
+	}
+	tc.Inject(md, c.tracePropagation.B3)
+
+	return tc, nil
+}
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+)
+
+// modulePath is the Go module path of the extension
+const modulePath = "github.com/farzanhaq/xk6-grpc-xds"
+
+// feature is an optional subsystem of the extension, which scripts can detect
+// either through the grpc.version().features or the Feature* constants.
+type feature struct {
+	// name is the key in the grpc.version().features
+	name string
+	// constant is the name of the exported Feature* constant
+	constant string
+	enabled  bool
+}
+
+// features is the list of the extension's optional subsystems, the transports which aren't
+// supported (yet) are listed as disabled, so the scripts could guard them the same way
+var features = []feature{ //nolint:gochecknoglobals
+	{name: "xds", constant: "FeatureXDS", enabled: true},
+	{name: "xdsCredentials", constant: "FeatureXDSCredentials", enabled: true},
+	{name: "googleDefaultCredentials", constant: "FeatureGoogleDefaultCredentials", enabled: true},
+	{name: "alts", constant: "FeatureALTS", enabled: true},
+	{name: "spiffe", constant: "FeatureSPIFFE", enabled: true},
+	{name: "http3", constant: "FeatureHTTP3", enabled: false},
+	{name: "grpcWeb", constant: "FeatureGRPCWeb", enabled: false},
+}
+
+// versionInfo is the version and build information of the extension.
+type versionInfo struct {
+	Version     string          `js:"version"`
+	GRPCVersion string          `js:"grpcVersion"`
+	GoVersion   string          `js:"goVersion"`
+	Features    map[string]bool `js:"features"`
+}
+
+// develVersion is the version of the extension built from a local checkout, e.g. by a local-path replace
+const develVersion = "(devel)"
+
+// extensionVersion returns the version of the extension module the binary has been built with.
+func extensionVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(unknown)"
+	}
+
+	return moduleVersion(info)
+}
+
+// moduleVersion returns the version of the extension module in the build information.
+func moduleVersion(info *debug.BuildInfo) string {
+	if info.Main.Path == modulePath {
+		return orDevel(info.Main.Version)
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+
+		// the local-path replaces (e.g. xk6's --with=...=../local) have no version
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+
+		return orDevel(dep.Version)
+	}
+
+	return "(unknown)"
+}
+
+// orDevel returns the version, or the develVersion if it has none
+func orDevel(version string) string {
+	if version == "" {
+		return develVersion
+	}
+
+	return version
+}
+
+// version returns the extension's version and build information.
+func (mi *ModuleInstance) version() *versionInfo {
+	info := &versionInfo{
+		Version:     extensionVersion(),
+		GRPCVersion: grpc.Version,
+		Features:    make(map[string]bool, len(features)),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = bi.GoVersion
+	}
+
+	for _, f := range features {
+		info.Features[f.name] = f.enabled
+	}
+
+	return info
+}
+
+// defineFeatures defines the feature-detection constants of the module.
+func (mi *ModuleInstance) defineFeatures() {
+	rt := mi.vu.Runtime()
+
+	for _, f := range features {
+		mi.exports[f.constant] = rt.ToValue(f.enabled)
+	}
+}
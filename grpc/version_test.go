@@ -0,0 +1,42 @@
+package grpc
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleVersion(t *testing.T) {
+	t.Parallel()
+
+	dep := func(version string, replace *debug.Module) *debug.BuildInfo {
+		return &debug.BuildInfo{
+			Main: debug.Module{Path: "k6"},
+			Deps: []*debug.Module{{Path: modulePath, Version: version, Replace: replace}},
+		}
+	}
+
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		want string
+	}{
+		{name: "Main", info: &debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "v1.2.3"}}, want: "v1.2.3"},
+		{name: "MainDevel", info: &debug.BuildInfo{Main: debug.Module{Path: modulePath}}, want: develVersion},
+		{name: "Dependency", info: dep("v1.2.3", nil), want: "v1.2.3"},
+		{name: "Replaced", info: dep("v1.2.3", &debug.Module{Path: "example.com/fork", Version: "v1.2.4"}), want: "v1.2.4"},
+		{name: "LocalPathReplace", info: dep("v1.2.3", &debug.Module{Path: "../xk6-grpc-xds"}), want: "v1.2.3"},
+		{name: "NoVersion", info: dep("", &debug.Module{Path: "../xk6-grpc-xds"}), want: develVersion},
+		{name: "Missing", info: &debug.BuildInfo{Main: debug.Module{Path: "k6"}}, want: "(unknown)"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, moduleVersion(tt.info))
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	durationMessage  protoreflect.FullName = "google.protobuf.Duration"
+	timestampMessage protoreflect.FullName = "google.protobuf.Timestamp"
+)
+
+// requestMessages caches the requestFields of the messages
+var requestMessages sync.Map //nolint:gochecknoglobals
+
+// requestFields is what the request's message (with the nested ones) has to be converted or validated for
+type requestFields struct {
+	// converted is set if it has the Duration or the FieldMask fields
+	converted bool
+	// oneofs is set if it has the oneofs
+	oneofs bool
+}
+
+// requestJSON returns the JSON of the request object for the message. The Dates are the RFC 3339 strings
+// of the Timestamps already, the Durations are converted from the k6's durations (e.g. "1m30s" or the milliseconds)
+// into the protojson's seconds (e.g. "90s"), and the FieldMasks from the arrays of the paths validated
+// against their targets, the masked is the target of the masks which don't apply to a resource (e.g. the read masks).
+// The oneofs are validated too.
+func requestJSON(
+	rt *goja.Runtime, md protoreflect.MessageDescriptor, masked protoreflect.MessageDescriptor, req goja.Value,
+) ([]byte, error) {
+	b, err := req.ToObject(rt).MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := messageRequestFields(md)
+	if !fields.converted && !fields.oneofs {
+		return b, nil
+	}
+
+	// the numbers are kept as they are, e.g. the 64-bit integers
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var v interface{}
+	if err = d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if fields.oneofs {
+		if err = validateOneofs(md, v); err != nil {
+			return nil, err
+		}
+	}
+	if !fields.converted {
+		return b, nil
+	}
+
+	grpcext.ConvertFields(md, v, func(fd protoreflect.FieldDescriptor, v interface{}) (interface{}, bool) {
+		if fd.Message() == nil || err != nil {
+			return v, false
+		}
+
+		switch fd.Message().FullName() { //nolint:exhaustive
+		case durationMessage:
+			return protojsonDuration(v), true
+		case fieldMaskMessage:
+			v, err = fieldMaskPaths(fd, masked, v)
+
+			return v, true
+		}
+
+		return v, false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// messageRequestFields returns the message's requestFields
+func messageRequestFields(md protoreflect.MessageDescriptor) requestFields {
+	if fields, ok := requestMessages.Load(md); ok {
+		return fields.(requestFields) //nolint:forcetypeassert
+	}
+
+	var fields requestFields
+	findRequestFields(md, make(map[protoreflect.FullName]bool), &fields)
+	requestMessages.Store(md, fields)
+
+	return fields
+}
+
+// findRequestFields looks for the converted fields and the oneofs,
+// the visited prevents the recursive messages from looping
+func findRequestFields(md protoreflect.MessageDescriptor, visited map[protoreflect.FullName]bool, fields *requestFields) {
+	if md.FullName() == durationMessage || md.FullName() == fieldMaskMessage {
+		fields.converted = true
+
+		return
+	}
+	if visited[md.FullName()] || strings.HasPrefix(string(md.FullName()), "google.protobuf.") {
+		return
+	}
+	visited[md.FullName()] = true
+
+	ods := md.Oneofs()
+	for i := 0; i < ods.Len(); i++ {
+		if !ods.Get(i).IsSynthetic() {
+			fields.oneofs = true
+		}
+	}
+
+	fs := md.Fields()
+	for i := 0; i < fs.Len(); i++ {
+		fd := fs.Get(i)
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+
+		if fd.Message() != nil {
+			findRequestFields(fd.Message(), visited, fields)
+		}
+	}
+}
+
+// protojsonDuration converts the k6 duration into the protojson's seconds,
+// the invalid values are left for the protojson to report.
+func protojsonDuration(v interface{}) interface{} {
+	var d time.Duration
+	switch v := v.(type) {
+	case string:
+		var err error
+		if d, err = time.ParseDuration(v); err != nil {
+			return v
+		}
+	case json.Number:
+		ms, err := v.Float64()
+		if err != nil {
+			return v
+		}
+		d = time.Duration(ms * float64(time.Millisecond))
+	default:
+		return v
+	}
+
+	return formatSeconds(d)
+}
+
+// formatSeconds formats the duration as the protojson's seconds with up to 9 fractional digits
+func formatSeconds(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+
+	secs, nanos := d/time.Second, d%time.Second
+	if nanos == 0 {
+		return fmt.Sprintf("%s%ds", sign, secs)
+	}
+
+	return strings.TrimRight(fmt.Sprintf("%s%d.%09d", sign, secs, nanos), "0") + "s"
+}
+
+// timestampDates converts the Timestamps of the message's JSON value into the Dates,
+// the Dates have the milliseconds' precision so the finer part is truncated.
+func timestampDates(rt *goja.Runtime, md protoreflect.MessageDescriptor, v interface{}) {
+	grpcext.ConvertFields(md, v, func(fd protoreflect.FieldDescriptor, v interface{}) (interface{}, bool) {
+		if fd.Message() == nil || fd.Message().FullName() != timestampMessage {
+			return v, false
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			return v, true
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return v, true
+		}
+
+		date, err := rt.New(rt.Get("Date"), rt.ToValue(t.UnixMilli()))
+		if err != nil {
+			return v, true
+		}
+
+		return date, true
+	})
+}
+
+// responseDates converts the Timestamps of the unary call's response message into the Dates,
+// the lazily converted message is converted once it's requested.
+func responseDates(rt *goja.Runtime, md protoreflect.MessageDescriptor, resp *grpcext.Response) {
+	if resp.Message != nil {
+		timestampDates(rt, md, resp.Message)
+	}
+
+	convert := resp.JSON
+	if convert == nil {
+		return
+	}
+
+	resp.JSON = func() (interface{}, error) {
+		v, err := convert()
+		if err == nil {
+			timestampDates(rt, md, v)
+		}
+
+		return v, err
+	}
+}
@@ -0,0 +1,177 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"go.k6.io/k6/metrics"
+)
+
+// xdsReporters push the metrics of the process' xDS client, which is shared by all the VUs,
+// so each message is pushed once, by the first VU connected to an xds: target still running.
+type xdsReporters struct {
+	// stop stops observing the xDS client, it has a lock of its own, since the observe locks the mu
+	// while the observers are locked, and stopping locks the observers
+	stopMu sync.Mutex
+	stop   func()
+
+	mu        sync.Mutex
+	reporters []*xdsReporter
+}
+
+type xdsReporter struct {
+	ctx         context.Context //nolint:containedctx
+	client      *Client
+	tagsAndMeta metrics.TagsAndMeta
+}
+
+// register makes the client connected to the xds: target a reporter of the xDS client's metrics
+func (r *xdsReporters) register(c *Client) {
+	r.stopMu.Lock()
+	if r.stop == nil {
+		r.stop = grpcext.ObserveXDS(r.observe)
+	}
+	r.stopMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rep := range r.reporters {
+		if rep.client == c {
+			return
+		}
+	}
+
+	r.reporters = append(r.reporters, &xdsReporter{
+		ctx:         c.vu.Context(),
+		client:      c,
+		tagsAndMeta: c.connTagsAndMeta(),
+	})
+}
+
+// close stops observing the xDS client, so its status isn't polled after the test's end
+func (r *xdsReporters) close() {
+	r.stopMu.Lock()
+	if r.stop != nil {
+		r.stop()
+		r.stop = nil
+	}
+	r.stopMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reporters = nil
+}
+
+// reporter returns the first reporter still running, the others are dropped
+func (r *xdsReporters) reporter() *xdsReporter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for len(r.reporters) > 0 && r.reporters[0].ctx.Err() != nil {
+		r.reporters = r.reporters[1:]
+	}
+
+	if len(r.reporters) == 0 {
+		return nil
+	}
+
+	return r.reporters[0]
+}
+
+func (r *xdsReporters) observe(e grpcext.XDSEvent) {
+	rep := r.reporter()
+	if rep == nil {
+		return
+	}
+
+	c := rep.client
+
+	var metric *metrics.Metric
+	switch e.Type {
+	case grpcext.XDSUpdate:
+		metric = c.metrics.XDSUpdates
+	case grpcext.XDSAck:
+		metric = c.metrics.XDSAcks
+	case grpcext.XDSNack:
+		metric = c.metrics.XDSNacks
+	default:
+		return
+	}
+
+	tm := rep.tagsAndMeta
+	tm.Tags = tm.Tags.With("resource", e.Resource)
+	c.pushMetric(metric, &tm, 1)
+}
+
+// watchXDSEvents passes the updates of the resources watched by the xDS client
+// to the client's xdsUpdate listeners while the client is connected
+func (c *Client) watchXDSEvents() {
+	stop := grpcext.ObserveXDS(func(e grpcext.XDSEvent) {
+		if e.Type != grpcext.XDSUpdate {
+			return
+		}
+
+		c.events.post(func() error {
+			return c.deliverXDSUpdate(e)
+		})
+	})
+
+	ctx := c.vu.Context()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	c.stopXDS = stop
+}
+
+// stopXDSEvents stops observing the xDS events of the client's connection
+func (c *Client) stopXDSEvents() {
+	if c.stopXDS != nil {
+		c.stopXDS()
+		c.stopXDS = nil
+	}
+}
+
+// deliverXDSUpdate calls the xdsUpdate's listeners with the update, its time is the time
+// it was received, so the config's propagation could be related to the latency.
+func (c *Client) deliverXDSUpdate(e grpcext.XDSEvent) error {
+	return c.callXDSListeners(eventXDSUpdate, e.Time, map[string]interface{}{
+		"resource": e.Resource,
+		"version":  e.Version,
+		"names":    e.Names,
+	})
+}
+
+// callXDSListeners calls the event's listeners with the event's fields and its time as a Date
+func (c *Client) callXDSListeners(event string, t time.Time, fields map[string]interface{}) error {
+	rt := c.vu.Runtime()
+
+	date, err := rt.New(rt.Get("Date"), rt.ToValue(t.UnixNano()/int64(time.Millisecond)))
+	if err != nil {
+		return err
+	}
+
+	obj := rt.NewObject()
+	if err = obj.Set("time", date); err != nil {
+		return err
+	}
+
+	for k, v := range fields {
+		if err = obj.Set(k, v); err != nil {
+			return err
+		}
+	}
+
+	return c.listeners.call(event, obj)
+}
+
+// isXDSTarget tells whether the target is resolved by the xDS
+func isXDSTarget(target string) bool {
+	return strings.HasPrefix(target, "xds:")
+}
@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/farzanhaq/xk6-grpc-xds/lib/netext/grpcext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_DispatchXDSEvents(t *testing.T) {
+	t.Parallel()
+
+	testRuntime, _ := newParamsTestRuntime(t, `{}`)
+	rt := testRuntime.VU.Runtime()
+
+	c := &Client{vu: testRuntime.VU, listeners: newClientEventListeners(), events: newClientEvents(testRuntime.VU)}
+	require.NoError(t, c.events.flush())
+
+	var updates []*goja.Object
+	require.NoError(t, c.On(eventXDSUpdate, func(v goja.Value) (goja.Value, error) {
+		updates = append(updates, v.ToObject(rt))
+		return goja.Undefined(), nil
+	}))
+
+	received := time.Now()
+	update := grpcext.XDSEvent{
+		Type: grpcext.XDSUpdate, Resource: "cluster", Version: "2", Names: []string{"a"}, Time: received,
+	}
+	c.events.post(func() error { return c.deliverXDSUpdate(update) })
+
+	require.NoError(t, c.events.flush())
+	require.NoError(t, c.events.flush())
+
+	require.Len(t, updates, 1)
+	assert.Equal(t, "cluster", updates[0].Get("resource").String())
+	assert.Equal(t, "2", updates[0].Get("version").String())
+	assert.Equal(t, []string{"a"}, updates[0].Get("names").Export())
+
+	date, ok := updates[0].Get("time").Export().(time.Time)
+	require.True(t, ok)
+	assert.Equal(t, received.UnixNano()/int64(time.Millisecond), date.UnixNano()/int64(time.Millisecond))
+}
+
+func TestXDSReporters_Close(t *testing.T) {
+	t.Parallel()
+
+	testRuntime, _ := newParamsTestRuntime(t, `{}`)
+	c := &Client{vu: testRuntime.VU}
+
+	var r xdsReporters
+	r.register(c)
+	require.NotNil(t, r.stop)
+	require.Len(t, r.reporters, 1)
+
+	r.close()
+	assert.Nil(t, r.stop)
+	assert.Empty(t, r.reporters)
+
+	// the reporters observe the xDS client again once a client is registered after
+	r.register(c)
+	assert.NotNil(t, r.stop)
+	r.close()
+}
@@ -0,0 +1,66 @@
+package grpcext
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/leastrequest"
+	_ "google.golang.org/grpc/balancer/weightedroundrobin" // registers the weighted_round_robin policy
+)
+
+// The load balancing policies of the connections, the policies delivered by the xDS are used by the xds: targets.
+const (
+	PolicyPickFirst          = "pick_first"
+	PolicyRoundRobin         = "round_robin"
+	PolicyLeastRequest       = "least_request"
+	PolicyWeightedRoundRobin = "weighted_round_robin"
+)
+
+// policyNames are the gRPC's names of the policies registered under the experimental names
+var policyNames = map[string]string{ //nolint:gochecknoglobals
+	PolicyLeastRequest: leastrequest.Name,
+}
+
+// LoadBalancing is the load balancing policy of the calls across the target's addresses.
+type LoadBalancing struct {
+	// Policy is the name of the policy, e.g. least_request
+	Policy string
+	// Config is the policy's config, as the loadBalancingConfig of the gRPC's service config has it,
+	// e.g. the choiceCount of the least_request or the enableOobLoadReport of the weighted_round_robin,
+	// which weights the addresses by the ORCA load reports of the backends.
+	Config map[string]interface{}
+}
+
+// WithLoadBalancing returns the dial option balancing the calls by the policy,
+// it fails if the policy isn't registered or its config is invalid.
+func WithLoadBalancing(lb LoadBalancing) (grpc.DialOption, error) {
+	name := lb.Policy
+	if n, ok := policyNames[name]; ok {
+		name = n
+	}
+
+	builder := balancer.Get(name)
+	if builder == nil {
+		return nil, fmt.Errorf("unknown load balancing policy %q", lb.Policy)
+	}
+
+	config := lb.Config
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", lb.Policy, err)
+	}
+
+	if parser, ok := builder.(balancer.ConfigParser); ok {
+		if _, err = parser.ParseConfig(b); err != nil {
+			return nil, fmt.Errorf("invalid %s config: %w", lb.Policy, err)
+		}
+	}
+
+	return grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig":[{%q:%s}]}`, name, b)), nil
+}
@@ -0,0 +1,60 @@
+package grpcext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/roundrobin"
+)
+
+func TestWithLoadBalancing(t *testing.T) {
+	t.Parallel()
+
+	for _, lb := range []LoadBalancing{
+		{Policy: PolicyPickFirst},
+		{Policy: PolicyRoundRobin},
+		{Policy: PolicyLeastRequest, Config: map[string]interface{}{"choiceCount": 4}},
+		{Policy: PolicyWeightedRoundRobin, Config: map[string]interface{}{
+			"enableOobLoadReport": true,
+			"blackoutPeriod":      "1s",
+			"weightUpdatePeriod":  "0.5s",
+		}},
+	} {
+		opt, err := WithLoadBalancing(lb)
+		require.NoError(t, err, lb.Policy)
+		assert.NotNil(t, opt)
+	}
+
+	_, err := WithLoadBalancing(LoadBalancing{Policy: "random"})
+	require.ErrorContains(t, err, `unknown load balancing policy "random"`)
+
+	_, err = WithLoadBalancing(LoadBalancing{
+		Policy: PolicyWeightedRoundRobin,
+		Config: map[string]interface{}{"blackoutPeriod": true},
+	})
+	require.ErrorContains(t, err, "invalid weighted_round_robin config")
+}
+
+// renamedBuilder is a builder of the wrapped policy under another name
+type renamedBuilder struct {
+	balancer.Builder
+	name string
+}
+
+func (b renamedBuilder) Name() string {
+	return b.name
+}
+
+func TestRegisterBalancer(t *testing.T) { //nolint:paralleltest // it registers the process-wide policy
+	RegisterBalancer(renamedBuilder{Builder: balancer.Get(roundrobin.Name), name: "k6_test_custom"})
+
+	opt, err := WithLoadBalancing(LoadBalancing{Policy: "k6_test_custom"})
+	require.NoError(t, err)
+	assert.NotNil(t, opt)
+
+	assert.PanicsWithValue(t, `the load balancing policy "round_robin" is already registered`, func() {
+		RegisterBalancer(balancer.Get(roundrobin.Name))
+	})
+}
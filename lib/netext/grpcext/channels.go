@@ -0,0 +1,48 @@
+package grpcext
+
+import (
+	"context"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+// channelPool is the pool of the client connections to the same target,
+// the RPCs are round-robined across them.
+type channelPool struct {
+	conns []*grpc.ClientConn
+	next  uint32
+}
+
+var _ clientConnCloser = &channelPool{}
+
+// pick returns the next connection in the round robin order
+func (p *channelPool) pick() *grpc.ClientConn {
+	n := atomic.AddUint32(&p.next, 1)
+
+	return p.conns[(n-1)%uint32(len(p.conns))]
+}
+
+func (p *channelPool) Invoke(
+	ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption,
+) error {
+	return p.pick().Invoke(ctx, method, args, reply, opts...)
+}
+
+func (p *channelPool) NewStream(
+	ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	return p.pick().NewStream(ctx, desc, method, opts...)
+}
+
+// Close closes all the connections, it returns the first error.
+func (p *channelPool) Close() error {
+	var err error
+	for _, conn := range p.conns {
+		if cerr := conn.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,133 @@
+package grpcext
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	channelzservice "google.golang.org/grpc/channelz/service"
+)
+
+// ChannelInfo is the channelz view of a channel.
+type ChannelInfo struct {
+	Target         string           `js:"target"`
+	State          string           `js:"state"`
+	CallsStarted   int64            `js:"callsStarted"`
+	CallsSucceeded int64            `js:"callsSucceeded"`
+	CallsFailed    int64            `js:"callsFailed"`
+	Subchannels    []SubchannelInfo `js:"subchannels"`
+}
+
+// SubchannelInfo is the channelz view of a channel's subchannel, its target is the address it's connected to.
+type SubchannelInfo struct {
+	Target         string `js:"target"`
+	State          string `js:"state"`
+	CallsStarted   int64  `js:"callsStarted"`
+	CallsSucceeded int64  `js:"callsSucceeded"`
+	CallsFailed    int64  `js:"callsFailed"`
+}
+
+// channelzRegistrar captures the channelz service's implementation, so it's queried in-process.
+type channelzRegistrar struct {
+	server channelzpb.ChannelzServer
+}
+
+func (r *channelzRegistrar) RegisterService(_ *grpc.ServiceDesc, impl interface{}) {
+	r.server, _ = impl.(channelzpb.ChannelzServer)
+}
+
+// errChannelzDisabled is returned by the channelz view before the channelz is enabled
+var errChannelzDisabled = errors.New("the channelz isn't enabled")
+
+// channelz is the in-process channelz service, it's created once the channelz is enabled
+var channelz struct { //nolint:gochecknoglobals
+	once   sync.Once
+	server atomic.Pointer[channelzpb.ChannelzServer]
+}
+
+// EnableChannelz turns the gRPC's channelz on for the whole process, so the channels dialed since then
+// are tracked. It isn't turned on by default, since it keeps the channels' and the calls' data.
+func EnableChannelz() {
+	channelz.once.Do(func() {
+		r := &channelzRegistrar{}
+		channelzservice.RegisterChannelzServiceToServer(r)
+		channelz.server.Store(&r.server)
+	})
+}
+
+// Channelz returns the channelz view of the channels connected to the connection's target,
+// including the channels of the other connections to the same target in the process.
+// Only the channels dialed after the channelz was enabled are included.
+func (c *Conn) Channelz(ctx context.Context) ([]ChannelInfo, error) {
+	server := channelz.server.Load()
+	if server == nil {
+		return nil, errChannelzDisabled
+	}
+
+	var (
+		channels []ChannelInfo
+		start    int64
+	)
+
+	for {
+		resp, err := (*server).GetTopChannels(ctx, &channelzpb.GetTopChannelsRequest{StartChannelId: start})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ch := range resp.GetChannel() {
+			start = ch.GetRef().GetChannelId() + 1
+
+			if ch.GetData().GetTarget() != c.target {
+				continue
+			}
+
+			channels = append(channels, channelInfo(ctx, *server, ch))
+		}
+
+		if resp.GetEnd() || len(resp.GetChannel()) == 0 {
+			return channels, nil
+		}
+	}
+}
+
+// channelInfo converts the channel and fetches its subchannels
+func channelInfo(ctx context.Context, server channelzpb.ChannelzServer, ch *channelzpb.Channel) ChannelInfo {
+	data := ch.GetData()
+	info := ChannelInfo{
+		Target:         data.GetTarget(),
+		State:          stateName(data.GetState()),
+		CallsStarted:   data.GetCallsStarted(),
+		CallsSucceeded: data.GetCallsSucceeded(),
+		CallsFailed:    data.GetCallsFailed(),
+		Subchannels:    []SubchannelInfo{},
+	}
+
+	for _, ref := range ch.GetSubchannelRef() {
+		resp, err := server.GetSubchannel(ctx, &channelzpb.GetSubchannelRequest{SubchannelId: ref.GetSubchannelId()})
+		if err != nil {
+			// the subchannel could have been removed meanwhile
+			continue
+		}
+
+		data := resp.GetSubchannel().GetData()
+		info.Subchannels = append(info.Subchannels, SubchannelInfo{
+			Target:         data.GetTarget(),
+			State:          stateName(data.GetState()),
+			CallsStarted:   data.GetCallsStarted(),
+			CallsSucceeded: data.GetCallsSucceeded(),
+			CallsFailed:    data.GetCallsFailed(),
+		})
+	}
+
+	return info
+}
+
+// stateName returns the name of the connectivity state, e.g. "READY"
+func stateName(s *channelzpb.ChannelConnectivityState) string {
+	return strings.ToUpper(s.GetState().String())
+}
@@ -0,0 +1,43 @@
+package grpcext
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
+)
+
+// rawCodec passes the already marshaled protobuf messages through as they are, the other
+// protobuf messages (e.g. the response of the marshaled request) are marshaled as usual.
+// It's named as the proto codec, so the content type stays the same and the servers decode the messages.
+type rawCodec struct{}
+
+var _ encoding.Codec = rawCodec{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case *[]byte:
+		return *m, nil
+	case proto.Message:
+		return proto.Marshal(m)
+	default:
+		return nil, fmt.Errorf("the raw codec can't marshal %T", v)
+	}
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case *[]byte:
+		// the data's buffer could be reused by the gRPC
+		*m = append((*m)[:0], data...)
+		return nil
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	default:
+		return fmt.Errorf("the raw codec can't unmarshal into %T", v)
+	}
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -4,28 +4,34 @@ package grpcext
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/netext"
 	"go.k6.io/k6/metrics"
 
 	protov1 "github.com/golang/protobuf/proto" //nolint:staticcheck,nolintlint // this is the old v1 version
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/metadata"
 	grpcstats "google.golang.org/grpc/stats"
 	"google.golang.org/grpc/status"
+	_ "google.golang.org/grpc/xds"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/dynamicpb"
-	_ "google.golang.org/grpc/xds"
 )
 
 // Request represents a gRPC request.
@@ -33,6 +39,26 @@ type Request struct {
 	MethodDescriptor protoreflect.MethodDescriptor
 	TagsAndMeta      *metrics.TagsAndMeta
 	Message          []byte
+	// Marshaled reports whether the Message is the marshaled protobuf instead of the JSON,
+	// then it's sent as it is.
+	Marshaled bool
+	// LazyMessage leaves the response's message unconverted, it's converted by the response's JSON or Binary.
+	LazyMessage bool
+	// Int64Numbers converts the response's 64-bit integers into the numbers if they're represented exactly,
+	// they're the strings otherwise.
+	Int64Numbers bool
+	// DiscardUnknown ignores the JSON Message's unknown fields instead of failing the call
+	DiscardUnknown bool
+	// ExpectedStatus reports whether the status is expected, if it's set
+	// the samples are tagged with the expected_response.
+	ExpectedStatus func(codes.Code) bool
+}
+
+// RawRequest represents a gRPC request with the already marshaled protobuf message.
+type RawRequest struct {
+	TagsAndMeta    *metrics.TagsAndMeta
+	Message        []byte
+	ExpectedStatus func(codes.Code) bool
 }
 
 // StreamRequest represents a gRPC stream request.
@@ -41,6 +67,11 @@ type StreamRequest struct {
 	MethodDescriptor protoreflect.MethodDescriptor
 	TagsAndMeta      *metrics.TagsAndMeta
 	Metadata         metadata.MD
+	ExpectedStatus   func(codes.Code) bool
+	// Int64Numbers converts the received messages' 64-bit integers like the Request's
+	Int64Numbers bool
+	// DiscardUnknown ignores the sent messages' unknown fields like the Request's
+	DiscardUnknown bool
 }
 
 // Response represents a gRPC response.
@@ -50,6 +81,40 @@ type Response struct {
 	Headers  map[string][]string
 	Trailers map[string][]string
 	Status   codes.Code
+	// OK is set if the call has finished with the OK status, e.g. for the checks
+	OK bool `js:"ok"`
+	// ErrorKind is the kind of the call's error (e.g. circuit_breaker), empty if it isn't classified
+	ErrorKind string `js:"errorKind"`
+	// TrailersOnly is set if the server (or a proxy) has replied with the status only, without the headers
+	TrailersOnly bool `js:"trailersOnly"`
+	// Trace is the trace context propagated with the call, if it's enabled
+	Trace *TraceContext
+	// GRPCTimeout is the grpc-timeout header sent with the call, i.e. the time left until its deadline
+	GRPCTimeout string `js:"grpcTimeout"`
+
+	// JSON and Binary return the message as the JS value or the marshaled protobuf,
+	// the lazy message is converted on demand.
+	JSON   func() (interface{}, error) `js:"json"`
+	Binary func() (interface{}, error) `js:"binary"`
+	// Oneofs returns the message's set oneof members by the oneofs, see the collectOneofs
+	Oneofs func() map[string]string `js:"oneofs"`
+	// Unknown returns the message's fields unknown to its descriptors, see the collectUnknown
+	Unknown func() map[string]interface{} `js:"unknown"`
+}
+
+// ErrorMessage returns the message of the call's error status, it's empty if the call hasn't failed
+func (r *Response) ErrorMessage() string {
+	if r.Status == codes.OK {
+		return ""
+	}
+
+	if e, ok := r.Error.(map[string]interface{}); ok {
+		if msg, ok := e["message"].(string); ok {
+			return msg
+		}
+	}
+
+	return r.Status.String()
 }
 
 type clientConnCloser interface {
@@ -59,23 +124,110 @@ type clientConnCloser interface {
 
 // Conn is a gRPC client connection.
 type Conn struct {
-	raw clientConnCloser
+	raw    clientConnCloser
+	target string
 }
 
 // DefaultOptions generates an option set
-// with common options for requests from a VU.
+// with common options for requests from a VU, including the registered extensions' ones.
 func DefaultOptions(getState func() *lib.State) []grpc.DialOption {
-	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
-		return getState().Dialer.DialContext(ctx, "tcp", addr)
-	}
-
-	return []grpc.DialOption{
+	opts := []grpc.DialOption{
 		grpc.WithBlock(),
 		grpc.FailOnNonTempDialError(true),
 		grpc.WithReturnConnectionError(),
 		grpc.WithStatsHandler(statsHandler{getState: getState}),
-		grpc.WithContextDialer(dialer),
+		grpc.WithContextDialer(Dialer(getState, nil)),
 	}
+
+	return append(opts, ExtensionOptions(getState)...)
+}
+
+// Dialer returns the dialer of the connections using the k6's dialer,
+// the connections are tunneled through the HTTP proxy if it's set.
+func Dialer(getState func() *lib.State, proxyURL *url.URL) func(context.Context, string) (net.Conn, error) {
+	return SocketDialer(getState, proxyURL, nil)
+}
+
+// SocketDialer returns the Dialer tuning the TCP connections by the socket options, if they're set.
+func SocketDialer(
+	getState func() *lib.State, proxyURL *url.URL, socket *SocketOptions,
+) func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		if path, ok := unixSocketPath(addr); ok {
+			return dialUnix(ctx, getState(), path)
+		}
+
+		dialer := socket.dialer(getState().Dialer)
+
+		var (
+			conn net.Conn
+			err  error
+		)
+		if proxyURL != nil {
+			conn, err = dialProxy(ctx, dialer, proxyURL, addr)
+		} else {
+			conn, err = dialer.DialContext(ctx, "tcp", addr)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err = socket.apply(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+
+		return conn, nil
+	}
+}
+
+// WithDialObserver returns a dial option using the SocketDialer, which reports the duration of each
+// established connection, including the re-dials. It overrides the dialer of the DefaultOptions,
+// so it needs to be placed after them.
+func WithDialObserver(
+	getState func() *lib.State, proxyURL *url.URL, socket *SocketOptions, observe func(d time.Duration),
+) grpc.DialOption {
+	dial := SocketDialer(getState, proxyURL, socket)
+
+	return grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		start := time.Now()
+
+		conn, err := dial(ctx, addr)
+		if err == nil {
+			observe(time.Since(start))
+		}
+
+		return conn, err
+	})
+}
+
+// unixSocketPath returns the socket path of the unix (or unix-abstract) targets,
+// as gRPC passes them to the custom dialers: unix://absolute-path, unix:relative-path or unix:@abstract-name.
+func unixSocketPath(addr string) (string, bool) {
+	if path := strings.TrimPrefix(addr, "unix://"); path != addr {
+		return path, true
+	}
+
+	if path := strings.TrimPrefix(addr, "unix:"); path != addr {
+		return path, true
+	}
+
+	return "", false
+}
+
+// dialUnix connects to the unix domain socket, bypassing the k6's dialer which supports only
+// the IP networks, while the data sent and received are still counted by it.
+func dialUnix(ctx context.Context, state *lib.State, path string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if d, ok := state.Dialer.(*netext.Dialer); ok {
+		conn = &netext.Conn{Conn: conn, BytesRead: &d.BytesRead, BytesWritten: &d.BytesWritten}
+	}
+
+	return conn, nil
 }
 
 // Dial establish a gRPC connection.
@@ -85,14 +237,187 @@ func Dial(ctx context.Context, addr string, options ...grpc.DialOption) (*Conn,
 		return nil, err
 	}
 	return &Conn{
-		raw: conn,
+		raw:    conn,
+		target: addr,
 	}, nil
 }
 
-// Reflect returns using the reflection the FileDescriptorSet describing the service.
-func (c *Conn) Reflect(ctx context.Context) (*descriptorpb.FileDescriptorSet, error) {
+// DialChannels establishes a pool of the n gRPC connections (the channels) to the address,
+// the RPCs are round-robined across them, so the throughput isn't capped by a single HTTP/2
+// connection's MAX_CONCURRENT_STREAMS and flow control.
+func DialChannels(ctx context.Context, addr string, n int, options ...grpc.DialOption) (*Conn, error) {
+	if n <= 1 {
+		return Dial(ctx, addr, options...)
+	}
+
+	pool := &channelPool{conns: make([]*grpc.ClientConn, 0, n)}
+	for i := 0; i < n; i++ {
+		conn, err := grpc.DialContext(ctx, addr, options...)
+		if err != nil {
+			_ = pool.Close()
+			return nil, err
+		}
+
+		pool.conns = append(pool.conns, conn)
+	}
+
+	return &Conn{
+		raw:    pool,
+		target: addr,
+	}, nil
+}
+
+// clientConns returns the underlying client connections, there are none for the mocked ones.
+func (c *Conn) clientConns() []*grpc.ClientConn {
+	switch raw := c.raw.(type) {
+	case *grpc.ClientConn:
+		return []*grpc.ClientConn{raw}
+	case *channelPool:
+		return raw.conns
+	default:
+		return nil
+	}
+}
+
+// Channels returns the number of the connection's channels.
+func (c *Conn) Channels() int {
+	if pool, ok := c.raw.(*channelPool); ok {
+		return len(pool.conns)
+	}
+
+	return 1
+}
+
+// ReadyChannels returns the number of the connection's channels in the READY state.
+func (c *Conn) ReadyChannels() int {
+	ccs := c.clientConns()
+	if len(ccs) == 0 {
+		return 1
+	}
+
+	ready := 0
+	for _, cc := range ccs {
+		if cc.GetState() == connectivity.Ready {
+			ready++
+		}
+	}
+
+	return ready
+}
+
+// AwaitConnection waits while the connection (all its channels) is being established, it returns false
+// if the context is done before the connection is ready or has failed.
+func (c *Conn) AwaitConnection(ctx context.Context) bool {
+	for _, cc := range c.clientConns() {
+		if !awaitConnection(ctx, cc) {
+			return false
+		}
+	}
+
+	return ctx.Err() == nil
+}
+
+func awaitConnection(ctx context.Context, cc *grpc.ClientConn) bool {
+	for {
+		state := cc.GetState()
+		if state != connectivity.Idle && state != connectivity.Connecting {
+			return ctx.Err() == nil
+		}
+
+		cc.Connect()
+		if !cc.WaitForStateChange(ctx, state) {
+			return false
+		}
+	}
+}
+
+// State returns the connectivity state of the connection, the states of the channels are aggregated
+// like the balancers do: it's READY if any channel is ready, then CONNECTING, IDLE and TRANSIENT_FAILURE.
+func (c *Conn) State() connectivity.State {
+	ccs := c.clientConns()
+	if len(ccs) == 0 {
+		return connectivity.Ready
+	}
+
+	states := make(map[connectivity.State]bool, len(ccs))
+	for _, cc := range ccs {
+		states[cc.GetState()] = true
+	}
+
+	for _, state := range []connectivity.State{
+		connectivity.Ready, connectivity.Connecting, connectivity.Idle, connectivity.TransientFailure,
+	} {
+		if states[state] {
+			return state
+		}
+	}
+
+	return connectivity.Shutdown
+}
+
+// WaitForState waits until the connection (all its channels) gets into the state,
+// the idle channels start connecting. It returns false if the context is done before.
+func (c *Conn) WaitForState(ctx context.Context, target connectivity.State) bool {
+	ccs := c.clientConns()
+	if len(ccs) == 0 {
+		return target == connectivity.Ready
+	}
+
+	for _, cc := range ccs {
+		if !waitForState(ctx, cc, target) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func waitForState(ctx context.Context, cc *grpc.ClientConn, target connectivity.State) bool {
+	for {
+		state := cc.GetState()
+		if state == target {
+			return true
+		}
+
+		if state == connectivity.Idle {
+			cc.Connect()
+		}
+
+		if !cc.WaitForStateChange(ctx, state) {
+			return false
+		}
+	}
+}
+
+// ExitIdle connects the idle channels, waiting until they're connected or failed to.
+// It returns how long it took, false if none of the channels was idle.
+func (c *Conn) ExitIdle(ctx context.Context) (time.Duration, bool) {
+	start := time.Now()
+	idle := false
+
+	for _, cc := range c.clientConns() {
+		if cc.GetState() != connectivity.Idle {
+			continue
+		}
+
+		idle = true
+		cc.Connect()
+
+		for state := cc.GetState(); state == connectivity.Idle || state == connectivity.Connecting; state = cc.GetState() {
+			if !cc.WaitForStateChange(ctx, state) {
+				break
+			}
+		}
+	}
+
+	return time.Since(start), idle
+}
+
+// Reflect returns using the reflection the FileDescriptorSet describing the service,
+// limited to the given symbols if any.
+func (c *Conn) Reflect(ctx context.Context, symbols ...string) (*descriptorpb.FileDescriptorSet, error) {
 	rc := reflectionClient{Conn: c.raw}
-	return rc.Reflect(ctx)
+	return rc.Reflect(ctx, symbols...)
 }
 
 // Invoke executes a unary gRPC request.
@@ -115,25 +440,37 @@ func (c *Conn) Invoke(
 
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
-	reqdm := dynamicpb.NewMessage(req.MethodDescriptor.Input())
-	if err := protojson.Unmarshal(req.Message, reqdm); err != nil {
-		return nil, fmt.Errorf("unable to serialise request object to protocol buffer: %w", err)
+	var reqm interface{}
+	if req.Marshaled {
+		reqm = &req.Message
+	} else {
+		reqdm := dynamicpb.NewMessage(req.MethodDescriptor.Input())
+		unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: req.DiscardUnknown}
+		if err := unmarshaler.Unmarshal(req.Message, reqdm); err != nil {
+			return nil, fmt.Errorf("unable to serialise request object to protocol buffer: %w", err)
+		}
+		reqm = reqdm
 	}
 
-	ctx = withRPCState(ctx, &rpcState{tagsAndMeta: req.TagsAndMeta})
+	stateRPC := &rpcState{tagsAndMeta: req.TagsAndMeta, expectedStatus: req.ExpectedStatus}
+	ctx = withRPCState(ctx, stateRPC)
 
 	resp := dynamicpb.NewMessage(req.MethodDescriptor.Output())
 	header, trailer := metadata.New(nil), metadata.New(nil)
 
-	copts := make([]grpc.CallOption, 0, len(opts)+2)
+	copts := make([]grpc.CallOption, 0, len(opts)+3)
 	copts = append(copts, opts...)
 	copts = append(copts, grpc.Header(&header), grpc.Trailer(&trailer))
+	if req.Marshaled {
+		copts = append(copts, grpc.ForceCodec(rawCodec{}))
+	}
 
-	err := c.raw.Invoke(ctx, url, reqdm, resp, copts...)
+	err := c.raw.Invoke(ctx, url, reqm, resp, copts...)
 
 	response := Response{
-		Headers:  header,
-		Trailers: trailer,
+		Headers:      header,
+		Trailers:     trailer,
+		TrailersOnly: stateRPC.trailersOnly(),
 	}
 
 	marshaler := protojson.MarshalOptions{EmitUnpopulated: true}
@@ -141,23 +478,20 @@ func (c *Conn) Invoke(
 	if err != nil {
 		sterr := status.Convert(err)
 		response.Status = sterr.Code()
+		response.Error = convertStatus(marshaler, sterr)
+		response.ErrorKind = stateRPC.errorKind(err)
 
-		// (rogchap) when you access a JSON property in goja, you are actually accessing the underling
-		// Go type (struct, map, slice etc); because these are dynamic messages the Unmarshaled JSON does
-		// not map back to a "real" field or value (as a normal Go type would). If we don't marshal and then
-		// unmarshal back to a map, you will get "undefined" when accessing JSON properties, even when
-		// JSON.Stringify() shows the object to be correctly present.
-
-		raw, _ := marshaler.Marshal(sterr.Proto())
-		errMsg := make(map[string]interface{})
-		_ = json.Unmarshal(raw, &errMsg)
-		response.Error = errMsg
+		// the gRPC's own fault filter aborts the RPCs before their attempts, which aren't handled by the stats
+		if IsFaultInjected(err) && req.TagsAndMeta != nil {
+			req.TagsAndMeta.SetTag(FaultTag, FaultInjected)
+		}
 	}
 
-	if resp != nil {
-		msg, err := convert(marshaler, resp)
+	response.setLazyMessage(marshaler, resp, req.Int64Numbers)
+	if !req.LazyMessage {
+		msg, err := response.JSON()
 		if err != nil {
-			return nil, fmt.Errorf("unable to convert response object to JSON: %w", err)
+			return nil, err
 		}
 
 		response.Message = msg
@@ -165,6 +499,159 @@ func (c *Conn) Invoke(
 	return &response, nil
 }
 
+// InvokeRaw executes a unary gRPC request with the already marshaled protobuf message,
+// the response's message is the marshaled protobuf too.
+func (c *Conn) InvokeRaw(
+	ctx context.Context,
+	url string,
+	md metadata.MD,
+	req RawRequest,
+	opts ...grpc.CallOption,
+) (*Response, error) {
+	if url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	stateRPC := &rpcState{tagsAndMeta: req.TagsAndMeta, expectedStatus: req.ExpectedStatus}
+	ctx = withRPCState(ctx, stateRPC)
+
+	reqb, resp := req.Message, []byte{}
+	header, trailer := metadata.New(nil), metadata.New(nil)
+
+	copts := make([]grpc.CallOption, 0, len(opts)+3)
+	copts = append(copts, opts...)
+	copts = append(copts, grpc.ForceCodec(rawCodec{}), grpc.Header(&header), grpc.Trailer(&trailer))
+
+	err := c.raw.Invoke(ctx, url, &reqb, &resp, copts...)
+
+	response := Response{
+		Headers:      header,
+		Trailers:     trailer,
+		Message:      resp,
+		TrailersOnly: stateRPC.trailersOnly(),
+	}
+	response.setRawMessage()
+
+	if err != nil {
+		sterr := status.Convert(err)
+		response.Status = sterr.Code()
+		response.Error = convertStatus(protojson.MarshalOptions{EmitUnpopulated: true}, sterr)
+		response.ErrorKind = stateRPC.errorKind(err)
+		response.Message = nil
+	}
+
+	return &response, nil
+}
+
+// setLazyMessage sets the response's JSON, Binary, Oneofs and Unknown converting the message once they're called,
+// the JSON's result is kept for the subsequent calls. The Message stays unset.
+func (r *Response) setLazyMessage(marshaler protojson.MarshalOptions, msg *dynamicpb.Message, numbers bool) {
+	var converted interface{}
+
+	r.JSON = func() (interface{}, error) {
+		if converted != nil {
+			return converted, nil
+		}
+
+		var err error
+		if converted, err = convert(marshaler, msg); err != nil {
+			return nil, fmt.Errorf("unable to convert response object to JSON: %w", err)
+		}
+		if numbers {
+			int64Numbers(msg.Descriptor(), converted)
+		}
+
+		return converted, nil
+	}
+
+	r.Binary = func() (interface{}, error) {
+		b, err := proto.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal response object: %w", err)
+		}
+
+		return b, nil
+	}
+
+	r.Oneofs = func() map[string]string {
+		oneofs := make(map[string]string)
+		collectOneofs(msg, "", oneofs)
+
+		return oneofs
+	}
+
+	r.Unknown = func() map[string]interface{} {
+		unknown := make(map[string][]byte)
+		collectUnknown(msg, "", unknown)
+
+		fields := make(map[string]interface{}, len(unknown))
+		for k, b := range unknown {
+			fields[k] = b
+		}
+
+		return fields
+	}
+}
+
+// setRawMessage sets the response's JSON and Binary returning the Message as it's set,
+// the marshaled protobuf is returned by the Binary only.
+func (r *Response) setRawMessage() {
+	r.JSON = func() (interface{}, error) {
+		if r.Message == nil {
+			return nil, nil
+		}
+
+		return nil, errors.New("the marshaled protobuf message can't be converted without its descriptors")
+	}
+
+	r.Binary = func() (interface{}, error) {
+		return r.Message, nil
+	}
+
+	// the marshaled protobuf's oneofs and unknown fields aren't known
+	r.Oneofs = func() map[string]string {
+		return map[string]string{}
+	}
+	r.Unknown = func() map[string]interface{} {
+		return map[string]interface{}{}
+	}
+}
+
+// NewStatusResponse creates a response for a call that has been finished
+// locally with the given status, without sending anything to the server.
+func NewStatusResponse(st *status.Status) *Response {
+	marshaler := protojson.MarshalOptions{EmitUnpopulated: true}
+
+	response := &Response{
+		Headers:  metadata.New(nil),
+		Trailers: metadata.New(nil),
+		Status:   st.Code(),
+	}
+	response.setRawMessage()
+
+	if st.Code() != codes.OK {
+		response.Error = convertStatus(marshaler, st)
+	}
+
+	return response
+}
+
+// convertStatus converts the status to the error object which could be returned to the JS
+func convertStatus(marshaler protojson.MarshalOptions, st *status.Status) map[string]interface{} {
+	// (rogchap) when you access a JSON property in goja, you are actually accessing the underling
+	// Go type (struct, map, slice etc); because these are dynamic messages the Unmarshaled JSON does
+	// not map back to a "real" field or value (as a normal Go type would). If we don't marshal and then
+	// unmarshal back to a map, you will get "undefined" when accessing JSON properties, even when
+	// JSON.Stringify() shows the object to be correctly present.
+
+	raw, _ := marshaler.Marshal(st.Proto())
+	errMsg := make(map[string]interface{})
+	_ = json.Unmarshal(raw, &errMsg)
+
+	return errMsg
+}
+
 // NewStream creates a new gRPC stream.
 func (c *Conn) NewStream(
 	ctx context.Context,
@@ -173,13 +660,19 @@ func (c *Conn) NewStream(
 ) (*Stream, error) {
 	ctx = metadata.NewOutgoingContext(ctx, req.Metadata)
 
-	ctx = withRPCState(ctx, &rpcState{tagsAndMeta: req.TagsAndMeta})
+	stateRPC := &rpcState{tagsAndMeta: req.TagsAndMeta, expectedStatus: req.ExpectedStatus}
+	ctx = withRPCState(ctx, stateRPC)
+
+	// the raw codec lets the stream send and receive the marshaled messages along with the converted ones
+	copts := make([]grpc.CallOption, 0, len(opts)+1)
+	copts = append(copts, opts...)
+	copts = append(copts, grpc.ForceCodec(rawCodec{}))
 
 	stream, err := c.raw.NewStream(ctx, &grpc.StreamDesc{
 		StreamName:    string(req.MethodDescriptor.Name()),
 		ServerStreams: req.MethodDescriptor.IsStreamingServer(),
 		ClientStreams: req.MethodDescriptor.IsStreamingClient(),
-	}, req.Method, opts...)
+	}, req.Method, copts...)
 	if err != nil {
 		return nil, err
 	}
@@ -188,6 +681,9 @@ func (c *Conn) NewStream(
 		raw:              stream,
 		method:           req.Method,
 		methodDescriptor: req.MethodDescriptor,
+		state:            stateRPC,
+		int64Numbers:     req.Int64Numbers,
+		discardUnknown:   req.DiscardUnknown,
 	}, nil
 }
 
@@ -210,26 +706,40 @@ func (statsHandler) HandleConn(context.Context, grpcstats.ConnStats) {
 	// noop
 }
 
-// TagRPC implements the grpcstats.Handler interface
-func (statsHandler) TagRPC(ctx context.Context, _ *grpcstats.RPCTagInfo) context.Context {
-	// noop
-	return ctx
+// TagRPC implements the grpcstats.Handler interface, it binds the RPC to the VU making it
+// or, if it isn't bound by its context, to the VU dialing the connection
+func (h statsHandler) TagRPC(ctx context.Context, _ *grpcstats.RPCTagInfo) context.Context {
+	if _, ok := ctx.Value(ctxKeyVUState).(*lib.State); ok {
+		return ctx
+	}
+
+	return WithVUState(ctx, h.getState())
 }
 
 // HandleRPC implements the grpcstats.Handler interface
 func (h statsHandler) HandleRPC(ctx context.Context, stat grpcstats.RPCStats) {
-	state := h.getState()
-	stateRPC := getRPCState(ctx) //nolint:ifshort
-
-	// If the request is done by the reflection handler then the tags will be
-	// nil. In this case, we can reuse the VU.State's Tags.
-	if stateRPC == nil {
-		// TODO: investigate this more, there has to be a way to fix it :/
-		ctm := state.Tags.GetCurrentValues()
-		stateRPC = &rpcState{tagsAndMeta: &ctm}
+	state := vuStateOf(ctx, h.getState)
+	stateRPC, ok := rpcStateOf(ctx, state)
+	if !ok {
+		return
 	}
 
 	switch s := stat.(type) {
+	case *grpcstats.Begin:
+		// the retried RPCs' attempts are classified apart
+		stateRPC.received.Store(false)
+		stateRPC.headers.Store(false)
+		stateRPC.fault.Store(false)
+	case *grpcstats.InHeader:
+		stateRPC.headers.Store(true)
+		if IsFaultResponse(s.Header) {
+			stateRPC.fault.Store(true)
+		}
+	case *grpcstats.InTrailer:
+		stateRPC.received.Store(true)
+		if IsFaultResponse(s.Trailer) {
+			stateRPC.fault.Store(true)
+		}
 	case *grpcstats.OutHeader:
 		// TODO: figure out something better, e.g. via TagConn() or TagRPC()?
 		if state.Options.SystemTags.Has(metrics.TagIP) && s.RemoteAddr != nil {
@@ -238,11 +748,24 @@ func (h statsHandler) HandleRPC(ctx context.Context, stat grpcstats.RPCStats) {
 			}
 		}
 	case *grpcstats.End:
+		code := status.Code(s.Error)
 		if state.Options.SystemTags.Has(metrics.TagStatus) {
-			stateRPC.tagsAndMeta.SetSystemTagOrMeta(metrics.TagStatus, strconv.Itoa(int(status.Code(s.Error))))
+			stateRPC.tagsAndMeta.SetSystemTagOrMeta(metrics.TagStatus, strconv.Itoa(int(code)))
+		}
+
+		if stateRPC.expectedStatus != nil && state.Options.SystemTags.Has(metrics.TagExpectedResponse) {
+			stateRPC.tagsAndMeta.SetSystemTagOrMeta(
+				metrics.TagExpectedResponse, strconv.FormatBool(stateRPC.expectedStatus(code)))
+		}
+
+		if kind := stateRPC.errorKind(s.Error); kind != "" {
+			stateRPC.tagsAndMeta.SetTag(ErrorKindTag, kind)
+		}
+		if IsFaultInjected(s.Error) || (s.Error != nil && stateRPC.fault.Load()) {
+			stateRPC.tagsAndMeta.SetTag(FaultTag, FaultInjected)
 		}
 
-		metrics.PushIfNotDone(ctx, state.Samples, metrics.Sample{
+		metrics.PushIfNotDone(samplesContext(ctx), state.Samples, metrics.Sample{
 			TimeSeries: metrics.TimeSeries{
 				Metric: state.BuiltinMetrics.GRPCReqDuration,
 				Tags:   stateRPC.tagsAndMeta.Tags,
@@ -254,13 +777,29 @@ func (h statsHandler) HandleRPC(ctx context.Context, stat grpcstats.RPCStats) {
 	}
 
 	// (rogchap) Re-using --http-debug flag as gRPC is technically still HTTP
-	if state.Options.HTTPDebug.String != "" {
+	httpDebugOption := state.Options.HTTPDebug.String
+	if mode, ok := ctx.Value(ctxKeyDebug).(string); ok {
+		httpDebugOption = mode
+	}
+
+	if httpDebugOption != "" {
 		logger := state.Logger.WithField("source", "http-debug")
-		httpDebugOption := state.Options.HTTPDebug.String
 		DebugStat(logger, stat, httpDebugOption)
 	}
 }
 
+// The modes of the RPCs' debug logging, like the --http-debug flag's values.
+const (
+	DebugHeaders = "headers"
+	DebugFull    = "full"
+)
+
+// WithDebug returns the context of the RPCs logged with the debug mode instead of the --http-debug flag's one,
+// the empty mode disables their logging.
+func WithDebug(ctx context.Context, mode string) context.Context {
+	return context.WithValue(ctx, ctxKeyDebug, mode)
+}
+
 // DebugStat prints debugging information based on RPCStats.
 func DebugStat(logger logrus.FieldLogger, stat grpcstats.RPCStats, httpDebugOption string) {
 	switch s := stat.(type) {
@@ -328,16 +867,92 @@ func formatPayload(payload interface{}) string {
 
 type contextKey string
 
-var ctxKeyRPCState = contextKey("rpcState") //nolint:gochecknoglobals
+var (
+	ctxKeyRPCState       = contextKey("rpcState")       //nolint:gochecknoglobals
+	ctxKeySamplesContext = contextKey("samplesContext") //nolint:gochecknoglobals
+	ctxKeyDebug          = contextKey("debug")          //nolint:gochecknoglobals
+	ctxKeyVUState        = contextKey("vuState")        //nolint:gochecknoglobals
+)
+
+// WithVUState returns the context of the RPCs made by the VU with the state, their samples are pushed
+// with the VU's state instead of the one of the VU dialing the connection, e.g. for the shared connections.
+func WithVUState(ctx context.Context, state *lib.State) context.Context {
+	return context.WithValue(ctx, ctxKeyVUState, state)
+}
+
+// vuStateOf returns the state of the VU the RPC is bound to, or the getState's one if it isn't bound
+func vuStateOf(ctx context.Context, getState func() *lib.State) *lib.State {
+	if state, ok := ctx.Value(ctxKeyVUState).(*lib.State); ok && state != nil {
+		return state
+	}
+
+	return getState()
+}
+
+// WithSamplesContext returns the context of the RPCs which samples are pushed until the samplesCtx is done,
+// e.g. the VU's context, so the samples of the RPCs ended by their deadline or canceled are pushed too.
+func WithSamplesContext(ctx, samplesCtx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeySamplesContext, samplesCtx)
+}
+
+// samplesContext returns the context the RPC's samples are pushed with
+func samplesContext(ctx context.Context) context.Context {
+	if samplesCtx, ok := ctx.Value(ctxKeySamplesContext).(context.Context); ok {
+		return samplesCtx
+	}
+
+	return ctx
+}
 
 type rpcState struct {
 	tagsAndMeta *metrics.TagsAndMeta
+	// internal is set for the RPCs made by the extension itself (e.g. the health watches),
+	// they don't emit any metrics
+	internal bool
+	// expectedStatus reports whether the RPC's status is expected
+	expectedStatus func(codes.Code) bool
+	// received is set once the RPC's attempt has received the trailers, i.e. its status is returned by the server
+	received atomic.Bool
+	// headers is set once the RPC's attempt has received the response headers
+	headers atomic.Bool
+	// fault is set once the RPC's attempt has received one of the Envoy's fault headers
+	fault atomic.Bool
+}
+
+// errorKind returns the kind of the RPC's error
+func (s *rpcState) errorKind(err error) string {
+	return ErrorKind(err, s.received.Load())
+}
+
+// trailersOnly tells whether the RPC's response has been trailers-only, i.e. the status without the headers,
+// like the local replies of the proxies and the load balancers
+func (s *rpcState) trailersOnly() bool {
+	return s.received.Load() && !s.headers.Load()
 }
 
 func withRPCState(ctx context.Context, rpcState *rpcState) context.Context {
 	return context.WithValue(ctx, ctxKeyRPCState, rpcState)
 }
 
+// rpcStateOf returns the state of the RPC the metrics are emitted for, it returns false for the internal RPCs.
+func rpcStateOf(ctx context.Context, state *lib.State) (*rpcState, bool) {
+	stateRPC := getRPCState(ctx) //nolint:ifshort
+
+	if stateRPC != nil && stateRPC.internal {
+		return nil, false
+	}
+
+	// If the request is done by the reflection handler then the tags will be
+	// nil. In this case, we can reuse the VU.State's Tags.
+	if stateRPC == nil {
+		// TODO: investigate this more, there has to be a way to fix it :/
+		ctm := state.Tags.GetCurrentValues()
+		stateRPC = &rpcState{tagsAndMeta: &ctm}
+	}
+
+	return stateRPC, true
+}
+
 func getRPCState(ctx context.Context) *rpcState {
 	v := ctx.Value(ctxKeyRPCState)
 	if v == nil {
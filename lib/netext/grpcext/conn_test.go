@@ -42,6 +42,27 @@ func TestInvoke(t *testing.T) {
 	assert.Empty(t, res.Error)
 }
 
+func TestUnixSocketPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		addr string
+		path string
+		ok   bool
+	}{
+		{addr: "unix:///var/run/app.sock", path: "/var/run/app.sock", ok: true},
+		{addr: "unix:app.sock", path: "app.sock", ok: true},
+		{addr: "unix:@app", path: "@app", ok: true},
+		{addr: "localhost:8080", ok: false},
+	}
+
+	for _, tt := range tests {
+		path, ok := unixSocketPath(tt.addr)
+		assert.Equal(t, tt.ok, ok, tt.addr)
+		assert.Equal(t, tt.path, path, tt.addr)
+	}
+}
+
 func TestInvokeWithCallOptions(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,278 @@
+package grpcext
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+)
+
+// The types of the connection events.
+const (
+	// ConnGoAway is a GOAWAY received from the server, i.e. a server-initiated connection churn
+	ConnGoAway = "goaway"
+	// ConnDrop is a connection closed by the peer or the network without a GOAWAY, i.e. a genuine failure
+	ConnDrop = "drop"
+	// ConnReconnect is a connection transparently established by gRPC in place of a gone away or dropped one
+	ConnReconnect = "reconnect"
+)
+
+const (
+	http2FrameHeaderLen = 9
+	http2FrameGoAway    = 0x7
+	// maxGoAwayPayload caps the GOAWAY's payload kept, i.e. its last stream ID, code and the debug data
+	maxGoAwayPayload = 8 + 256
+)
+
+// ConnEvent is a change of a connection not initiated by the client.
+type ConnEvent struct {
+	Type       string `js:"type"`
+	RemoteAddr string `js:"remoteAddress"`
+	// Code is the HTTP/2 error code of the GOAWAY, e.g. NO_ERROR for a graceful shutdown
+	Code string `js:"code"`
+	// DebugData is the GOAWAY's opaque debug data
+	DebugData string `js:"debugData"`
+	// Rotation is set for the graceful GOAWAYs (NO_ERROR), i.e. the server rotating the connections
+	// by their max age or draining them, and for the reconnects replacing the rotated connections
+	Rotation bool `js:"rotation"`
+	// Downtime is the time from the end of the replaced connection to the reconnect, the time
+	// its channel stayed idle (i.e. until a call needed the connection again) isn't counted
+	Downtime time.Duration `js:"-"`
+}
+
+// ConnEvents observes the GOAWAYs received on a Conn's connections, their drops and the reconnects replacing them.
+type ConnEvents struct {
+	tracker *connTracker
+}
+
+// NewConnEvents returns the observer of the connection events, the observe func is called from the gRPC's goroutines.
+func NewConnEvents(observe func(ConnEvent)) *ConnEvents {
+	return &ConnEvents{tracker: newConnTracker(observe)}
+}
+
+// Credentials wraps the transport credentials of the observed connections.
+func (e *ConnEvents) Credentials(tcred credentials.TransportCredentials) credentials.TransportCredentials {
+	return eventsCredentials{TransportCredentials: tcred, tracker: e.tracker}
+}
+
+// Watch watches the connectivity states of the conn's channels until it's closed,
+// so the reconnects' downtime doesn't count the time the channels stayed idle.
+func (e *ConnEvents) Watch(conn *Conn) {
+	for _, cc := range conn.clientConns() {
+		go e.tracker.watch(cc)
+	}
+}
+
+// connTracker keeps the connections gone away or dropped by their remote address, i.e. by their subchannel,
+// so the next handshakes to the same address are reported as their reconnects
+type connTracker struct {
+	mu   sync.Mutex
+	gone map[string][]goneConn
+	// connecting is the last time a channel has left the idle state, i.e. a call has needed the connection
+	connecting time.Time
+	observe    func(ConnEvent)
+}
+
+func newConnTracker(observe func(ConnEvent)) *connTracker {
+	return &connTracker{gone: make(map[string][]goneConn), observe: observe}
+}
+
+// goneConn is a connection gone away or dropped, waiting for its reconnect
+type goneConn struct {
+	at       time.Time
+	rotation bool
+}
+
+func (t *connTracker) ended(e ConnEvent) {
+	t.mu.Lock()
+	t.gone[e.RemoteAddr] = append(t.gone[e.RemoteAddr], goneConn{at: time.Now(), rotation: e.Rotation})
+	t.mu.Unlock()
+
+	t.observe(e)
+}
+
+func (t *connTracker) established(remote net.Addr) {
+	addr := addrString(remote)
+
+	t.mu.Lock()
+	gone, reconnect := t.gone[addr]
+	var conn goneConn
+	if reconnect {
+		conn = gone[0]
+		if len(gone) > 1 {
+			t.gone[addr] = gone[1:]
+		} else {
+			delete(t.gone, addr)
+		}
+	}
+
+	// the channel idle since the connection has ended was reconnected by a call
+	start := conn.at
+	if t.connecting.After(start) {
+		start = t.connecting
+	}
+	t.mu.Unlock()
+
+	if reconnect {
+		t.observe(ConnEvent{
+			Type:       ConnReconnect,
+			RemoteAddr: addr,
+			Rotation:   conn.rotation,
+			Downtime:   time.Since(start),
+		})
+	}
+}
+
+// watch records the channel leaving the idle state until the channel is closed
+func (t *connTracker) watch(cc *grpc.ClientConn) {
+	state := cc.GetState()
+	for state != connectivity.Shutdown {
+		if !cc.WaitForStateChange(context.Background(), state) {
+			return
+		}
+
+		next := cc.GetState()
+		if state == connectivity.Idle && next != connectivity.Idle {
+			t.mu.Lock()
+			t.connecting = time.Now()
+			t.mu.Unlock()
+		}
+		state = next
+	}
+}
+
+type eventsCredentials struct {
+	credentials.TransportCredentials
+	tracker *connTracker
+}
+
+// ClientHandshake implements the credentials.TransportCredentials interface
+func (ec eventsCredentials) ClientHandshake(
+	ctx context.Context, authority string, rawConn net.Conn,
+) (net.Conn, credentials.AuthInfo, error) {
+	conn, info, err := ec.TransportCredentials.ClientHandshake(ctx, authority, rawConn)
+	if err != nil {
+		return conn, info, err
+	}
+
+	ec.tracker.established(conn.RemoteAddr())
+
+	return &eventsConn{Conn: conn, tracker: ec.tracker}, info, nil
+}
+
+// Clone implements the credentials.TransportCredentials interface
+func (ec eventsCredentials) Clone() credentials.TransportCredentials {
+	return eventsCredentials{
+		TransportCredentials: ec.TransportCredentials.Clone(),
+		tracker:              ec.tracker,
+	}
+}
+
+// eventsConn sniffs the HTTP/2 frames read for a GOAWAY and tells the reads failed
+// before the client closed the connection, i.e. its drops.
+type eventsConn struct {
+	net.Conn
+	tracker *connTracker
+
+	// ended is set once the connection is gone away, dropped or closed by the client
+	ended uint32
+
+	// the state of the frame being read, the reads aren't concurrent
+	header    [http2FrameHeaderLen]byte
+	headerLen int
+	remaining uint32
+	goAway    bool
+	payload   []byte
+}
+
+func (c *eventsConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.sniff(b[:n])
+
+	if err != nil && atomic.CompareAndSwapUint32(&c.ended, 0, 1) {
+		c.tracker.ended(ConnEvent{Type: ConnDrop, RemoteAddr: addrString(c.RemoteAddr())})
+	}
+
+	return n, err
+}
+
+func (c *eventsConn) Close() error {
+	atomic.StoreUint32(&c.ended, 1)
+
+	return c.Conn.Close()
+}
+
+// sniff follows the frames in the bytes read
+func (c *eventsConn) sniff(b []byte) {
+	for len(b) > 0 {
+		if c.headerLen < http2FrameHeaderLen {
+			n := copy(c.header[c.headerLen:], b)
+			c.headerLen += n
+			b = b[n:]
+
+			if c.headerLen == http2FrameHeaderLen {
+				c.remaining = uint32(c.header[0])<<16 | uint32(c.header[1])<<8 | uint32(c.header[2])
+				c.goAway = c.header[3] == http2FrameGoAway
+				c.payload = c.payload[:0]
+				if c.remaining == 0 {
+					c.frameRead()
+				}
+			}
+
+			continue
+		}
+
+		n := uint32(len(b))
+		if n > c.remaining {
+			n = c.remaining
+		}
+
+		if c.goAway && len(c.payload) < maxGoAwayPayload {
+			keep := maxGoAwayPayload - len(c.payload)
+			if keep > int(n) {
+				keep = int(n)
+			}
+			c.payload = append(c.payload, b[:keep]...)
+		}
+
+		c.remaining -= n
+		b = b[n:]
+
+		if c.remaining == 0 {
+			c.frameRead()
+		}
+	}
+}
+
+// frameRead reports the GOAWAY just read, the following ones (e.g. of a graceful shutdown) are the same churn
+func (c *eventsConn) frameRead() {
+	c.headerLen = 0
+
+	if !c.goAway || len(c.payload) < 8 || !atomic.CompareAndSwapUint32(&c.ended, 0, 1) {
+		return
+	}
+
+	code := http2.ErrCode(binary.BigEndian.Uint32(c.payload[4:8]))
+	c.tracker.ended(ConnEvent{
+		Type:       ConnGoAway,
+		RemoteAddr: addrString(c.RemoteAddr()),
+		Code:       code.String(),
+		DebugData:  string(c.payload[8:]),
+		Rotation:   code == http2.ErrCodeNo,
+	})
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+
+	return addr.String()
+}
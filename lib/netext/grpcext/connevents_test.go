@@ -0,0 +1,183 @@
+package grpcext
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+)
+
+// connEventsRecorder records the observed connection events
+type connEventsRecorder struct {
+	mu     sync.Mutex
+	events []ConnEvent
+}
+
+func (r *connEventsRecorder) observe(e ConnEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+}
+
+func (r *connEventsRecorder) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	types := make([]string, 0, len(r.events))
+	for _, e := range r.events {
+		types = append(types, e.Type)
+	}
+
+	return types
+}
+
+func TestEventsConnGoAway(t *testing.T) {
+	t.Parallel()
+
+	client, server := net.Pipe()
+	defer server.Close() //nolint:errcheck
+
+	r := &connEventsRecorder{}
+	conn := &eventsConn{Conn: client, tracker: newConnTracker(r.observe)}
+
+	go func() {
+		fr := http2.NewFramer(server, nil)
+		_ = fr.WriteSettings()
+		_ = fr.WriteGoAway(5, http2.ErrCodeEnhanceYourCalm, []byte("too_many_pings"))
+		_ = fr.WriteGoAway(5, http2.ErrCodeNo, nil)
+		_ = server.Close()
+	}()
+
+	// the frames are split across the reads
+	buf := make([]byte, 3)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			break
+		}
+	}
+
+	require.Len(t, r.events, 1)
+	assert.Equal(t, ConnGoAway, r.events[0].Type)
+	assert.Equal(t, "ENHANCE_YOUR_CALM", r.events[0].Code)
+	assert.Equal(t, "too_many_pings", r.events[0].DebugData)
+	assert.False(t, r.events[0].Rotation)
+}
+
+func TestEventsConnDrop(t *testing.T) {
+	t.Parallel()
+
+	r := &connEventsRecorder{}
+	tracker := newConnTracker(r.observe)
+
+	client, server := net.Pipe()
+	conn := &eventsConn{Conn: client, tracker: tracker}
+	require.NoError(t, server.Close())
+
+	_, err := conn.Read(make([]byte, 1))
+	require.Error(t, err)
+	assert.Equal(t, []string{ConnDrop}, r.types())
+
+	// the connections closed by the client aren't the drops
+	client, server = net.Pipe()
+	defer server.Close() //nolint:errcheck
+
+	conn = &eventsConn{Conn: client, tracker: tracker}
+	require.NoError(t, conn.Close())
+
+	_, err = conn.Read(make([]byte, 1))
+	require.Error(t, err)
+	assert.Equal(t, []string{ConnDrop}, r.types())
+
+	tracker.established(client.RemoteAddr())
+	tracker.established(client.RemoteAddr())
+	assert.Equal(t, []string{ConnDrop, ConnReconnect}, r.types())
+}
+
+func TestConnTrackerReconnects(t *testing.T) {
+	t.Parallel()
+
+	r := &connEventsRecorder{}
+	tracker := newConnTracker(r.observe)
+	a := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}
+	b := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 443}
+
+	// the reconnects are paired with the connections to the same address
+	tracker.ended(ConnEvent{Type: ConnGoAway, RemoteAddr: a.String(), Rotation: true})
+	tracker.ended(ConnEvent{Type: ConnDrop, RemoteAddr: b.String()})
+	tracker.established(b)
+	tracker.established(a)
+	tracker.established(a)
+
+	require.Equal(t, []string{ConnGoAway, ConnDrop, ConnReconnect, ConnReconnect}, r.types())
+	assert.Equal(t, b.String(), r.events[2].RemoteAddr)
+	assert.False(t, r.events[2].Rotation)
+	assert.Equal(t, a.String(), r.events[3].RemoteAddr)
+	assert.True(t, r.events[3].Rotation)
+
+	// the time the channel stayed idle until a call needed the connection isn't the downtime
+	tracker.ended(ConnEvent{Type: ConnGoAway, RemoteAddr: a.String(), Rotation: true})
+	time.Sleep(100 * time.Millisecond)
+	tracker.mu.Lock()
+	tracker.connecting = time.Now()
+	tracker.mu.Unlock()
+	tracker.established(a)
+
+	require.Len(t, r.events, 6)
+	assert.Less(t, r.events[5].Downtime, 100*time.Millisecond)
+}
+
+func TestWithConnEvents(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer(grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionAge:      100 * time.Millisecond,
+		MaxConnectionAgeGrace: time.Second,
+	}))
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	r := &connEventsRecorder{}
+	events := NewConnEvents(r.observe)
+	conn, err := grpc.Dial(l.Addr().String(),
+		grpc.WithTransportCredentials(events.Credentials(insecure.NewCredentials())))
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+	events.Watch(&Conn{raw: conn})
+
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	// the connection is churned by its max age, then the client reconnects
+	assert.Eventually(t, func() bool {
+		_, _ = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+
+		types := r.types()
+
+		return len(types) >= 2 && types[0] == ConnGoAway && types[1] == ConnReconnect
+	}, 5*time.Second, 20*time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	assert.Equal(t, "NO_ERROR", r.events[0].Code)
+	assert.Equal(t, l.Addr().String(), r.events[1].RemoteAddr)
+
+	// the max age's GOAWAY is a rotation, completed by the reconnect
+	assert.True(t, r.events[0].Rotation)
+	assert.True(t, r.events[1].Rotation)
+	assert.Positive(t, r.events[1].Downtime)
+}
@@ -0,0 +1,46 @@
+package grpcext
+
+import (
+	"strconv"
+	"time"
+)
+
+// maxTimeoutValue is the maximal value of the grpc-timeout header, it has at most 8 digits
+const maxTimeoutValue int64 = 100000000 - 1
+
+// EncodeTimeout encodes the timeout into the grpc-timeout header's value in the most precise unit,
+// like the gRPC's transport sends it.
+func EncodeTimeout(t time.Duration) string {
+	if t <= 0 {
+		return "0n"
+	}
+
+	units := []struct {
+		d    time.Duration
+		unit string
+	}{
+		{time.Nanosecond, "n"},
+		{time.Microsecond, "u"},
+		{time.Millisecond, "m"},
+		{time.Second, "S"},
+		{time.Minute, "M"},
+	}
+
+	for _, u := range units {
+		if v := divCeil(t, u.d); v <= maxTimeoutValue {
+			return strconv.FormatInt(v, 10) + u.unit
+		}
+	}
+
+	// the maxTimeoutValue hours exceed the maximal duration
+	return strconv.FormatInt(divCeil(t, time.Hour), 10) + "H"
+}
+
+// divCeil divides the duration rounding up, so the timeout isn't shortened
+func divCeil(d, r time.Duration) int64 {
+	if d%r > 0 {
+		return int64(d/r + 1)
+	}
+
+	return int64(d / r)
+}
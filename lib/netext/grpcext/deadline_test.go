@@ -0,0 +1,27 @@
+package grpcext
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeTimeout(t *testing.T) {
+	t.Parallel()
+
+	for d, expected := range map[time.Duration]string{
+		-time.Second:                     "0n",
+		0:                                "0n",
+		time.Millisecond:                 "1000000n",
+		5 * time.Second:                  "5000000u",
+		2 * time.Minute:                  "120000m",
+		2*time.Minute + 1:                "120001m",
+		30 * time.Hour:                   "108000S",
+		time.Duration(1<<63 - 1):         "2562048H",
+		100000000 * time.Second:          "1666667M",
+		100000000*time.Millisecond + 999: "100001S",
+	} {
+		assert.Equal(t, expected, EncodeTimeout(d), d.String())
+	}
+}
@@ -0,0 +1,213 @@
+package grpcext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/netext"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+// dnsRefreshScheme is the scheme of the DNS targets re-resolved by the dnsRefreshBuilder
+const dnsRefreshScheme = "k6-dns"
+
+// defaultDNSPort is the port of the DNS targets without it, like the gRPC's DNS resolver uses
+const defaultDNSPort = "443"
+
+// DNSRefresh is the re-resolution of the DNS targets, so the long tests pick up the scale-out
+// of the target instead of staying on the endpoints resolved at the connect time.
+type DNSRefresh struct {
+	// Interval is the period of the re-resolution, zero means it's re-resolved on errors only
+	Interval time.Duration
+	// OnError re-resolves the target immediately when a connection fails,
+	// unlike the gRPC's DNS resolver, which does it at most every 30 seconds
+	OnError bool
+}
+
+// WithDNSRefresh returns the target and the dial options re-resolving the dns:/// target
+// by the refresh. The other targets aren't resolved by the DNS, so they can't be refreshed.
+// The target is resolved by the VU's dialer, like the k6's other connections, so the hosts
+// and the dns options apply, each resolution picks one of the addresses by the dns' select.
+func WithDNSRefresh(target string, refresh DNSRefresh, dialer lib.DialContexter) (string, []grpc.DialOption, error) {
+	hostport := strings.TrimPrefix(target, "dns:///")
+	if hostport == target || hostport == "" {
+		return "", nil, errors.New("the DNS re-resolution needs a dns:///host:port target")
+	}
+
+	b := &dnsRefreshBuilder{refresh: refresh, lookup: dialerLookup(dialer)}
+
+	return dnsRefreshScheme + ":///" + hostport, []grpc.DialOption{
+		grpc.WithResolvers(b),
+		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig":[{"round_robin":{}}]}`),
+	}, nil
+}
+
+// dialerLookup returns the lookup of the host's addresses by the dialer, the k6's dialer
+// resolves the hosts option, the blocked hostnames and the DNS like its connections do.
+func dialerLookup(dialer lib.DialContexter) func(ctx context.Context, host, port string) ([]string, error) {
+	d, ok := dialer.(*netext.Dialer)
+	if !ok {
+		return func(ctx context.Context, host, port string) ([]string, error) {
+			ips, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+
+			addrs := make([]string, 0, len(ips))
+			for _, ip := range ips {
+				addrs = append(addrs, net.JoinHostPort(ip, port))
+			}
+
+			return addrs, nil
+		}
+	}
+
+	return func(_ context.Context, host, port string) ([]string, error) {
+		if d.BlockedHostnames != nil {
+			if match, blocked := d.BlockedHostnames.Contains(host); blocked {
+				return nil, fmt.Errorf("hostname (%s) is in a blocked pattern (%s)", host, match)
+			}
+		}
+
+		if d.Hosts != nil {
+			if remote := d.Hosts.Match(net.JoinHostPort(host, port)); remote != nil {
+				return []string{remote.String()}, nil
+			}
+			if remote := d.Hosts.Match(host); remote != nil {
+				if remote.Port != 0 {
+					return []string{remote.String()}, nil
+				}
+
+				return []string{net.JoinHostPort(remote.IP.String(), port)}, nil
+			}
+		}
+
+		ip, err := d.Resolver.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+		if ip == nil {
+			return nil, fmt.Errorf("lookup %s: no such host", host)
+		}
+
+		return []string{net.JoinHostPort(ip.String(), port)}, nil
+	}
+}
+
+// dnsRefreshBuilder builds the resolvers of the DNS targets re-resolved by the refresh
+type dnsRefreshBuilder struct {
+	refresh DNSRefresh
+	lookup  func(ctx context.Context, host, port string) ([]string, error)
+}
+
+var _ resolver.Builder = &dnsRefreshBuilder{}
+
+func (b *dnsRefreshBuilder) Build(
+	target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions,
+) (resolver.Resolver, error) {
+	host, port, err := net.SplitHostPort(target.Endpoint())
+	if err != nil {
+		// the port is optional
+		host, port = target.Endpoint(), defaultDNSPort
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &dnsRefreshResolver{
+		host:    host,
+		port:    port,
+		refresh: b.refresh,
+		lookup:  b.lookup,
+		cc:      cc,
+		cancel:  cancel,
+		now:     make(chan struct{}, 1),
+	}
+
+	r.wg.Add(1)
+	go r.watch(ctx)
+
+	return r, nil
+}
+
+func (b *dnsRefreshBuilder) Scheme() string {
+	return dnsRefreshScheme
+}
+
+// dnsRefreshResolver resolves the host on the start, then periodically and on the errors
+type dnsRefreshResolver struct {
+	host, port string
+	refresh    DNSRefresh
+	lookup     func(ctx context.Context, host, port string) ([]string, error)
+	cc         resolver.ClientConn
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// now triggers an immediate re-resolution
+	now chan struct{}
+}
+
+// watch resolves the host until the resolver is closed
+func (r *dnsRefreshResolver) watch(ctx context.Context) {
+	defer r.wg.Done()
+
+	var tick <-chan time.Time
+	if r.refresh.Interval > 0 {
+		ticker := time.NewTicker(r.refresh.Interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		r.resolve(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-r.now:
+		}
+	}
+}
+
+// resolve looks the host up and updates the addresses
+func (r *dnsRefreshResolver) resolve(ctx context.Context) {
+	found, err := r.lookup(ctx, r.host, r.port)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.cc.ReportError(err)
+		}
+
+		return
+	}
+
+	addrs := make([]resolver.Address, 0, len(found))
+	for _, addr := range found {
+		addrs = append(addrs, resolver.Address{Addr: addr, ServerName: r.host})
+	}
+
+	_ = r.cc.UpdateState(resolver.State{Addresses: addrs})
+}
+
+// ResolveNow is called by the gRPC when a connection fails
+func (r *dnsRefreshResolver) ResolveNow(resolver.ResolveNowOptions) {
+	if !r.refresh.OnError {
+		return
+	}
+
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+func (r *dnsRefreshResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
@@ -0,0 +1,126 @@
+package grpcext
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/lib/netext"
+	"go.k6.io/k6/lib/types"
+	"google.golang.org/grpc/resolver"
+)
+
+// updatesClientConn records the addresses' updates of the resolver
+type updatesClientConn struct {
+	resolver.ClientConn
+	updates chan []resolver.Address
+	errs    chan error
+}
+
+func (cc *updatesClientConn) UpdateState(s resolver.State) error {
+	cc.updates <- s.Addresses
+	return nil
+}
+
+func (cc *updatesClientConn) ReportError(err error) {
+	cc.errs <- err
+}
+
+func TestWithDNSRefresh(t *testing.T) {
+	t.Parallel()
+
+	target, opts, err := WithDNSRefresh("dns:///api.example.com:8443", DNSRefresh{Interval: time.Second}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "k6-dns:///api.example.com:8443", target)
+	assert.Len(t, opts, 2)
+
+	_, _, err = WithDNSRefresh("api.example.com:8443", DNSRefresh{Interval: time.Second}, nil)
+	assert.ErrorContains(t, err, "the DNS re-resolution needs a dns:///host:port target")
+}
+
+func TestDNSRefreshResolver(t *testing.T) {
+	t.Parallel()
+
+	var lookups int32
+	b := &dnsRefreshBuilder{
+		refresh: DNSRefresh{Interval: 20 * time.Millisecond, OnError: true},
+		lookup: func(_ context.Context, _, port string) ([]string, error) {
+			switch atomic.AddInt32(&lookups, 1) {
+			case 1:
+				return []string{"10.0.0.1:" + port}, nil
+			case 2:
+				return nil, errors.New("no such host")
+			default:
+				return []string{"10.0.0.1:" + port, "10.0.0.2:" + port}, nil
+			}
+		},
+	}
+
+	cc := &updatesClientConn{updates: make(chan []resolver.Address, 10), errs: make(chan error, 10)}
+	r, err := b.Build(resolver.Target{URL: *mustParseURL(t, "k6-dns:///api.example.com")}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	assert.Equal(t, []resolver.Address{{Addr: "10.0.0.1:443", ServerName: "api.example.com"}}, <-cc.updates)
+	assert.EqualError(t, <-cc.errs, "no such host")
+
+	// the scale-out is picked up by the next re-resolution
+	addrs := <-cc.updates
+	assert.Len(t, addrs, 2)
+	assert.Equal(t, "10.0.0.2:443", addrs[1].Addr)
+
+	// the connection errors trigger the re-resolution immediately
+	before := atomic.LoadInt32(&lookups)
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	<-cc.updates
+	assert.Greater(t, atomic.LoadInt32(&lookups), before)
+}
+
+func TestDialerLookup(t *testing.T) {
+	t.Parallel()
+
+	hosts, err := types.NewHosts(map[string]types.Host{
+		"api.example.com":      {IP: net.ParseIP("10.0.0.1")},
+		"admin.example.com:80": {IP: net.ParseIP("10.0.0.2"), Port: 8080},
+	})
+	require.NoError(t, err)
+	blocked, err := types.NewHostnameTrie([]string{"*.internal"})
+	require.NoError(t, err)
+
+	d := netext.NewDialer(net.Dialer{}, netext.NewResolver(func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("10.0.0.3"), net.ParseIP("10.0.0.4")}, nil
+	}, 0, types.DNSfirst, types.DNSpreferIPv4))
+	d.Hosts = hosts
+	d.BlockedHostnames = blocked
+	lookup := dialerLookup(d)
+
+	addrs, err := lookup(context.Background(), "api.example.com", "443")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:443"}, addrs)
+
+	addrs, err = lookup(context.Background(), "admin.example.com", "80")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2:8080"}, addrs)
+
+	addrs, err = lookup(context.Background(), "orders.example.com", "443")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.3:443"}, addrs, "the address is picked by the dns' select")
+
+	_, err = lookup(context.Background(), "db.internal", "443")
+	assert.ErrorContains(t, err, "is in a blocked pattern")
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+
+	return u
+}
@@ -0,0 +1,49 @@
+package grpcext
+
+import (
+	"regexp"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorKindTag is the tag of the failed RPCs' samples telling the kind of their error
+const ErrorKindTag = "error_kind"
+
+// The kinds of the RPCs' errors.
+const (
+	// ErrorKindNetwork is an RPC failed by the dialer or the transport, e.g. the connection refused or reset
+	ErrorKindNetwork = "network"
+	// ErrorKindTimeout is an RPC which deadline has been exceeded, on the client or on the server
+	ErrorKindTimeout = "timeout"
+	// ErrorKindApplication is a non-OK status returned by the server
+	ErrorKindApplication = "application"
+	// ErrorKindCircuitBreaker is an RPC rejected by a circuit breaker, e.g. the xDS cluster's max_requests
+	ErrorKindCircuitBreaker = "circuit_breaker"
+)
+
+// xdsCircuitBreakerRE matches the error of the xDS cluster's picker rejecting the RPCs over its max_requests
+var xdsCircuitBreakerRE = regexp.MustCompile(`^max requests \d+ exceeded on service `) //nolint:gochecknoglobals
+
+// ErrorKind returns the kind of the RPC's error, received tells whether its status has been returned
+// by the server. It's empty if the error isn't classified, e.g. the RPC canceled by the client
+// or the errors which aren't the gRPC statuses.
+func ErrorKind(err error, received bool) string {
+	st, ok := status.FromError(err)
+	if err == nil || !ok {
+		return ""
+	}
+
+	switch {
+	case st.Code() == codes.DeadlineExceeded:
+		return ErrorKindTimeout
+	case received:
+		return ErrorKindApplication
+	case st.Code() != codes.Unavailable:
+		return ""
+	case xdsCircuitBreakerRE.MatchString(st.Message()):
+		return ErrorKindCircuitBreaker
+	default:
+		return ErrorKindNetwork
+	}
+}
@@ -0,0 +1,58 @@
+package grpcext
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorKind(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      error
+		received bool
+		kind     string
+	}{
+		{name: "NoError"},
+		{
+			name: "XDSCircuitBreaker",
+			err:  status.Error(codes.Unavailable, "max requests 1024 exceeded on service outbound|443||svc"),
+			kind: ErrorKindCircuitBreaker,
+		},
+		{
+			name:     "XDSCircuitBreakerReturned",
+			err:      status.Error(codes.Unavailable, "max requests 1024 exceeded on service svc"),
+			received: true,
+			kind:     ErrorKindApplication,
+		},
+		{
+			name: "Network",
+			err:  status.Error(codes.Unavailable, "connection error: desc = \"transport: connection refused\""),
+			kind: ErrorKindNetwork,
+		},
+		{name: "Timeout", err: status.Error(codes.DeadlineExceeded, "context deadline exceeded"), kind: ErrorKindTimeout},
+		{
+			name:     "TimeoutReturned",
+			err:      status.Error(codes.DeadlineExceeded, "upstream timeout"),
+			received: true,
+			kind:     ErrorKindTimeout,
+		},
+		{name: "Application", err: status.Error(codes.NotFound, "not found"), received: true, kind: ErrorKindApplication},
+		{name: "Canceled", err: status.Error(codes.Canceled, "context canceled")},
+		{name: "NotStatus", err: errors.New("max requests 1 exceeded on service svc"), received: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.kind, ErrorKind(tt.err, tt.received))
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package grpcext
+
+import (
+	"fmt"
+	"sync"
+
+	"go.k6.io/k6/lib"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	grpcstats "google.golang.org/grpc/stats"
+)
+
+// DialOptionFactory creates an extension's dial option for a new connection,
+// the getState returns the state of the VU dialing it.
+type DialOptionFactory func(getState func() *lib.State) grpc.DialOption
+
+// extensions is the dial options' factories registered by the other extensions
+var extensions struct { //nolint:gochecknoglobals
+	mu        sync.RWMutex
+	factories []DialOptionFactory
+}
+
+// RegisterDialOption registers the factory of a dial option added to all the connections dialed
+// with the DefaultOptions, so the other xk6 extensions could attach their own telemetry or auth
+// without patching the dialing. It's meant to be called from the extension's init.
+func RegisterDialOption(factory DialOptionFactory) {
+	extensions.mu.Lock()
+	defer extensions.mu.Unlock()
+
+	extensions.factories = append(extensions.factories, factory)
+}
+
+// RegisterStatsHandler registers the factory of a stats handler attached to all the connections.
+func RegisterStatsHandler(factory func(getState func() *lib.State) grpcstats.Handler) {
+	RegisterDialOption(func(getState func() *lib.State) grpc.DialOption {
+		return grpc.WithStatsHandler(factory(getState))
+	})
+}
+
+// RegisterUnaryInterceptor registers an interceptor of all the unary RPCs,
+// the interceptors are chained in the order of their registration.
+func RegisterUnaryInterceptor(interceptor grpc.UnaryClientInterceptor) {
+	RegisterDialOption(func(func() *lib.State) grpc.DialOption {
+		return grpc.WithChainUnaryInterceptor(interceptor)
+	})
+}
+
+// RegisterStreamInterceptor registers an interceptor of all the streams,
+// the interceptors are chained in the order of their registration.
+func RegisterStreamInterceptor(interceptor grpc.StreamClientInterceptor) {
+	RegisterDialOption(func(func() *lib.State) grpc.DialOption {
+		return grpc.WithChainStreamInterceptor(interceptor)
+	})
+}
+
+// RegisterBalancer registers the builder of a custom load balancing policy, so it could be selected
+// by the connections' loadBalancing or referenced from the xDS config (by the type name of its TypedStruct)
+// without patching the dialing. It panics if a policy with the same name is already registered.
+func RegisterBalancer(builder balancer.Builder) {
+	if balancer.Get(builder.Name()) != nil {
+		panic(fmt.Sprintf("the load balancing policy %q is already registered", builder.Name()))
+	}
+
+	balancer.Register(builder)
+}
+
+// ExtensionOptions returns the dial options of the registered extensions.
+func ExtensionOptions(getState func() *lib.State) []grpc.DialOption {
+	extensions.mu.RLock()
+	defer extensions.mu.RUnlock()
+
+	opts := make([]grpc.DialOption, 0, len(extensions.factories))
+	for _, factory := range extensions.factories {
+		opts = append(opts, factory(getState))
+	}
+
+	return opts
+}
@@ -0,0 +1,58 @@
+package grpcext
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/lib"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestExtensionOptions(t *testing.T) { //nolint:paralleltest // it registers the process-wide extensions
+	state := &lib.State{}
+	getState := func() *lib.State { return state }
+
+	// the registered interceptor and user agent would apply to the other tests' connections
+	extensions.mu.Lock()
+	registered := extensions.factories
+	extensions.mu.Unlock()
+	t.Cleanup(func() {
+		extensions.mu.Lock()
+		defer extensions.mu.Unlock()
+
+		extensions.factories = registered
+	})
+
+	var (
+		factoryState *lib.State
+		intercepted  []string
+	)
+	RegisterDialOption(func(getState func() *lib.State) grpc.DialOption {
+		factoryState = getState()
+		return grpc.WithUserAgent("extension")
+	})
+	RegisterUnaryInterceptor(func(
+		ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		intercepted = append(intercepted, method)
+		return errors.New("intercepted")
+	})
+
+	opts := ExtensionOptions(getState)
+	require.Len(t, opts, 2)
+	assert.Same(t, state, factoryState)
+
+	opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	cc, err := grpc.Dial("passthrough:///unused", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+
+	err = cc.Invoke(context.Background(), "/hello.HelloService/SayHello", nil, nil)
+	require.EqualError(t, err, "intercepted")
+	assert.Equal(t, []string{"/hello.HelloService/SayHello"}, intercepted)
+}
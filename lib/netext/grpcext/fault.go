@@ -0,0 +1,48 @@
+package grpcext
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// FaultTag is the tag of the failed RPCs' samples telling their failure has been injected,
+// e.g. by a chaos experiment, so the thresholds could tell them apart from the organic ones
+const FaultTag = "fault"
+
+// FaultInjected is the FaultTag's value of the RPCs failed by an injected fault
+const FaultInjected = "injected"
+
+// faultAbortMessages are the status messages of the RPCs aborted by the fault injection: the Envoy's
+// local replies of its fault filter (for both the grpc_status and the http_status aborts) and the gRPC's
+// own fault filter applied by the xDS client
+var faultAbortMessages = map[string]bool{ //nolint:gochecknoglobals
+	"fault filter abort":                    true,
+	"RPC terminated due to fault injection": true,
+}
+
+// envoyFaultHeaderPrefix is the prefix of the Envoy's fault headers
+const envoyFaultHeaderPrefix = "x-envoy-fault-"
+
+// IsFaultInjected tells whether the RPC's error is an injected fault, i.e. the abort of the Envoy's
+// or the gRPC's fault filter. The injected delays can't be told apart, the responses don't carry any indicator of them.
+func IsFaultInjected(err error) bool {
+	st, ok := status.FromError(err)
+
+	return err != nil && ok && faultAbortMessages[st.Message()]
+}
+
+// IsFaultResponse tells whether the response's headers or trailers carry one of the Envoy's fault headers,
+// e.g. the x-envoy-fault-abort-request of the header-controlled faults.
+func IsFaultResponse(mds ...metadata.MD) bool {
+	for _, md := range mds {
+		for k := range md {
+			if strings.HasPrefix(k, envoyFaultHeaderPrefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
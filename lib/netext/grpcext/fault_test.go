@@ -0,0 +1,33 @@
+package grpcext
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsFaultInjected(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsFaultInjected(status.Error(codes.Unavailable, "fault filter abort")))
+	assert.True(t, IsFaultInjected(status.Error(codes.Internal, "fault filter abort")))
+	assert.True(t, IsFaultInjected(status.Error(codes.Unavailable, "RPC terminated due to fault injection")))
+
+	assert.False(t, IsFaultInjected(nil))
+	assert.False(t, IsFaultInjected(status.Error(codes.Unavailable, "no healthy upstream")))
+	assert.False(t, IsFaultInjected(errors.New("fault filter abort")))
+}
+
+func TestIsFaultResponse(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsFaultResponse(metadata.Pairs("x-envoy-fault-abort-request", "503")))
+	assert.True(t, IsFaultResponse(nil, metadata.Pairs("x-envoy-fault-delay-request", "100")))
+
+	assert.False(t, IsFaultResponse())
+	assert.False(t, IsFaultResponse(metadata.Pairs("x-envoy-upstream-service-time", "3")))
+}
@@ -0,0 +1,63 @@
+package grpcext
+
+import "google.golang.org/protobuf/reflect/protoreflect"
+
+// ConvertFields converts the fields of the message's JSON value (the protojson's JSON decoded into the maps),
+// the convert returns the field's new value (or the list's and the map's item's) and whether it's converted,
+// the fields of the unconverted messages are converted recursively. The fields are looked up by their JSON names
+// and by their names, as the protojson accepts both. The extensions are left as they are.
+func ConvertFields(
+	md protoreflect.MessageDescriptor, v interface{},
+	convert func(fd protoreflect.FieldDescriptor, v interface{}) (interface{}, bool),
+) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+
+		name := fd.JSONName()
+		fv, ok := obj[name]
+		if !ok {
+			name = string(fd.Name())
+			fv, ok = obj[name]
+		}
+		if !ok || fv == nil {
+			continue
+		}
+
+		switch {
+		case fd.IsMap():
+			m, _ := fv.(map[string]interface{})
+			for k, e := range m {
+				m[k] = convertField(fd.MapValue(), e, convert)
+			}
+		case fd.IsList():
+			l, _ := fv.([]interface{})
+			for j, e := range l {
+				l[j] = convertField(fd, e, convert)
+			}
+		default:
+			obj[name] = convertField(fd, fv, convert)
+		}
+	}
+}
+
+// convertField converts the field's value, or its fields if it's a message
+func convertField(
+	fd protoreflect.FieldDescriptor, v interface{},
+	convert func(fd protoreflect.FieldDescriptor, v interface{}) (interface{}, bool),
+) interface{} {
+	if converted, ok := convert(fd, v); ok {
+		return converted
+	}
+
+	if fd.Message() != nil {
+		ConvertFields(fd.Message(), v, convert)
+	}
+
+	return v
+}
@@ -0,0 +1,31 @@
+package grpcext
+
+import (
+	"context"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// WatchHealth watches the serving status of the service using the gRPC health checking protocol,
+// the onStatus is called for every received status until the stream fails or the context is done.
+func (c *Conn) WatchHealth(
+	ctx context.Context,
+	service string,
+	onStatus func(healthpb.HealthCheckResponse_ServingStatus),
+) error {
+	ctx = withRPCState(ctx, &rpcState{internal: true})
+
+	stream, err := healthpb.NewHealthClient(c.raw).Watch(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		onStatus(resp.GetStatus())
+	}
+}
@@ -0,0 +1,48 @@
+package grpcext
+
+import (
+	"strconv"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxSafeInteger is the largest integer the JS numbers represent exactly, the Number.MAX_SAFE_INTEGER
+const maxSafeInteger = 1<<53 - 1
+
+// int64Numbers converts the 64-bit integers of the converted message (the protojson's strings) into the numbers,
+// the ones which the JS numbers can't represent exactly are kept as the strings.
+func int64Numbers(md protoreflect.MessageDescriptor, v interface{}) {
+	ConvertFields(md, v, int64Number)
+}
+
+// int64Number converts the field's value if it's a 64-bit integer
+func int64Number(fd protoreflect.FieldDescriptor, v interface{}) (interface{}, bool) {
+	switch fd.Kind() { //nolint:exhaustive
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return safeInteger(v), true
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		// the wrappers are converted like their values
+		switch fd.Message().FullName() {
+		case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+			return safeInteger(v), true
+		}
+	}
+
+	return v, false
+}
+
+// safeInteger returns the integer's string as the number if it's represented exactly
+func safeInteger(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || i > maxSafeInteger || i < -maxSafeInteger {
+		return v
+	}
+
+	return i
+}
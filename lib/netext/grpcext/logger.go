@@ -0,0 +1,150 @@
+package grpcext
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/grpclog"
+)
+
+// the severities of the gRPC internal logs
+const (
+	LogSeverityInfo = iota
+	LogSeverityWarning
+	LogSeverityError
+)
+
+// Logger is a grpclog.LoggerV2 writing the gRPC internal logs (resolvers, balancers, xDS client, transport)
+// into the logrus logger instead of the stderr, the component of the message is set as the log's field.
+// Since the gRPC logger is process-wide, the severity and the verbosity can only be raised.
+type Logger struct {
+	mu        sync.RWMutex
+	logger    logrus.FieldLogger
+	severity  int
+	verbosity int
+}
+
+var _ grpclog.LoggerV2 = &Logger{}
+
+// NewLogger creates a new logger writing the errors only.
+func NewLogger() *Logger {
+	return &Logger{severity: LogSeverityError}
+}
+
+// SetOutput sets the logger the logs are written into, if it hasn't been set yet.
+func (l *Logger) SetOutput(logger logrus.FieldLogger) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.logger == nil {
+		l.logger = logger.WithField("source", "grpc")
+	}
+}
+
+// Raise lowers the minimal severity of the written logs and raises their verbosity.
+func (l *Logger) Raise(severity int, verbosity int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if severity < l.severity {
+		l.severity = severity
+	}
+
+	if verbosity > l.verbosity {
+		l.verbosity = verbosity
+	}
+}
+
+func (l *Logger) log(severity int, msg string) {
+	l.mu.RLock()
+	logger, minSeverity := l.logger, l.severity
+	l.mu.RUnlock()
+
+	if logger == nil || severity < minSeverity {
+		return
+	}
+
+	// the gRPC components prefix their logs with their name, e.g. "[core] ..." or "[xds] ..."
+	if strings.HasPrefix(msg, "[") {
+		if end := strings.Index(msg, "] "); end > 0 {
+			logger = logger.WithField("component", msg[1:end])
+			msg = msg[end+2:]
+		}
+	}
+
+	switch severity {
+	case LogSeverityInfo:
+		logger.Info(msg)
+	case LogSeverityWarning:
+		logger.Warn(msg)
+	default:
+		logger.Error(msg)
+	}
+}
+
+// Info logs to the INFO log.
+func (l *Logger) Info(args ...interface{}) { l.log(LogSeverityInfo, fmt.Sprint(args...)) }
+
+// Infoln logs to the INFO log.
+func (l *Logger) Infoln(args ...interface{}) { l.log(LogSeverityInfo, sprintln(args...)) }
+
+// Infof logs to the INFO log.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(LogSeverityInfo, fmt.Sprintf(format, args...))
+}
+
+// Warning logs to the WARNING log.
+func (l *Logger) Warning(args ...interface{}) { l.log(LogSeverityWarning, fmt.Sprint(args...)) }
+
+// Warningln logs to the WARNING log.
+func (l *Logger) Warningln(args ...interface{}) { l.log(LogSeverityWarning, sprintln(args...)) }
+
+// Warningf logs to the WARNING log.
+func (l *Logger) Warningf(format string, args ...interface{}) {
+	l.log(LogSeverityWarning, fmt.Sprintf(format, args...))
+}
+
+// Error logs to the ERROR log.
+func (l *Logger) Error(args ...interface{}) { l.log(LogSeverityError, fmt.Sprint(args...)) }
+
+// Errorln logs to the ERROR log.
+func (l *Logger) Errorln(args ...interface{}) { l.log(LogSeverityError, sprintln(args...)) }
+
+// Errorf logs to the ERROR log.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(LogSeverityError, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs to the ERROR log and exits the process, as the gRPC expects.
+func (l *Logger) Fatal(args ...interface{}) {
+	l.log(LogSeverityError, fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+// Fatalln logs to the ERROR log and exits the process, as the gRPC expects.
+func (l *Logger) Fatalln(args ...interface{}) {
+	l.log(LogSeverityError, sprintln(args...))
+	os.Exit(1)
+}
+
+// Fatalf logs to the ERROR log and exits the process, as the gRPC expects.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.log(LogSeverityError, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// V reports whether the verbosity level is enabled.
+func (l *Logger) V(level int) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return level <= l.verbosity
+}
+
+// sprintln formats the args like the fmt.Sprintln without the trailing new line
+func sprintln(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
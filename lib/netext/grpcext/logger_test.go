@@ -0,0 +1,44 @@
+package grpcext
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	l := NewLogger()
+	l.SetOutput(logger)
+
+	l.Infof("[core] %s", "dropped")
+	l.Warning("dropped")
+	assert.Empty(t, hook.AllEntries())
+	assert.False(t, l.V(2))
+
+	l.Errorln("[xds]", "failed")
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.ErrorLevel, entry.Level)
+	assert.Equal(t, "failed", entry.Message)
+	assert.Equal(t, "xds", entry.Data["component"])
+	assert.Equal(t, "grpc", entry.Data["source"])
+
+	l.Raise(LogSeverityInfo, 2)
+	l.Raise(LogSeverityError, 0)
+
+	l.Infof("[core] %s", "connected")
+	entry = hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.InfoLevel, entry.Level)
+	assert.Equal(t, "connected", entry.Message)
+	assert.Equal(t, "core", entry.Data["component"])
+	assert.True(t, l.V(2))
+}
@@ -0,0 +1,206 @@
+package grpcext
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc"
+	grpcstats "google.golang.org/grpc/stats"
+)
+
+// Metrics are the extension's metrics emitted by the stats handler added by the WithMetrics.
+type Metrics struct {
+	MessagesSent     *metrics.Metric
+	MessagesReceived *metrics.Metric
+	DataSent         *metrics.Metric
+	DataReceived     *metrics.Metric
+
+	// the phases of the unary RPCs, like the http_req_* ones
+	Blocked   *metrics.Metric
+	Sending   *metrics.Metric
+	Waiting   *metrics.Metric
+	Receiving *metrics.Metric
+}
+
+// payloadsPushInterval is the period the streams' messages and data are pushed at until they end
+const payloadsPushInterval = time.Second
+
+// WithMetrics returns a dial option emitting the metrics of the RPCs' events, tagged with the RPC's tags.
+// They're pushed once the RPCs end, so they have the RPC's status, while the streams' messages and data
+// are pushed every second too (without the status, which isn't known yet).
+func WithMetrics(getState func() *lib.State, m *Metrics) grpc.DialOption {
+	return grpc.WithStatsHandler(metricsHandler{getState: getState, metrics: m})
+}
+
+type metricsHandler struct {
+	getState func() *lib.State
+	metrics  *Metrics
+}
+
+// TagConn implements the grpcstats.Handler interface
+func (metricsHandler) TagConn(ctx context.Context, _ *grpcstats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn implements the grpcstats.Handler interface
+func (metricsHandler) HandleConn(context.Context, grpcstats.ConnStats) {
+	// noop
+}
+
+// TagRPC implements the grpcstats.Handler interface
+func (metricsHandler) TagRPC(ctx context.Context, _ *grpcstats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, ctxKeyRPCTrail, &rpcTrail{})
+}
+
+// HandleRPC implements the grpcstats.Handler interface
+func (h metricsHandler) HandleRPC(ctx context.Context, stat grpcstats.RPCStats) {
+	trail, _ := ctx.Value(ctxKeyRPCTrail).(*rpcTrail)
+	if trail == nil {
+		return
+	}
+
+	if end, ok := stat.(*grpcstats.End); ok {
+		h.pushTrail(ctx, end.EndTime, trail)
+		return
+	}
+
+	if at, due := trail.record(stat); due {
+		if push := h.sampler(ctx, at); push != nil {
+			trail.mu.Lock()
+			defer trail.mu.Unlock()
+
+			h.pushPayloads(trail, push)
+		}
+	}
+}
+
+// sampler returns the push of the RPC's samples at the time, tagged with the RPC's tags,
+// it's nil if the RPC isn't made by a VU
+func (h metricsHandler) sampler(ctx context.Context, at time.Time) func(metric *metrics.Metric, value float64) {
+	state := vuStateOf(ctx, h.getState)
+	stateRPC, ok := rpcStateOf(ctx, state)
+	if !ok {
+		return nil
+	}
+
+	return func(metric *metrics.Metric, value float64) {
+		metrics.PushIfNotDone(samplesContext(ctx), state.Samples, metrics.Sample{
+			TimeSeries: metrics.TimeSeries{
+				Metric: metric,
+				Tags:   stateRPC.tagsAndMeta.Tags,
+			},
+			Time:     at,
+			Metadata: stateRPC.tagsAndMeta.Metadata,
+			Value:    value,
+		})
+	}
+}
+
+// pushPayloads pushes the messages and the data counted since the previous push, the zeros are skipped
+func (h metricsHandler) pushPayloads(trail *rpcTrail, push func(metric *metrics.Metric, value float64)) {
+	for _, p := range []struct {
+		metric *metrics.Metric
+		value  *int64
+	}{
+		{h.metrics.MessagesSent, &trail.sent},
+		{h.metrics.MessagesReceived, &trail.received},
+		{h.metrics.DataSent, &trail.sentBytes},
+		{h.metrics.DataReceived, &trail.receivedBytes},
+	} {
+		if *p.value > 0 {
+			push(p.metric, float64(*p.value))
+			*p.value = 0
+		}
+	}
+}
+
+// pushTrail pushes the RPC's trail once it has ended, so the samples have the same tags
+// (e.g. the status) as the RPC's grpc_req_duration
+func (h metricsHandler) pushTrail(ctx context.Context, end time.Time, trail *rpcTrail) {
+	push := h.sampler(ctx, end)
+	if push == nil {
+		return
+	}
+
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+
+	h.pushPayloads(trail, push)
+
+	// the phases of the streams are ambiguous, since their messages are interleaved
+	if !trail.unary {
+		return
+	}
+
+	pushPhase := func(metric *metrics.Metric, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() {
+			push(metric, metrics.D(to.Sub(from)))
+		}
+	}
+
+	pushPhase(h.metrics.Blocked, trail.begin, trail.headerSent)
+	pushPhase(h.metrics.Sending, trail.headerSent, trail.lastSent)
+	pushPhase(h.metrics.Waiting, trail.lastSent, trail.headerReceived)
+	pushPhase(h.metrics.Receiving, trail.headerReceived, end)
+}
+
+var ctxKeyRPCTrail = contextKey("rpcTrail") //nolint:gochecknoglobals
+
+// rpcTrail is the RPC's events recorded until it ends,
+// the stream's messages are sent and received concurrently
+type rpcTrail struct {
+	mu sync.Mutex
+
+	// the messages and the data counted since the previous push
+	sent, received           int64
+	sentBytes, receivedBytes int64
+	pushed                   time.Time
+
+	unary          bool
+	begin          time.Time
+	headerSent     time.Time
+	lastSent       time.Time
+	headerReceived time.Time
+}
+
+// record records the RPC's event, it reports the time the stream's payloads are due to be pushed
+func (t *rpcTrail) record(stat grpcstats.RPCStats) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var at time.Time
+	switch s := stat.(type) {
+	case *grpcstats.Begin:
+		t.begin = s.BeginTime
+		t.pushed = s.BeginTime
+		t.unary = !s.IsClientStream && !s.IsServerStream
+	case *grpcstats.OutHeader:
+		t.headerSent = time.Now()
+	case *grpcstats.OutPayload:
+		t.sent++
+		t.sentBytes += int64(s.WireLength)
+		t.lastSent = s.SentTime
+		at = s.SentTime
+	case *grpcstats.InHeader:
+		t.headerReceived = time.Now()
+	case *grpcstats.InTrailer:
+		// the trailers-only responses have no headers
+		if t.headerReceived.IsZero() {
+			t.headerReceived = time.Now()
+		}
+	case *grpcstats.InPayload:
+		t.received++
+		t.receivedBytes += int64(s.WireLength)
+		at = s.RecvTime
+	}
+
+	if t.unary || at.IsZero() || at.Sub(t.pushed) < payloadsPushInterval {
+		return at, false
+	}
+	t.pushed = at
+
+	return at, true
+}
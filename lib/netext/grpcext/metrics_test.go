@@ -0,0 +1,126 @@
+package grpcext
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc/codes"
+	grpcstats "google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	t.Parallel()
+
+	handle, samples := newMetricsTestHandler()
+
+	now := time.Now()
+	handle(&grpcstats.Begin{BeginTime: now.Add(-time.Second)})
+	handle(&grpcstats.OutHeader{})
+	handle(&grpcstats.OutPayload{WireLength: 10, SentTime: time.Now()})
+	handle(&grpcstats.InHeader{})
+	handle(&grpcstats.InPayload{WireLength: 20, RecvTime: now})
+	handle(&grpcstats.InPayload{WireLength: 30, RecvTime: now})
+	handle(&grpcstats.End{BeginTime: now, EndTime: time.Now(), Error: status.Error(codes.NotFound, "")})
+
+	values := make(map[string]float64)
+	for _, sc := range metrics.GetBufferedSamples(samples) {
+		for _, sample := range sc.GetSamples() {
+			code, ok := sample.Tags.Get("status")
+			require.True(t, ok, sample.Metric.Name)
+			assert.Equal(t, "5", code)
+
+			values[sample.Metric.Name] = sample.Value
+		}
+	}
+
+	assert.Equal(t, float64(1), values["grpc_msgs_sent"])
+	assert.Equal(t, float64(2), values["grpc_msgs_received"])
+	assert.Equal(t, float64(10), values["grpc_data_sent"])
+	assert.Equal(t, float64(50), values["grpc_data_received"])
+	assert.GreaterOrEqual(t, values["grpc_req_blocked"], float64(1000))
+
+	for _, name := range []string{"grpc_req_sending", "grpc_req_waiting", "grpc_req_receiving"} {
+		require.Contains(t, values, name)
+		assert.GreaterOrEqual(t, values[name], float64(0), name)
+	}
+}
+
+// newMetricsTestHandler returns the handling of the RPC's events by the stats and the metrics handlers
+// and the samples they push
+func newMetricsTestHandler() (func(stat grpcstats.RPCStats), chan metrics.SampleContainer) {
+	registry := metrics.NewRegistry()
+	samples := make(chan metrics.SampleContainer, 100)
+	state := &lib.State{
+		Options: lib.Options{
+			SystemTags: metrics.NewSystemTagSet(metrics.TagStatus),
+		},
+		Samples:        samples,
+		BuiltinMetrics: metrics.RegisterBuiltinMetrics(registry),
+		Tags:           lib.NewVUStateTags(registry.RootTagSet()),
+	}
+	getState := func() *lib.State { return state }
+
+	m := &Metrics{
+		MessagesSent:     registry.MustNewMetric("grpc_msgs_sent", metrics.Counter),
+		MessagesReceived: registry.MustNewMetric("grpc_msgs_received", metrics.Counter),
+		DataSent:         registry.MustNewMetric("grpc_data_sent", metrics.Counter, metrics.Data),
+		DataReceived:     registry.MustNewMetric("grpc_data_received", metrics.Counter, metrics.Data),
+		Blocked:          registry.MustNewMetric("grpc_req_blocked", metrics.Trend, metrics.Time),
+		Sending:          registry.MustNewMetric("grpc_req_sending", metrics.Trend, metrics.Time),
+		Waiting:          registry.MustNewMetric("grpc_req_waiting", metrics.Trend, metrics.Time),
+		Receiving:        registry.MustNewMetric("grpc_req_receiving", metrics.Trend, metrics.Time),
+	}
+
+	tagsAndMeta := state.Tags.GetCurrentValues()
+	ctx := withRPCState(context.Background(), &rpcState{tagsAndMeta: &tagsAndMeta})
+
+	sh := statsHandler{getState: getState}
+	mh := metricsHandler{getState: getState, metrics: m}
+	ctx = mh.TagRPC(sh.TagRPC(ctx, nil), nil)
+
+	return func(stat grpcstats.RPCStats) {
+		sh.HandleRPC(ctx, stat)
+		mh.HandleRPC(ctx, stat)
+	}, samples
+}
+
+func TestMetricsHandlerStream(t *testing.T) {
+	t.Parallel()
+
+	handle, samples := newMetricsTestHandler()
+
+	begin := time.Now()
+	handle(&grpcstats.Begin{BeginTime: begin, IsClientStream: true, IsServerStream: true})
+	handle(&grpcstats.OutPayload{WireLength: 10, SentTime: begin.Add(500 * time.Millisecond)})
+	// the stream's payloads are pushed each second, before the stream ends
+	handle(&grpcstats.InPayload{WireLength: 20, RecvTime: begin.Add(1500 * time.Millisecond)})
+	pushed := metrics.GetBufferedSamples(samples)
+	handle(&grpcstats.InPayload{WireLength: 30, RecvTime: begin.Add(2 * time.Second)})
+	handle(&grpcstats.End{BeginTime: begin, EndTime: begin.Add(3 * time.Second)})
+	endedSamples := metrics.GetBufferedSamples(samples)
+
+	values := func(containers []metrics.SampleContainer) map[string]float64 {
+		result := make(map[string]float64)
+		for _, sc := range containers {
+			for _, sample := range sc.GetSamples() {
+				result[sample.Metric.Name] = sample.Value
+			}
+		}
+
+		return result
+	}
+
+	assert.Equal(t, map[string]float64{
+		"grpc_msgs_sent": 1, "grpc_msgs_received": 1, "grpc_data_sent": 10, "grpc_data_received": 20,
+	}, values(pushed))
+	// the zeros aren't pushed
+	ended := values(endedSamples)
+	delete(ended, "grpc_req_duration")
+	assert.Equal(t, map[string]float64{"grpc_msgs_received": 1, "grpc_data_received": 30}, ended)
+}
@@ -0,0 +1,28 @@
+package grpcext
+
+import "google.golang.org/protobuf/reflect/protoreflect"
+
+// collectOneofs collects the message's set oneof members, the JSON names of the members are keyed by the oneofs'
+// paths, the oneofs' names prefixed by the JSON names of the singular message fields (e.g. the author.contact).
+func collectOneofs(m protoreflect.Message, prefix string, oneofs map[string]string) {
+	ods := m.Descriptor().Oneofs()
+	for i := 0; i < ods.Len(); i++ {
+		od := ods.Get(i)
+		// the proto3 optional fields are the synthetic oneofs
+		if od.IsSynthetic() {
+			continue
+		}
+
+		if fd := m.WhichOneof(od); fd != nil {
+			oneofs[prefix+string(od.Name())] = fd.JSONName()
+		}
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() != nil && !fd.IsExtension() && !fd.IsList() && !fd.IsMap() {
+			collectOneofs(v.Message(), prefix+fd.JSONName()+".", oneofs)
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,116 @@
+package grpcext
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startProxy starts an HTTP CONNECT proxy accepting only the given proxy authorization
+func startProxy(t *testing.T, authorization string) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer func() { _ = conn.Close() }()
+
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+
+				if req.Method != http.MethodConnect || req.Header.Get("Proxy-Authorization") != authorization {
+					_, _ = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					return
+				}
+
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					_, _ = io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					return
+				}
+				defer func() { _ = target.Close() }()
+
+				_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+				go func() { _, _ = io.Copy(target, conn) }()
+				_, _ = io.Copy(conn, target)
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// startEcho starts a TCP server echoing the received data back
+func startEcho(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestDialProxy(t *testing.T) {
+	t.Parallel()
+
+	// "user:secret" base64 encoded
+	proxyAddr := startProxy(t, "Basic dXNlcjpzZWNyZXQ=")
+	echoAddr := startEcho(t)
+	conn, err := dialProxy(context.Background(), &net.Dialer{}, &url.URL{
+		Scheme: "http",
+		User:   url.UserPassword("user", "secret"),
+		Host:   proxyAddr,
+	}, echoAddr)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	_, err = io.WriteString(conn, "ping")
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+}
+
+func TestDialProxyRefused(t *testing.T) {
+	t.Parallel()
+
+	proxyAddr := startProxy(t, "Basic dXNlcjpzZWNyZXQ=")
+	_, err := dialProxy(context.Background(), &net.Dialer{}, &url.URL{Scheme: "http", Host: proxyAddr}, startEcho(t))
+	assert.ErrorContains(t, err, "407 Proxy Authentication Required")
+}
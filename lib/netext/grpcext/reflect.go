@@ -17,26 +17,43 @@ type reflectionClient struct {
 
 // Reflect will use the grpc reflection api to make the file descriptors available to request.
 // It is called in the connect function the first time the Client.Connect function is called.
-func (rc *reflectionClient) Reflect(ctx context.Context) (*descriptorpb.FileDescriptorSet, error) {
+// The grpc.reflection.v1 is used if the server supports it, otherwise it falls back to the v1alpha.
+// If the symbols (e.g. the services) are given, only the files defining them and their dependencies are fetched,
+// instead of the files of all the server's services.
+func (rc *reflectionClient) Reflect(ctx context.Context, symbols ...string) (*descriptorpb.FileDescriptorSet, error) {
 	client := grpcreflect.NewClientAuto(ctx, rc.Conn)
 
-	services, err := client.ListServices()
-	if err != nil {
-		return nil, fmt.Errorf("can't list services: %w", err)
+	var files []*desc.FileDescriptor
+	if len(symbols) > 0 {
+		for _, symbol := range symbols {
+			fd, err := client.FileContainingSymbol(symbol)
+			if err != nil {
+				return nil, fmt.Errorf("can't get the file containing %q: %w", symbol, err)
+			}
+			files = append(files, fd)
+		}
+	} else {
+		services, err := client.ListServices()
+		if err != nil {
+			return nil, fmt.Errorf("can't list services: %w", err)
+		}
+
+		for _, srv := range services {
+			srvDescriptor, err := client.ResolveService(srv)
+			if err != nil {
+				return nil, fmt.Errorf("can't get method on service %q: %w", srv, err)
+			}
+			files = append(files, srvDescriptor.GetFile())
+		}
 	}
 
-	seen := make(map[fileDescriptorLookupKey]bool, len(services))
+	seen := make(map[fileDescriptorLookupKey]bool, len(files))
 	fdset := &descriptorpb.FileDescriptorSet{
-		File: make([]*descriptorpb.FileDescriptorProto, 0, len(services)),
+		File: make([]*descriptorpb.FileDescriptorProto, 0, len(files)),
 	}
 
-	for _, srv := range services {
-		srvDescriptor, err := client.ResolveService(srv)
-		if err != nil {
-			return nil, fmt.Errorf("can't get method on service %q: %w", srv, err)
-		}
-
-		stack := []*desc.FileDescriptor{srvDescriptor.GetFile()}
+	for _, file := range files {
+		stack := []*desc.FileDescriptor{file}
 
 		for len(stack) > 0 {
 			fdp := stack[len(stack)-1]
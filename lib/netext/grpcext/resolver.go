@@ -0,0 +1,29 @@
+package grpcext
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// endpointsScheme is the scheme of the targets resolved to the static endpoints
+const endpointsScheme = "k6-endpoints"
+
+// WithEndpoints returns the target and the dial options resolving the target to the static list of
+// the endpoints (host:port), bypassing the DNS or xDS resolution. The calls are spread across the endpoints
+// in the round robin order, while the original target is still used as the authority.
+func WithEndpoints(target string, endpoints []string) (string, []grpc.DialOption) {
+	addrs := make([]resolver.Address, 0, len(endpoints))
+	for _, e := range endpoints {
+		addrs = append(addrs, resolver.Address{Addr: e})
+	}
+
+	r := manual.NewBuilderWithScheme(endpointsScheme)
+	r.InitialState(resolver.State{Addresses: addrs})
+
+	return endpointsScheme + ":///" + target, []grpc.DialOption{
+		grpc.WithResolvers(r),
+		grpc.WithAuthority(target),
+		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig":[{"round_robin":{}}]}`),
+	}
+}
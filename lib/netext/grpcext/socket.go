@@ -0,0 +1,101 @@
+package grpcext
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/netext"
+)
+
+// SocketOptions is the TCP-level tuning of the dialed connections.
+type SocketOptions struct {
+	// KeepAlive is the period of the TCP keep-alive probes, a negative one disables them,
+	// zero keeps the k6's dialer's one.
+	KeepAlive time.Duration
+	// NoDelay sets the TCP_NODELAY (it's enabled by default) if it's set.
+	NoDelay *bool
+	// LocalAddrs are the local IPs the connections are bound to, in the round robin order,
+	// e.g. to source the traffic from the multiple IPs of a multi-homed load generator.
+	LocalAddrs []net.IP
+
+	next uint32
+}
+
+// dialer returns the k6's dialer with the keep-alive period and bound to the next local address, if they're set.
+// The bytes read and written are still counted by the k6's dialer.
+func (o *SocketOptions) dialer(d lib.DialContexter) lib.DialContexter {
+	if o == nil || (o.KeepAlive == 0 && len(o.LocalAddrs) == 0) {
+		return d
+	}
+
+	tune := func(nd *net.Dialer) {
+		if o.KeepAlive != 0 {
+			nd.KeepAlive = o.KeepAlive
+		}
+
+		if len(o.LocalAddrs) > 0 {
+			n := atomic.AddUint32(&o.next, 1)
+			nd.LocalAddr = &net.TCPAddr{IP: o.LocalAddrs[(n-1)%uint32(len(o.LocalAddrs))]}
+		}
+	}
+
+	switch kd := d.(type) {
+	case *netext.Dialer:
+		tuned := &netext.Dialer{
+			Dialer:           kd.Dialer,
+			Resolver:         kd.Resolver,
+			Blacklist:        kd.Blacklist,
+			BlockedHostnames: kd.BlockedHostnames,
+			Hosts:            kd.Hosts,
+		}
+		tune(&tuned.Dialer)
+
+		return countingDialer{dialer: tuned, counter: kd}
+	case *net.Dialer:
+		tuned := *kd
+		tune(&tuned)
+
+		return &tuned
+	default:
+		return d
+	}
+}
+
+// apply sets the options of the established connection
+func (o *SocketOptions) apply(conn net.Conn) error {
+	if o == nil || o.NoDelay == nil {
+		return nil
+	}
+
+	if kc, ok := conn.(*netext.Conn); ok {
+		conn = kc.Conn
+	}
+
+	if tc, ok := conn.(*net.TCPConn); ok {
+		return tc.SetNoDelay(*o.NoDelay)
+	}
+
+	return nil
+}
+
+// countingDialer counts the bytes of the dialer's connections by the counter's counters
+type countingDialer struct {
+	dialer  *netext.Dialer
+	counter *netext.Dialer
+}
+
+func (d countingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if kc, ok := conn.(*netext.Conn); ok {
+		conn = &netext.Conn{Conn: kc.Conn, BytesRead: &d.counter.BytesRead, BytesWritten: &d.counter.BytesWritten}
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,80 @@
+package grpcext
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/netext"
+)
+
+func TestSocketDialer(t *testing.T) {
+	t.Parallel()
+
+	echoAddr := startEcho(t)
+	k6Dialer := netext.NewDialer(net.Dialer{}, nil)
+	state := &lib.State{Dialer: k6Dialer}
+
+	noDelay := false
+	socket := &SocketOptions{
+		KeepAlive:  -1,
+		NoDelay:    &noDelay,
+		LocalAddrs: []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	dial := SocketDialer(func() *lib.State { return state }, nil, socket)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := dial(ctx, echoAddr)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	host, _, err := net.SplitHostPort(conn.LocalAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+
+	_, err = io.WriteString(conn, "ping")
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+
+	// the bytes are still counted by the k6's dialer
+	assert.Equal(t, int64(4), k6Dialer.BytesWritten)
+	assert.Equal(t, int64(4), k6Dialer.BytesRead)
+}
+
+func TestSocketOptionsDialer(t *testing.T) {
+	t.Parallel()
+
+	d := &net.Dialer{}
+
+	var socket *SocketOptions
+	assert.Same(t, d, socket.dialer(d))
+	assert.Same(t, d, (&SocketOptions{}).dialer(d))
+
+	socket = &SocketOptions{
+		KeepAlive:  time.Minute,
+		LocalAddrs: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")},
+	}
+
+	var ips []string
+	for i := 0; i < 3; i++ {
+		tuned, ok := socket.dialer(d).(*net.Dialer)
+		require.True(t, ok)
+		assert.Equal(t, time.Minute, tuned.KeepAlive)
+
+		ips = append(ips, tuned.LocalAddr.(*net.TCPAddr).IP.String())
+	}
+
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"}, ips)
+	assert.Nil(t, d.LocalAddr)
+}
@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync/atomic"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -21,6 +23,10 @@ type Stream struct {
 	methodDescriptor protoreflect.MethodDescriptor
 	raw              grpc.ClientStream
 	marshaler        protojson.MarshalOptions
+	state            *rpcState
+	trailer          atomic.Pointer[metadata.MD]
+	int64Numbers     bool
+	discardUnknown   bool
 }
 
 // ErrCanceled canceled by client (k6)
@@ -39,25 +45,56 @@ func (s *Stream) ReceiveConverted() (interface{}, error) {
 	if errConv != nil {
 		return nil, errConv
 	}
+	if s.int64Numbers {
+		int64Numbers(raw.Descriptor(), msg)
+	}
 
 	return msg, err
 }
 
+// ReceiveRaw receives a marshaled protobuf message from the stream,
+// the errors are the same as the ReceiveConverted's
+func (s *Stream) ReceiveRaw() ([]byte, error) {
+	var msg []byte
+	if err := s.recvMsg(&msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
 func (s *Stream) receive() (*dynamicpb.Message, error) {
 	msg := dynamicpb.NewMessage(s.methodDescriptor.Output())
-	err := s.raw.RecvMsg(msg)
+	err := s.recvMsg(msg)
 
 	// io.EOF means that the stream has been closed successfully
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return msg, err
+}
+
+// recvMsg receives the message, io.EOF means that the stream has been closed successfully
+// and ErrCanceled that it has been cancelled
+func (s *Stream) recvMsg(msg interface{}) error {
+	err := s.raw.RecvMsg(msg)
+	if err != nil {
+		// the trailers are received with the end of the stream
+		trailer := s.raw.Trailer()
+		s.trailer.Store(&trailer)
+	}
+
 	if err == nil || errors.Is(err, io.EOF) {
-		return msg, err
+		return err
 	}
 
 	sterr := status.Convert(err)
 	if sterr.Code() == codes.Canceled {
-		return nil, ErrCanceled
+		return ErrCanceled
 	}
 
-	return nil, err
+	return err
 }
 
 // convert converts the message to the interface{}
@@ -101,10 +138,30 @@ func (s *Stream) CloseSend() error {
 	return s.raw.CloseSend()
 }
 
+// Trailer returns the trailers of the ended stream, it's nil until the stream's end has been received
+func (s *Stream) Trailer() metadata.MD {
+	if trailer := s.trailer.Load(); trailer != nil {
+		return *trailer
+	}
+
+	return nil
+}
+
+// ErrorKind returns the kind of the stream's error, e.g. network, it's empty if the error isn't classified
+func (s *Stream) ErrorKind(err error) string {
+	return s.state.errorKind(err)
+}
+
+// TrailersOnly tells whether the stream has ended with the status only, without the headers
+func (s *Stream) TrailersOnly() bool {
+	return s.state.trailersOnly()
+}
+
 // BuildMessage builds a message from the input
 func (s *Stream) buildMessage(b []byte) (*dynamicpb.Message, error) {
 	msg := dynamicpb.NewMessage(s.methodDescriptor.Input())
-	if err := protojson.Unmarshal(b, msg); err != nil {
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: s.discardUnknown}
+	if err := unmarshaler.Unmarshal(b, msg); err != nil {
 		return nil, fmt.Errorf("can't serialise request object to protocol buffer: %w", err)
 	}
 
@@ -120,3 +177,8 @@ func (s *Stream) Send(b []byte) error {
 
 	return s.raw.SendMsg(msg)
 }
+
+// SendRaw sends the already marshaled protobuf message to the stream
+func (s *Stream) SendRaw(b []byte) error {
+	return s.raw.SendMsg(&b)
+}
@@ -0,0 +1,167 @@
+package grpcext
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrStreamLimit is the error of the RPCs rejected by the client-side limit of the concurrent streams
+var ErrStreamLimit = errors.New("rejected by the client-side limit of the concurrent streams") //nolint:gochecknoglobals
+
+// StreamLimit is the client-side limit of the connection's concurrent RPCs (the HTTP/2 streams of all
+// its channels), e.g. to emulate the clients constrained by the server-advertised MAX_CONCURRENT_STREAMS.
+type StreamLimit struct {
+	Max int64
+	// Reject rejects the RPCs above the limit with the ResourceExhausted status instead of queueing them
+	Reject bool
+
+	// QueueDuration is the time the queued RPCs have waited for the slot
+	QueueDuration *metrics.Metric
+	// Rejections counts the rejected RPCs
+	Rejections *metrics.Metric
+}
+
+// WithStreamLimit returns the dial options limiting the connection's concurrent RPCs,
+// the RPCs above the limit wait for a slot until their deadline or they're rejected.
+func WithStreamLimit(getState func() *lib.State, limit StreamLimit) []grpc.DialOption {
+	l := &streamLimiter{
+		getState: getState,
+		limit:    limit,
+		slots:    make(chan struct{}, limit.Max),
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(l.unary),
+		grpc.WithChainStreamInterceptor(l.stream),
+	}
+}
+
+type streamLimiter struct {
+	getState func() *lib.State
+	limit    StreamLimit
+	slots    chan struct{}
+}
+
+func (l *streamLimiter) unary(
+	ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+) error {
+	if err := l.acquire(ctx); err != nil {
+		return err
+	}
+	defer l.release()
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (l *streamLimiter) stream(
+	ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		l.release()
+
+		return nil, err
+	}
+
+	ls := &limitedStream{ClientStream: cs, release: l.release}
+
+	// the stream's slot is released once it's ended by the server or canceled
+	go func() {
+		<-cs.Context().Done()
+		ls.done()
+	}()
+
+	return ls, nil
+}
+
+// acquire takes a slot of the RPC, waiting for it if the RPCs above the limit are queued
+func (l *streamLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	state := vuStateOf(ctx, l.getState)
+	stateRPC, ok := rpcStateOf(ctx, state)
+
+	if l.limit.Reject {
+		if ok {
+			if state.Options.SystemTags.Has(metrics.TagStatus) {
+				stateRPC.tagsAndMeta.SetSystemTagOrMeta(metrics.TagStatus, strconv.Itoa(int(codes.ResourceExhausted)))
+			}
+			l.push(ctx, state, stateRPC, l.limit.Rejections, 1)
+		}
+
+		return status.Error(codes.ResourceExhausted, ErrStreamLimit.Error())
+	}
+
+	start := time.Now()
+	defer func() {
+		if ok {
+			l.push(ctx, state, stateRPC, l.limit.QueueDuration, metrics.D(time.Since(start)))
+		}
+	}()
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (l *streamLimiter) release() {
+	<-l.slots
+}
+
+func (l *streamLimiter) push(
+	ctx context.Context, state *lib.State, stateRPC *rpcState, metric *metrics.Metric, value float64,
+) {
+	metrics.PushIfNotDone(samplesContext(ctx), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   stateRPC.tagsAndMeta.Tags,
+		},
+		Time:     time.Now(),
+		Metadata: stateRPC.tagsAndMeta.Metadata,
+		Value:    value,
+	})
+}
+
+// limitedStream releases the slot of the stream once it has ended
+type limitedStream struct {
+	grpc.ClientStream
+
+	once    sync.Once
+	release func()
+}
+
+func (s *limitedStream) done() {
+	s.once.Do(s.release)
+}
+
+// RecvMsg releases the slot once the stream's end has been received
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.done()
+	}
+
+	return err
+}
@@ -0,0 +1,48 @@
+package grpcext
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceContext is the trace context propagated with the call, so the load-generated
+// requests could be correlated with the backends' distributed traces.
+type TraceContext struct {
+	TraceID string `js:"traceId"`
+	SpanID  string `js:"spanId"`
+}
+
+// NewTraceContext creates the context of a new sampled trace with the random ids.
+func NewTraceContext() (*TraceContext, error) {
+	var ids [24]byte
+	if _, err := rand.Read(ids[:]); err != nil {
+		return nil, fmt.Errorf("can't generate the trace ids: %w", err)
+	}
+
+	return &TraceContext{
+		TraceID: hex.EncodeToString(ids[:16]),
+		SpanID:  hex.EncodeToString(ids[16:]),
+	}, nil
+}
+
+// Traceparent returns the W3C traceparent header's value.
+func (tc *TraceContext) Traceparent() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-01"
+}
+
+// B3 returns the single b3 header's value.
+func (tc *TraceContext) B3() string {
+	return tc.TraceID + "-" + tc.SpanID + "-1"
+}
+
+// Inject sets the traceparent metadata, and the b3 one if it's asked, replacing the existing ones.
+func (tc *TraceContext) Inject(md metadata.MD, b3 bool) {
+	md.Set("traceparent", tc.Traceparent())
+
+	if b3 {
+		md.Set("b3", tc.B3())
+	}
+}
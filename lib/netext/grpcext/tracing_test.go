@@ -0,0 +1,31 @@
+package grpcext
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTraceContext(t *testing.T) {
+	t.Parallel()
+
+	tc, err := NewTraceContext()
+	require.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`), tc.Traceparent())
+
+	md := metadata.Pairs("traceparent", "stale")
+	tc.Inject(md, false)
+	assert.Equal(t, []string{tc.Traceparent()}, md.Get("traceparent"))
+	assert.Empty(t, md.Get("b3"))
+
+	tc.Inject(md, true)
+	assert.Equal(t, []string{tc.TraceID + "-" + tc.SpanID + "-1"}, md.Get("b3"))
+
+	other, err := NewTraceContext()
+	require.NoError(t, err)
+	assert.NotEqual(t, tc.TraceID, other.TraceID)
+}
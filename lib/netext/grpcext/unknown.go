@@ -0,0 +1,57 @@
+package grpcext
+
+import (
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// collectUnknown collects the message's unknown fields (e.g. added by a newer schema of the server), the fields'
+// marshaled protobuf (their tags and values) are keyed by their numbers prefixed by the paths of the messages
+// having them, e.g. the author.9 or the items.0.12.
+func collectUnknown(m protoreflect.Message, prefix string, unknown map[string][]byte) {
+	b := m.GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		vn := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if vn < 0 {
+			break
+		}
+
+		key := prefix + strconv.Itoa(int(num))
+		unknown[key] = append(unknown[key], b[:n+vn]...)
+		b = b[n+vn:]
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() == nil || fd.IsExtension() {
+			return true
+		}
+
+		path := prefix + fd.JSONName() + "."
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				return true
+			}
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				collectUnknown(mv.Message(), path+k.String()+".", unknown)
+
+				return true
+			})
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				collectUnknown(list.Get(i).Message(), path+strconv.Itoa(i)+".", unknown)
+			}
+		default:
+			collectUnknown(v.Message(), path, unknown)
+		}
+
+		return true
+	})
+}
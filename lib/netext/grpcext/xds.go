@@ -0,0 +1,246 @@
+package grpcext
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	adminpb "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	statuspb "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
+	"google.golang.org/grpc/xds/csds"
+)
+
+// The types of the xDS events.
+const (
+	// XDSUpdate is a response of the management server updating the resources
+	XDSUpdate = "update"
+	// XDSAck is the update accepted by the xDS client
+	XDSAck = "ack"
+	// XDSNack is the update rejected by the xDS client, e.g. an invalid config
+	XDSNack = "nack"
+)
+
+// xdsStatusInterval is the period of the xDS client's status polls
+const xdsStatusInterval = 100 * time.Millisecond
+
+// xdsResources are the short names of the xDS resources' types used by the gRPC
+var xdsResources = map[string]string{ //nolint:gochecknoglobals
+	"type.googleapis.com/envoy.config.listener.v3.Listener":              "listener",
+	"type.googleapis.com/envoy.config.route.v3.RouteConfiguration":       "route",
+	"type.googleapis.com/envoy.config.cluster.v3.Cluster":                "cluster",
+	"type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment": "endpoints",
+}
+
+// XDSEvent is a change of the resources watched by the process' xDS client.
+type XDSEvent struct {
+	Type string `js:"type"`
+	// Resource is the short name of the resources' type, e.g. cluster, or the type URL of the other ones
+	Resource string `js:"resource"`
+	Version  string `js:"version"`
+	// Names are the names of the updated resources
+	Names []string `js:"names"`
+	// Error is the NACK's error detail
+	Error string `js:"error"`
+	// Time is the time the update was received
+	Time time.Time `js:"time"`
+}
+
+// xdsObservers are the observers of the xDS client's updates, the xDS client is shared by the whole process
+var xdsObservers xdsObserverList //nolint:gochecknoglobals
+
+// xdsStatus polls the xDS client's status while there are the observers
+var xdsStatus xdsStatusPoller //nolint:gochecknoglobals
+
+// ObserveXDS calls the observe func with the updates of the resources watched by the process' xDS client
+// (bootstrapped by the GRPC_XDS_BOOTSTRAP or the GRPC_XDS_BOOTSTRAP_CONFIG), until the returned func is called.
+// They're read from the xDS client's status (its CSDS), which is polled, so the updates of the same resource
+// between the polls are coalesced, while their time is the time the last one was received.
+func ObserveXDS(observe func(XDSEvent)) func() {
+	remove := xdsObservers.add(observe)
+	xdsStatus.acquire()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			remove()
+			xdsStatus.release()
+		})
+	}
+}
+
+// xdsObserverList is the list of the observers of the xDS client's updates
+type xdsObserverList struct {
+	mu   sync.RWMutex
+	list map[int]func(XDSEvent)
+	next int
+}
+
+// add adds the observer until the returned func is called
+func (o *xdsObserverList) add(observe func(XDSEvent)) func() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.list == nil {
+		o.list = make(map[int]func(XDSEvent))
+	}
+
+	id := o.next
+	o.next++
+	o.list[id] = observe
+
+	return func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		delete(o.list, id)
+	}
+}
+
+func (o *xdsObserverList) notify(e XDSEvent) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	for _, observe := range o.list {
+		observe(e)
+	}
+}
+
+// xdsStatusPoller polls the status of the process' xDS client, by the CSDS server's implementation
+type xdsStatusPoller struct {
+	mu   sync.Mutex
+	refs int
+	stop chan struct{}
+}
+
+func (p *xdsStatusPoller) acquire() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.refs++
+	if p.refs == 1 {
+		p.stop = make(chan struct{})
+		go p.poll(p.stop)
+	}
+}
+
+func (p *xdsStatusPoller) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.refs--
+	if p.refs == 0 {
+		close(p.stop)
+	}
+}
+
+func (p *xdsStatusPoller) poll(stop <-chan struct{}) {
+	// the CSDS server holds the process' xDS client, it's empty if the xDS isn't bootstrapped
+	server, err := csds.NewClientStatusDiscoveryServer()
+	if err != nil {
+		return
+	}
+	defer server.Close()
+
+	ticker := time.NewTicker(xdsStatusInterval)
+	defer ticker.Stop()
+
+	seen := make(map[string]xdsResourceStatus)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		resp, err := server.FetchClientStatus(context.Background(), &statuspb.ClientStatusRequest{})
+		if err != nil {
+			continue
+		}
+
+		for _, e := range xdsStatusEvents(resp, seen) {
+			xdsObservers.notify(e)
+		}
+	}
+}
+
+// xdsResourceStatus is the resource's status seen by the last poll
+type xdsResourceStatus struct {
+	// version is the accepted version, nacked is the last rejected one
+	version string
+	nacked  string
+}
+
+// xdsStatusEvents returns the events of the resources' changes since the seen status, which is updated.
+// The resources updated with the same version are reported by the same event, like the management server sends them.
+func xdsStatusEvents(resp *statuspb.ClientStatusResponse, seen map[string]xdsResourceStatus) []XDSEvent {
+	var events []XDSEvent
+
+	add := func(e XDSEvent, name string) {
+		for i := range events {
+			prev := &events[i]
+			if prev.Type == e.Type && prev.Resource == e.Resource && prev.Version == e.Version && prev.Error == e.Error {
+				prev.Names = append(prev.Names, name)
+				if e.Time.After(prev.Time) {
+					prev.Time = e.Time
+				}
+
+				return
+			}
+		}
+
+		e.Names = []string{name}
+		events = append(events, e)
+	}
+
+	for _, config := range resp.GetConfig() {
+		for _, x := range config.GetGenericXdsConfigs() {
+			key := x.GetTypeUrl() + "\x00" + x.GetName()
+			prev := seen[key]
+			resource := xdsResourceName(x.GetTypeUrl())
+
+			cur := xdsResourceStatus{version: prev.version, nacked: prev.nacked}
+			switch x.GetClientStatus() {
+			case adminpb.ClientResourceStatus_ACKED:
+				cur.version = x.GetVersionInfo()
+				if cur.version != prev.version {
+					received := x.GetLastUpdated().AsTime()
+					add(XDSEvent{Type: XDSUpdate, Resource: resource, Version: cur.version, Time: received}, x.GetName())
+					add(XDSEvent{Type: XDSAck, Resource: resource, Version: cur.version, Time: received}, x.GetName())
+				}
+			case adminpb.ClientResourceStatus_NACKED:
+				failure := x.GetErrorState()
+				cur.nacked = failure.GetVersionInfo()
+				if cur.nacked != prev.nacked {
+					received := failure.GetLastUpdateAttempt().AsTime()
+					add(XDSEvent{Type: XDSUpdate, Resource: resource, Version: cur.nacked, Time: received}, x.GetName())
+					add(XDSEvent{
+						Type: XDSNack, Resource: resource, Version: cur.nacked, Error: failure.GetDetails(), Time: received,
+					}, x.GetName())
+				}
+			default:
+				// the requested resources aren't received yet, the other statuses aren't updates
+			}
+
+			seen[key] = cur
+		}
+	}
+
+	for i := range events {
+		sort.Strings(events[i].Names)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	return events
+}
+
+func xdsResourceName(typeURL string) string {
+	if name, ok := xdsResources[typeURL]; ok {
+		return name
+	}
+
+	return typeURL
+}
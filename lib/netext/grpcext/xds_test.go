@@ -0,0 +1,257 @@
+package grpcext
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	adminpb "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointpb "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerpb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	faultpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	routerpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	discoverypb "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	statuspb "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	_ "google.golang.org/grpc/xds" // registers the xds: resolver
+)
+
+const (
+	clusterTypeURL  = "type.googleapis.com/envoy.config.cluster.v3.Cluster"
+	listenerTypeURL = "type.googleapis.com/envoy.config.listener.v3.Listener"
+)
+
+func TestXDSStatusEvents(t *testing.T) {
+	t.Parallel()
+
+	received := time.Unix(1700000000, 0).UTC()
+	status := func(configs ...*statuspb.ClientConfig_GenericXdsConfig) *statuspb.ClientStatusResponse {
+		return &statuspb.ClientStatusResponse{
+			Config: []*statuspb.ClientConfig{{GenericXdsConfigs: configs}},
+		}
+	}
+	acked := func(typeURL, name, version string, at time.Time) *statuspb.ClientConfig_GenericXdsConfig {
+		return &statuspb.ClientConfig_GenericXdsConfig{
+			TypeUrl: typeURL, Name: name, VersionInfo: version,
+			ClientStatus: adminpb.ClientResourceStatus_ACKED, LastUpdated: timestamppb.New(at),
+		}
+	}
+
+	seen := make(map[string]xdsResourceStatus)
+
+	// the requested resources aren't updates
+	events := xdsStatusEvents(status(&statuspb.ClientConfig_GenericXdsConfig{
+		TypeUrl: clusterTypeURL, Name: "a", ClientStatus: adminpb.ClientResourceStatus_REQUESTED,
+	}), seen)
+	assert.Empty(t, events)
+
+	// the resources updated by the same response are reported together
+	events = xdsStatusEvents(status(
+		acked(clusterTypeURL, "b", "1", received),
+		acked(clusterTypeURL, "a", "1", received),
+		acked(listenerTypeURL, "svc", "1", received.Add(-time.Second)),
+	), seen)
+	require.Len(t, events, 4)
+	assert.Equal(t, XDSEvent{
+		Type: XDSUpdate, Resource: "listener", Version: "1", Names: []string{"svc"}, Time: received.Add(-time.Second),
+	}, events[0])
+	assert.Equal(t, XDSEvent{
+		Type: XDSAck, Resource: "listener", Version: "1", Names: []string{"svc"}, Time: received.Add(-time.Second),
+	}, events[1])
+	assert.Equal(t, XDSEvent{Type: XDSUpdate, Resource: "cluster", Version: "1", Names: []string{"a", "b"}, Time: received}, events[2])
+	assert.Equal(t, XDSEvent{Type: XDSAck, Resource: "cluster", Version: "1", Names: []string{"a", "b"}, Time: received}, events[3])
+
+	// the unchanged resources aren't reported again, the rejected versions are
+	later := received.Add(time.Second)
+	events = xdsStatusEvents(status(
+		acked(clusterTypeURL, "a", "1", received),
+		&statuspb.ClientConfig_GenericXdsConfig{
+			TypeUrl: clusterTypeURL, Name: "b", VersionInfo: "1", ClientStatus: adminpb.ClientResourceStatus_NACKED,
+			ErrorState: &adminpb.UpdateFailureState{
+				VersionInfo: "2", Details: "invalid cluster", LastUpdateAttempt: timestamppb.New(later),
+			},
+		},
+		acked(listenerTypeURL, "svc", "1", received.Add(-time.Second)),
+	), seen)
+	require.Len(t, events, 2)
+	assert.Equal(t, XDSEvent{Type: XDSUpdate, Resource: "cluster", Version: "2", Names: []string{"b"}, Time: later}, events[0])
+	assert.Equal(t, XDSEvent{
+		Type: XDSNack, Resource: "cluster", Version: "2", Names: []string{"b"}, Error: "invalid cluster", Time: later,
+	}, events[1])
+
+	// the accepted version following the rejected one is reported
+	events = xdsStatusEvents(status(acked(clusterTypeURL, "b", "3", later)), seen)
+	require.Len(t, events, 2)
+	assert.Equal(t, XDSAck, events[1].Type)
+	assert.Equal(t, "3", events[1].Version)
+}
+
+// xdsChildEnv marks the test's process started with the xDS bootstrap of the parent's management server
+const xdsChildEnv = "K6_TEST_XDS_CHILD"
+
+// runXDSChild runs the test again in a child process, with the xDS client bootstrapped with a management
+// server serving the resources. The process' xDS client is bootstrapped once, by the environment read
+// at the gRPC's init, so the test's process can't use it.
+func runXDSChild(t *testing.T, resources map[resource.Type][]types.Resource) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	snapshots := cache.NewSnapshotCache(false, cache.IDHash{}, nil)
+	snapshot, err := cache.NewSnapshot("1", resources)
+	require.NoError(t, err)
+	require.NoError(t, snapshots.SetSnapshot(ctx, "k6-test", snapshot))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	discoverypb.RegisterAggregatedDiscoveryServiceServer(srv, server.NewServer(ctx, snapshots, nil))
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^"+t.Name()+"$", "-test.count=1") //nolint:gosec
+	cmd.Env = append(os.Environ(), xdsChildEnv+"=1", "GRPC_XDS_BOOTSTRAP_CONFIG="+fmt.Sprintf(`{
+		"xds_servers": [{"server_uri": %q, "channel_creds": [{"type": "insecure"}], "server_features": ["xds_v3"]}],
+		"node": {"id": "k6-test"}
+	}`, lis.Addr().String()))
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func TestObserveXDSClient(t *testing.T) { //nolint:paralleltest // it observes the process-wide xDS client
+	if os.Getenv(xdsChildEnv) != "" {
+		observeXDSClient(t)
+
+		return
+	}
+
+	// the listener without the api_listener is rejected by the gRPC's xDS client
+	runXDSChild(t, map[resource.Type][]types.Resource{
+		resource.ListenerType: {&listenerpb.Listener{Name: "svc"}},
+	})
+}
+
+func observeXDSClient(t *testing.T) {
+	events := make(chan XDSEvent, 100)
+	stop := ObserveXDS(func(e XDSEvent) {
+		select {
+		case events <- e:
+		default:
+		}
+	})
+	defer stop()
+
+	cc, err := grpc.Dial("xds:///svc", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close() //nolint:errcheck
+	cc.Connect()
+
+	var observed []string
+	timeout := time.After(5 * time.Second)
+	for len(observed) < 2 {
+		select {
+		case e := <-events:
+			assert.Equal(t, "listener", e.Resource)
+			assert.Equal(t, []string{"svc"}, e.Names)
+			assert.Equal(t, "1", e.Version)
+			observed = append(observed, e.Type)
+		case <-timeout:
+			t.Fatalf("the xDS updates aren't observed, got %v", observed)
+		}
+	}
+
+	assert.Equal(t, []string{XDSUpdate, XDSNack}, observed)
+}
+
+func TestFaultInjectedByXDS(t *testing.T) { //nolint:paralleltest // it uses the process-wide xDS client
+	if os.Getenv(xdsChildEnv) != "" {
+		invokeXDSFault(t)
+
+		return
+	}
+
+	// the gRPC's fault filter aborts all the RPCs routed by the listener
+	fault, err := anypb.New(&faultpb.HTTPFault{
+		Abort: &faultpb.FaultAbort{
+			ErrorType:  &faultpb.FaultAbort_GrpcStatus{GrpcStatus: uint32(codes.Unavailable)},
+			Percentage: &typepb.FractionalPercent{Numerator: 100, Denominator: typepb.FractionalPercent_HUNDRED},
+		},
+	})
+	require.NoError(t, err)
+	router, err := anypb.New(&routerpb.Router{})
+	require.NoError(t, err)
+	manager, err := anypb.New(&hcmpb.HttpConnectionManager{
+		RouteSpecifier: &hcmpb.HttpConnectionManager_RouteConfig{RouteConfig: &routepb.RouteConfiguration{
+			Name: "svc",
+			VirtualHosts: []*routepb.VirtualHost{{
+				Name:    "svc",
+				Domains: []string{"*"},
+				Routes: []*routepb.Route{{
+					Match: &routepb.RouteMatch{PathSpecifier: &routepb.RouteMatch_Prefix{Prefix: ""}},
+					Action: &routepb.Route_Route{Route: &routepb.RouteAction{
+						ClusterSpecifier: &routepb.RouteAction_Cluster{Cluster: "svc"},
+					}},
+				}},
+			}},
+		}},
+		HttpFilters: []*hcmpb.HttpFilter{
+			{Name: "envoy.filters.http.fault", ConfigType: &hcmpb.HttpFilter_TypedConfig{TypedConfig: fault}},
+			{Name: "envoy.filters.http.router", ConfigType: &hcmpb.HttpFilter_TypedConfig{TypedConfig: router}},
+		},
+	})
+	require.NoError(t, err)
+
+	runXDSChild(t, map[resource.Type][]types.Resource{
+		resource.ListenerType: {&listenerpb.Listener{
+			Name:        "svc",
+			ApiListener: &listenerpb.ApiListener{ApiListener: manager},
+		}},
+		resource.ClusterType: {&clusterpb.Cluster{
+			Name:                 "svc",
+			ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_EDS},
+			EdsClusterConfig: &clusterpb.Cluster_EdsClusterConfig{
+				EdsConfig: &corepb.ConfigSource{
+					ConfigSourceSpecifier: &corepb.ConfigSource_Ads{Ads: &corepb.AggregatedConfigSource{}},
+				},
+			},
+		}},
+		resource.EndpointType: {&endpointpb.ClusterLoadAssignment{ClusterName: "svc"}},
+	})
+}
+
+func invokeXDSFault(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cc, err := grpc.Dial("xds:///svc", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close() //nolint:errcheck
+
+	_, err = grpc_health_v1.NewHealthClient(cc).Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.True(t, IsFaultInjected(err), err)
+}